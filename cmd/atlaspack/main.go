@@ -0,0 +1,116 @@
+// Command atlaspack はディレクトリ内の画像をテクスチャアトラスへ詰め込み、
+// ページ画像とアトラス定義を書き出す。領域名は -prefix を付けた各画像の相対パスになる。
+//
+//	atlaspack -o themes/beach/assets/atlas/enemies.atlas.yaml -prefix assets/enemies themes/beach/assets/enemies
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg" // JPEG デコーダの登録
+	"image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"muscle-dreamer/internal/atlas"
+)
+
+func main() {
+	output := flag.String("o", "", "書き出すアトラス定義 (*"+atlas.Ext+")。ページは同じディレクトリに書き出す")
+	prefix := flag.String("prefix", "", "領域名の前に付けるパス (例: assets/enemies)")
+	pageSize := flag.Int("page", atlas.DefaultPageSize, "ページの一辺 (ピクセル)")
+	padding := flag.Int("padding", 1, "スプライト周囲の余白 (ピクセル)")
+	flag.Parse()
+
+	if flag.NArg() != 1 || !strings.HasSuffix(*output, atlas.Ext) {
+		fmt.Fprintln(os.Stderr, "usage: atlaspack -o name"+atlas.Ext+" [-prefix path] [-page size] [-padding px] dir")
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *output, *prefix, atlas.Options{PageSize: *pageSize, Padding: *padding}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output, prefix string, opts atlas.Options) error {
+	sprites, err := readSprites(os.DirFS(dir), prefix)
+	if err != nil {
+		return err
+	}
+	if len(sprites) == 0 {
+		return fmt.Errorf("%s に画像がありません", dir)
+	}
+	result, err := atlas.Pack(sprites, opts)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(output), atlas.Ext)
+	def := &atlas.Atlas{Regions: result.Regions}
+	for i, page := range result.Pages {
+		name := fmt.Sprintf("%s_%d.png", base, i)
+		if err := writePNG(filepath.Join(filepath.Dir(output), name), page); err != nil {
+			return err
+		}
+		def.Pages = append(def.Pages, name)
+	}
+	data, err := def.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d スプライト, %d ページ\n", output, len(sprites), len(result.Pages))
+	return nil
+}
+
+// readSprites - PNG と JPEG を名前順に読み込む
+func readSprites(fsys fs.FS, prefix string) ([]atlas.Sprite, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			switch strings.ToLower(path.Ext(p)) {
+			case ".png", ".jpg", ".jpeg":
+				names = append(names, p)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	sprites := make([]atlas.Sprite, 0, len(names))
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sprites = append(sprites, atlas.Sprite{Name: path.Join(prefix, name), Image: img})
+	}
+	return sprites, nil
+}
+
+func writePNG(name string, img image.Image) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
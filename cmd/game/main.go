@@ -1,12 +1,67 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+
+	"muscle-dreamer/internal/config"
 	"muscle-dreamer/internal/core"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/settings"
 )
 
 func main() {
-	game := core.NewGame()
+	configPath := flag.String("config", config.DefaultPath, "同梱設定ファイルのパス")
+	printConfig := flag.Bool("print-config", false, "実効設定と各値の出どころを表示して終了する")
+	themeName := flag.String("theme", "", "起動時に有効にするテーマのID (themes/ 以下のディレクトリ名)")
+	debug := flag.Bool("debug", false, "設定・テーマYAMLのホットリロードなど開発用機能を有効にする")
+	overrides := config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	userDir := config.UserDir()
+	loadConfig := func() (*config.Layered, error) {
+		return config.LoadLayered(config.Options{
+			BasePath: *configPath,
+			UserDir:  userDir,
+			Environ:  os.Environ(),
+			Flags:    overrides,
+		})
+	}
+	layered, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(layered.Warnings) > 0 {
+		log.Print(schema.FormatAll(layered.Warnings, schema.LangFromEnv()))
+	}
+	if *printConfig {
+		fmt.Print(layered.Report())
+		return
+	}
+
+	modState, modData := "", ""
+	if userDir != "" {
+		modState = filepath.Join(userDir, mod.StateFile)
+		modData = filepath.Join(userDir, "mods")
+	}
+	game := core.NewGame(settings.NewService(layered, userDir), core.InitOptions{
+		ConfigPath:   *configPath,
+		ThemePath:    "themes",
+		ModPath:      "mods",
+		EnableDebug:  *debug,
+		ModStatePath: modState,
+		ModDataPath:  modData,
+		LoadConfig:   loadConfig,
+	})
+	if *themeName != "" {
+		if err := game.SetTheme(*themeName); err != nil {
+			log.Fatal(err)
+		}
+	}
 	if err := game.Run(); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,70 @@
+// Command new-theme は新しいテーマの雛形を作成する。スキーマを満たす theme.yaml、
+// コンテンツ作成ガイドのディレクトリ構造、正しい大きさの仮のスプライト、
+// supported_languages の言語ごとのテキストを生成し、そのまま検証を通る状態にする。
+//
+//	new-theme [-themes dir] [-name "My Theme"] [-author name] [-languages ja,en] theme_id
+//
+// 既にあるディレクトリには書き出さない。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"muscle-dreamer/internal/scaffold"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/themecheck"
+)
+
+func main() {
+	themesDir := flag.String("themes", "themes", "テーマを作成するディレクトリ")
+	name := flag.String("name", "", "テーマの表示名 (省略時はテーマID)")
+	author := flag.String("author", "", "作者名")
+	languages := flag.String("languages", strings.Join(scaffold.DefaultLanguages, ","), "対応する言語 (カンマ区切り、先頭が既定の言語)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: new-theme [-themes dir] [-name name] [-author name] [-languages ja,en] theme_id")
+		os.Exit(2)
+	}
+	id := flag.Arg(0)
+
+	s, err := scaffold.Theme(scaffold.Options{
+		ID:        id,
+		Name:      *name,
+		Author:    *author,
+		Languages: splitList(*languages),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	dir := filepath.Join(*themesDir, id)
+	if err := s.Write(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// 生成したテーマを validate-theme と同じ手順で確認する
+	r := themecheck.Check(os.DirFS(*themesDir), id, themecheck.Options{Lang: schema.LangFromEnv()})
+	if !r.OK() {
+		_ = r.WriteText(os.Stderr)
+		os.Exit(1)
+	}
+	fmt.Printf("%s を作成しました (ファイル %d 件)\n", dir, len(s.Files))
+	fmt.Printf("  検証: go run ./cmd/validate-theme -themes %s %s\n", *themesDir, id)
+	fmt.Printf("  起動: go run ./cmd/game -theme %s\n", id)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+// Command schemacheck はゲーム設定・テーマ・MODのYAMLをスキーマで検証し、
+// 見つかった全ての問題を位置・エラーコード・修正案とともに表示する。
+//
+//	schemacheck [-lang ja|en] [-kind game|theme|mod] file...
+//
+// -kind を省略した場合はファイル名 (game.yaml / theme.yaml / mod.yaml) から判定する。
+// エラーが1件でもあれば終了コード1を返す。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"muscle-dreamer/internal/schema"
+)
+
+var schemas = map[string]func() *schema.Node{
+	"game":  schema.GameSchema,
+	"theme": schema.ThemeSchema,
+	"mod":   schema.ModSchema,
+}
+
+func main() {
+	lang := flag.String("lang", schema.LangFromEnv(), "メッセージの言語 (ja, en)")
+	kind := flag.String("kind", "", "ファイルの種類 (game, theme, mod)。省略時はファイル名から判定")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: schemacheck [-lang ja|en] [-kind game|theme|mod] file...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		root, err := schemaFor(*kind, path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+
+		issues := schema.Validate(path, data, root)
+		fmt.Print(schema.FormatAll(issues, *lang))
+		if schema.HasErrors(issues) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// schemaFor - 種類の指定かファイル名から使用するスキーマを決める
+func schemaFor(kind, path string) (*schema.Node, error) {
+	if kind == "" {
+		switch filepath.Base(path) {
+		case "game.yaml":
+			kind = "game"
+		case "theme.yaml":
+			kind = "theme"
+		case "mod.yaml":
+			kind = "mod"
+		default:
+			return nil, fmt.Errorf("%s: 種類を判定できません。-kind を指定してください", path)
+		}
+	}
+	build, ok := schemas[kind]
+	if !ok {
+		return nil, fmt.Errorf("未知の種類です: %s", kind)
+	}
+	return build(), nil
+}
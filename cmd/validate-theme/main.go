@@ -0,0 +1,107 @@
+// Command validate-theme はテーマディレクトリを検証する。theme.yaml のスキーマ
+// (ID・バージョン・フレーム数・出現率・色の形式など) と依存関係に加え、参照されている
+// アセットが存在してデコードできるか、パフォーマンス設定が推奨の範囲かを調べる。
+//
+//	validate-theme [-themes dir] [-game-version 1.0.0] [-lang ja|en] [-json] [theme...]
+//
+// 引数はテーマのID (-themes の下のディレクトリ名かアーカイブ名) か、テーマディレクトリ・
+// テーマのアーカイブ (.mdpk) のパス。パスを指定した場合は、その親ディレクトリから依存テーマを探す。引数を省略すると
+// -themes の下の全てのテーマを検証する。-json を指定すると CI 向けに JSON で出力する。
+// エラーが1件でもあれば終了コード1を返す (警告だけなら0)。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/themecheck"
+	"muscle-dreamer/internal/vfs"
+)
+
+func main() {
+	themesDir := flag.String("themes", "themes", "テーマを置くディレクトリ")
+	gameVersion := flag.String("game-version", "", "テーマの game_version と照合するゲームのバージョン (省略時は照合しない)")
+	lang := flag.String("lang", schema.LangFromEnv(), "メッセージの言語 (ja, en)")
+	asJSON := flag.Bool("json", false, "結果を JSON で出力する")
+	flag.Parse()
+
+	targets := flag.Args()
+	if len(targets) == 0 {
+		root := os.DirFS(*themesDir)
+		m := theme.NewManager(root, vfs.New(), theme.Options{})
+		// 壊れたアーカイブも一覧に入れ、検証結果として報告する
+		m.Install()
+		targets = append(m.GetAvailableThemes(), brokenArchives(root, m.GetAvailableThemes())...)
+		sort.Strings(targets)
+		if len(targets) == 0 {
+			fmt.Fprintf(os.Stderr, "%s にテーマがありません\n", *themesDir)
+			fmt.Fprintln(os.Stderr, "usage: validate-theme [-themes dir] [-game-version version] [-lang ja|en] [-json] [theme...]")
+			os.Exit(2)
+		}
+	}
+
+	opts := themecheck.Options{GameVersion: *gameVersion, Lang: *lang}
+	reports := make([]*themecheck.Report, 0, len(targets))
+	for _, target := range targets {
+		dir, name := locate(*themesDir, target)
+		reports = append(reports, themecheck.Check(os.DirFS(dir), name, opts))
+	}
+
+	var err error
+	if *asJSON {
+		err = themecheck.WriteJSON(os.Stdout, reports)
+	} else {
+		for _, r := range reports {
+			if err = r.WriteText(os.Stdout); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, r := range reports {
+		if !r.OK() {
+			os.Exit(1)
+		}
+	}
+}
+
+// brokenArchives - インストールできなかったアーカイブのテーマID
+func brokenArchives(root fs.FS, installed []string) []string {
+	entries, _ := fs.ReadDir(root, ".")
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), pack.Ext); ok && !e.IsDir() && !slices.Contains(installed, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// locate - 引数がテーマディレクトリかアーカイブのパスなら親ディレクトリとテーマのIDに分け、
+// そうでなければ -themes の下のテーマのIDとして扱う
+func locate(themesDir, target string) (dir, name string) {
+	if id, ok := strings.CutSuffix(filepath.Base(target), pack.Ext); ok {
+		if abs, err := filepath.Abs(target); err == nil {
+			return filepath.Dir(abs), id
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, theme.FileName)); err == nil {
+		abs, err := filepath.Abs(target)
+		if err == nil {
+			return filepath.Dir(abs), filepath.Base(abs)
+		}
+	}
+	return themesDir, target
+}
@@ -0,0 +1,142 @@
+// Package anim はテーマで定義されたアニメーションクリップを扱う。
+//
+// クリップはテクスチャ (アトラスまたは切り分けたスプライトシート) 上の領域を
+// フレームとして順に並べたもので、フレームごとにイベントを持てる。
+package anim
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"muscle-dreamer/internal/atlas"
+)
+
+// Spec - theme.yaml の animations の1項目
+type Spec struct {
+	FrameCount    int            `yaml:"frame_count"`
+	FrameDuration float64        `yaml:"frame_duration"` // 秒
+	Loop          bool           `yaml:"loop"`
+	FrameWidth    int            `yaml:"frame_width"`  // 省略時はシートを横一列に等分する
+	FrameHeight   int            `yaml:"frame_height"` // 省略時はシートの高さ
+	Events        map[string]int `yaml:"events"`       // イベント名と発生するフレーム (0 始まり)
+}
+
+// Grid - シートの切り分け方
+func (s Spec) Grid() atlas.Grid {
+	return atlas.Grid{Frames: s.FrameCount, FrameWidth: s.FrameWidth, FrameHeight: s.FrameHeight}
+}
+
+// Clip - 名前付きのアニメーション
+type Clip struct {
+	Name          string
+	Texture       string   // フレームの領域を持つテクスチャ
+	Frames        []string // 各フレームの領域名
+	FrameDuration time.Duration
+	Loop          bool
+	Events        map[int][]string // フレーム番号ごとのイベント名 (名前順)
+}
+
+// NewClip - スプライトシート sheet を切り分けたテクスチャからクリップを作成する
+func NewClip(name, texture, sheet string, spec Spec) (*Clip, error) {
+	if spec.FrameCount <= 0 {
+		return nil, fmt.Errorf("アニメーション %s のフレーム数が不正です: %d", name, spec.FrameCount)
+	}
+	// 1ナノ秒未満は Duration に変換すると0になり、FrameAt で0除算になる
+	duration := time.Duration(spec.FrameDuration * float64(time.Second))
+	if !(spec.FrameDuration > 0) || duration <= 0 {
+		return nil, fmt.Errorf("アニメーション %s のフレーム時間が不正です: %v", name, spec.FrameDuration)
+	}
+	c := &Clip{
+		Name:          name,
+		Texture:       texture,
+		Frames:        make([]string, spec.FrameCount),
+		FrameDuration: duration,
+		Loop:          spec.Loop,
+		Events:        make(map[int][]string),
+	}
+	for i := range c.Frames {
+		c.Frames[i] = atlas.FrameName(sheet, i)
+	}
+	for event, frame := range spec.Events {
+		if frame < 0 || frame >= spec.FrameCount {
+			return nil, fmt.Errorf("アニメーション %s のイベント %s のフレーム %d が範囲外です", name, event, frame)
+		}
+		c.Events[frame] = append(c.Events[frame], event)
+	}
+	for _, events := range c.Events {
+		sort.Strings(events)
+	}
+	return c, nil
+}
+
+// Duration - 1周の長さ
+func (c *Clip) Duration() time.Duration {
+	return c.FrameDuration * time.Duration(len(c.Frames))
+}
+
+// FrameAt - 再生開始から t 経過したときの通し番号。ループするクリップは周回ごとに
+// 増え続け、ループしないクリップは最後のフレームで止まる。
+func (c *Clip) FrameAt(t time.Duration) int {
+	frame := int(max(t, 0) / c.FrameDuration)
+	if !c.Loop {
+		frame = min(frame, len(c.Frames)-1)
+	}
+	return frame
+}
+
+// Region - 通し番号のフレームの領域名
+func (c *Clip) Region(frame int) string {
+	return c.Frames[frame%len(c.Frames)]
+}
+
+// Finished - ループしないクリップが最後まで再生されたか
+func (c *Clip) Finished(t time.Duration) bool {
+	return !c.Loop && t >= c.Duration()
+}
+
+// AppendEvents - 通し番号 from の次から to までのフレームに入ったときのイベントを dst に追加する。
+// 1回の更新で何周もした場合でも、各フレームのイベントは1周分しか発生させない。
+func (c *Clip) AppendEvents(dst []string, from, to int) []string {
+	if len(c.Events) == 0 {
+		return dst
+	}
+	for frame := max(from+1, to-len(c.Frames)+1); frame <= to; frame++ {
+		dst = append(dst, c.Events[frame%len(c.Frames)]...)
+	}
+	return dst
+}
+
+// Library - 名前で引けるクリップの集合
+type Library map[string]*Clip
+
+// SheetLoader - シートを切り分けて読み込み、フレームの領域を持つテクスチャ名を返す
+type SheetLoader func(sheet string, grid atlas.Grid) (texture string, err error)
+
+// Load - theme.yaml の sprite_sheets と animations からクリップを作成する。
+// アニメーションと同じ名前のシートを切り分けて使う。
+func Load(sheets map[string]string, specs map[string]Spec, load SheetLoader) (Library, error) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lib := make(Library, len(specs))
+	for _, name := range names {
+		sheet, ok := sheets[name]
+		if !ok {
+			return nil, fmt.Errorf("アニメーション %s のスプライトシートが sprite_sheets にありません", name)
+		}
+		texture, err := load(sheet, specs[name].Grid())
+		if err != nil {
+			return nil, err
+		}
+		clip, err := NewClip(name, texture, sheet, specs[name])
+		if err != nil {
+			return nil, err
+		}
+		lib[name] = clip
+	}
+	return lib, nil
+}
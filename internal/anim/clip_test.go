@@ -0,0 +1,109 @@
+package anim_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/atlas"
+)
+
+var attack = anim.Spec{FrameCount: 3, FrameDuration: 0.1, Events: map[string]int{"attack_hit": 2}}
+
+func TestClip(t *testing.T) {
+	t.Run("FramesReferToSheetRegions", func(t *testing.T) {
+		clip, err := anim.NewClip("attack", "sheet", "assets/characters/player_attack.png", attack)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"assets/characters/player_attack.png#0",
+			"assets/characters/player_attack.png#1",
+			"assets/characters/player_attack.png#2",
+		}, clip.Frames)
+		assert.Equal(t, 300*time.Millisecond, clip.Duration())
+	})
+
+	t.Run("NonLoopingClipStopsAtLastFrame", func(t *testing.T) {
+		clip, err := anim.NewClip("attack", "sheet", "attack.png", attack)
+		require.NoError(t, err)
+		assert.Equal(t, 1, clip.FrameAt(150*time.Millisecond))
+		assert.Equal(t, 2, clip.FrameAt(time.Second))
+		assert.False(t, clip.Finished(250*time.Millisecond))
+		assert.True(t, clip.Finished(300*time.Millisecond))
+	})
+
+	t.Run("LoopingClipWraps", func(t *testing.T) {
+		clip, err := anim.NewClip("idle", "sheet", "idle.png", anim.Spec{FrameCount: 4, FrameDuration: 0.25, Loop: true})
+		require.NoError(t, err)
+		frame := clip.FrameAt(1250 * time.Millisecond)
+		assert.Equal(t, 5, frame)
+		assert.Equal(t, "idle.png#1", clip.Region(frame))
+		assert.False(t, clip.Finished(time.Hour))
+	})
+
+	t.Run("EventsFireOncePerFrameEntry", func(t *testing.T) {
+		clip, err := anim.NewClip("swing", "sheet", "swing.png", anim.Spec{
+			FrameCount: 3, FrameDuration: 0.1, Loop: true, Events: map[string]int{"whoosh": 0, "attack_hit": 2},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"whoosh"}, clip.AppendEvents(nil, -1, 0), "再生開始時に最初のフレームのイベントが発生する")
+		assert.Empty(t, clip.AppendEvents(nil, 0, 1))
+		assert.Equal(t, []string{"attack_hit", "whoosh"}, clip.AppendEvents(nil, 1, 3))
+		assert.Len(t, clip.AppendEvents(nil, 0, 1000), 2, "大きく進んでも1周分まで")
+	})
+
+	t.Run("InvalidSpec", func(t *testing.T) {
+		testCases := map[string]anim.Spec{
+			"NoFrames":         {FrameDuration: 0.1},
+			"NoDuration":       {FrameCount: 2},
+			"EventOutOfRange":  {FrameCount: 2, FrameDuration: 0.1, Events: map[string]int{"hit": 2}},
+			"NegativeDuration": {FrameCount: 2, FrameDuration: -1},
+			"BelowNanosecond":  {FrameCount: 2, FrameDuration: 1e-10},
+			"NaNDuration":      {FrameCount: 2, FrameDuration: math.NaN()},
+		}
+		for name, spec := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := anim.NewClip("clip", "sheet", "sheet.png", spec)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	sheets := map[string]string{
+		"idle":   "assets/characters/player_idle.png",
+		"attack": "assets/characters/player_attack.png",
+	}
+	specs := map[string]anim.Spec{
+		"idle":   {FrameCount: 4, FrameDuration: 0.25, Loop: true},
+		"attack": attack,
+	}
+
+	t.Run("SlicesEachSheet", func(t *testing.T) {
+		grids := make(map[string]atlas.Grid)
+		lib, err := anim.Load(sheets, specs, func(sheet string, grid atlas.Grid) (string, error) {
+			grids[sheet] = grid
+			return sheet + "#sliced", nil
+		})
+		require.NoError(t, err)
+		require.Len(t, lib, 2)
+		assert.Equal(t, "assets/characters/player_idle.png#sliced", lib["idle"].Texture)
+		assert.Equal(t, atlas.Grid{Frames: 3}, grids["assets/characters/player_attack.png"])
+	})
+
+	t.Run("MissingSheet", func(t *testing.T) {
+		_, err := anim.Load(map[string]string{}, specs, func(string, atlas.Grid) (string, error) { return "", nil })
+		assert.Error(t, err)
+	})
+
+	t.Run("LoaderError", func(t *testing.T) {
+		failure := errors.New("failure")
+		_, err := anim.Load(sheets, specs, func(string, atlas.Grid) (string, error) { return "", failure })
+		assert.ErrorIs(t, err, failure)
+	})
+}
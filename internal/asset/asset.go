@@ -0,0 +1,103 @@
+// Package asset は画像・音声・フォントの読み込みと寿命を管理する。
+package asset
+
+import (
+	"image"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	xfont "golang.org/x/image/font"
+
+	"muscle-dreamer/internal/asset/cache"
+	"muscle-dreamer/internal/asset/inspect"
+)
+
+// アセットエラーコード
+const (
+	CodeInvalidPath  = "ASSET_INVALID_PATH"
+	CodeNotFound     = "ASSET_NOT_FOUND"
+	CodeReadFailed   = "ASSET_READ_FAILED"
+	CodeDecodeFailed = "ASSET_DECODE_FAILED"
+	CodeKindMismatch = "ASSET_KIND_MISMATCH"
+	CodeUnsupported  = "ASSET_UNSUPPORTED"
+	CodeTooLarge     = inspect.CodeTooLarge
+)
+
+// Asset - アセット基底インターフェース
+type Asset interface {
+	GetPath() string
+	GetSize() int64 // 読み込み後に占有するメモリ量 (バイト)
+	IsLoaded() bool
+}
+
+// AudioClip - オーディオアセット
+type AudioClip interface {
+	Asset
+	Play() error
+	Stop() error
+	IsPlaying() bool
+	SetVolume(float64)
+	GetDuration() time.Duration
+}
+
+// Font - フォントアセット。サイズはピクセル単位。
+type Font interface {
+	Asset
+	RenderText(text string, size int) *ebiten.Image
+	Face(size int) xfont.Face
+}
+
+// base - 全アセット共通の状態
+type base struct {
+	path     string
+	size     int64
+	unloaded bool
+	sources  []string // 読み込み元のファイル。いずれかが変更されたら読み直す
+}
+
+func (b *base) GetPath() string { return b.path }
+func (b *base) GetSize() int64  { return b.size }
+func (b *base) IsLoaded() bool  { return !b.unloaded }
+
+func (b *base) sourceFiles() []string { return b.sources }
+
+// reloadable - 同じ値のまま中身を差し替えられるアセット
+type reloadable interface {
+	sourceFiles() []string
+	replace(fresh cache.Value)
+}
+
+// Image - 画像アセット。再読み込みされても同じ値のまま中身が差し替わるため、
+// 保持するのはこのハンドルにして、描画のたびに Image() を呼ぶ。
+type Image struct {
+	base
+	image *ebiten.Image
+}
+
+// newImage - RGBA 4バイト/ピクセルとしてGPUメモリ量を見積もる
+func newImage(path string, img *ebiten.Image) *Image {
+	return &Image{base: base{path: path, size: imageSize(img), sources: []string{path}}, image: img}
+}
+
+func imageSize(img *ebiten.Image) int64 {
+	return int64(img.Bounds().Dx()) * int64(img.Bounds().Dy()) * 4
+}
+
+// Image - 描画に使う ebiten.Image
+func (i *Image) Image() *ebiten.Image { return i.image }
+
+// Bounds - 画像の範囲
+func (i *Image) Bounds() image.Rectangle { return i.image.Bounds() }
+
+func (i *Image) kind() Kind { return KindImage }
+
+func (i *Image) unload() {
+	i.unloaded = true
+	i.image.Dispose()
+}
+
+func (i *Image) replace(fresh cache.Value) {
+	i.image.Dispose()
+	i.image = fresh.(*Image).image
+	i.size = fresh.(*Image).size
+}
@@ -0,0 +1,230 @@
+package asset
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"muscle-dreamer/internal/asset/cache"
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// Atlas - テクスチャアトラス。領域名 (元画像のアセットパス) からページ上の矩形を引く。
+// 同じページの領域は描画時に1回の描画命令へまとめられる。
+type Atlas struct {
+	base
+	pages   []*ebiten.Image
+	regions map[string]atlas.Region
+}
+
+func newAtlas(path string, sources []string, regions map[string]atlas.Region, pages []image.Image) *Atlas {
+	a := &Atlas{base: base{path: path, sources: sources}, regions: regions, pages: make([]*ebiten.Image, len(pages))}
+	for i, page := range pages {
+		a.pages[i] = ebiten.NewImageFromImage(page)
+		a.size += int64(page.Bounds().Dx()) * int64(page.Bounds().Dy()) * 4
+	}
+	return a
+}
+
+// Region - 領域を含むページ画像と、ページ上の矩形
+func (a *Atlas) Region(name string) (*ebiten.Image, image.Rectangle, bool) {
+	r, ok := a.regions[name]
+	if !ok {
+		return nil, image.Rectangle{}, false
+	}
+	return a.pages[r.Page], r.Rect(), true
+}
+
+// Regions - 名前順の領域名
+func (a *Atlas) Regions() []string {
+	names := make([]string, 0, len(a.regions))
+	for name := range a.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PageCount - ページ数
+func (a *Atlas) PageCount() int {
+	return len(a.pages)
+}
+
+func (a *Atlas) kind() Kind { return KindAtlas }
+
+func (a *Atlas) unload() {
+	a.unloaded = true
+	for _, page := range a.pages {
+		page.Dispose()
+	}
+}
+
+func (a *Atlas) replace(fresh cache.Value) {
+	for _, page := range a.pages {
+		page.Dispose()
+	}
+	f := fresh.(*Atlas)
+	a.pages, a.regions, a.size, a.sources = f.pages, f.regions, f.size, f.sources
+}
+
+// decodedAtlas - デコード済みでアップロード前のアトラス
+type decodedAtlas struct {
+	sources []string
+	regions map[string]atlas.Region
+	pages   []image.Image
+}
+
+// decodeAtlas - アトラス定義を解析し、定義ファイルからの相対パスにあるページを読み込む
+func decodeAtlas(m *Manager, path string, data []byte) (interface{}, error) {
+	def, err := atlas.Parse(data)
+	if err != nil {
+		return nil, gameerr.AssetError{
+			Code:      CodeDecodeFailed,
+			Message:   fmt.Sprintf("アトラスを読み込めません: %s: %v", path, err),
+			AssetPath: path,
+		}
+	}
+	pages := make([]image.Image, len(def.Pages))
+	pagePaths := def.PagePaths(path)
+	for i, pagePath := range pagePaths {
+		pageData, err := m.read(pagePath)
+		if err != nil {
+			return nil, err
+		}
+		page, err := decodeImage(m, pagePath, pageData)
+		if err != nil {
+			return nil, err
+		}
+		pages[i] = page.(image.Image)
+	}
+	for name, r := range def.Regions {
+		if !r.Rect().Add(pages[r.Page].Bounds().Min).In(pages[r.Page].Bounds()) {
+			return nil, gameerr.AssetError{
+				Code:      CodeDecodeFailed,
+				Message:   fmt.Sprintf("アトラス %s の領域 %s がページの外にあります", path, name),
+				AssetPath: path,
+			}
+		}
+	}
+	return &decodedAtlas{sources: append([]string{path}, pagePaths...), regions: def.Regions, pages: pages}, nil
+}
+
+func uploadAtlas(path string, decoded interface{}) cache.Value {
+	d := decoded.(*decodedAtlas)
+	return newAtlas(path, d.sources, d.regions, d.pages)
+}
+
+// BuildAtlas - 画像を実行時にアトラスへ詰め込む。領域名は各画像のパスになる。
+// name はキャッシュ上の名前で、UnloadAsset に渡して参照を手放す。
+// 既に同じ名前のアトラスがあれば、それを共有する。
+func (m *Manager) BuildAtlas(name string, paths []string, opts atlas.Options) (*Atlas, error) {
+	v, err := m.acquire(name, KindAtlas, func() (cache.Value, error) {
+		sprites := make([]atlas.Sprite, 0, len(paths))
+		for _, path := range paths {
+			if err := validatePath(path); err != nil {
+				return nil, err
+			}
+			data, err := m.read(path)
+			if err != nil {
+				return nil, err
+			}
+			img, err := decodeImage(m, path, data)
+			if err != nil {
+				return nil, err
+			}
+			sprites = append(sprites, atlas.Sprite{Name: path, Image: img.(image.Image)})
+		}
+		packed, err := atlas.Pack(sprites, opts)
+		if err != nil {
+			return nil, gameerr.AssetError{
+				Code:      CodeDecodeFailed,
+				Message:   fmt.Sprintf("アトラス %s を作成できません: %v", name, err),
+				AssetPath: name,
+			}
+		}
+		pages := make([]image.Image, len(packed.Pages))
+		for i, page := range packed.Pages {
+			pages[i] = page
+		}
+		return newAtlas(name, append([]string(nil), paths...), packed.Regions, pages), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Atlas), nil
+}
+
+// LoadSpriteSheet - スプライトシートを grid で切り分け、各フレームを領域に持つアトラスとして読み込む。
+// 領域名は atlas.FrameName(path, i)。切り分け方ごとに別のアセットとして扱うため、
+// 参照は返したアトラスの GetPath() を UnloadAsset に渡して手放す。
+func (m *Manager) LoadSpriteSheet(path string, grid atlas.Grid) (*Atlas, error) {
+	if err := validatePath(path); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s#%dx%dx%d", path, grid.Frames, grid.FrameWidth, grid.FrameHeight)
+	v, err := m.acquire(key, KindAtlas, func() (cache.Value, error) {
+		data, err := m.read(path)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := decodeImage(m, path, data)
+		if err != nil {
+			return nil, err
+		}
+		img := decoded.(image.Image)
+		frames, err := grid.Slice(img.Bounds().Size())
+		if err != nil {
+			return nil, gameerr.AssetError{
+				Code:      CodeDecodeFailed,
+				Message:   fmt.Sprintf("スプライトシートを切り分けられません: %s: %v", path, err),
+				AssetPath: path,
+			}
+		}
+		regions := make(map[string]atlas.Region, len(frames))
+		for i, r := range frames {
+			regions[atlas.FrameName(path, i)] = atlas.Region{X: r.Min.X, Y: r.Min.Y, W: r.Dx(), H: r.Dy()}
+		}
+		return newAtlas(key, []string{path}, regions, []image.Image{img}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Atlas), nil
+}
+
+// Sprite - 描画に使う画像と矩形を返す。texture がアトラスなら region の領域を、
+// 画像なら画像全体を返す。texture は読み込み済みである必要があり、参照は増やさない。
+func (m *Manager) Sprite(texture, region string) (*ebiten.Image, image.Rectangle, error) {
+	v, ok := m.cache.Get(texture)
+	if !ok {
+		return nil, image.Rectangle{}, gameerr.AssetError{
+			Code:      CodeNotFound,
+			Message:   fmt.Sprintf("テクスチャが読み込まれていません: %s", texture),
+			AssetPath: texture,
+		}
+	}
+	switch t := v.(type) {
+	case *Atlas:
+		if page, rect, ok := t.Region(region); ok {
+			return page, rect, nil
+		}
+	case *Image:
+		if region == "" {
+			return t.image, t.image.Bounds(), nil
+		}
+	default:
+		return nil, image.Rectangle{}, gameerr.AssetError{
+			Code:      CodeKindMismatch,
+			Message:   fmt.Sprintf("%s は画像またはアトラスではありません", texture),
+			AssetPath: texture,
+		}
+	}
+	return nil, image.Rectangle{}, gameerr.AssetError{
+		Code:      CodeNotFound,
+		Message:   fmt.Sprintf("%s に領域 %s がありません", texture, region),
+		AssetPath: texture,
+	}
+}
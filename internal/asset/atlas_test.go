@@ -0,0 +1,109 @@
+package asset_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+func TestAtlas(t *testing.T) {
+	t.Run("BuildAtlasAtRuntime", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		a, err := m.BuildAtlas("atlas:enemies", []string{"assets/player.png", "assets/enemy.png"}, atlas.Options{PageSize: 128})
+		require.NoError(t, err)
+		assert.Equal(t, 1, a.PageCount())
+		assert.Equal(t, []string{"assets/enemy.png", "assets/player.png"}, a.Regions())
+
+		player, rect, err := m.Sprite("atlas:enemies", "assets/player.png")
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(64, 32), rect.Size())
+		enemy, _, err := m.Sprite("atlas:enemies", "assets/enemy.png")
+		require.NoError(t, err)
+		assert.Same(t, player, enemy, "同じページを共有する")
+	})
+
+	t.Run("LoadAtlasFromManifest", func(t *testing.T) {
+		def := &atlas.Atlas{
+			Pages:   []string{"enemies_0.png"},
+			Regions: map[string]atlas.Region{"assets/enemies/burger.png": {X: 0, Y: 0, W: 16, H: 16}},
+		}
+		manifest, err := def.Marshal()
+		require.NoError(t, err)
+
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("theme:beach", fstest.MapFS{
+			"assets/atlas/enemies.atlas.yaml": {Data: manifest},
+			"assets/atlas/enemies_0.png":      {Data: pngData(t, 32, 32)},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+		m := asset.NewManager(fsys, asset.Options{})
+
+		a, err := m.LoadAtlas("assets/atlas/enemies.atlas.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, int64(32*32*4), a.GetSize())
+
+		_, rect, err := m.Sprite("assets/atlas/enemies.atlas.yaml", "assets/enemies/burger.png")
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 16, 16), rect)
+	})
+
+	t.Run("LoadSpriteSheetSlicesFrames", func(t *testing.T) {
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("theme:beach", fstest.MapFS{
+			"assets/characters/player_idle.png": {Data: pngData(t, 128, 48)},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+		m := asset.NewManager(fsys, asset.Options{})
+
+		sheet, err := m.LoadSpriteSheet("assets/characters/player_idle.png", atlas.Grid{Frames: 4})
+		require.NoError(t, err)
+		assert.Len(t, sheet.Regions(), 4)
+
+		_, rect, err := m.Sprite(sheet.GetPath(), atlas.FrameName("assets/characters/player_idle.png", 3))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(96, 0, 128, 48), rect)
+
+		_, err = m.LoadSpriteSheet("assets/characters/player_idle.png", atlas.Grid{Frames: 5})
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr), "幅が割り切れない")
+		assert.Equal(t, asset.CodeDecodeFailed, assetErr.Code)
+	})
+
+	t.Run("SpriteErrors", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		_, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+
+		_, rect, err := m.Sprite("assets/player.png", "")
+		require.NoError(t, err, "画像は領域名なしで全体を使う")
+		assert.Equal(t, image.Rect(0, 0, 64, 32), rect)
+
+		for _, tc := range [][2]string{{"atlas:missing", "x"}, {"assets/player.png", "head"}} {
+			_, _, err := m.Sprite(tc[0], tc[1])
+			var assetErr gameerr.AssetError
+			require.True(t, errors.As(err, &assetErr), tc)
+			assert.Equal(t, asset.CodeNotFound, assetErr.Code, tc)
+		}
+	})
+
+	t.Run("RegionOutsidePageIsDecodeError", func(t *testing.T) {
+		manifest := []byte("pages: [page.png]\nregions:\n  a: {page: 0, x: 60, y: 0, w: 16, h: 16}\n")
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("theme:beach", fstest.MapFS{
+			"assets/broken.atlas.yaml": {Data: manifest},
+			"assets/page.png":          {Data: pngData(t, 64, 64)},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+
+		_, err := asset.NewManager(fsys, asset.Options{}).LoadAtlas("assets/broken.atlas.yaml")
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, asset.CodeDecodeFailed, assetErr.Code)
+	})
+}
@@ -0,0 +1,189 @@
+package asset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+
+	"muscle-dreamer/internal/asset/cache"
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// DefaultSampleRate - 音声をデコードするサンプルレートの既定値
+const DefaultSampleRate = 44100
+
+// bytesPerFrame - デコード後の PCM のフレームのバイト数
+const bytesPerFrame = inspect.BytesPerFrame
+
+// audioContextMu - ebiten の音声コンテキストはプロセスに1つしか作れないため、作成を直列化する
+var audioContextMu sync.Mutex
+
+// audioContext - 音声コンテキストを取得する。まだなければ sampleRate で作成する。
+func audioContext(sampleRate int) *audio.Context {
+	audioContextMu.Lock()
+	defer audioContextMu.Unlock()
+	if c := audio.CurrentContext(); c != nil {
+		return c
+	}
+	return audio.NewContext(sampleRate)
+}
+
+// pcm - ワーカーでデコードした音声
+type pcm struct {
+	data       []byte
+	sampleRate int
+}
+
+// decodeAudio - 内容から形式を判定して PCM へデコードする。音声コンテキストが
+// 既にあればそのサンプルレートに、なければ Manager のサンプルレートに変換する。
+// ヘッダーから分かる長さが上限を超える場合はデコードしない。
+func decodeAudio(m *Manager, path string, data []byte) (interface{}, error) {
+	sampleRate := m.sampleRate
+	if c := audio.CurrentContext(); c != nil {
+		sampleRate = c.SampleRate()
+	}
+
+	var stream interface {
+		io.Reader
+		Length() int64
+	}
+	var err error
+	src := bytes.NewReader(data)
+	switch f := inspect.Sniff(data); f {
+	case inspect.FormatOGG:
+		stream, err = vorbis.DecodeWithSampleRate(sampleRate, src)
+	case inspect.FormatWAV:
+		stream, err = wav.DecodeWithSampleRate(sampleRate, src)
+	case inspect.FormatMP3:
+		stream, err = mp3.DecodeWithSampleRate(sampleRate, src)
+	default:
+		return nil, unsupportedFormat(path, KindAudio, f)
+	}
+	if err == nil {
+		limits := m.limitsFor(path)
+		if err := limits.CheckAudio(path, stream.Length(), sampleRate); err != nil {
+			return nil, err
+		}
+		data, err = inspect.ReadAtMost(path, stream, limits.MaxDecodedSize, "音声の展開後のサイズ")
+	}
+	var assetErr gameerr.AssetError
+	if errors.As(err, &assetErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, gameerr.AssetError{
+			Code:      CodeDecodeFailed,
+			Message:   fmt.Sprintf("音声をデコードできません: %s: %v", path, err),
+			AssetPath: path,
+		}
+	}
+	return pcm{data: data, sampleRate: sampleRate}, nil
+}
+
+func uploadAudio(path string, decoded interface{}) cache.Value {
+	p := decoded.(pcm)
+	return &audioClip{
+		base:       base{path: path, size: int64(len(p.data)), sources: []string{path}},
+		pcm:        p.data,
+		sampleRate: p.sampleRate,
+		volume:     1,
+	}
+}
+
+// audioClip - PCM を保持する音声アセット。プレイヤーは最初の Play で作成する。
+type audioClip struct {
+	base
+	pcm        []byte
+	sampleRate int
+	volume     float64
+	player     *audio.Player
+}
+
+// Play - 先頭から再生する。再生中なら頭出しする。
+func (a *audioClip) Play() error {
+	if a.unloaded {
+		return gameerr.AssetError{
+			Code:      CodeNotFound,
+			Message:   fmt.Sprintf("解放済みの音声は再生できません: %s", a.path),
+			AssetPath: a.path,
+		}
+	}
+	if a.player == nil {
+		ctx := audioContext(a.sampleRate)
+		if ctx.SampleRate() != a.sampleRate {
+			return gameerr.AssetError{
+				Code:      CodeUnsupported,
+				Message:   fmt.Sprintf("音声のサンプルレート %d が再生環境 (%d) と異なります: %s", a.sampleRate, ctx.SampleRate(), a.path),
+				AssetPath: a.path,
+			}
+		}
+		a.player = ctx.NewPlayerFromBytes(a.pcm)
+		a.player.SetVolume(a.volume)
+	} else if err := a.player.Rewind(); err != nil {
+		return err
+	}
+	a.player.Play()
+	return nil
+}
+
+// Stop - 再生を止めて先頭に戻す
+func (a *audioClip) Stop() error {
+	if a.player == nil {
+		return nil
+	}
+	a.player.Pause()
+	return a.player.Rewind()
+}
+
+// IsPlaying - 再生中か
+func (a *audioClip) IsPlaying() bool {
+	return a.player != nil && a.player.IsPlaying()
+}
+
+// SetVolume - 音量 (0〜1) を設定する。再生前に設定した値も次の Play から反映される。
+func (a *audioClip) SetVolume(volume float64) {
+	a.volume = volume
+	if a.player != nil {
+		a.player.SetVolume(volume)
+	}
+}
+
+// GetDuration - 再生時間
+func (a *audioClip) GetDuration() time.Duration {
+	if a.sampleRate == 0 {
+		return 0
+	}
+	frames := int64(len(a.pcm) / bytesPerFrame)
+	return time.Duration(frames) * time.Second / time.Duration(a.sampleRate)
+}
+
+func (a *audioClip) kind() Kind { return KindAudio }
+
+// closePlayer - 再生中のプレイヤーを破棄する。次の Play で新しい PCM から作り直す。
+func (a *audioClip) closePlayer() {
+	if a.player != nil {
+		_ = a.player.Close()
+		a.player = nil
+	}
+}
+
+func (a *audioClip) replace(fresh cache.Value) {
+	f := fresh.(*audioClip)
+	a.closePlayer()
+	a.pcm, a.sampleRate, a.size = f.pcm, f.sampleRate, f.size
+}
+
+func (a *audioClip) unload() {
+	a.unloaded = true
+	a.closePlayer()
+	a.pcm = nil
+}
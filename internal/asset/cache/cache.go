@@ -0,0 +1,264 @@
+// Package cache はパスをキーとしたアセットキャッシュを提供する。
+// 読み込みを重複排除し、参照カウントで使用中のアセットを保護しながら、
+// 参照されていないアセットをメモリ予算に収まるよう LRU で解放する。
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Value - キャッシュに格納できる値
+type Value interface {
+	GetSize() int64
+}
+
+// LoadFunc - キャッシュにない場合に値を読み込む関数
+type LoadFunc func() (Value, error)
+
+// EvictFunc - 値がキャッシュから取り除かれたときに呼ばれる関数
+type EvictFunc func(key string, v Value)
+
+// Stats - キャッシュの統計
+type Stats struct {
+	Entries int
+	Used    int64 // 格納中の値の合計サイズ (バイト)
+	Budget  int64 // 0 は無制限
+	Hits    int
+	Misses  int
+	Evicted int
+}
+
+type entry struct {
+	key   string
+	value Value
+	size  int64
+	refs  int
+	idle  *list.Element // 参照がない間だけ LRU リストに入る
+
+	ready chan struct{} // 読み込み完了で閉じられる
+	err   error
+}
+
+// Cache - 参照カウントとLRU解放を備えたキャッシュ。複数のゴルーチンから使用できる。
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	idle    *list.List // 先頭が最近解放されたもの
+	used    int64
+	budget  int64
+	onEvict EvictFunc
+	stats   Stats
+}
+
+// New - メモリ予算 (バイト、0 は無制限) を指定してキャッシュを作成する
+func New(budget int64, onEvict EvictFunc) *Cache {
+	return &Cache{
+		entries: make(map[string]*entry),
+		idle:    list.New(),
+		budget:  budget,
+		onEvict: onEvict,
+	}
+}
+
+// Acquire - 値を取得して参照を1つ増やす。キャッシュにない場合は load で読み込む。
+// 同じキーの読み込みが進行中であれば、その完了を待って結果を共有する。
+func (c *Cache) Acquire(key string, load LoadFunc) (Value, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		e.refs++
+		if e.idle != nil {
+			c.idle.Remove(e.idle)
+			e.idle = nil
+		}
+		c.stats.Hits++
+		c.mu.Unlock()
+
+		<-e.ready
+		if e.err != nil {
+			return nil, e.err
+		}
+		return e.value, nil
+	}
+
+	e := &entry{key: key, refs: 1, ready: make(chan struct{})}
+	c.entries[key] = e
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	value, err := load()
+
+	c.mu.Lock()
+	if err != nil {
+		e.err = err
+		delete(c.entries, key)
+	} else {
+		e.value = value
+		e.size = value.GetSize()
+		c.used += e.size
+	}
+	close(e.ready)
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Release - 参照を1つ減らす。参照がなくなった値はすぐには解放せず、
+// 予算を超えたときに古いものから解放する。キーが存在しなければ false を返す。
+func (c *Cache) Release(key string) bool {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok || e.refs == 0 {
+		c.mu.Unlock()
+		return false
+	}
+	e.refs--
+	if e.refs == 0 {
+		e.idle = c.idle.PushFront(e)
+	}
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return true
+}
+
+// Remove - 参照の有無にかかわらず値をキャッシュから取り除く
+func (c *Cache) Remove(key string) bool {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok || !isReady(e) {
+		c.mu.Unlock()
+		return false
+	}
+	c.removeLocked(e)
+	c.mu.Unlock()
+
+	c.notify([]*entry{e})
+	return true
+}
+
+// Resize - 値の中身が差し替えられたときにサイズを計り直し、予算を超えた分を解放する
+func (c *Cache) Resize(key string) bool {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok || !isReady(e) || e.err != nil {
+		c.mu.Unlock()
+		return false
+	}
+	size := e.value.GetSize()
+	c.used += size - e.size
+	e.size = size
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return true
+}
+
+// SetBudget - メモリ予算を変更し、超えている分を解放する
+func (c *Cache) SetBudget(budget int64) {
+	c.mu.Lock()
+	c.budget = budget
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Get - 参照を増やさずに読み込み済みの値を取得する
+func (c *Cache) Get(key string) (Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !isReady(e) || e.err != nil {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Refs - キーの参照数
+func (c *Cache) Refs(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+// Snapshot - 読み込み済みの全ての値
+func (c *Cache) Snapshot() map[string]Value {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]Value, len(c.entries))
+	for key, e := range c.entries {
+		if isReady(e) && e.err == nil {
+			out[key] = e.value
+		}
+	}
+	return out
+}
+
+// Stats - 現在の統計
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = len(c.entries)
+	s.Used = c.used
+	s.Budget = c.budget
+	return s
+}
+
+// evictLocked - 予算を超えている間、参照のない値を古い順に取り除く
+func (c *Cache) evictLocked() []*entry {
+	if c.budget <= 0 {
+		return nil
+	}
+	var evicted []*entry
+	for c.used > c.budget {
+		back := c.idle.Back()
+		if back == nil {
+			break // 残りは全て使用中
+		}
+		e := back.Value.(*entry)
+		c.removeLocked(e)
+		c.stats.Evicted++
+		evicted = append(evicted, e)
+	}
+	return evicted
+}
+
+func (c *Cache) removeLocked(e *entry) {
+	if e.idle != nil {
+		c.idle.Remove(e.idle)
+		e.idle = nil
+	}
+	delete(c.entries, e.key)
+	c.used -= e.size
+}
+
+// notify - ロックの外で解放通知を送る
+func (c *Cache) notify(evicted []*entry) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+func isReady(e *entry) bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
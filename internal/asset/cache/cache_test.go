@@ -0,0 +1,192 @@
+package cache_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset/cache"
+)
+
+// sized - 指定サイズのテスト用の値
+type sized int64
+
+func (s sized) GetSize() int64 { return int64(s) }
+
+func loader(size int64, calls *int32) cache.LoadFunc {
+	return func() (cache.Value, error) {
+		atomic.AddInt32(calls, 1)
+		return sized(size), nil
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Run("DeduplicatesLoads", func(t *testing.T) {
+		c := cache.New(0, nil)
+		var calls int32
+
+		for i := 0; i < 3; i++ {
+			v, err := c.Acquire("a.png", loader(100, &calls))
+			require.NoError(t, err)
+			assert.Equal(t, sized(100), v)
+		}
+
+		assert.Equal(t, int32(1), calls)
+		assert.Equal(t, 3, c.Refs("a.png"))
+		stats := c.Stats()
+		assert.Equal(t, 1, stats.Misses)
+		assert.Equal(t, 2, stats.Hits)
+		assert.Equal(t, int64(100), stats.Used)
+	})
+
+	t.Run("ConcurrentLoadsShareResult", func(t *testing.T) {
+		c := cache.New(0, nil)
+		var calls int32
+		release := make(chan struct{})
+		slow := func() (cache.Value, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return sized(10), nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := c.Acquire("bgm.ogg", slow)
+				assert.NoError(t, err)
+			}()
+		}
+		for c.Refs("bgm.ogg") < 8 {
+			runtime.Gosched() // 全てのゴルーチンが参照を取得するまで待つ
+		}
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls)
+		assert.Equal(t, 8, c.Refs("bgm.ogg"))
+	})
+
+	t.Run("ReferencedValuesAreNeverEvicted", func(t *testing.T) {
+		var evicted []string
+		c := cache.New(150, func(key string, _ cache.Value) { evicted = append(evicted, key) })
+		var calls int32
+
+		_, err := c.Acquire("a", loader(100, &calls))
+		require.NoError(t, err)
+		_, err = c.Acquire("b", loader(100, &calls))
+		require.NoError(t, err)
+
+		assert.Empty(t, evicted, "使用中の値は予算超過でも解放しない")
+		assert.Equal(t, int64(200), c.Stats().Used)
+
+		c.Release("a")
+		assert.Equal(t, []string{"a"}, evicted)
+		assert.Equal(t, int64(100), c.Stats().Used)
+	})
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		var evicted []string
+		c := cache.New(250, func(key string, _ cache.Value) { evicted = append(evicted, key) })
+		var calls int32
+
+		for _, key := range []string{"a", "b"} {
+			_, err := c.Acquire(key, loader(100, &calls))
+			require.NoError(t, err)
+		}
+		c.Release("a")
+		c.Release("b")
+
+		// a を再利用して最近使ったものにする
+		_, err := c.Acquire("a", loader(100, &calls))
+		require.NoError(t, err)
+		c.Release("a")
+
+		_, err = c.Acquire("c", loader(100, &calls))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"b"}, evicted)
+		_, ok := c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, int32(3), calls)
+	})
+
+	t.Run("UnreferencedValuesStayCachedWithinBudget", func(t *testing.T) {
+		c := cache.New(1000, nil)
+		var calls int32
+
+		_, err := c.Acquire("a", loader(100, &calls))
+		require.NoError(t, err)
+		require.True(t, c.Release("a"))
+		assert.Contains(t, c.Snapshot(), "a")
+
+		_, err = c.Acquire("a", loader(100, &calls))
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), calls)
+	})
+
+	t.Run("FailedLoadIsNotCached", func(t *testing.T) {
+		c := cache.New(0, nil)
+		_, err := c.Acquire("broken.png", func() (cache.Value, error) {
+			return nil, errors.New("decode failed")
+		})
+		assert.Error(t, err)
+		assert.NotContains(t, c.Snapshot(), "broken.png")
+		assert.Equal(t, 0, c.Refs("broken.png"))
+	})
+
+	t.Run("RemoveIgnoresReferences", func(t *testing.T) {
+		var evicted []string
+		c := cache.New(0, func(key string, _ cache.Value) { evicted = append(evicted, key) })
+		var calls int32
+
+		_, err := c.Acquire("a", loader(100, &calls))
+		require.NoError(t, err)
+		assert.True(t, c.Remove("a"))
+		assert.Equal(t, []string{"a"}, evicted)
+		assert.Equal(t, int64(0), c.Stats().Used)
+		assert.False(t, c.Release("a"))
+	})
+
+	t.Run("SetBudgetEvicts", func(t *testing.T) {
+		c := cache.New(0, nil)
+		var calls int32
+		for _, key := range []string{"a", "b", "c"} {
+			_, err := c.Acquire(key, loader(100, &calls))
+			require.NoError(t, err)
+			c.Release(key)
+		}
+
+		c.SetBudget(100)
+		assert.Equal(t, int64(100), c.Stats().Used)
+		assert.Contains(t, c.Snapshot(), "c")
+		assert.Equal(t, 2, c.Stats().Evicted)
+	})
+	t.Run("ResizeTracksReplacedContents", func(t *testing.T) {
+		c := cache.New(150, nil)
+		value := &mutable{size: 100}
+		_, err := c.Acquire("a", func() (cache.Value, error) { return value, nil })
+		require.NoError(t, err)
+		var calls int32
+		_, err = c.Acquire("b", loader(50, &calls))
+		require.NoError(t, err)
+		c.Release("b")
+
+		value.size = 120
+		assert.True(t, c.Resize("a"))
+		assert.Equal(t, int64(120), c.Stats().Used, "予算を超えた参照のない b は解放される")
+		assert.NotContains(t, c.Snapshot(), "b")
+		assert.False(t, c.Resize("missing"))
+	})
+}
+
+// mutable - 中身を差し替えられるテスト用の値
+type mutable struct{ size int64 }
+
+func (m *mutable) GetSize() int64 { return m.size }
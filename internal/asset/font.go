@@ -0,0 +1,110 @@
+package asset
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	xfont "golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+
+	"muscle-dreamer/internal/asset/cache"
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// decodeFont - 内容から形式を判定して TrueType / OpenType フォントを解析する。
+// コレクションは最初のフォントを使う。
+func decodeFont(_ *Manager, path string, data []byte) (interface{}, error) {
+	var parsed *opentype.Font
+	var err error
+	switch f := inspect.Sniff(data); f {
+	case inspect.FormatTTF, inspect.FormatOTF:
+		parsed, err = opentype.Parse(data)
+	case inspect.FormatTTC:
+		var collection *opentype.Collection
+		if collection, err = opentype.ParseCollection(data); err == nil {
+			parsed, err = collection.Font(0)
+		}
+	default:
+		return nil, unsupportedFormat(path, KindFont, f)
+	}
+	if err != nil {
+		return nil, gameerr.AssetError{
+			Code:      CodeDecodeFailed,
+			Message:   fmt.Sprintf("フォントを解析できません: %s: %v", path, err),
+			AssetPath: path,
+		}
+	}
+	return parsedFont{font: parsed, size: int64(len(data))}, nil
+}
+
+// parsedFont - ワーカーで解析したフォント。解析結果は元のデータを参照し続ける。
+type parsedFont struct {
+	font *opentype.Font
+	size int64
+}
+
+func uploadFont(path string, decoded interface{}) cache.Value {
+	p := decoded.(parsedFont)
+	return &font{base: base{path: path, size: p.size, sources: []string{path}}, font: p.font}
+}
+
+// font - フォントアセット。サイズごとのフェイスを作成した順に保持する。
+type font struct {
+	base
+	font  *opentype.Font
+	faces map[int]xfont.Face
+}
+
+// Face - size ピクセルのフォントフェイス
+func (f *font) Face(size int) xfont.Face {
+	size = max(size, 1)
+	if face, ok := f.faces[size]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(f.font, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: xfont.HintingFull})
+	if err != nil {
+		// 解析済みのフォントとサイズ・DPI が正の値ならエラーにならない
+		panic(err)
+	}
+	if f.faces == nil {
+		f.faces = make(map[int]xfont.Face)
+	}
+	f.faces[size] = face
+	return face
+}
+
+// RenderText - 文字列を白で描画した画像を作成する。色は描画時に ColorScale で変える。
+// 画像の破棄は呼び出し元が行う。
+func (f *font) RenderText(s string, size int) *ebiten.Image {
+	face := f.Face(size)
+	bounds := text.BoundString(face, s)
+	if bounds.Empty() {
+		return ebiten.NewImage(1, 1)
+	}
+	img := ebiten.NewImage(bounds.Dx(), bounds.Dy())
+	text.Draw(img, s, face, -bounds.Min.X, -bounds.Min.Y, color.White)
+	return img
+}
+
+func (f *font) kind() Kind { return KindFont }
+
+func (f *font) closeFaces() {
+	for _, face := range f.faces {
+		_ = face.Close()
+	}
+	f.faces = nil
+}
+
+func (f *font) replace(fresh cache.Value) {
+	f.closeFaces()
+	f.font, f.size = fresh.(*font).font, fresh.(*font).size
+}
+
+func (f *font) unload() {
+	f.unloaded = true
+	f.closeFaces()
+	f.font = nil
+}
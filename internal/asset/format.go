@@ -0,0 +1,21 @@
+package asset
+
+import (
+	"fmt"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// unsupportedFormat - 種類に対して読み込めない形式のエラー
+func unsupportedFormat(path string, kind Kind, f inspect.Format) error {
+	detected := string(f)
+	if f == inspect.FormatUnknown {
+		detected = "不明な形式"
+	}
+	return gameerr.AssetError{
+		Code:      CodeUnsupported,
+		Message:   fmt.Sprintf("%sとして未対応の形式です: %s (%s)", kind, path, detected),
+		AssetPath: path,
+	}
+}
@@ -0,0 +1,119 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Format - 先頭のマジックバイトから判定したファイル形式
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatOGG     Format = "Ogg Vorbis"
+	FormatWAV     Format = "WAV"
+	FormatMP3     Format = "MP3"
+	FormatFLAC    Format = "FLAC"
+	FormatTTF     Format = "TrueType"
+	FormatOTF     Format = "OpenType"
+	FormatTTC     Format = "TrueType Collection"
+	FormatWOFF    Format = "WOFF"
+	FormatWOFF2   Format = "WOFF2"
+)
+
+// Sniff - 拡張子ではなく内容から形式を判定する
+func Sniff(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return FormatOGG
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return FormatWAV
+	case bytes.HasPrefix(data, []byte("ID3")), isMP3Frame(data):
+		return FormatMP3
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return FormatFLAC
+	case bytes.HasPrefix(data, []byte{0x00, 0x01, 0x00, 0x00}), bytes.HasPrefix(data, []byte("true")):
+		return FormatTTF
+	case bytes.HasPrefix(data, []byte("OTTO")):
+		return FormatOTF
+	case bytes.HasPrefix(data, []byte("ttcf")):
+		return FormatTTC
+	case bytes.HasPrefix(data, []byte("wOFF")):
+		return FormatWOFF
+	case bytes.HasPrefix(data, []byte("wOF2")):
+		return FormatWOFF2
+	default:
+		return FormatUnknown
+	}
+}
+
+// isMP3Frame - ID3 タグのない MP3 はフレーム同期ワード (11ビットの1) で始まる。
+// MPEG のバージョン・レイヤーが予約値のものは除く。
+func isMP3Frame(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	header := binary.BigEndian.Uint16(data)
+	return header&0xFFE0 == 0xFFE0 && header&0x0018 != 0x0008 && header&0x0006 != 0
+}
+
+// WAV - WAV ファイルのヘッダー
+type WAV struct {
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	DataSize      int64 // data チャンクのバイト数
+}
+
+// PCMSize - 16bit ステレオに変換したときの PCM のバイト数
+func (w WAV) PCMSize() int64 {
+	frames := w.DataSize / int64(w.Channels*w.BitsPerSample/8)
+	return frames * BytesPerFrame
+}
+
+// ParseWAV - RIFF/WAVE のチャンクをたどり、ゲームが再生できる PCM の fmt と data を読む
+func ParseWAV(data []byte) (WAV, error) {
+	if Sniff(data) != FormatWAV {
+		return WAV{}, errors.New("RIFF/WAVE のヘッダーがありません")
+	}
+	var w WAV
+	for rest := data[12:]; len(rest) >= 8; {
+		id, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			return WAV{}, fmt.Errorf("%q チャンクが途中で切れています", id)
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return WAV{}, errors.New("fmt チャンクが短すぎます")
+			}
+			if tag := binary.LittleEndian.Uint16(rest); tag != 1 {
+				return WAV{}, fmt.Errorf("PCM 以外の形式 (%d) には対応していません", tag)
+			}
+			w.Channels = int(binary.LittleEndian.Uint16(rest[2:]))
+			w.SampleRate = int(binary.LittleEndian.Uint32(rest[4:]))
+			w.BitsPerSample = int(binary.LittleEndian.Uint16(rest[14:]))
+			if w.Channels != 1 && w.Channels != 2 {
+				return WAV{}, fmt.Errorf("チャンネル数 %d には対応していません", w.Channels)
+			}
+			if w.BitsPerSample != 8 && w.BitsPerSample != 16 && w.BitsPerSample != 24 {
+				return WAV{}, fmt.Errorf("量子化ビット数 %d には対応していません", w.BitsPerSample)
+			}
+			if w.SampleRate <= 0 {
+				return WAV{}, errors.New("サンプルレートが不正です")
+			}
+		case "data":
+			if w.Channels == 0 {
+				return WAV{}, errors.New("data チャンクの前に fmt チャンクがありません")
+			}
+			w.DataSize = int64(size)
+			return w, nil
+		}
+		// チャンクは2バイト境界に揃えられる
+		rest = rest[min(size+size%2, len(rest)):]
+	}
+	return WAV{}, errors.New("data チャンクがありません")
+}
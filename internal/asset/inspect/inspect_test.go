@@ -0,0 +1,93 @@
+package inspect_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// wavHeader - fmt と data だけを持つ PCM の WAV
+func wavHeader(channels, rate, bits uint16, dataSize uint32) []byte {
+	le16 := func(v uint16) []byte { return []byte{byte(v), byte(v >> 8)} }
+	le32 := func(v uint32) []byte { return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)} }
+	var b []byte
+	b = append(b, "RIFF"...)
+	b = append(b, le32(36+dataSize)...)
+	b = append(b, "WAVEfmt "...)
+	b = append(b, le32(16)...)
+	b = append(b, le16(1)...)
+	b = append(b, le16(channels)...)
+	b = append(b, le32(uint32(rate))...)
+	b = append(b, le32(uint32(rate)*uint32(channels*bits/8))...)
+	b = append(b, le16(channels*bits/8)...)
+	b = append(b, le16(bits)...)
+	b = append(b, "data"...)
+	b = append(b, le32(dataSize)...)
+	return append(b, make([]byte, dataSize)...)
+}
+
+func TestSniff(t *testing.T) {
+	testCases := map[inspect.Format][]byte{
+		inspect.FormatOGG:     []byte("OggS\x00"),
+		inspect.FormatWAV:     wavHeader(1, 8000, 16, 2),
+		inspect.FormatMP3:     []byte("ID3\x04"),
+		inspect.FormatTTF:     {0x00, 0x01, 0x00, 0x00},
+		inspect.FormatOTF:     []byte("OTTO"),
+		inspect.FormatUnknown: []byte("not media"),
+	}
+	for want, data := range testCases {
+		assert.Equal(t, want, inspect.Sniff(data))
+	}
+}
+
+func TestParseWAV(t *testing.T) {
+	t.Run("ReadsHeader", func(t *testing.T) {
+		w, err := inspect.ParseWAV(wavHeader(1, 22050, 8, 100))
+		require.NoError(t, err)
+		assert.Equal(t, inspect.WAV{Channels: 1, SampleRate: 22050, BitsPerSample: 8, DataSize: 100}, w)
+		assert.Equal(t, int64(100*inspect.BytesPerFrame), w.PCMSize(), "16bit ステレオに換算する")
+	})
+
+	t.Run("RejectsUnsupported", func(t *testing.T) {
+		testCases := map[string][]byte{
+			"NotWAV":    []byte("RIFF\x04\x00\x00\x00AVI "),
+			"NoData":    []byte("RIFF\x04\x00\x00\x00WAVE"),
+			"Bits32":    wavHeader(2, 44100, 32, 8),
+			"Truncated": wavHeader(2, 44100, 16, 8)[:48],
+		}
+		for name, data := range testCases {
+			_, err := inspect.ParseWAV(data)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestLimits(t *testing.T) {
+	limits := inspect.DefaultLimits()
+	assert.Equal(t, limits.Mod, limits.ForPriority(vfs.PriorityMod))
+	assert.Equal(t, limits.Theme, limits.ForPriority(vfs.PriorityTheme))
+	assert.Equal(t, limits.Base, limits.ForPriority(vfs.PriorityBase))
+
+	theme := limits.Theme
+	requireTooLarge := func(t *testing.T, err error) {
+		t.Helper()
+		require.ErrorIs(t, err, inspect.ErrTooLarge)
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, inspect.CodeTooLarge, assetErr.Code)
+		assert.Equal(t, "assets/x", assetErr.AssetPath)
+	}
+	requireTooLarge(t, theme.CheckFileSize("assets/x", theme.MaxFileSize+1))
+	requireTooLarge(t, theme.CheckImage("assets/x", image.Config{Width: theme.MaxImageSide + 1, Height: 1}))
+	requireTooLarge(t, theme.CheckAudio("assets/x", int64(theme.MaxAudioDuration/time.Second+1)*inspect.BytesPerFrame, 1))
+	assert.NoError(t, theme.CheckAudio("assets/x", -1, 44100), "長さが分からない場合は確認しない")
+	assert.NoError(t, inspect.Limits{}.CheckImage("assets/x", image.Config{Width: 1 << 20, Height: 1 << 20}), "0 は無制限")
+}
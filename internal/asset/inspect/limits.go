@@ -0,0 +1,142 @@
+// Package inspect はアセットをデコードする前に、内容から形式を判定し、ヘッダーから
+// 分かる大きさを上限と比べる。描画・再生のデバイスを使わないため、ゲーム本体の
+// 読み込みと、テーマの検証ツールやアーカイブのインストールで同じ検査を共有できる。
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// CodeTooLarge - 上限を超えるアセットのエラーコード
+const CodeTooLarge = "ASSET_TOO_LARGE"
+
+// BytesPerFrame - デコード後の PCM は 16bit ステレオ
+const BytesPerFrame = 4
+
+// ErrTooLarge - 読み込み元に設定された上限を超えるアセット
+var ErrTooLarge = errors.New("アセットが大きすぎます")
+
+// Limits - 読み込むアセットの上限。テーマやMODは第三者が作るため、デコードする前に
+// ヘッダーから分かる大きさを確認して、展開するとメモリを使い果たすファイルを拒否する。
+// 0 の項目は無制限。
+type Limits struct {
+	MaxFileSize      int64         // ファイルのサイズ (パック内のファイルは展開後)
+	MaxImageSide     int           // 画像の幅・高さ
+	MaxDecodedSize   int64         // デコード後のメモリ量 (画像は RGBA、音声は PCM)
+	MaxAudioDuration time.Duration // 音声の再生時間
+}
+
+// SourceLimits - 読み込み元ごとの上限。アセットを提供している仮想ファイルシステムの
+// レイヤーの優先度で、ゲーム本体・テーマ・MODのどれを適用するかが決まる。
+type SourceLimits struct {
+	Base  Limits
+	Theme Limits
+	Mod   Limits
+}
+
+// DefaultLimits - 既定の上限
+func DefaultLimits() SourceLimits {
+	return SourceLimits{
+		Base: Limits{
+			MaxFileSize:  512 << 20,
+			MaxImageSide: 16384,
+		},
+		Theme: Limits{
+			MaxFileSize:      64 << 20,
+			MaxImageSide:     8192,
+			MaxDecodedSize:   256 << 20,
+			MaxAudioDuration: 15 * time.Minute,
+		},
+		Mod: Limits{
+			MaxFileSize:      16 << 20,
+			MaxImageSide:     4096,
+			MaxDecodedSize:   64 << 20,
+			MaxAudioDuration: 10 * time.Minute,
+		},
+	}
+}
+
+// ForPriority - レイヤーの優先度に対応する上限
+func (s SourceLimits) ForPriority(priority int) Limits {
+	switch {
+	case priority >= vfs.PriorityMod:
+		return s.Mod
+	case priority >= vfs.PriorityTheme:
+		return s.Theme
+	default:
+		return s.Base
+	}
+}
+
+// CheckFileSize - 読み込む前に宣言されたファイルのサイズを確認する
+func (l Limits) CheckFileSize(path string, size int64) error {
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return TooLarge(path, "ファイルサイズ %d バイトが上限 %d バイトを超えています", size, l.MaxFileSize)
+	}
+	return nil
+}
+
+// ReadLimited - ファイルを上限まで読む
+func (l Limits) ReadLimited(path string, r io.Reader) ([]byte, error) {
+	return ReadAtMost(path, r, l.MaxFileSize, "ファイルサイズ")
+}
+
+// ReadAtMost - 上限を1バイト超えるまでしか読まないため、展開後のサイズを偽った
+// 圧縮データでもメモリを使い果たさない。limit が 0 以下なら全て読む。
+func ReadAtMost(path string, r io.Reader, limit int64, what string) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, TooLarge(path, "%sが上限 %d バイトを超えています", what, limit)
+	}
+	return data, nil
+}
+
+// CheckImage - デコード前にヘッダーの寸法を確認する
+func (l Limits) CheckImage(path string, cfg image.Config) error {
+	if l.MaxImageSide > 0 && (cfg.Width > l.MaxImageSide || cfg.Height > l.MaxImageSide) {
+		return TooLarge(path, "画像の大きさ %dx%d が上限 %d を超えています", cfg.Width, cfg.Height, l.MaxImageSide)
+	}
+	if size := int64(cfg.Width) * int64(cfg.Height) * 4; l.MaxDecodedSize > 0 && size > l.MaxDecodedSize {
+		return TooLarge(path, "画像の展開後のサイズ %d バイトが上限 %d バイトを超えています", size, l.MaxDecodedSize)
+	}
+	return nil
+}
+
+// CheckAudio - デコード前に PCM (16bit ステレオ) のバイト数から再生時間とサイズを確認する。
+// 長さが分からない場合 (size < 0) は確認しないため、デコード中に ReadAtMost で打ち切る。
+func (l Limits) CheckAudio(path string, size int64, sampleRate int) error {
+	if size < 0 || sampleRate <= 0 {
+		return nil
+	}
+	duration := time.Duration(size/BytesPerFrame) * time.Second / time.Duration(sampleRate)
+	if l.MaxAudioDuration > 0 && duration > l.MaxAudioDuration {
+		return TooLarge(path, "音声の長さ %s が上限 %s を超えています", duration.Round(time.Second), l.MaxAudioDuration)
+	}
+	if l.MaxDecodedSize > 0 && size > l.MaxDecodedSize {
+		return TooLarge(path, "音声の展開後のサイズ %d バイトが上限 %d バイトを超えています", size, l.MaxDecodedSize)
+	}
+	return nil
+}
+
+// TooLarge - 上限を超えたアセットのエラー。errors.Is で ErrTooLarge と判定できる。
+func TooLarge(path, format string, args ...interface{}) error {
+	return gameerr.AssetError{
+		Code:      CodeTooLarge,
+		Message:   fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)),
+		AssetPath: path,
+		Err:       ErrTooLarge,
+	}
+}
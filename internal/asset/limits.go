@@ -0,0 +1,19 @@
+package asset
+
+import (
+	"muscle-dreamer/internal/asset/inspect"
+)
+
+// ErrAssetTooLarge - 読み込み元に設定された上限を超えるアセット
+var ErrAssetTooLarge = inspect.ErrTooLarge
+
+// Limits - 読み込むアセットの上限 (inspect.Limits)。0 の項目は無制限。
+type Limits = inspect.Limits
+
+// SourceLimits - 読み込み元ごとの上限 (inspect.SourceLimits)
+type SourceLimits = inspect.SourceLimits
+
+// DefaultLimits - 既定の上限
+func DefaultLimits() SourceLimits {
+	return inspect.DefaultLimits()
+}
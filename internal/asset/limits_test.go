@@ -0,0 +1,118 @@
+package asset_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// pngHeader - IHDR だけを持つ PNG。ヘッダー上は巨大でも実データはない。
+func pngHeader(w, h uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := func(typ string, data []byte) {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.WriteString(typ)
+		buf.Write(data)
+		_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	ihdr[8], ihdr[9] = 8, 6 // 8bit RGBA
+	chunk("IHDR", ihdr)
+	chunk("IEND", nil)
+	return buf.Bytes()
+}
+
+func requireTooLarge(t *testing.T, err error, path string) {
+	t.Helper()
+	require.ErrorIs(t, err, asset.ErrAssetTooLarge, path)
+	var assetErr gameerr.AssetError
+	require.True(t, errors.As(err, &assetErr), path)
+	assert.Equal(t, asset.CodeTooLarge, assetErr.Code, path)
+	assert.Equal(t, path, assetErr.AssetPath)
+}
+
+func TestLimits(t *testing.T) {
+	mod := func(t *testing.T, files fstest.MapFS) *vfs.FS {
+		t.Helper()
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("mod:evil", files, vfs.MountOptions{Priority: vfs.PriorityMod}))
+		return fsys
+	}
+
+	t.Run("RejectsImageBombFromHeader", func(t *testing.T) {
+		m := asset.NewManager(mod(t, fstest.MapFS{"assets/bomb.png": {Data: pngHeader(30000, 30000)}}), asset.Options{})
+
+		_, err := m.LoadImage("assets/bomb.png")
+		requireTooLarge(t, err, "assets/bomb.png")
+		assert.Empty(t, m.GetLoadedAssets())
+	})
+
+	t.Run("LimitsDependOnSource", func(t *testing.T) {
+		limits := asset.SourceLimits{Mod: asset.Limits{MaxImageSide: 32}}
+		fsys := mod(t, fstest.MapFS{"assets/mod.png": {Data: pngData(t, 64, 32)}})
+		m := asset.NewManager(fsys, asset.Options{Limits: &limits})
+
+		_, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		_, err = m.LoadImage("assets/mod.png")
+		requireTooLarge(t, err, "assets/mod.png")
+	})
+
+	t.Run("DecodedSizeCountsRGBA", func(t *testing.T) {
+		limits := asset.SourceLimits{Base: asset.Limits{MaxDecodedSize: 64 * 32 * 4}}
+		m := asset.NewManager(testFS(t), asset.Options{Limits: &limits})
+
+		_, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		_, err = m.LoadImage("assets/enemy.png")
+		require.NoError(t, err)
+
+		limits.Base.MaxDecodedSize--
+		m = asset.NewManager(testFS(t), asset.Options{Limits: &limits})
+		_, err = m.LoadImage("assets/player.png")
+		requireTooLarge(t, err, "assets/player.png")
+	})
+
+	t.Run("FileSize", func(t *testing.T) {
+		limits := asset.SourceLimits{Mod: asset.Limits{MaxFileSize: 16}}
+		fsys := mod(t, fstest.MapFS{"assets/big.wav": {Data: wavData(100)}})
+		m := asset.NewManager(fsys, asset.Options{Limits: &limits})
+
+		_, err := m.LoadAudio("assets/big.wav")
+		requireTooLarge(t, err, "assets/big.wav")
+	})
+
+	t.Run("AudioDurationBeforeDecoding", func(t *testing.T) {
+		limits := asset.SourceLimits{Mod: asset.Limits{MaxAudioDuration: 100 * time.Millisecond}}
+		fsys := mod(t, fstest.MapFS{"assets/long.wav": {Data: wavData(asset.DefaultSampleRate)}})
+		m := asset.NewManager(fsys, asset.Options{Limits: &limits})
+
+		_, err := m.LoadAudio("assets/long.wav")
+		requireTooLarge(t, err, "assets/long.wav")
+		_, err = m.LoadAudio("assets/bgm.wav")
+		require.NoError(t, err, "ゲーム本体の音声にはMODの上限を適用しない")
+	})
+
+	t.Run("LoaderReportsLimitErrors", func(t *testing.T) {
+		m := asset.NewManager(mod(t, fstest.MapFS{"assets/bomb.png": {Data: pngHeader(30000, 30000)}}), asset.Options{})
+
+		l := m.LoadAsync([]asset.Request{{Path: "assets/bomb.png", Kind: asset.KindImage}}, 1)
+		progress := waitLoaded(t, l)
+		require.Len(t, progress.Errors, 1)
+		assert.ErrorIs(t, progress.Errors[0], asset.ErrAssetTooLarge)
+	})
+}
@@ -0,0 +1,221 @@
+package asset
+
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"sync"
+	"time"
+
+	"muscle-dreamer/internal/asset/cache"
+)
+
+// UploadBudget - 1フレームでGPUへのアップロードに使う時間の目安
+const UploadBudget = 4 * time.Millisecond
+
+// Request - 非同期読み込みの要求
+type Request struct {
+	Path string
+	Kind Kind
+}
+
+// Progress - 非同期読み込みの進捗
+type Progress struct {
+	Items     int    // 要求数
+	ItemsDone int    // 完了数 (失敗を含む)
+	Bytes     int64  // 読み込むファイルの合計サイズ
+	BytesDone int64  // 完了したファイルの合計サイズ
+	Current   string // 処理中のファイル
+	Errors    []error
+	Done      bool
+}
+
+// Fraction - 進捗率 (0〜1)。サイズが分かる場合はバイト数、そうでなければ件数で計算する。
+func (p Progress) Fraction() float64 {
+	switch {
+	case p.Done:
+		return 1
+	case p.Bytes > 0:
+		return float64(p.BytesDone) / float64(p.Bytes)
+	case p.Items > 0:
+		return float64(p.ItemsDone) / float64(p.Items)
+	default:
+		return 0
+	}
+}
+
+// decoded - ワーカーがデコードを終えた結果
+type decoded struct {
+	req   Request
+	size  int64
+	value interface{}
+	err   error
+}
+
+// Loader - ファイルの読み込みとデコードをワーカーゴルーチンで行い、
+// メインスレッドで Update が呼ばれるたびにアップロードする。
+// 読み込んだアセットの参照は Release まで Loader が保持する。
+type Loader struct {
+	manager *Manager
+	cancel  context.CancelFunc
+	results chan decoded
+	sizes   map[string]int64
+
+	mu       sync.Mutex
+	progress Progress
+	acquired []string
+}
+
+// LoadAsync - 要求されたアセットの非同期読み込みを開始する。workers が0以下なら CPU 数を使う。
+func (m *Manager) LoadAsync(reqs []Request, workers int) *Loader {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Loader{
+		manager: m,
+		cancel:  cancel,
+		results: make(chan decoded, len(reqs)),
+		sizes:   make(map[string]int64, len(reqs)),
+	}
+	l.progress.Items = len(reqs)
+	for _, req := range reqs {
+		if info, err := fs.Stat(m.fsys, req.Path); err == nil {
+			l.sizes[req.Path] = info.Size()
+			l.progress.Bytes += info.Size()
+		}
+	}
+	if len(reqs) == 0 {
+		l.progress.Done = true
+		return l
+	}
+
+	queue := make(chan Request)
+	go func() {
+		defer close(queue)
+		for _, req := range reqs {
+			select {
+			case queue <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < min(workers, len(reqs)); i++ {
+		go l.work(ctx, queue)
+	}
+	return l
+}
+
+// work - ワーカーゴルーチン。読み込みとデコードのみを行う。
+func (l *Loader) work(ctx context.Context, queue <-chan Request) {
+	for req := range queue {
+		result := decoded{req: req, size: l.sizes[req.Path]}
+		if err := validatePath(req.Path); err != nil {
+			result.err = err
+		} else if _, cached := l.manager.cache.Get(req.Path); !cached {
+			l.setCurrent(req.Path)
+			result.value, result.err = l.decode(req)
+		}
+
+		select {
+		case l.results <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (l *Loader) decode(req Request) (interface{}, error) {
+	data, err := l.manager.read(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return decoders[req.Kind].decode(l.manager, req.Path, data)
+}
+
+// Update - デコード済みのアセットをアップロードする。メインスレッドから毎フレーム呼ぶ。
+func (l *Loader) Update() {
+	deadline := time.Now().Add(UploadBudget)
+	for time.Now().Before(deadline) {
+		select {
+		case result := <-l.results:
+			l.upload(result)
+		default:
+			return
+		}
+	}
+}
+
+func (l *Loader) upload(result decoded) {
+	err := result.err
+	if err == nil {
+		_, err = l.manager.acquire(result.req.Path, result.req.Kind, func() (cache.Value, error) {
+			// ワーカーがデコードした結果は最初の1回だけ使い、ワーカーの確認後に
+			// 解放されていた場合や再読み込みではここで読み直す
+			value := result.value
+			result.value = nil
+			if value == nil {
+				var err error
+				if value, err = l.decode(result.req); err != nil {
+					return nil, err
+				}
+			}
+			return decoders[result.req.Kind].upload(result.req.Path, value), nil
+		})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.progress.Errors = append(l.progress.Errors, err)
+	} else {
+		l.acquired = append(l.acquired, result.req.Path)
+	}
+	l.progress.ItemsDone++
+	l.progress.BytesDone += result.size
+	if l.progress.ItemsDone == l.progress.Items {
+		l.progress.Done = true
+		l.progress.Current = ""
+	}
+}
+
+func (l *Loader) setCurrent(path string) {
+	l.mu.Lock()
+	l.progress.Current = path
+	l.mu.Unlock()
+}
+
+// Progress - 現在の進捗
+func (l *Loader) Progress() Progress {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.progress
+	p.Errors = append([]error(nil), l.progress.Errors...)
+	return p
+}
+
+// Loaded - 読み込みに成功したアセットのパス
+func (l *Loader) Loaded() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.acquired...)
+}
+
+// Cancel - 未処理の読み込みを中止する。読み込み済みのアセットは保持したまま。
+func (l *Loader) Cancel() {
+	l.cancel()
+}
+
+// Release - 読み込みを中止し、Loader が保持している参照を全て手放す
+func (l *Loader) Release() {
+	l.cancel()
+	l.mu.Lock()
+	acquired := l.acquired
+	l.acquired = nil
+	l.mu.Unlock()
+
+	for _, path := range acquired {
+		l.manager.UnloadAsset(path)
+	}
+}
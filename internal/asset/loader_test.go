@@ -0,0 +1,97 @@
+package asset_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// waitLoaded - メインループの代わりに Update を呼び続けて完了を待つ
+func waitLoaded(t *testing.T, l *asset.Loader) asset.Progress {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		l.Update()
+		if p := l.Progress(); p.Done {
+			return p
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("読み込みが完了しない")
+	return asset.Progress{}
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("LoadsAndReportsProgress", func(t *testing.T) {
+		files := testFiles(t)
+		m := asset.NewManager(testFS(t), asset.Options{})
+		l := m.LoadAsync([]asset.Request{
+			{Path: "assets/player.png", Kind: asset.KindImage},
+			{Path: "assets/enemy.png", Kind: asset.KindImage},
+			{Path: "assets/bgm.wav", Kind: asset.KindAudio},
+		}, 2)
+
+		before := l.Progress()
+		assert.Equal(t, 3, before.Items)
+		assert.Equal(t, int64(len(files["assets/player.png"].Data)+len(files["assets/enemy.png"].Data)+len(files["assets/bgm.wav"].Data)), before.Bytes)
+
+		p := waitLoaded(t, l)
+		assert.Equal(t, 3, p.ItemsDone)
+		assert.Equal(t, p.Bytes, p.BytesDone)
+		assert.Equal(t, 1.0, p.Fraction())
+		assert.Empty(t, p.Errors)
+		assert.ElementsMatch(t, []string{"assets/player.png", "assets/enemy.png", "assets/bgm.wav"}, l.Loaded())
+		assert.Equal(t, 1, m.RefCount("assets/player.png"))
+
+		l.Release()
+		assert.Equal(t, 0, m.RefCount("assets/player.png"))
+	})
+
+	t.Run("CollectsErrors", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		l := m.LoadAsync([]asset.Request{
+			{Path: "assets/player.png", Kind: asset.KindImage},
+			{Path: "assets/broken.png", Kind: asset.KindImage},
+			{Path: "assets/missing.png", Kind: asset.KindImage},
+		}, 0)
+
+		p := waitLoaded(t, l)
+		assert.Equal(t, 3, p.ItemsDone)
+		require.Len(t, p.Errors, 2)
+		for _, err := range p.Errors {
+			var assetErr gameerr.AssetError
+			assert.True(t, errors.As(err, &assetErr))
+		}
+		assert.Equal(t, []string{"assets/player.png"}, l.Loaded())
+	})
+
+	t.Run("SharesCachedAssets", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		img, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+
+		waitLoaded(t, m.LoadAsync([]asset.Request{{Path: "assets/player.png", Kind: asset.KindImage}}, 1))
+
+		again, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		assert.Same(t, img, again)
+		assert.Equal(t, 3, m.RefCount("assets/player.png"))
+	})
+
+	t.Run("EmptyRequestIsDone", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		assert.True(t, m.LoadAsync(nil, 0).Progress().Done)
+	})
+}
+
+func TestProgressFraction(t *testing.T) {
+	assert.Equal(t, 0.25, asset.Progress{Bytes: 400, BytesDone: 100, Items: 2, ItemsDone: 1}.Fraction())
+	assert.Equal(t, 0.5, asset.Progress{Items: 2, ItemsDone: 1}.Fraction())
+	assert.Equal(t, 0.0, asset.Progress{}.Fraction())
+}
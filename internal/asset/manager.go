@@ -0,0 +1,424 @@
+package asset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // JPEG デコーダの登録
+	_ "image/png"  // PNG デコーダの登録
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"muscle-dreamer/internal/asset/cache"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// DefaultBudget - 参照されていないアセットを保持しておくメモリ予算の既定値
+const DefaultBudget int64 = 256 << 20
+
+// Kind - アセットの種類
+type Kind int
+
+const (
+	KindImage Kind = iota
+	KindAudio
+	KindFont
+	KindAtlas
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindImage:
+		return "画像"
+	case KindAudio:
+		return "音声"
+	case KindFont:
+		return "フォント"
+	case KindAtlas:
+		return "アトラス"
+	default:
+		return "不明なアセット"
+	}
+}
+
+// decoder - 種類ごとの読み込み処理。decode はワーカーゴルーチンで、
+// upload はメインスレッドで実行される。decode は関連するファイルを m から読める。
+type decoder struct {
+	decode func(m *Manager, path string, data []byte) (interface{}, error)
+	upload func(path string, decoded interface{}) cache.Value
+}
+
+var decoders = map[Kind]decoder{
+	KindImage: {
+		decode: decodeImage,
+		upload: func(path string, decoded interface{}) cache.Value {
+			return newImage(path, ebiten.NewImageFromImage(decoded.(image.Image)))
+		},
+	},
+	KindAudio: {
+		decode: decodeAudio,
+		upload: uploadAudio,
+	},
+	KindFont: {
+		decode: decodeFont,
+		upload: uploadFont,
+	},
+	KindAtlas: {
+		decode: decodeAtlas,
+		upload: uploadAtlas,
+	},
+}
+
+// Options - Manager の設定
+type Options struct {
+	Budget     int64 // メモリ予算 (バイト)。0 は DefaultBudget、負の値は無制限。
+	SampleRate int   // 音声をデコードするサンプルレート。0 は DefaultSampleRate。
+
+	// Limits - 読み込み元ごとの上限。nil は DefaultLimits。
+	Limits *SourceLimits
+}
+
+// Manager - パス単位でアセットを共有するアセットマネージャー。
+// Load* を呼ぶたびに参照が1つ増え、UnloadAsset で1つ減る。参照がなくなった
+// アセットはすぐには破棄せず、メモリ予算を超えたときに最も長く使われていない
+// ものから破棄する。
+// Load* が返す値はハンドルで、Reload で読み直しても同じ値のまま中身が差し替わる。
+type Manager struct {
+	fsys       *vfs.FS
+	cache      *cache.Cache
+	sampleRate int
+	limits     SourceLimits
+
+	mu       sync.Mutex
+	builders map[string]cache.LoadFunc // キーごとの読み直し処理
+	origins  map[string][]string       // キーごとの読み込み元ファイルを提供していたレイヤー名
+}
+
+// NewManager - 仮想ファイルシステムからアセットを読み込むマネージャーを作成する
+func NewManager(fsys *vfs.FS, opts Options) *Manager {
+	budget := opts.Budget
+	switch {
+	case budget == 0:
+		budget = DefaultBudget
+	case budget < 0:
+		budget = 0
+	}
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = DefaultSampleRate
+	}
+	limits := DefaultLimits()
+	if opts.Limits != nil {
+		limits = *opts.Limits
+	}
+	return &Manager{
+		fsys:       fsys,
+		cache:      cache.New(budget, evict),
+		sampleRate: sampleRate,
+		limits:     limits,
+		builders:   make(map[string]cache.LoadFunc),
+		origins:    make(map[string][]string),
+	}
+}
+
+// LoadImage - 画像を読み込む。同じパスの画像は同じハンドルを共有する。
+func (m *Manager) LoadImage(path string) (*Image, error) {
+	v, err := m.load(path, KindImage)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Image), nil
+}
+
+// LoadAudio - 音声を読み込む
+func (m *Manager) LoadAudio(path string) (AudioClip, error) {
+	v, err := m.load(path, KindAudio)
+	if err != nil {
+		return nil, err
+	}
+	return v.(AudioClip), nil
+}
+
+// LoadFont - フォントを読み込む
+func (m *Manager) LoadFont(path string) (Font, error) {
+	v, err := m.load(path, KindFont)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Font), nil
+}
+
+// LoadAtlas - アトラス定義とそのページ画像を読み込む
+func (m *Manager) LoadAtlas(path string) (*Atlas, error) {
+	v, err := m.load(path, KindAtlas)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Atlas), nil
+}
+
+// UnloadAsset - アセットの参照を1つ手放す
+func (m *Manager) UnloadAsset(path string) {
+	m.cache.Release(path)
+}
+
+// GetLoadedAssets - メモリ上にある全てのアセット (参照されていないキャッシュを含む)
+func (m *Manager) GetLoadedAssets() map[string]Asset {
+	snapshot := m.cache.Snapshot()
+	assets := make(map[string]Asset, len(snapshot))
+	for path, v := range snapshot {
+		assets[path] = v.(Asset)
+	}
+	return assets
+}
+
+// RefCount - アセットの参照数
+func (m *Manager) RefCount(path string) int {
+	return m.cache.Refs(path)
+}
+
+// SetBudget - メモリ予算を変更する。超過分はすぐに破棄される。
+func (m *Manager) SetBudget(budget int64) {
+	m.cache.SetBudget(budget)
+}
+
+// Stats - キャッシュの統計
+func (m *Manager) Stats() cache.Stats {
+	return m.cache.Stats()
+}
+
+// load - ファイルの読み込みからアップロードまでを呼び出し元のゴルーチンで行う
+func (m *Manager) load(path string, kind Kind) (cache.Value, error) {
+	if err := validatePath(path); err != nil {
+		return nil, err
+	}
+	return m.acquire(path, kind, func() (cache.Value, error) {
+		data, err := m.read(path)
+		if err != nil {
+			return nil, err
+		}
+		d := decoders[kind]
+		decoded, err := d.decode(m, path, data)
+		if err != nil {
+			return nil, err
+		}
+		return d.upload(path, decoded), nil
+	})
+}
+
+// Reload - file から作られた読み込み済みのアセットを全て読み直し、同じハンドルのまま
+// 中身を差し替える。差し替えたアセットのキーを返す。読み直しに失敗したアセットは
+// 元の中身のまま残る。メインスレッドから呼ぶ。
+func (m *Manager) Reload(file string) ([]string, error) {
+	snapshot := m.cache.Snapshot()
+	var keys []string
+	for _, key := range sortedKeys(snapshot) {
+		if v, ok := snapshot[key].(reloadable); ok && slices.Contains(v.sourceFiles(), file) {
+			keys = append(keys, key)
+		}
+	}
+	return m.reload(snapshot, keys)
+}
+
+// Revalidate - 仮想ファイルシステムのマウント構成が変わったときに呼ぶ。読み込み元の
+// ファイルを提供するレイヤーが変わったアセットのうち、参照されていないものは破棄し、
+// 参照中のものは同じハンドルのまま読み直す。読み直したアセットのキーを返す。
+// 読み直しに失敗したアセットは元の中身のまま残る。メインスレッドから呼ぶ。
+func (m *Manager) Revalidate() ([]string, error) {
+	snapshot := m.cache.Snapshot()
+	var stale []string
+	for _, key := range sortedKeys(snapshot) {
+		v, ok := snapshot[key].(reloadable)
+		if !ok {
+			continue
+		}
+		m.mu.Lock()
+		origins := m.origins[key]
+		m.mu.Unlock()
+		if slices.Equal(origins, m.originsOf(v)) {
+			continue
+		}
+		if m.cache.Refs(key) == 0 {
+			m.cache.Remove(key)
+			continue
+		}
+		stale = append(stale, key)
+	}
+	return m.reload(snapshot, stale)
+}
+
+func (m *Manager) reload(snapshot map[string]cache.Value, keys []string) ([]string, error) {
+	var reloaded []string
+	var errs []error
+	for _, key := range keys {
+		v := snapshot[key].(reloadable)
+		m.mu.Lock()
+		build := m.builders[key]
+		m.mu.Unlock()
+		if build == nil {
+			continue
+		}
+		fresh, err := build()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		v.replace(fresh)
+		m.cache.Resize(key)
+		m.mu.Lock()
+		m.origins[key] = m.originsOf(v)
+		m.mu.Unlock()
+		reloaded = append(reloaded, key)
+	}
+	return reloaded, errors.Join(errs...)
+}
+
+// originsOf - アセットの読み込み元ファイルを現在提供しているレイヤー名。見つからないファイルは空文字列。
+func (m *Manager) originsOf(v reloadable) []string {
+	sources := v.sourceFiles()
+	origins := make([]string, len(sources))
+	for i, file := range sources {
+		if info, err := m.fsys.Lookup(file); err == nil {
+			origins[i] = info.Name
+		}
+	}
+	return origins
+}
+
+func sortedKeys(snapshot map[string]cache.Value) []string {
+	keys := make([]string, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// acquire - キャッシュから参照を取得し、期待した種類のアセットか確認する。
+// load は Reload で読み直すときにも呼ばれるため、呼ぶたびにファイルを読む必要がある。
+func (m *Manager) acquire(path string, kind Kind, load cache.LoadFunc) (cache.Value, error) {
+	v, err := m.cache.Acquire(path, func() (cache.Value, error) {
+		v, err := load()
+		if err == nil {
+			var origins []string
+			if r, ok := v.(reloadable); ok {
+				origins = m.originsOf(r)
+			}
+			m.mu.Lock()
+			m.builders[path] = load
+			m.origins[path] = origins
+			m.mu.Unlock()
+		}
+		return v, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := v.(interface{ kind() Kind }); !ok || k.kind() != kind {
+		m.cache.Release(path)
+		return nil, gameerr.AssetError{
+			Code:      CodeKindMismatch,
+			Message:   fmt.Sprintf("%s は%sとして読み込まれていません", path, kind),
+			AssetPath: path,
+		}
+	}
+	return v, nil
+}
+
+// read - 読み込み元の上限を超えない範囲でファイルを読む
+func (m *Manager) read(path string) ([]byte, error) {
+	data, err := m.readFile(path)
+	if err != nil {
+		var assetErr gameerr.AssetError
+		if errors.As(err, &assetErr) {
+			return nil, assetErr
+		}
+		code := CodeReadFailed
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			code = CodeNotFound
+		case errors.Is(err, vfs.ErrNotAllowed), errors.Is(err, fs.ErrInvalid):
+			code = CodeInvalidPath
+		}
+		return nil, gameerr.AssetError{
+			Code:      code,
+			Message:   fmt.Sprintf("アセットを読み込めません: %s: %v", path, err),
+			AssetPath: path,
+		}
+	}
+	return data, nil
+}
+
+func (m *Manager) readFile(path string) ([]byte, error) {
+	info, err := m.fsys.Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	// パックのように開くときに全体を展開するレイヤーがあるため、先に宣言されたサイズを確認する
+	limits := m.limits.ForPriority(info.Priority)
+	if st, err := m.fsys.Stat(path); err == nil {
+		if err := limits.CheckFileSize(path, st.Size()); err != nil {
+			return nil, err
+		}
+	}
+	f, err := m.fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return limits.ReadLimited(path, f)
+}
+
+// limitsFor - path を提供しているレイヤーに適用する上限。見つからない場合は最も厳しいMODの上限。
+func (m *Manager) limitsFor(path string) Limits {
+	info, err := m.fsys.Lookup(path)
+	if err != nil {
+		return m.limits.Mod
+	}
+	return m.limits.ForPriority(info.Priority)
+}
+
+// decodeImage - ヘッダーの寸法を上限と比べてからデコードする
+func decodeImage(m *Manager, path string, data []byte) (interface{}, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == nil {
+		if err := m.limitsFor(path).CheckImage(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, gameerr.AssetError{
+			Code:      CodeDecodeFailed,
+			Message:   fmt.Sprintf("画像をデコードできません: %s: %v", path, err),
+			AssetPath: path,
+		}
+	}
+	return img, nil
+}
+
+// evict - キャッシュから取り除かれたアセットの資源を解放する
+func evict(_ string, v cache.Value) {
+	if u, ok := v.(interface{ unload() }); ok {
+		u.unload()
+	}
+}
+
+// validatePath - スラッシュ区切りの相対パスのみを受け付ける
+func validatePath(path string) error {
+	if fs.ValidPath(path) && path != "." && !strings.ContainsRune(path, 0) {
+		return nil
+	}
+	return gameerr.AssetError{
+		Code:      CodeInvalidPath,
+		Message:   fmt.Sprintf("不正なアセットパスです: %q", path),
+		AssetPath: path,
+	}
+}
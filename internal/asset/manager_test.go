@@ -0,0 +1,333 @@
+package asset_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"golang.org/x/image/font/gofont/goregular"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// pngData - 指定サイズの PNG データ
+func pngData(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+// wavData - 無音の 16bit ステレオ WAV データ
+func wavData(frames int) []byte {
+	const channels, bits = 2, 16
+	blockAlign := channels * bits / 8
+	size := frames * blockAlign
+
+	var buf bytes.Buffer
+	le := func(v interface{}) { _ = binary.Write(&buf, binary.LittleEndian, v) }
+	buf.WriteString("RIFF")
+	le(uint32(36 + size))
+	buf.WriteString("WAVEfmt ")
+	le(uint32(16))
+	le(uint16(1)) // PCM
+	le(uint16(channels))
+	le(uint32(asset.DefaultSampleRate))
+	le(uint32(asset.DefaultSampleRate * blockAlign))
+	le(uint16(blockAlign))
+	le(uint16(bits))
+	buf.WriteString("data")
+	le(uint32(size))
+	buf.Write(make([]byte, size))
+	return buf.Bytes()
+}
+
+func testFiles(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"assets/player.png": {Data: pngData(t, 64, 32)},
+		"assets/enemy.png":  {Data: pngData(t, 32, 32)},
+		"assets/bgm.wav":    {Data: wavData(asset.DefaultSampleRate / 2)},
+		"assets/broken.png": {Data: []byte("not a png")},
+		"config/game.yaml":  {Data: []byte("game: {}")},
+	}
+}
+
+// testFS - テスト用ファイルをゲーム本体レイヤーとしてマウントした仮想ファイルシステム
+func testFS(t *testing.T) *vfs.FS {
+	t.Helper()
+	v := vfs.New(vfs.DefaultRoots...)
+	require.NoError(t, v.Mount("base", testFiles(t), vfs.MountOptions{Priority: vfs.PriorityBase}))
+	return v
+}
+
+func TestManager(t *testing.T) {
+	t.Run("DeduplicatesAndCountsReferences", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+
+		first, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		second, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+
+		assert.Same(t, first, second)
+		assert.Equal(t, 2, m.RefCount("assets/player.png"))
+
+		m.UnloadAsset("assets/player.png")
+		assert.Equal(t, 1, m.RefCount("assets/player.png"))
+	})
+
+	t.Run("LoadsThroughOverlayLayers", func(t *testing.T) {
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("theme:beach", fstest.MapFS{
+			"assets/player.png": {Data: pngData(t, 16, 16)},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+		m := asset.NewManager(fsys, asset.Options{})
+
+		img, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		assert.Equal(t, 16, img.Bounds().Dx())
+	})
+
+	t.Run("GetSizeReportsDecodedMemory", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+
+		_, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+
+		loaded := m.GetLoadedAssets()
+		require.Contains(t, loaded, "assets/player.png")
+		assert.Equal(t, int64(64*32*4), loaded["assets/player.png"].GetSize())
+		assert.Equal(t, int64(64*32*4), m.Stats().Used)
+	})
+
+	t.Run("EvictsUnreferencedAssetsOverBudget", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{Budget: 64 * 32 * 4})
+
+		_, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		m.UnloadAsset("assets/player.png")
+		player := m.GetLoadedAssets()["assets/player.png"]
+		require.NotNil(t, player, "予算内なら参照がなくても保持する")
+
+		_, err = m.LoadImage("assets/enemy.png")
+		require.NoError(t, err)
+
+		loaded := m.GetLoadedAssets()
+		assert.NotContains(t, loaded, "assets/player.png")
+		assert.Contains(t, loaded, "assets/enemy.png")
+		assert.False(t, player.IsLoaded())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+
+		testCases := []struct {
+			path string
+			code string
+		}{
+			{"../../../etc/passwd", asset.CodeInvalidPath},
+			{"", asset.CodeInvalidPath},
+			{"assets/\x00malicious.png", asset.CodeInvalidPath},
+			{"config/game.yaml", asset.CodeInvalidPath},
+			{"assets/missing.png", asset.CodeNotFound},
+			{"assets/broken.png", asset.CodeDecodeFailed},
+		}
+		for _, tc := range testCases {
+			_, err := m.LoadImage(tc.path)
+
+			var assetErr gameerr.AssetError
+			require.True(t, errors.As(err, &assetErr), tc.path)
+			assert.Equal(t, tc.code, assetErr.Code, tc.path)
+		}
+		assert.Empty(t, m.GetLoadedAssets())
+	})
+
+	t.Run("DetectsFormatByContent", func(t *testing.T) {
+		fsys := testFS(t)
+		require.NoError(t, fsys.Mount("theme:test", fstest.MapFS{
+			"assets/renamed.ogg":   {Data: wavData(100)},
+			"assets/flac.mp3":      {Data: []byte("fLaC\x00\x00\x00\x22")},
+			"assets/text.wav":      {Data: []byte("not audio")},
+			"assets/font.ttf":      {Data: goregular.TTF},
+			"assets/web.ttf":       {Data: []byte("wOFF\x00\x01\x00\x00")},
+			"assets/truncated.ogg": {Data: []byte("OggS")},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+		m := asset.NewManager(fsys, asset.Options{})
+
+		clip, err := m.LoadAudio("assets/renamed.ogg")
+		require.NoError(t, err)
+		assert.Equal(t, 100*time.Second/asset.DefaultSampleRate, clip.GetDuration())
+
+		font, err := m.LoadFont("assets/font.ttf")
+		require.NoError(t, err)
+		assert.NotNil(t, font.Face(16))
+		assert.Same(t, font.Face(16), font.Face(16))
+
+		testCases := []struct {
+			path string
+			load func(string) error
+			code string
+		}{
+			{"assets/flac.mp3", func(p string) error { _, err := m.LoadAudio(p); return err }, asset.CodeUnsupported},
+			{"assets/text.wav", func(p string) error { _, err := m.LoadAudio(p); return err }, asset.CodeUnsupported},
+			{"assets/truncated.ogg", func(p string) error { _, err := m.LoadAudio(p); return err }, asset.CodeDecodeFailed},
+			{"assets/web.ttf", func(p string) error { _, err := m.LoadFont(p); return err }, asset.CodeUnsupported},
+			{"assets/bgm.wav", func(p string) error { _, err := m.LoadFont(p); return err }, asset.CodeUnsupported},
+		}
+		for _, tc := range testCases {
+			var assetErr gameerr.AssetError
+			require.True(t, errors.As(tc.load(tc.path), &assetErr), tc.path)
+			assert.Equal(t, tc.code, assetErr.Code, tc.path)
+			assert.Equal(t, tc.path, assetErr.AssetPath, tc.path)
+		}
+	})
+
+	t.Run("KindMismatchReleasesReference", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+
+		_, err := m.LoadAudio("assets/bgm.wav")
+		require.NoError(t, err)
+		_, err = m.LoadImage("assets/bgm.wav")
+
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, asset.CodeKindMismatch, assetErr.Code)
+		assert.Equal(t, 1, m.RefCount("assets/bgm.wav"))
+	})
+}
+
+func TestReload(t *testing.T) {
+	// mounted - 書き換えられるテスト用ファイルをマウントしたマネージャー
+	mounted := func(t *testing.T) (fstest.MapFS, *asset.Manager) {
+		files := testFiles(t)
+		v := vfs.New(vfs.DefaultRoots...)
+		require.NoError(t, v.Mount("base", files, vfs.MountOptions{Priority: vfs.PriorityBase}))
+		return files, asset.NewManager(v, asset.Options{})
+	}
+
+	t.Run("SwapsContentsBehindSameHandle", func(t *testing.T) {
+		files, m := mounted(t)
+		img, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		before := img.Image()
+
+		files["assets/player.png"] = &fstest.MapFile{Data: pngData(t, 16, 8)}
+		reloaded, err := m.Reload("assets/player.png")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"assets/player.png"}, reloaded)
+
+		again, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		assert.Same(t, img, again, "ハンドルは変わらない")
+		assert.NotSame(t, before, img.Image())
+		assert.Equal(t, 16, img.Bounds().Dx())
+		assert.Equal(t, int64(16*8*4), m.Stats().Used, "差し替え後のサイズで予算を計算する")
+	})
+
+	t.Run("RebuildsDerivedAtlases", func(t *testing.T) {
+		files, m := mounted(t)
+		_, err := m.BuildAtlas("atlas:wave", []string{"assets/player.png", "assets/enemy.png"}, atlas.Options{PageSize: 128})
+		require.NoError(t, err)
+
+		files["assets/enemy.png"] = &fstest.MapFile{Data: pngData(t, 48, 48)}
+		reloaded, err := m.Reload("assets/enemy.png")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"atlas:wave"}, reloaded)
+
+		_, rect, err := m.Sprite("atlas:wave", "assets/enemy.png")
+		require.NoError(t, err)
+		assert.Equal(t, 48, rect.Dx(), "スプライトは名前で引くため差し替えが反映される")
+	})
+
+	t.Run("FailedReloadKeepsOldContents", func(t *testing.T) {
+		files, m := mounted(t)
+		img, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+
+		files["assets/player.png"] = &fstest.MapFile{Data: []byte("not a png")}
+		reloaded, err := m.Reload("assets/player.png")
+		assert.Empty(t, reloaded)
+
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, asset.CodeDecodeFailed, assetErr.Code)
+		assert.True(t, img.IsLoaded())
+		assert.Equal(t, 64, img.Bounds().Dx())
+	})
+
+	t.Run("IgnoresAssetsNotLoaded", func(t *testing.T) {
+		_, m := mounted(t)
+		reloaded, err := m.Reload("assets/enemy.png")
+		assert.NoError(t, err)
+		assert.Empty(t, reloaded)
+	})
+
+	t.Run("RevalidateFollowsMountChanges", func(t *testing.T) {
+		v := testFS(t)
+		m := asset.NewManager(v, asset.Options{})
+		player, err := m.LoadImage("assets/player.png")
+		require.NoError(t, err)
+		_, err = m.LoadImage("assets/enemy.png")
+		require.NoError(t, err)
+		m.UnloadAsset("assets/enemy.png")
+		_, err = m.LoadImage("assets/broken.png")
+		require.Error(t, err)
+
+		reloaded, err := m.Revalidate()
+		require.NoError(t, err)
+		assert.Empty(t, reloaded, "レイヤーが変わっていなければ何もしない")
+
+		require.NoError(t, v.Mount("theme", fstest.MapFS{
+			"assets/player.png": {Data: pngData(t, 16, 8)},
+			"assets/enemy.png":  {Data: pngData(t, 8, 8)},
+		}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+		reloaded, err = m.Revalidate()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"assets/player.png"}, reloaded)
+		assert.Equal(t, 16, player.Bounds().Dx(), "参照中のアセットは同じハンドルのまま読み直す")
+		assert.NotContains(t, m.GetLoadedAssets(), "assets/enemy.png", "参照されていないアセットは破棄する")
+
+		enemy, err := m.LoadImage("assets/enemy.png")
+		require.NoError(t, err)
+		assert.Equal(t, 8, enemy.Bounds().Dx())
+
+		v.Unmount("theme")
+		reloaded, err = m.Revalidate()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"assets/enemy.png", "assets/player.png"}, reloaded)
+		assert.Equal(t, 64, player.Bounds().Dx())
+	})
+}
+
+func TestAssetManagerPerformance(t *testing.T) {
+	t.Run("CachePerformance", func(t *testing.T) {
+		m := asset.NewManager(testFS(t), asset.Options{})
+		assetPath := "assets/player.png"
+
+		first, err := m.LoadImage(assetPath)
+		require.NoError(t, err)
+		assert.Equal(t, 1, m.Stats().Misses)
+		assert.Equal(t, 0, m.Stats().Hits)
+
+		for i := 0; i < 3; i++ {
+			cached, err := m.LoadImage(assetPath)
+			require.NoError(t, err)
+			assert.Same(t, first, cached)
+		}
+
+		assert.Contains(t, m.GetLoadedAssets(), assetPath)
+		assert.Equal(t, 1, m.Stats().Misses, "2回目以降はデコードしない")
+		assert.Equal(t, 3, m.Stats().Hits)
+	})
+}
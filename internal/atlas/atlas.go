@@ -0,0 +1,80 @@
+// Package atlas は複数のスプライトを大きなページ画像へ詰め込むテクスチャアトラスを扱う。
+//
+// アトラスはページ画像と、領域名からページ上の矩形を引く定義ファイル (YAML) からなる。
+//
+//	pages:
+//	  - enemies_0.png
+//	regions:
+//	  enemy_goblin: {page: 0, x: 1, y: 1, w: 32, h: 32}
+//
+// ページのパスは定義ファイルからの相対パス。
+package atlas
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ext - アトラス定義ファイルの拡張子
+const Ext = ".atlas.yaml"
+
+// Region - ページ上のスプライトの位置
+type Region struct {
+	Page int `yaml:"page"`
+	X    int `yaml:"x"`
+	Y    int `yaml:"y"`
+	W    int `yaml:"w"`
+	H    int `yaml:"h"`
+}
+
+// Rect - ページ画像上の矩形
+func (r Region) Rect() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+}
+
+// Atlas - アトラス定義
+type Atlas struct {
+	Pages   []string          `yaml:"pages"`
+	Regions map[string]Region `yaml:"regions"`
+}
+
+// Parse - アトラス定義を読み込み、ページ番号とパスを検証する
+func Parse(data []byte) (*Atlas, error) {
+	var a Atlas
+	if err := yaml.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("アトラス定義を解析できません: %w", err)
+	}
+	for _, page := range a.Pages {
+		if !fs.ValidPath(page) || page == "." {
+			return nil, fmt.Errorf("不正なページのパスです: %q", page)
+		}
+	}
+	for name, r := range a.Regions {
+		if r.Page < 0 || r.Page >= len(a.Pages) {
+			return nil, fmt.Errorf("領域 %s のページ番号 %d が範囲外です", name, r.Page)
+		}
+		if r.X < 0 || r.Y < 0 || r.W <= 0 || r.H <= 0 {
+			return nil, fmt.Errorf("領域 %s の矩形が不正です", name)
+		}
+	}
+	return &a, nil
+}
+
+// Marshal - アトラス定義を YAML に変換する
+func (a *Atlas) Marshal() ([]byte, error) {
+	return yaml.Marshal(a)
+}
+
+// PagePaths - 定義ファイルのパスを基準にしたページのパス
+func (a *Atlas) PagePaths(manifest string) []string {
+	dir := path.Dir(manifest)
+	paths := make([]string, len(a.Pages))
+	for i, page := range a.Pages {
+		paths[i] = path.Join(dir, page)
+	}
+	return paths
+}
@@ -0,0 +1,168 @@
+package atlas_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/atlas"
+)
+
+func solid(w, h int, c color.NRGBA) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// assertNoOverlap - 同じページの領域が重ならず、ページ内に収まっていること
+func assertNoOverlap(t *testing.T, regions []atlas.Region, pages []image.Point, pad int) {
+	t.Helper()
+	for i, a := range regions {
+		require.Less(t, a.Page, len(pages))
+		assert.True(t, a.Rect().Inset(-pad).In(image.Rectangle{Max: pages[a.Page]}), "領域 %d がページ外", i)
+		for j, b := range regions[i+1:] {
+			if a.Page == b.Page {
+				assert.False(t, a.Rect().Inset(-pad).Overlaps(b.Rect().Inset(-pad)), "領域 %d と %d が重なる", i, i+1+j)
+			}
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	t.Run("PacksWithoutOverlap", func(t *testing.T) {
+		var sizes []image.Point
+		for i := 0; i < 200; i++ {
+			sizes = append(sizes, image.Pt(8+i*7%57, 8+i*13%43))
+		}
+		regions, pages, err := atlas.Layout(sizes, atlas.Options{PageSize: 256, Padding: 1})
+		require.NoError(t, err)
+		require.Len(t, regions, len(sizes))
+		for i, r := range regions {
+			assert.Equal(t, sizes[i], r.Rect().Size())
+		}
+		assertNoOverlap(t, regions, pages, 1)
+	})
+
+	t.Run("SpillsToNewPages", func(t *testing.T) {
+		sizes := []image.Point{{64, 64}, {64, 64}, {64, 64}, {64, 64}, {64, 64}}
+		regions, pages, err := atlas.Layout(sizes, atlas.Options{PageSize: 128})
+		require.NoError(t, err)
+		assert.Len(t, pages, 2)
+		assert.Equal(t, image.Pt(64, 64), pages[1], "最後のページは使用範囲に切り詰める")
+		assertNoOverlap(t, regions, pages, 0)
+	})
+
+	t.Run("RejectsSpritesLargerThanPage", func(t *testing.T) {
+		_, _, err := atlas.Layout([]image.Point{{100, 10}}, atlas.Options{PageSize: 100, Padding: 1})
+		assert.Error(t, err)
+	})
+}
+
+func TestPack(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	sprites := []atlas.Sprite{
+		{Name: "enemy_goblin", Image: solid(16, 16, red)},
+		{Name: "enemy_slime", Image: solid(24, 12, blue)},
+	}
+
+	t.Run("CopiesPixelsAndExtrudesEdges", func(t *testing.T) {
+		result, err := atlas.Pack(sprites, atlas.Options{PageSize: 64, Padding: 2})
+		require.NoError(t, err)
+		require.Len(t, result.Pages, 1)
+
+		goblin := result.Regions["enemy_goblin"]
+		page := result.Pages[goblin.Page]
+		assert.Equal(t, red, page.NRGBAAt(goblin.X, goblin.Y))
+		assert.Equal(t, red, page.NRGBAAt(goblin.X-2, goblin.Y-2), "余白は縁の色で埋める")
+
+		slime := result.Regions["enemy_slime"]
+		assert.Equal(t, image.Pt(24, 12), slime.Rect().Size())
+		assert.Equal(t, blue, result.Pages[slime.Page].NRGBAAt(slime.X+23, slime.Y+11))
+	})
+
+	t.Run("DeterministicRegardlessOfInputOrder", func(t *testing.T) {
+		a, err := atlas.Pack(sprites, atlas.Options{PageSize: 64})
+		require.NoError(t, err)
+		b, err := atlas.Pack([]atlas.Sprite{sprites[1], sprites[0]}, atlas.Options{PageSize: 64})
+		require.NoError(t, err)
+		assert.Equal(t, a.Regions, b.Regions)
+	})
+
+	t.Run("RejectsDuplicateNames", func(t *testing.T) {
+		_, err := atlas.Pack([]atlas.Sprite{sprites[0], sprites[0]}, atlas.Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		a := &atlas.Atlas{
+			Pages:   []string{"enemies_0.png"},
+			Regions: map[string]atlas.Region{"enemy_goblin": {Page: 0, X: 1, Y: 1, W: 32, H: 32}},
+		}
+		data, err := a.Marshal()
+		require.NoError(t, err)
+		parsed, err := atlas.Parse(data)
+		require.NoError(t, err)
+		assert.Equal(t, a, parsed)
+		assert.Equal(t, []string{"assets/atlas/enemies_0.png"}, parsed.PagePaths("assets/atlas/enemies"+atlas.Ext))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		testCases := map[string]string{
+			"PageOutOfRange": "pages: [a.png]\nregions:\n  x: {page: 1, x: 0, y: 0, w: 1, h: 1}\n",
+			"EmptyRect":      "pages: [a.png]\nregions:\n  x: {page: 0, x: 0, y: 0, w: 0, h: 1}\n",
+			"PageTraversal":  "pages: [../a.png]\n",
+			"Syntax":         "pages: [",
+		}
+		for name, data := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := atlas.Parse([]byte(data))
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestGrid(t *testing.T) {
+	t.Run("HorizontalStrip", func(t *testing.T) {
+		rects, err := atlas.Grid{Frames: 4}.Slice(image.Pt(128, 48))
+		require.NoError(t, err)
+		assert.Equal(t, []image.Rectangle{
+			image.Rect(0, 0, 32, 48), image.Rect(32, 0, 64, 48),
+			image.Rect(64, 0, 96, 48), image.Rect(96, 0, 128, 48),
+		}, rects)
+	})
+
+	t.Run("RowMajorGrid", func(t *testing.T) {
+		rects, err := atlas.Grid{Frames: 3, FrameWidth: 16, FrameHeight: 16}.Slice(image.Pt(32, 32))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 16, 16, 32), rects[2])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		testCases := map[string]struct {
+			grid atlas.Grid
+			size image.Point
+		}{
+			"NoFrames":      {atlas.Grid{}, image.Pt(32, 32)},
+			"NotDivisible":  {atlas.Grid{Frames: 3}, image.Pt(32, 32)},
+			"TooManyFrames": {atlas.Grid{Frames: 5, FrameWidth: 16, FrameHeight: 16}, image.Pt(32, 32)},
+			"FrameTooLarge": {atlas.Grid{Frames: 1, FrameWidth: 64}, image.Pt(32, 32)},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := tc.grid.Slice(tc.size)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package atlas
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+)
+
+// DefaultPageSize - ページの一辺の既定値。多くの GPU で扱える大きさにしている。
+const DefaultPageSize = 2048
+
+// Options - 詰め込みの設定
+type Options struct {
+	PageSize int // ページの一辺 (ピクセル)。0 は DefaultPageSize。
+	Padding  int // スプライトの周囲に確保する余白。縁のピクセルで埋めて滲みを防ぐ。
+}
+
+func (o Options) pageSize() int {
+	if o.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return o.PageSize
+}
+
+// Sprite - 詰め込むスプライト
+type Sprite struct {
+	Name  string
+	Image image.Image
+}
+
+// Result - 詰め込みの結果
+type Result struct {
+	Regions map[string]Region
+	Pages   []*image.NRGBA
+}
+
+// Pack - スプライトをページへ詰め込む。結果は入力の順序によらず同じになる。
+func Pack(sprites []Sprite, opts Options) (*Result, error) {
+	sizes := make([]image.Point, len(sprites))
+	seen := make(map[string]bool, len(sprites))
+	for i, s := range sprites {
+		if seen[s.Name] {
+			return nil, fmt.Errorf("スプライト名が重複しています: %s", s.Name)
+		}
+		seen[s.Name] = true
+		sizes[i] = s.Image.Bounds().Size()
+	}
+	order := make([]int, len(sprites))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return sprites[order[i]].Name < sprites[order[j]].Name })
+	sorted := make([]image.Point, len(order))
+	for i, idx := range order {
+		sorted[i] = sizes[idx]
+	}
+
+	regions, pages, err := Layout(sorted, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Regions: make(map[string]Region, len(sprites)), Pages: make([]*image.NRGBA, len(pages))}
+	for i, size := range pages {
+		result.Pages[i] = image.NewNRGBA(image.Rectangle{Max: size})
+	}
+	for i, idx := range order {
+		r := regions[i]
+		result.Regions[sprites[idx].Name] = r
+		blit(result.Pages[r.Page], r.Rect(), sprites[idx].Image, opts.Padding)
+	}
+	return result, nil
+}
+
+// Layout - 大きさの一覧をページに配置する。戻り値は入力と同じ順序の配置と、
+// 使用した範囲に切り詰めた各ページの大きさ。
+func Layout(sizes []image.Point, opts Options) ([]Region, []image.Point, error) {
+	pageSize, pad := opts.pageSize(), max(opts.Padding, 0)
+
+	// 高いものから置くと隙間が少なくなる
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := sizes[order[i]], sizes[order[j]]
+		if a.Y != b.Y {
+			return a.Y > b.Y
+		}
+		return a.X > b.X
+	})
+
+	regions := make([]Region, len(sizes))
+	var pages []*skyline
+	for _, i := range order {
+		w, h := sizes[i].X+2*pad, sizes[i].Y+2*pad
+		if sizes[i].X <= 0 || sizes[i].Y <= 0 {
+			return nil, nil, fmt.Errorf("%d 番目のスプライトの大きさが不正です: %v", i, sizes[i])
+		}
+		if w > pageSize || h > pageSize {
+			return nil, nil, fmt.Errorf("%d 番目のスプライト (%dx%d) がページ (%d) に収まりません", i, sizes[i].X, sizes[i].Y, pageSize)
+		}
+
+		placed := false
+		for p, page := range pages {
+			if x, y, ok := page.insert(w, h); ok {
+				regions[i] = Region{Page: p, X: x + pad, Y: y + pad, W: sizes[i].X, H: sizes[i].Y}
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			page := newSkyline(pageSize)
+			x, y, _ := page.insert(w, h)
+			regions[i] = Region{Page: len(pages), X: x + pad, Y: y + pad, W: sizes[i].X, H: sizes[i].Y}
+			pages = append(pages, page)
+		}
+	}
+
+	used := make([]image.Point, len(pages))
+	for i, page := range pages {
+		used[i] = page.used
+	}
+	return regions, used, nil
+}
+
+// skyline - スカイライン法による配置。置いた矩形の上端を左から順に持つ。
+type skyline struct {
+	size  int
+	nodes []segment
+	used  image.Point
+}
+
+type segment struct{ x, y, w int }
+
+func newSkyline(size int) *skyline {
+	return &skyline{size: size, nodes: []segment{{0, 0, size}}}
+}
+
+// insert - 上端が最も低くなる位置 (同じなら最も狭い段) に置く
+func (s *skyline) insert(w, h int) (x, y int, ok bool) {
+	best, bestTop, bestWidth := -1, 0, 0
+	for i := range s.nodes {
+		top, fits := s.fit(i, w, h)
+		if !fits {
+			continue
+		}
+		if best < 0 || top+h < bestTop || (top+h == bestTop && s.nodes[i].w < bestWidth) {
+			best, bestTop, bestWidth, y = i, top+h, s.nodes[i].w, top
+		}
+	}
+	if best < 0 {
+		return 0, 0, false
+	}
+	x = s.nodes[best].x
+	s.place(best, segment{x, y + h, w})
+	s.used = image.Pt(max(s.used.X, x+w), max(s.used.Y, y+h))
+	return x, y, true
+}
+
+// fit - i 番目の段から幅 w を置いたときの下端の高さ
+func (s *skyline) fit(i, w, h int) (int, bool) {
+	if s.nodes[i].x+w > s.size {
+		return 0, false
+	}
+	y := 0
+	for j, remaining := i, w; remaining > 0; j++ {
+		y = max(y, s.nodes[j].y)
+		if y+h > s.size {
+			return 0, false
+		}
+		remaining -= s.nodes[j].w
+	}
+	return y, true
+}
+
+// place - 新しい段を挿入し、覆われた段を削って隣り合う同じ高さの段をまとめる
+func (s *skyline) place(i int, seg segment) {
+	s.nodes = append(s.nodes[:i], append([]segment{seg}, s.nodes[i:]...)...)
+	for j := i + 1; j < len(s.nodes); {
+		end := s.nodes[j-1].x + s.nodes[j-1].w
+		if s.nodes[j].x >= end {
+			break
+		}
+		shrink := end - s.nodes[j].x
+		s.nodes[j].x += shrink
+		s.nodes[j].w -= shrink
+		if s.nodes[j].w > 0 {
+			break
+		}
+		s.nodes = append(s.nodes[:j], s.nodes[j+1:]...)
+	}
+	for j := 0; j+1 < len(s.nodes); {
+		if s.nodes[j].y == s.nodes[j+1].y {
+			s.nodes[j].w += s.nodes[j+1].w
+			s.nodes = append(s.nodes[:j+1], s.nodes[j+2:]...)
+			continue
+		}
+		j++
+	}
+}
+
+// blit - スプライトを描き込み、余白を縁のピクセルで埋める
+func blit(page *image.NRGBA, r image.Rectangle, src image.Image, pad int) {
+	draw.Draw(page, r, src, src.Bounds().Min, draw.Src)
+	if pad <= 0 {
+		return
+	}
+	for y := r.Min.Y - pad; y < r.Max.Y+pad; y++ {
+		for x := r.Min.X - pad; x < r.Max.X+pad; x++ {
+			if image.Pt(x, y).In(r) {
+				continue
+			}
+			cx := min(max(x, r.Min.X), r.Max.X-1)
+			cy := min(max(y, r.Min.Y), r.Max.Y-1)
+			page.SetNRGBA(x, y, page.NRGBAAt(cx, cy))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package atlas
+
+import (
+	"fmt"
+	"image"
+)
+
+// Grid - スプライトシートの切り分け方。フレームの大きさを省略すると
+// シート全体を横一列に Frames 等分する。指定した場合は左上から行優先で切り出す。
+type Grid struct {
+	Frames      int
+	FrameWidth  int
+	FrameHeight int
+}
+
+// Slice - 大きさ size のシートをフレームの矩形に切り分ける
+func (g Grid) Slice(size image.Point) ([]image.Rectangle, error) {
+	if g.Frames <= 0 {
+		return nil, fmt.Errorf("フレーム数が不正です: %d", g.Frames)
+	}
+	w, h := g.FrameWidth, g.FrameHeight
+	if w <= 0 {
+		if size.X%g.Frames != 0 {
+			return nil, fmt.Errorf("シートの幅 %d はフレーム数 %d で割り切れません", size.X, g.Frames)
+		}
+		w = size.X / g.Frames
+	}
+	if h <= 0 {
+		h = size.Y
+	}
+	if w == 0 || h == 0 || w > size.X || h > size.Y {
+		return nil, fmt.Errorf("フレーム (%dx%d) がシート (%dx%d) に収まりません", w, h, size.X, size.Y)
+	}
+
+	columns := size.X / w
+	if g.Frames > columns*(size.Y/h) {
+		return nil, fmt.Errorf("シート (%dx%d) に %dx%d のフレームは %d 枚しか入りません", size.X, size.Y, w, h, columns*(size.Y/h))
+	}
+	rects := make([]image.Rectangle, g.Frames)
+	for i := range rects {
+		x, y := i%columns*w, i/columns*h
+		rects[i] = image.Rect(x, y, x+w, y+h)
+	}
+	return rects, nil
+}
+
+// FrameName - シートの i 番目のフレームの領域名
+func FrameName(sheet string, i int) string {
+	return fmt.Sprintf("%s#%d", sheet, i)
+}
@@ -0,0 +1,36 @@
+package atomicfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/atomicfile"
+)
+
+// TestWriteFile - 一時ファイル経由の保存テスト
+func TestWriteFile(t *testing.T) {
+	t.Run("ReplacesFileWithoutLeavingTemporaries", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+		require.NoError(t, atomicfile.WriteFile(path, []byte("new")))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "一時ファイルを残さない")
+	})
+
+	t.Run("MissingDirectory", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "state.yaml")
+		assert.Error(t, atomicfile.WriteFile(path, []byte("x")))
+		assert.NoFileExists(t, path)
+	})
+}
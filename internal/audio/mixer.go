@@ -0,0 +1,40 @@
+// Package audio はBGM・効果音の音量管理を行う。
+package audio
+
+import "muscle-dreamer/internal/config"
+
+// Mixer - マスター・BGM・効果音の音量を保持するミキサー
+type Mixer struct {
+	master float64
+	bgm    float64
+	sfx    float64
+}
+
+// NewMixer - 設定からミキサーを作成する
+func NewMixer(settings config.AudioSettings) *Mixer {
+	m := &Mixer{}
+	m.Apply(settings)
+	return m
+}
+
+// Apply - 音量設定を反映する
+func (m *Mixer) Apply(settings config.AudioSettings) {
+	m.master = settings.MasterVolume
+	m.bgm = settings.BGMVolume
+	m.sfx = settings.SFXVolume
+}
+
+// MasterVolume - マスター音量
+func (m *Mixer) MasterVolume() float64 {
+	return m.master
+}
+
+// BGMVolume - マスター音量を掛けた実効BGM音量
+func (m *Mixer) BGMVolume() float64 {
+	return m.master * m.bgm
+}
+
+// SFXVolume - マスター音量を掛けた実効効果音音量
+func (m *Mixer) SFXVolume() float64 {
+	return m.master * m.sfx
+}
@@ -0,0 +1,230 @@
+// Package config は config/game.yaml の読み込みと検証を行う。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+// DefaultPath - 標準の設定ファイルパス
+const DefaultPath = "config/game.yaml"
+
+// 設定エラーコード
+const (
+	CodeReadFailed  = "CONFIG_READ_FAILED"
+	CodeParseFailed = "CONFIG_PARSE_FAILED"
+	CodeOutOfRange  = "CONFIG_OUT_OF_RANGE"
+	CodeRequired    = "CONFIG_REQUIRED"
+	CodeUnknownKey  = "CONFIG_UNKNOWN_KEY"
+)
+
+// GameConfig - game.yamlから読み込まれる設定
+type GameConfig struct {
+	Game     GameSettings     `yaml:"game"`
+	Graphics GraphicsSettings `yaml:"graphics"`
+	Audio    AudioSettings    `yaml:"audio"`
+	Input    InputSettings    `yaml:"input"`
+}
+
+// GameSettings - ゲーム基本設定
+type GameSettings struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// GraphicsSettings - 描画設定
+type GraphicsSettings struct {
+	Width      int  `yaml:"width"`
+	Height     int  `yaml:"height"`
+	Fullscreen bool `yaml:"fullscreen"`
+	VSync      bool `yaml:"vsync"`
+}
+
+// AudioSettings - オーディオ設定
+type AudioSettings struct {
+	MasterVolume float64 `yaml:"master_volume"`
+	BGMVolume    float64 `yaml:"bgm_volume"`
+	SFXVolume    float64 `yaml:"sfx_volume"`
+}
+
+// InputSettings - 入力設定
+type InputSettings struct {
+	KeyboardEnabled bool              `yaml:"keyboard_enabled"`
+	MouseEnabled    bool              `yaml:"mouse_enabled"`
+	GamepadEnabled  bool              `yaml:"gamepad_enabled"`
+	KeyBindings     map[string]string `yaml:"key_bindings"` // アクション名 → キー名
+}
+
+// Default - ファイルに記述がない項目に使われる既定値
+func Default() *GameConfig {
+	return &GameConfig{
+		Game: GameSettings{
+			Title:   "マッスルドリーマー〜観光編〜",
+			Version: "0.1.0",
+		},
+		Graphics: GraphicsSettings{
+			Width:  1280,
+			Height: 720,
+			VSync:  true,
+		},
+		Audio: AudioSettings{
+			MasterVolume: 1.0,
+			BGMVolume:    0.8,
+			SFXVolume:    1.0,
+		},
+		Input: InputSettings{
+			KeyboardEnabled: true,
+			MouseEnabled:    true,
+			GamepadEnabled:  true,
+			KeyBindings: map[string]string{
+				"move_up":    "W",
+				"move_down":  "S",
+				"move_left":  "A",
+				"move_right": "D",
+				"action":     "Space",
+				"menu":       "Escape",
+			},
+		},
+	}
+}
+
+// Load - 設定ファイルを読み込み、既定値を補完して検証する。
+// ファイルが存在しない場合は既定値をそのまま返す。
+func Load(path string) (*GameConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, gameerr.ConfigError{
+			Code:    CodeReadFailed,
+			Message: fmt.Sprintf("設定ファイルを読み込めません: %s: %v", path, err),
+		}
+	}
+	return parse(path, data)
+}
+
+// Parse - YAMLデータを既定値の上に展開して検証する
+func Parse(data []byte) (*GameConfig, error) {
+	return parse("", data)
+}
+
+func parse(file string, data []byte) (*GameConfig, error) {
+	cfg := Default()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, gameerr.ConfigError{
+			Code:    CodeParseFailed,
+			Message: fmt.Sprintf("設定ファイルの解析に失敗しました: %v", err),
+		}
+	}
+	if _, err := checkSchema(file, "", data); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate - 設定値の範囲を検証し、最初に見つかった問題を返す
+func (c *GameConfig) Validate() error {
+	if c.Game.Title == "" {
+		return required("game.title")
+	}
+	if c.Graphics.Width <= 0 {
+		return outOfRange("graphics.width", "正の整数", c.Graphics.Width)
+	}
+	if c.Graphics.Height <= 0 {
+		return outOfRange("graphics.height", "正の整数", c.Graphics.Height)
+	}
+
+	volumes := []struct {
+		field string
+		value float64
+	}{
+		{"audio.master_volume", c.Audio.MasterVolume},
+		{"audio.bgm_volume", c.Audio.BGMVolume},
+		{"audio.sfx_volume", c.Audio.SFXVolume},
+	}
+	for _, v := range volumes {
+		if v.value < 0 || v.value > 1 {
+			return outOfRange(v.field, "0.0〜1.0", v.value)
+		}
+	}
+
+	for action, key := range c.Input.KeyBindings {
+		if action == "" {
+			return required("input.key_bindings")
+		}
+		if key == "" {
+			return required("input.key_bindings." + action)
+		}
+	}
+	return nil
+}
+
+// checkSchema - ファイルの内容を GameSchema で検証する。section が指定された場合は
+// その節だけを記述したユーザー上書きファイルとして扱う。警告は戻り値で返し、
+// エラーがあれば全ての問題を列挙した ConfigError を返す。
+func checkSchema(file, section string, data []byte) ([]schema.Issue, error) {
+	root := schema.GameSchema()
+	if section != "" {
+		root = root.Lookup(section)
+	}
+
+	var warnings, errs []schema.Issue
+	for _, issue := range schema.Validate(file, data, root) {
+		if section != "" && issue.Path != "" {
+			issue.Path = section + "." + issue.Path
+		}
+		if issue.Severity >= gameerr.ErrorSeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, gameerr.ConfigError{
+		Code:    errs[0].Code,
+		Message: strings.TrimSuffix(schema.FormatAll(errs, schema.LangFromEnv()), "\n"),
+		Field:   errs[0].Path,
+	}
+}
+
+func required(field string) error {
+	return gameerr.ConfigError{
+		Code:    CodeRequired,
+		Message: fmt.Sprintf("%s は必須です", field),
+		Field:   field,
+	}
+}
+
+func outOfRange(field, want string, got interface{}) error {
+	return gameerr.ConfigError{
+		Code:    CodeOutOfRange,
+		Message: fmt.Sprintf("%s は %s である必要があります (値: %v)", field, want, got),
+		Field:   field,
+	}
+}
+
+// Clone - 設定のディープコピー
+func (c *GameConfig) Clone() *GameConfig {
+	clone := *c
+	if c.Input.KeyBindings != nil {
+		clone.Input.KeyBindings = make(map[string]string, len(c.Input.KeyBindings))
+		for action, key := range c.Input.KeyBindings {
+			clone.Input.KeyBindings[action] = key
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,76 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/config"
+	"muscle-dreamer/internal/gameerr"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("ShippedConfig", func(t *testing.T) {
+		cfg, err := config.Load(filepath.Join("..", "..", config.DefaultPath))
+		require.NoError(t, err)
+
+		assert.Equal(t, "マッスルドリーマー〜観光編〜", cfg.Game.Title)
+		assert.Equal(t, 1280, cfg.Graphics.Width)
+		assert.Equal(t, 720, cfg.Graphics.Height)
+		assert.True(t, cfg.Graphics.VSync)
+		assert.Equal(t, 0.8, cfg.Audio.BGMVolume)
+		assert.True(t, cfg.Input.GamepadEnabled)
+	})
+
+	t.Run("MissingFileUsesDefaults", func(t *testing.T) {
+		cfg, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, config.Default(), cfg)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("PartialFileKeepsDefaults", func(t *testing.T) {
+		cfg, err := config.Parse([]byte("graphics:\n  fullscreen: true\n"))
+		require.NoError(t, err)
+
+		assert.True(t, cfg.Graphics.Fullscreen)
+		assert.Equal(t, 1280, cfg.Graphics.Width)
+		assert.Equal(t, 1.0, cfg.Audio.MasterVolume)
+	})
+
+	t.Run("MalformedYAML", func(t *testing.T) {
+		_, err := config.Parse([]byte("graphics: [\n"))
+
+		var cfgErr gameerr.ConfigError
+		require.True(t, errors.As(err, &cfgErr))
+		assert.Equal(t, config.CodeParseFailed, cfgErr.Code)
+	})
+
+	testCases := []struct {
+		name  string
+		yaml  string
+		field string
+	}{
+		{"ZeroWidth", "graphics:\n  width: 0\n", "graphics.width"},
+		{"NegativeHeight", "graphics:\n  height: -720\n", "graphics.height"},
+		{"MasterVolumeTooLoud", "audio:\n  master_volume: 1.5\n", "audio.master_volume"},
+		{"NegativeBGMVolume", "audio:\n  bgm_volume: -0.1\n", "audio.bgm_volume"},
+		{"SFXVolumeTooLoud", "audio:\n  sfx_volume: 2\n", "audio.sfx_volume"},
+		{"EmptyTitle", "game:\n  title: \"\"\n", "game.title"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := config.Parse([]byte(tc.yaml))
+
+			var cfgErr gameerr.ConfigError
+			require.True(t, errors.As(err, &cfgErr))
+			assert.Equal(t, tc.field, cfgErr.Field)
+			assert.Equal(t, gameerr.ErrorSeverityError, cfgErr.GetSeverity())
+		})
+	}
+}
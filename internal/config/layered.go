@@ -0,0 +1,410 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+// EnvPrefix - 設定を上書きする環境変数の接頭辞
+const EnvPrefix = "MUSCLE_DREAMER_"
+
+// Source - 設定値の出どころ
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceUser
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "file"
+	case SourceUser:
+		return "user"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "unknown"
+	}
+}
+
+// Origin - 実効値がどのレイヤーから来たか
+type Origin struct {
+	Source   Source
+	Location string // ファイルパス・環境変数名・フラグ名
+}
+
+func (o Origin) String() string {
+	if o.Location == "" {
+		return o.Source.String()
+	}
+	return o.Source.String() + " " + o.Location
+}
+
+// userFiles - ユーザー設定ディレクトリ内の上書きファイルと対応セクション。
+// 後ろのファイルほど優先される。
+var userFiles = []struct {
+	name    string
+	section string
+}{
+	{"config.yaml", ""},
+	{"graphics.yaml", "graphics"},
+	{"audio.yaml", "audio"},
+	{"keybinds.yaml", "input"},
+}
+
+// Options - レイヤー読み込みの入力
+type Options struct {
+	BasePath string      // 同梱設定ファイル (通常 config/game.yaml)
+	UserDir  string      // ユーザー上書きファイルのディレクトリ (空なら読まない)
+	Environ  []string    // os.Environ() 形式の環境変数
+	Flags    *FlagValues // コマンドラインで明示された値
+}
+
+// Layered - 全レイヤーを合成した結果
+type Layered struct {
+	Config  *GameConfig
+	Base    *GameConfig // 既定値と同梱ファイルのみを合成した設定 (ユーザー上書きの比較元)
+	User    *GameConfig // Base にユーザー設定ファイルまでを合成した設定 (環境変数・フラグを含まない)
+	Origins map[string]Origin
+
+	// Sources - 読み込んだ設定ファイル。まだないユーザー設定ファイルも、作られたら読むので含む。
+	Sources []string
+
+	// Warnings - 読み込みを止めないスキーマ上の問題 (未知のキーなど)
+	Warnings []schema.Issue
+}
+
+// Origin - キーの実効値の出どころ
+func (l *Layered) Origin(key string) Origin {
+	return l.Origins[key]
+}
+
+// Report - サポート問い合わせ用に全キーの実効値と出どころを列挙する
+func (l *Layered) Report() string {
+	fields := fieldsOf(l.Config)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s = %v (%s)\n", key, fields[key].Interface(), l.Origins[key])
+	}
+	return b.String()
+}
+
+// UserDir - ユーザー設定ディレクトリ。取得できない環境では空文字を返す。
+func UserDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "muscle-dreamer")
+}
+
+// LoadLayered - 既定値・同梱ファイル・ユーザー上書き・環境変数・フラグの順に合成する
+func LoadLayered(opts Options) (*Layered, error) {
+	l := &Layered{Config: Default(), Origins: make(map[string]Origin)}
+	fields := fieldsOf(l.Config)
+	for key := range fields {
+		l.Origins[key] = Origin{Source: SourceDefault}
+	}
+
+	if opts.BasePath != "" {
+		l.Sources = append(l.Sources, opts.BasePath)
+		if err := l.mergeFile(fields, opts.BasePath, "", SourceFile); err != nil {
+			return nil, err
+		}
+	}
+	l.Base = l.Config.Clone()
+	if opts.UserDir != "" {
+		for _, uf := range userFiles {
+			path := filepath.Join(opts.UserDir, uf.name)
+			l.Sources = append(l.Sources, path)
+			if err := l.mergeFile(fields, path, uf.section, SourceUser); err != nil {
+				return nil, err
+			}
+		}
+	}
+	l.User = l.Config.Clone()
+	if err := l.mergeEnv(fields, opts.Environ); err != nil {
+		return nil, err
+	}
+	if opts.Flags != nil {
+		for _, key := range opts.Flags.order {
+			origin := Origin{Source: SourceFlag, Location: "-" + key}
+			if err := l.set(fields, key, opts.Flags.values[key], origin); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := l.Config.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// mergeFile - YAMLファイルを読み込み、存在するキーだけを上書きする
+func (l *Layered) mergeFile(fields map[string]reflect.Value, path, section string, source Source) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return gameerr.ConfigError{
+			Code:    CodeReadFailed,
+			Message: fmt.Sprintf("設定ファイルを読み込めません: %s: %v", path, err),
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return gameerr.ConfigError{
+			Code:    CodeParseFailed,
+			Message: fmt.Sprintf("設定ファイルの解析に失敗しました: %s: %v", path, err),
+		}
+	}
+	warnings, err := checkSchema(path, section, data)
+	l.Warnings = append(l.Warnings, warnings...)
+	if err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	return l.mergeNode(fields, doc.Content[0], section, Origin{Source: source, Location: path})
+}
+
+func (l *Layered) mergeNode(fields map[string]reflect.Value, node *yaml.Node, prefix string, origin Origin) error {
+	if target, ok := fields[prefix]; ok {
+		if err := node.Decode(target.Addr().Interface()); err != nil {
+			return invalidValue(prefix, origin, err)
+		}
+		l.Origins[prefix] = origin
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if err := l.mergeNode(fields, node.Content[i+1], key, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeEnv - MUSCLE_DREAMER_GRAPHICS_WIDTH のような環境変数を反映する
+func (l *Layered) mergeEnv(fields map[string]reflect.Value, environ []string) error {
+	byName := make(map[string]string, len(fields))
+	for key := range fields {
+		byName[EnvName(key)] = key
+	}
+
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		key, known := byName[name]
+		if !known {
+			continue
+		}
+		if err := l.set(fields, key, value, Origin{Source: SourceEnv, Location: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set - 文字列で与えられた値をフィールドの型に変換して設定する
+func (l *Layered) set(fields map[string]reflect.Value, key, value string, origin Origin) error {
+	target, ok := fields[key]
+	if !ok {
+		return gameerr.ConfigError{
+			Code:    CodeUnknownKey,
+			Message: fmt.Sprintf("未知の設定キーです: %s (%s)", key, origin),
+			Field:   key,
+		}
+	}
+
+	if target.Kind() == reflect.String {
+		target.SetString(value)
+	} else if err := yaml.Unmarshal([]byte(value), target.Addr().Interface()); err != nil {
+		return invalidValue(key, origin, err)
+	}
+	l.Origins[key] = origin
+	return nil
+}
+
+// UserFile - セクションの上書きを保存するユーザー設定ファイル名
+func UserFile(section string) string {
+	for _, uf := range userFiles {
+		if uf.section == section {
+			return uf.name
+		}
+	}
+	return ""
+}
+
+// Overrides - base と値が異なるセクション内のキーと値を返す。
+// キーはセクション名を除いた形式 (例: "width")。
+func (c *GameConfig) Overrides(base *GameConfig, section string) map[string]interface{} {
+	current := fieldsOf(c)
+	original := fieldsOf(base)
+
+	overrides := make(map[string]interface{})
+	for key, value := range current {
+		name, ok := strings.CutPrefix(key, section+".")
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(value.Interface(), original[key].Interface()) {
+			overrides[name] = value.Interface()
+		}
+	}
+	return overrides
+}
+
+// CopyChanged - previous と next で値が異なるセクション内のキーだけを c に写す
+func (c *GameConfig) CopyChanged(previous, next *GameConfig, section string) {
+	target := fieldsOf(c)
+	before := fieldsOf(previous)
+	for key, value := range fieldsOf(next) {
+		if !strings.HasPrefix(key, section+".") {
+			continue
+		}
+		if reflect.DeepEqual(value.Interface(), before[key].Interface()) {
+			continue
+		}
+		if value.Kind() == reflect.Map && !value.IsNil() {
+			// キーバインドなどのマップは next と共有しない
+			copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+			for iter := value.MapRange(); iter.Next(); {
+				copied.SetMapIndex(iter.Key(), iter.Value())
+			}
+			value = copied
+		}
+		target[key].Set(value)
+	}
+}
+
+// EnvName - 設定キーに対応する環境変数名
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func invalidValue(key string, origin Origin, err error) error {
+	return gameerr.ConfigError{
+		Code:    CodeParseFailed,
+		Message: fmt.Sprintf("%s の値が不正です (%s): %v", key, origin, err),
+		Field:   key,
+	}
+}
+
+// fieldsOf - yamlタグから "section.field" 形式のキーで末端フィールドを列挙する
+func fieldsOf(cfg *GameConfig) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	collectFields(reflect.ValueOf(cfg).Elem(), "", fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			collectFields(field, name, fields)
+			continue
+		}
+		fields[name] = field
+	}
+}
+
+// FlagValues - コマンドラインで明示的に指定された設定値
+type FlagValues struct {
+	values map[string]string
+	order  []string
+}
+
+// BindFlags - 全設定キーを "-graphics.width" 形式のフラグとして登録する
+func BindFlags(flags *flag.FlagSet) *FlagValues {
+	fv := &FlagValues{values: make(map[string]string)}
+
+	defaults := Default()
+	fields := fieldsOf(defaults)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		usage := fmt.Sprintf("%s を上書きする (既定値: %v)", key, fields[key].Interface())
+		flags.Var(&flagValue{key: key, fv: fv, isBool: fields[key].Kind() == reflect.Bool}, key, usage)
+	}
+	return fv
+}
+
+type flagValue struct {
+	key    string
+	fv     *FlagValues
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.fv == nil {
+		return ""
+	}
+	return f.fv.values[f.key]
+}
+
+func (f *flagValue) Set(value string) error {
+	if _, seen := f.fv.values[f.key]; !seen {
+		f.fv.order = append(f.fv.order, f.key)
+	}
+	f.fv.values[f.key] = value
+	return nil
+}
+
+// IsBoolFlag - "-graphics.fullscreen" のように値を省略できるようにする
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
@@ -0,0 +1,130 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/config"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadLayered(t *testing.T) {
+	t.Run("LayerPrecedence", func(t *testing.T) {
+		dir := t.TempDir()
+		base := writeFile(t, dir, "game.yaml", "graphics:\n  width: 1600\n  height: 900\naudio:\n  bgm_volume: 0.5\n")
+
+		userDir := filepath.Join(dir, "user")
+		require.NoError(t, os.Mkdir(userDir, 0o755))
+		writeFile(t, userDir, "config.yaml", "graphics:\n  height: 1000\n")
+		writeFile(t, userDir, "graphics.yaml", "height: 1050\nfullscreen: true\n")
+		writeFile(t, userDir, "audio.yaml", "sfx_volume: 0.3\n")
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := config.BindFlags(fs)
+		require.NoError(t, fs.Parse([]string{"-audio.master_volume", "0.9", "-input.mouse_enabled=false"}))
+
+		l, err := config.LoadLayered(config.Options{
+			BasePath: base,
+			UserDir:  userDir,
+			Environ:  []string{"MUSCLE_DREAMER_AUDIO_SFX_VOLUME=0.4", "MUSCLE_DREAMER_AUDIO_MASTER_VOLUME=0.1", "PATH=/bin"},
+			Flags:    flags,
+		})
+		require.NoError(t, err)
+
+		cfg := l.Config
+		assert.Equal(t, 1600, cfg.Graphics.Width)
+		assert.Equal(t, 1050, cfg.Graphics.Height)
+		assert.True(t, cfg.Graphics.Fullscreen)
+		assert.Equal(t, 0.5, cfg.Audio.BGMVolume)
+		assert.Equal(t, 0.4, cfg.Audio.SFXVolume)
+		assert.Equal(t, 0.9, cfg.Audio.MasterVolume)
+		assert.False(t, cfg.Input.MouseEnabled)
+
+		assert.Equal(t, config.Origin{Source: config.SourceDefault}, l.Origin("game.title"))
+		assert.Equal(t, config.Origin{Source: config.SourceFile, Location: base}, l.Origin("graphics.width"))
+		assert.Equal(t, config.SourceUser, l.Origin("graphics.height").Source)
+		assert.Equal(t, filepath.Join(userDir, "graphics.yaml"), l.Origin("graphics.height").Location)
+		assert.Equal(t, config.Origin{Source: config.SourceEnv, Location: "MUSCLE_DREAMER_AUDIO_SFX_VOLUME"}, l.Origin("audio.sfx_volume"))
+		assert.Equal(t, config.Origin{Source: config.SourceFlag, Location: "-audio.master_volume"}, l.Origin("audio.master_volume"))
+
+		assert.Equal(t, []string{
+			base,
+			filepath.Join(userDir, "config.yaml"),
+			filepath.Join(userDir, "graphics.yaml"),
+			filepath.Join(userDir, "audio.yaml"),
+			filepath.Join(userDir, "keybinds.yaml"),
+		}, l.Sources, "まだないユーザー設定ファイルも含む")
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		l, err := config.LoadLayered(config.Options{
+			Environ: []string{"MUSCLE_DREAMER_GRAPHICS_VSYNC=false"},
+		})
+		require.NoError(t, err)
+
+		report := l.Report()
+		assert.Contains(t, report, "graphics.vsync = false (env MUSCLE_DREAMER_GRAPHICS_VSYNC)\n")
+		assert.Contains(t, report, "graphics.width = 1280 (default)\n")
+	})
+
+	t.Run("InvalidEnvValue", func(t *testing.T) {
+		_, err := config.LoadLayered(config.Options{
+			Environ: []string{"MUSCLE_DREAMER_GRAPHICS_WIDTH=wide"},
+		})
+
+		var cfgErr gameerr.ConfigError
+		require.True(t, errors.As(err, &cfgErr))
+		assert.Equal(t, config.CodeParseFailed, cfgErr.Code)
+		assert.Equal(t, "graphics.width", cfgErr.Field)
+		assert.Contains(t, cfgErr.Message, "MUSCLE_DREAMER_GRAPHICS_WIDTH")
+	})
+
+	t.Run("MergedResultIsValidated", func(t *testing.T) {
+		_, err := config.LoadLayered(config.Options{
+			Environ: []string{"MUSCLE_DREAMER_AUDIO_BGM_VOLUME=3"},
+		})
+
+		var cfgErr gameerr.ConfigError
+		require.True(t, errors.As(err, &cfgErr))
+		assert.Equal(t, config.CodeOutOfRange, cfgErr.Code)
+		assert.Equal(t, "audio.bgm_volume", cfgErr.Field)
+	})
+
+	t.Run("UserFileIsCheckedAgainstSchema", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeFile(t, dir, "graphics.yaml", "widht: 800\nheight: 0\n")
+
+		_, err := config.LoadLayered(config.Options{UserDir: dir})
+
+		var cfgErr gameerr.ConfigError
+		require.True(t, errors.As(err, &cfgErr))
+		assert.Equal(t, schema.CodeOutOfRange, cfgErr.Code)
+		assert.Equal(t, "graphics.height", cfgErr.Field)
+		assert.Contains(t, cfgErr.Message, path+":2:9")
+	})
+
+	t.Run("UnknownKeysAreWarnings", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "graphics.yaml", "widht: 800\n")
+
+		l, err := config.LoadLayered(config.Options{UserDir: dir})
+		require.NoError(t, err)
+		require.Len(t, l.Warnings, 1)
+		assert.Equal(t, "graphics.widht", l.Warnings[0].Path)
+		assert.Equal(t, "did you mean width?", l.Warnings[0].Suggestion(schema.LangEn))
+	})
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/hotreload"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/theme"
+)
+
+// デバッグオーバーレイのレイアウト
+const (
+	overlayMargin     = 8
+	overlayLineHeight = 16
+)
+
+var overlayBackground = color.RGBA{120, 0, 0, 200}
+
+// setupHotReload - 設定ファイル・テーマYAML・アセットの監視を開始する。設定は同梱ファイルと
+// ユーザー設定ファイルのどれが変わっても全レイヤーから読み直す。
+func (g *Game) setupHotReload() {
+	g.watcher = hotreload.NewWatcher(hotreload.DefaultInterval)
+
+	if g.options.LoadConfig != nil {
+		sources := g.settings.Sources()
+		if g.options.ConfigPath != "" && !slices.Contains(sources, g.options.ConfigPath) {
+			sources = append(sources, g.options.ConfigPath)
+		}
+		for _, path := range sources {
+			g.watcher.Watch(path, g.reloadConfig)
+		}
+	}
+	g.watcher.WatchDir(g.options.ThemePath, ".yaml", g.reloadThemeYAML)
+	for _, dir := range []string{"assets", g.options.ThemePath} {
+		for _, ext := range reloadableExts {
+			g.watcher.WatchDir(dir, ext, g.reloadAsset)
+		}
+	}
+}
+
+// reloadableExts - 変更時に読み直すアセットの拡張子
+var reloadableExts = []string{".png", ".jpg", ".jpeg", ".ogg", ".wav", ".mp3", ".ttf", ".otf"}
+
+// reloadAsset - 変更されたファイルから読み込まれたアセットを読み直す。テーマ内のファイルは
+// テーマのルートからの相対パスでも仮想ファイルシステム上に現れるため、両方を対象にする。
+func (g *Game) reloadAsset(path string) error {
+	names := []string{filepath.ToSlash(filepath.Clean(path))}
+	if rel, err := filepath.Rel(g.options.ThemePath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		// themes/<テーマ>/assets/... → assets/...
+		if _, inTheme, ok := strings.Cut(filepath.ToSlash(rel), "/"); ok {
+			names = append(names, inTheme)
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		if _, err := g.assets.Reload(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reloadConfig - 設定を全レイヤーから読み直して反映する
+func (g *Game) reloadConfig(string) error {
+	layered, err := g.options.LoadConfig()
+	if err != nil {
+		return err
+	}
+	return g.settings.Reload(layered)
+}
+
+// reloadThemeYAML - テーマのYAMLを検証し、有効なテーマか基底テーマのファイルであれば
+// テーマを読み直して反映する (ステージ定義やプレハブも作り直される)
+func (g *Game) reloadThemeYAML(path string) error {
+	id := g.themeOf(path)
+	if id != "" && filepath.Base(path) == theme.FileName {
+		if err := g.checkTheme(id); err != nil {
+			return err
+		}
+	} else if err := checkYAML(path); err != nil {
+		return err
+	}
+
+	if current := g.themes.GetCurrentTheme(); current != nil && slices.Contains(current.Chain, id) {
+		return g.SetTheme(current.ID)
+	}
+	return g.reloadAsset(path)
+}
+
+// themeOf - テーマディレクトリ内のファイルが属するテーマのID。テーマの外なら空文字。
+func (g *Game) themeOf(path string) string {
+	rel, err := filepath.Rel(g.options.ThemePath, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	id, _, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// checkTheme - 基底テーマを重ねた theme.yaml をスキーマで検証する
+func (g *Game) checkTheme(id string) error {
+	_, issues, err := g.themes.Inspect(id)
+	if err != nil {
+		return err
+	}
+	return issuesError(issues)
+}
+
+// checkYAML - YAMLとして解析できることを確認する
+func checkYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return issuesError(schema.Validate("", data, schema.Any()))
+}
+
+// issuesError - スキーマのエラーを1つのエラーにまとめる。警告だけなら nil を返す。
+func issuesError(issues []schema.Issue) error {
+	var errs []schema.Issue
+	for _, issue := range issues {
+		if issue.Severity >= gameerr.ErrorSeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	first := errs[0]
+	msg := fmt.Sprintf("%s [%s] %s: %s", first.Location(), first.Code, first.Path, first.Message(schema.LangFromEnv()))
+	if len(errs) > 1 {
+		msg += fmt.Sprintf(" (他 %d 件)", len(errs)-1)
+	}
+	return errors.New(msg)
+}
+
+// updateHotReload - 監視中のファイルの変更を確認する
+func (g *Game) updateHotReload() {
+	if g.watcher != nil {
+		g.watcher.Poll(time.Now())
+	}
+}
+
+// drawDebugOverlay - 再読み込みエラーを画面上部に表示する
+func (g *Game) drawDebugOverlay(screen *ebiten.Image) {
+	if g.watcher == nil {
+		return
+	}
+	errs := g.watcher.Errors()
+	if len(errs) == 0 {
+		return
+	}
+
+	width := float32(screen.Bounds().Dx())
+	height := float32(overlayMargin*2 + overlayLineHeight*(len(errs)+1))
+	vector.DrawFilledRect(screen, 0, 0, width, height, overlayBackground, false)
+
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("再読み込みエラー (%d件)", len(errs)), overlayMargin, overlayMargin)
+	for i, err := range errs {
+		ebitenutil.DebugPrintAt(screen, err.Error(), overlayMargin, overlayMargin+overlayLineHeight*(i+1))
+	}
+}
@@ -0,0 +1,250 @@
+package ecs
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+
+	"muscle-dreamer/internal/anim"
+)
+
+// ComponentType - コンポーネント種別。1ビットずつ割り当て、ComponentMask で組み合わせる。
+type ComponentType uint64
+
+// ComponentMask - コンポーネント種別の組み合わせ
+type ComponentMask uint64
+
+const (
+	TransformComponentType ComponentType = 1 << iota
+	SpriteComponentType
+	VelocityComponentType
+	HealthComponentType
+	CollisionComponentType
+	InputComponentType
+	AudioComponentType
+	AIComponentType
+	AnimationComponentType
+	ParticleComponentType
+	ProteinComponentType
+)
+
+var componentNames = map[ComponentType]string{
+	TransformComponentType: "transform",
+	SpriteComponentType:    "sprite",
+	VelocityComponentType:  "velocity",
+	HealthComponentType:    "health",
+	CollisionComponentType: "collision",
+	InputComponentType:     "input",
+	AudioComponentType:     "audio",
+	AIComponentType:        "ai",
+	AnimationComponentType: "animation",
+	ParticleComponentType:  "particle",
+	ProteinComponentType:   "protein",
+}
+
+func (t ComponentType) String() string {
+	if name, ok := componentNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("component(%d)", bits.TrailingZeros64(uint64(t)))
+}
+
+// ParseComponentType - String() の名前からコンポーネント種別を求める
+func ParseComponentType(name string) (ComponentType, bool) {
+	for t, n := range componentNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// MaskOf - 種別の一覧からマスクを作る
+func MaskOf(types ...ComponentType) ComponentMask {
+	var mask ComponentMask
+	for _, t := range types {
+		mask |= ComponentMask(t)
+	}
+	return mask
+}
+
+// Component - コンポーネント基底インターフェース
+type Component interface {
+	GetType() ComponentType
+	Clone() Component
+}
+
+// Vector2 - 2次元ベクトル
+type Vector2 struct {
+	X, Y float64
+}
+
+// TransformComponent - 位置・回転・拡大率。位置はスプライトの中心を指す。
+type TransformComponent struct {
+	Position Vector2
+	Rotation float64 // ラジアン
+	Scale    Vector2
+}
+
+// NewTransformComponent - 等倍・無回転の TransformComponent
+func NewTransformComponent(x, y float64) *TransformComponent {
+	return &TransformComponent{Position: Vector2{x, y}, Scale: Vector2{1, 1}}
+}
+
+func (t *TransformComponent) GetType() ComponentType { return TransformComponentType }
+
+func (t *TransformComponent) Clone() Component {
+	clone := *t
+	return &clone
+}
+
+// SpriteComponent - スプライト描画。Texture はアトラスまたは画像のアセット名、
+// SourceRect はアトラス内の領域名を指す。画像を直接使う場合 SourceRect は空にする。
+type SpriteComponent struct {
+	Texture    string
+	SourceRect string
+	Visible    bool
+	Layer      int // 小さいものから描画する
+	FlipX      bool
+	FlipY      bool
+	Opacity    float64
+}
+
+// NewSpriteComponent - 表示状態・不透明の SpriteComponent
+func NewSpriteComponent(texture, region string) *SpriteComponent {
+	return &SpriteComponent{Texture: texture, SourceRect: region, Visible: true, Opacity: 1}
+}
+
+func (s *SpriteComponent) GetType() ComponentType { return SpriteComponentType }
+
+func (s *SpriteComponent) Clone() Component {
+	clone := *s
+	return &clone
+}
+
+// VelocityComponent - 移動速度 (ピクセル/秒)
+type VelocityComponent struct {
+	Velocity Vector2
+	MaxSpeed float64 // 0 は無制限
+}
+
+func (v *VelocityComponent) GetType() ComponentType { return VelocityComponentType }
+
+func (v *VelocityComponent) Clone() Component {
+	clone := *v
+	return &clone
+}
+
+// HealthComponent - 体力
+type HealthComponent struct {
+	Current      int
+	Maximum      int
+	Regeneration float64 // 1秒あたりの回復量
+	Invulnerable bool
+}
+
+// NewHealthComponent - 最大値まで回復した HealthComponent
+func NewHealthComponent(maximum int) *HealthComponent {
+	return &HealthComponent{Current: maximum, Maximum: maximum}
+}
+
+// Ratio - 最大値に対する現在値の割合 (0〜1)
+func (h *HealthComponent) Ratio() float64 {
+	if h.Maximum <= 0 {
+		return 0
+	}
+	return min(max(float64(h.Current)/float64(h.Maximum), 0), 1)
+}
+
+func (h *HealthComponent) GetType() ComponentType { return HealthComponentType }
+
+func (h *HealthComponent) Clone() Component {
+	clone := *h
+	return &clone
+}
+
+// ProteinComponent - プロテインメーター。スキルを使うと減り、プロテインを拾うと増える。
+type ProteinComponent struct {
+	Current  float64
+	Capacity float64
+}
+
+// NewProteinComponent - 満タンの ProteinComponent
+func NewProteinComponent(capacity float64) *ProteinComponent {
+	return &ProteinComponent{Current: capacity, Capacity: capacity}
+}
+
+// Ratio - 容量に対する現在値の割合 (0〜1)
+func (p *ProteinComponent) Ratio() float64 {
+	if p.Capacity <= 0 {
+		return 0
+	}
+	return min(max(p.Current/p.Capacity, 0), 1)
+}
+
+func (p *ProteinComponent) GetType() ComponentType { return ProteinComponentType }
+
+func (p *ProteinComponent) Clone() Component {
+	clone := *p
+	return &clone
+}
+
+// CollisionComponent - 当たり判定。矩形は Transform の位置を中心に Offset だけずらした位置に置く。
+type CollisionComponent struct {
+	Size      Vector2
+	Offset    Vector2
+	Layer     int // 自身が属するレイヤーのビット
+	Mask      int // 衝突するレイヤーのビット
+	IsTrigger bool
+}
+
+func (c *CollisionComponent) GetType() ComponentType { return CollisionComponentType }
+
+func (c *CollisionComponent) Clone() Component {
+	clone := *c
+	return &clone
+}
+
+// AnimationComponent - アニメーションの再生状態。AnimationSystem が進め、
+// 現在のフレームを同じエンティティの SpriteComponent に反映する。
+type AnimationComponent struct {
+	Clips    anim.Library
+	Current  string        // 再生中のクリップ名
+	Time     time.Duration // 再生開始からの経過時間
+	Speed    float64       // 再生速度の倍率。0 で停止する
+	Frame    int           // 直前に表示したフレームの通し番号。再生開始前は -1
+	Finished bool          // ループしないクリップが最後まで再生された
+	Events   []string      // 直前の更新で発生したイベント
+}
+
+// NewAnimationComponent - clip を等速で再生する AnimationComponent
+func NewAnimationComponent(clips anim.Library, clip string) *AnimationComponent {
+	a := &AnimationComponent{Clips: clips, Speed: 1}
+	a.Play(clip)
+	return a
+}
+
+// Play - クリップを切り替えて最初から再生する。再生中のクリップを指定した場合は何もしない。
+// 存在しないクリップなら false を返す。
+func (a *AnimationComponent) Play(clip string) bool {
+	if _, ok := a.Clips[clip]; !ok {
+		return false
+	}
+	if clip != a.Current {
+		a.Restart(clip)
+	}
+	return true
+}
+
+// Restart - クリップを最初から再生し直す
+func (a *AnimationComponent) Restart(clip string) {
+	a.Current, a.Time, a.Frame, a.Finished, a.Events = clip, 0, -1, false, a.Events[:0]
+}
+
+func (a *AnimationComponent) GetType() ComponentType { return AnimationComponentType }
+
+func (a *AnimationComponent) Clone() Component {
+	clone := *a
+	clone.Events = append([]string(nil), a.Events...)
+	return &clone
+}
@@ -0,0 +1,135 @@
+package ecs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+)
+
+func TestEntityManager(t *testing.T) {
+	t.Run("EntityLifecycle", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		e := em.CreateEntity()
+		assert.True(t, em.IsEntityValid(e))
+		assert.Equal(t, 1, em.GetEntityCount())
+
+		require.NoError(t, em.DestroyEntity(e))
+		assert.False(t, em.IsEntityValid(e))
+		assert.Equal(t, 0, em.GetEntityCount())
+		assert.ErrorIs(t, em.DestroyEntity(e), ecs.ErrEntityNotFound)
+
+		reused := em.CreateEntity()
+		assert.Equal(t, e.Index, reused.Index, "破棄したインデックスは再利用する")
+		assert.NotEqual(t, e, reused)
+		assert.False(t, em.IsEntityValid(e), "古い ID は無効のまま")
+		assert.False(t, em.IsEntityValid(ecs.NoEntity))
+	})
+
+	t.Run("Components", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		e := em.CreateEntity()
+		require.NoError(t, em.AddComponent(e, ecs.NewTransformComponent(10, 20)))
+
+		c, err := em.GetComponent(e, ecs.TransformComponentType)
+		require.NoError(t, err)
+		c.(*ecs.TransformComponent).Position.X = 30
+
+		c, _ = em.GetComponent(e, ecs.TransformComponentType)
+		assert.Equal(t, 30.0, c.(*ecs.TransformComponent).Position.X, "取得したコンポーネントを直接更新できる")
+
+		_, err = em.GetComponent(e, ecs.SpriteComponentType)
+		assert.ErrorIs(t, err, ecs.ErrComponentNotFound)
+
+		require.NoError(t, em.RemoveComponent(e, ecs.TransformComponentType))
+		assert.False(t, em.HasComponent(e, ecs.TransformComponentType))
+	})
+
+	t.Run("QueryWith", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		a := em.CreateEntity()
+		b := em.CreateEntity()
+		c := em.CreateEntity()
+		for _, e := range []ecs.EntityID{a, b, c} {
+			require.NoError(t, em.AddComponent(e, ecs.NewTransformComponent(0, 0)))
+		}
+		require.NoError(t, em.AddComponent(a, ecs.NewSpriteComponent("enemies", "goblin")))
+		require.NoError(t, em.AddComponent(c, ecs.NewSpriteComponent("enemies", "slime")))
+		require.NoError(t, em.DestroyEntity(c))
+
+		assert.Equal(t, []ecs.EntityID{a}, em.QueryWith(ecs.TransformComponentType, ecs.SpriteComponentType))
+		assert.Equal(t, []ecs.EntityID{a, b}, em.QueryWith(ecs.TransformComponentType))
+	})
+}
+
+type countingSystem struct {
+	kind  ecs.SystemType
+	calls *[]ecs.SystemType
+	err   error
+}
+
+func (s countingSystem) GetType() ecs.SystemType { return s.kind }
+
+func (s countingSystem) Update(*ecs.EntityManager, time.Duration) error {
+	*s.calls = append(*s.calls, s.kind)
+	return s.err
+}
+
+func TestHealthComponent(t *testing.T) {
+	h := ecs.NewHealthComponent(120)
+	assert.Equal(t, 1.0, h.Ratio())
+
+	h.Current = 30
+	assert.Equal(t, 0.25, h.Ratio())
+	h.Current = -10
+	assert.Equal(t, 0.0, h.Ratio(), "0 未満にはならない")
+
+	clone := h.Clone().(*ecs.HealthComponent)
+	clone.Current = 120
+	assert.Equal(t, -10, h.Current)
+	assert.Equal(t, 0.0, (&ecs.HealthComponent{}).Ratio())
+}
+
+func TestProteinComponent(t *testing.T) {
+	p := ecs.NewProteinComponent(200)
+	assert.Equal(t, 1.0, p.Ratio())
+
+	p.Current = 50
+	assert.Equal(t, 0.25, p.Ratio())
+	p.Current = 300
+	assert.Equal(t, 1.0, p.Ratio(), "1 を超えない")
+	assert.Equal(t, 0.0, (&ecs.ProteinComponent{}).Ratio())
+	assert.Equal(t, "protein", ecs.ProteinComponentType.String())
+}
+
+func TestSystemManager(t *testing.T) {
+	t.Run("RunsInRegistrationOrder", func(t *testing.T) {
+		var calls []ecs.SystemType
+		sm := ecs.NewSystemManager()
+		require.NoError(t, sm.RegisterSystem(countingSystem{kind: ecs.AnimationSystemType, calls: &calls}))
+		require.NoError(t, sm.RegisterSystem(countingSystem{kind: ecs.MovementSystemType, calls: &calls}))
+		assert.Error(t, sm.RegisterSystem(countingSystem{kind: ecs.MovementSystemType, calls: &calls}))
+
+		require.NoError(t, sm.UpdateSystems(ecs.NewEntityManager(), time.Second/60))
+		assert.Equal(t, []ecs.SystemType{ecs.AnimationSystemType, ecs.MovementSystemType}, calls)
+
+		require.NoError(t, sm.UnregisterSystem(ecs.AnimationSystemType))
+		assert.Equal(t, []ecs.SystemType{ecs.MovementSystemType}, sm.GetRegisteredSystems())
+		assert.Error(t, sm.UnregisterSystem(ecs.AnimationSystemType))
+	})
+
+	t.Run("StopsAtFirstError", func(t *testing.T) {
+		var calls []ecs.SystemType
+		failure := errors.New("failure")
+		sm := ecs.NewSystemManager()
+		require.NoError(t, sm.RegisterSystem(countingSystem{kind: ecs.AISystemType, calls: &calls, err: failure}))
+		require.NoError(t, sm.RegisterSystem(countingSystem{kind: ecs.MovementSystemType, calls: &calls}))
+
+		assert.ErrorIs(t, sm.UpdateSystems(ecs.NewEntityManager(), 0), failure)
+		assert.Equal(t, []ecs.SystemType{ecs.AISystemType}, calls)
+	})
+}
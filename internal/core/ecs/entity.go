@@ -0,0 +1,148 @@
+// Package ecs はエンティティ・コンポーネント・システムの基盤を提供する。
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrEntityNotFound    = errors.New("エンティティが存在しません")
+	ErrComponentNotFound = errors.New("コンポーネントがありません")
+)
+
+// EntityID - エンティティ識別子。破棄されたインデックスは世代番号を進めて再利用する。
+type EntityID struct {
+	Index      uint32
+	Generation uint32
+}
+
+// NoEntity - どのエンティティも指さない ID。IsEntityValid は常に false を返す。
+var NoEntity = EntityID{Index: math.MaxUint32, Generation: math.MaxUint32}
+
+func (id EntityID) String() string {
+	return fmt.Sprintf("%d#%d", id.Index, id.Generation)
+}
+
+// EntityManager - エンティティとコンポーネントの管理
+type EntityManager struct {
+	generations []uint32
+	masks       []ComponentMask
+	alive       []bool
+	free        []uint32
+	count       int
+	components  map[ComponentType]map[uint32]Component
+}
+
+// NewEntityManager - 空の EntityManager を作成する
+func NewEntityManager() *EntityManager {
+	return &EntityManager{components: make(map[ComponentType]map[uint32]Component)}
+}
+
+// CreateEntity - エンティティを作成する
+func (em *EntityManager) CreateEntity() EntityID {
+	em.count++
+	if n := len(em.free); n > 0 {
+		index := em.free[n-1]
+		em.free = em.free[:n-1]
+		em.alive[index] = true
+		return EntityID{Index: index, Generation: em.generations[index]}
+	}
+	em.generations = append(em.generations, 0)
+	em.masks = append(em.masks, 0)
+	em.alive = append(em.alive, true)
+	return EntityID{Index: uint32(len(em.alive) - 1)}
+}
+
+// DestroyEntity - エンティティと全てのコンポーネントを破棄する
+func (em *EntityManager) DestroyEntity(id EntityID) error {
+	if !em.IsEntityValid(id) {
+		return fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+	for t, store := range em.components {
+		if em.masks[id.Index]&ComponentMask(t) != 0 {
+			delete(store, id.Index)
+		}
+	}
+	em.masks[id.Index] = 0
+	em.alive[id.Index] = false
+	em.generations[id.Index]++
+	em.free = append(em.free, id.Index)
+	em.count--
+	return nil
+}
+
+// IsEntityValid - エンティティが存在するか
+func (em *EntityManager) IsEntityValid(id EntityID) bool {
+	return int(id.Index) < len(em.alive) && em.alive[id.Index] && em.generations[id.Index] == id.Generation
+}
+
+// GetEntityCount - 存在するエンティティの数
+func (em *EntityManager) GetEntityCount() int {
+	return em.count
+}
+
+// AddComponent - コンポーネントを追加する。同じ種類のコンポーネントは置き換える。
+func (em *EntityManager) AddComponent(id EntityID, c Component) error {
+	if !em.IsEntityValid(id) {
+		return fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+	t := c.GetType()
+	store := em.components[t]
+	if store == nil {
+		store = make(map[uint32]Component)
+		em.components[t] = store
+	}
+	store[id.Index] = c
+	em.masks[id.Index] |= ComponentMask(t)
+	return nil
+}
+
+// RemoveComponent - コンポーネントを取り除く
+func (em *EntityManager) RemoveComponent(id EntityID, t ComponentType) error {
+	if !em.HasComponent(id, t) {
+		return fmt.Errorf("%w: %s %s", ErrComponentNotFound, id, t)
+	}
+	delete(em.components[t], id.Index)
+	em.masks[id.Index] &^= ComponentMask(t)
+	return nil
+}
+
+// GetComponent - コンポーネントを取得する
+func (em *EntityManager) GetComponent(id EntityID, t ComponentType) (Component, error) {
+	if !em.IsEntityValid(id) {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+	c, ok := em.components[t][id.Index]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrComponentNotFound, id, t)
+	}
+	return c, nil
+}
+
+// HasComponent - コンポーネントを持っているか
+func (em *EntityManager) HasComponent(id EntityID, t ComponentType) bool {
+	return em.IsEntityValid(id) && em.masks[id.Index]&ComponentMask(t) != 0
+}
+
+// Query - mask の全てのコンポーネントを持つエンティティをインデックス順に返す
+func (em *EntityManager) Query(mask ComponentMask) []EntityID {
+	var ids []EntityID
+	for index, m := range em.masks {
+		if em.alive[index] && m&mask == mask {
+			ids = append(ids, EntityID{Index: uint32(index), Generation: em.generations[index]})
+		}
+	}
+	return ids
+}
+
+// QueryWith - 指定した全ての種類のコンポーネントを持つエンティティ
+func (em *EntityManager) QueryWith(types ...ComponentType) []EntityID {
+	return em.Query(MaskOf(types...))
+}
+
+// Entities - 存在する全てのエンティティをインデックス順に返す
+func (em *EntityManager) Entities() []EntityID {
+	return em.Query(0)
+}
@@ -0,0 +1,86 @@
+package ecs
+
+import (
+	"fmt"
+	"time"
+)
+
+// SystemType - システム種別
+type SystemType string
+
+const (
+	MovementSystemType  SystemType = "movement"
+	RenderSystemType    SystemType = "render"
+	PhysicsSystemType   SystemType = "physics"
+	InputSystemType     SystemType = "input"
+	AudioSystemType     SystemType = "audio"
+	AISystemType        SystemType = "ai"
+	AnimationSystemType SystemType = "animation"
+	HUDSystemType       SystemType = "hud"
+	CollisionSystemType SystemType = "collision"
+)
+
+// System - 毎フレーム更新されるシステム
+type System interface {
+	GetType() SystemType
+	Update(entities *EntityManager, dt time.Duration) error
+}
+
+// SystemManager - システムを登録順に実行する
+type SystemManager struct {
+	systems []System
+}
+
+// NewSystemManager - 空の SystemManager を作成する
+func NewSystemManager() *SystemManager {
+	return &SystemManager{}
+}
+
+// RegisterSystem - システムを登録する。同じ種別は二重に登録できない。
+func (sm *SystemManager) RegisterSystem(s System) error {
+	if sm.IsSystemRegistered(s.GetType()) {
+		return fmt.Errorf("システム %s は登録済みです", s.GetType())
+	}
+	sm.systems = append(sm.systems, s)
+	return nil
+}
+
+// UnregisterSystem - システムの登録を解除する
+func (sm *SystemManager) UnregisterSystem(t SystemType) error {
+	for i, s := range sm.systems {
+		if s.GetType() == t {
+			sm.systems = append(sm.systems[:i], sm.systems[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("システム %s は登録されていません", t)
+}
+
+// IsSystemRegistered - システムが登録されているか
+func (sm *SystemManager) IsSystemRegistered(t SystemType) bool {
+	for _, s := range sm.systems {
+		if s.GetType() == t {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRegisteredSystems - 実行順のシステム種別
+func (sm *SystemManager) GetRegisteredSystems() []SystemType {
+	types := make([]SystemType, len(sm.systems))
+	for i, s := range sm.systems {
+		types[i] = s.GetType()
+	}
+	return types
+}
+
+// UpdateSystems - 全てのシステムを登録順に更新する。エラーが起きたシステムで中断する。
+func (sm *SystemManager) UpdateSystems(entities *EntityManager, dt time.Duration) error {
+	for _, s := range sm.systems {
+		if err := s.Update(entities, dt); err != nil {
+			return fmt.Errorf("システム %s: %w", s.GetType(), err)
+		}
+	}
+	return nil
+}
@@ -1,39 +1,223 @@
 package core
 
 import (
-	"image/color"
+	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/audio"
+	"muscle-dreamer/internal/config"
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/core/systems"
+	"muscle-dreamer/internal/hotreload"
+	"muscle-dreamer/internal/input"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/settings"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
 )
 
+// InitOptions - 初期化オプション
+type InitOptions struct {
+	ConfigPath  string
+	ThemePath   string
+	ModPath     string // 空の場合はMODを読み込まない
+	EnableDebug bool
+
+	// ModStatePath - 有効なMODの一覧を保存するファイル。空の場合は保存しない。
+	ModStatePath string
+
+	// ModDataPath - MODが file_access で mods/ 以下に書き込むファイルの保存先。
+	// 空の場合はMODはファイルを書き込めない。
+	ModDataPath string
+
+	// LoadConfig - ホットリロード時に全レイヤーから設定を読み直す
+	LoadConfig func() (*config.Layered, error)
+}
+
 type Game struct {
 	// ゲーム状態
+	options  InitOptions
+	state    GameState
+	config   *config.GameConfig
+	settings *settings.Service
+
+	// 読み込み画面
+	loader       *asset.Loader
+	afterLoading GameState
+
+	// ステージ
+	stage       string
+	stageLoader *asset.Loader // ステージのアセットの参照を保持する
+
+	// テーマ
+	themeLoader   *asset.Loader // テーマの事前読み込みグループの参照を保持する
+	themeSheets   []string      // プレハブ用に読み込んだスプライトシートのキー
+	themeWarnings []error       // テーマの表示資源を読み込めなかったときのエラー
+	themeScripts  *mod.Context  // テーマのスクリプトが加えたシステムとエンティティ
+	palette       theme.Palette
+	texts         *theme.Strings
+	font          asset.Font
+	bgm           asset.AudioClip
+	bgmPath       string
+	menu          menu
+
+	// ECS
+	entities *ecs.EntityManager
+	systems  *ecs.SystemManager
+	renderer *systems.RenderSystem
+	hud      *systems.HUDSystem
+	player   ecs.EntityID // ステージ外では ecs.NoEntity
+
+	// サブシステム
+	files  *vfs.FS
+	assets *asset.Manager
+	themes *theme.Manager
+	mods   *mod.Manager
+	audio  *audio.Mixer
+	input  *input.Manager
+
+	// 開発用
+	watcher *hotreload.Watcher
 }
 
-func NewGame() *Game {
-	return &Game{}
+func NewGame(svc *settings.Service, options InitOptions) *Game {
+	cfg := svc.Current()
+	files := vfs.New(vfs.DefaultRoots...)
+	// 配布版のパックがあれば同じ優先度で先にマウントし、後からマウントするファイルを優先する
+	if packed, err := pack.OpenFile("assets" + pack.Ext); err == nil {
+		_ = files.Mount("base:pack", packed, vfs.MountOptions{Priority: vfs.PriorityBase})
+	}
+	// 固定のパスと読み取り専用のマウントなので失敗しない
+	_ = files.Mount("base", os.DirFS("."), vfs.MountOptions{Priority: vfs.PriorityBase})
+
+	assets := asset.NewManager(files, asset.Options{})
+
+	g := &Game{
+		options:  options,
+		config:   cfg,
+		settings: svc,
+		entities: ecs.NewEntityManager(),
+		systems:  ecs.NewSystemManager(),
+		renderer: systems.NewRenderSystem(assets),
+		hud:      systems.NewHUDSystem(assets),
+		player:   ecs.NoEntity,
+		files:    files,
+		assets:   assets,
+		audio:    audio.NewMixer(cfg.Audio),
+		input:    input.NewManager(cfg.Input),
+	}
+	g.themes = theme.NewManager(os.DirFS(options.ThemePath), files, theme.Options{
+		LoadSheet:   g.loadSheet,
+		PackSprites: g.packSprites,
+		GameVersion: cfg.Game.Version,
+	})
+	// themes/ に置かれたアーカイブを検証してテーマ一覧に加える。壊れたものはメニューに表示する
+	g.menu.installErrors = g.themes.Install()
+	// 新しい SystemManager への最初の登録なので失敗しない
+	_ = g.systems.RegisterSystem(systems.NewAnimationSystem())
+	g.setupMods()
+	svc.SetApplier(g)
+	if options.EnableDebug {
+		g.setupHotReload()
+	}
+	g.startLoading(nil, GameStateMenu)
+	return g
 }
 
 func (g *Game) Update() error {
 	// ゲーム更新ロジック
+	g.updateHotReload()
+	switch g.state {
+	case GameStateLoading:
+		g.updateLoading()
+	case GameStateMenu:
+		g.updateMenu()
+	case GameStatePaused:
+		if g.input.IsActionJustPressed("menu") {
+			g.state = GameStatePlaying
+			break
+		}
+		g.updateMenu()
+	case GameStatePlaying:
+		if g.input.IsActionJustPressed("menu") {
+			g.state = GameStatePaused
+			break
+		}
+		if err := g.systems.UpdateSystems(g.entities, time.Second/time.Duration(ebiten.TPS())); err != nil {
+			return err
+		}
+	}
+	g.updateBGM()
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	// 描画ロジック
-	ebitenutil.DebugPrint(screen, "マッスルドリーマー開発中...")
-	screen.Fill(color.RGBA{50, 50, 100, 255})
+	screen.Fill(g.uiColor(theme.ColorBackground, defaultBackground))
+	switch g.state {
+	case GameStateLoading:
+		g.drawLoading(screen)
+	case GameStatePlaying:
+		g.renderer.Draw(g.entities, screen)
+		g.hud.Draw(g.entities, g.player, screen)
+	case GameStatePaused:
+		g.renderer.Draw(g.entities, screen)
+		g.hud.Draw(g.entities, g.player, screen)
+		g.drawMenu(screen)
+	case GameStateMenu:
+		g.drawMenu(screen)
+	default:
+		ebitenutil.DebugPrint(screen, "マッスルドリーマー開発中...")
+	}
+	g.drawDebugOverlay(screen)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 1280, 720
+	return g.config.Graphics.Width, g.config.Graphics.Height
 }
 
 func (g *Game) Run() error {
-	ebiten.SetWindowSize(1280, 720)
-	ebiten.SetWindowTitle("マッスルドリーマー〜観光編〜")
+	g.applyWindow(g.config.Graphics)
+	ebiten.SetWindowTitle(g.config.Game.Title)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
 	return ebiten.RunGame(g)
 }
+
+// ApplyGraphics - 描画設定を実行中のウィンドウへ反映する
+func (g *Game) ApplyGraphics(graphics config.GraphicsSettings) error {
+	g.applyWindow(graphics)
+	g.config.Graphics = graphics
+	return nil
+}
+
+// ApplyAudio - 音量設定をミキサーへ反映する
+func (g *Game) ApplyAudio(audio config.AudioSettings) error {
+	g.audio.Apply(audio)
+	g.config.Audio = audio
+	if g.bgm != nil {
+		g.bgm.SetVolume(g.audio.BGMVolume())
+	}
+	return nil
+}
+
+// ApplyInput - 入力設定とキーバインドを反映する
+func (g *Game) ApplyInput(input config.InputSettings) error {
+	if err := g.input.Apply(input); err != nil {
+		return err
+	}
+	g.config.Input = input
+	return nil
+}
+
+// applyWindow - ウィンドウ設定を反映する
+func (g *Game) applyWindow(graphics config.GraphicsSettings) {
+	ebiten.SetWindowSize(graphics.Width, graphics.Height)
+	ebiten.SetFullscreen(graphics.Fullscreen)
+	ebiten.SetVsyncEnabled(graphics.VSync)
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"image"
+	"image/color"
+
+	"muscle-dreamer/internal/core/systems"
+	"muscle-dreamer/internal/theme"
+)
+
+var (
+	defaultProtein = color.RGBA{243, 156, 18, 255}
+	hudBack        = color.RGBA{0, 0, 0, 128}
+)
+
+// hudLayout - テーマの ui.elements とスキルのアイコンから HUD の構成を作る。
+// スプライトのない要素はテーマのUI色で塗る (体力は accent、プロテインは secondary)。
+func hudLayout(t *theme.Theme) systems.HUDLayout {
+	elements := t.Spec.UI.Elements
+	layout := systems.HUDLayout{
+		HealthBar:    hudElement(elements, theme.ElementHealthBar),
+		ProteinMeter: hudElement(elements, theme.ElementProteinMeter),
+		SkillIcons:   hudElement(elements, theme.ElementSkillIcons),
+		Colors: systems.HUDColors{
+			Health:  t.Colors.Color(theme.ColorAccent, defaultAccent),
+			Protein: t.Colors.Color(theme.ColorSecondary, defaultProtein),
+			Frame:   t.Colors.Color(theme.ColorText, defaultText),
+			Back:    hudBack,
+		},
+	}
+	for _, skill := range t.Spec.Skills.MuscleSkills {
+		layout.Skills = append(layout.Skills, skill.Icon)
+	}
+	return layout
+}
+
+func hudElement(elements map[string]theme.UIElement, name string) *systems.HUDElement {
+	e, ok := elements[name]
+	if !ok {
+		return nil
+	}
+	return &systems.HUDElement{
+		Sprite:   e.Sprite,
+		Position: point(e.Position),
+		Size:     point(e.Size),
+		IconSize: point(e.IconSize),
+		Spacing:  e.Spacing,
+	}
+}
+
+// point - [x, y] の組。スキーマで2要素に制限しているが、省略された場合は原点にする。
+func point(v []int) image.Point {
+	if len(v) != 2 {
+		return image.Point{}
+	}
+	return image.Pt(v[0], v[1])
+}
+
+// spawnPlayer - ステージの読み込みが終わったとき、プレイヤーを画面の中央に作成する。
+// 既にいる場合 (一時停止から戻った場合など) は何もしない。
+func (g *Game) spawnPlayer() {
+	if g.stage == "" || g.entities.IsEntityValid(g.player) {
+		return
+	}
+	t := g.themes.GetCurrentTheme()
+	if t == nil {
+		return
+	}
+	prefab, ok := t.Prefabs[theme.PrefabPlayer]
+	if !ok {
+		return
+	}
+	w, h := g.Layout(0, 0)
+	id, err := prefab.Spawn(g.entities, float64(w)/2, float64(h)/2)
+	if err != nil {
+		g.themeWarnings = append(g.themeWarnings, err)
+		return
+	}
+	g.player = id
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"muscle-dreamer/internal/asset"
+)
+
+// 読み込み画面のレイアウト
+const (
+	progressBarWidth   = 480
+	progressBarHeight  = 16
+	progressLineHeight = 16
+	maxLoadingErrors   = 8
+)
+
+var (
+	progressBarBackground = color.RGBA{30, 30, 60, 255}
+	progressBarFill       = color.RGBA{255, 107, 53, 255}
+)
+
+// startLoading - 非同期読み込みを開始し、完了したら next の状態へ進む
+func (g *Game) startLoading(reqs []asset.Request, next GameState) {
+	g.loader = g.assets.LoadAsync(reqs, 0)
+	g.afterLoading = next
+	g.state = GameStateLoading
+}
+
+// updateLoading - アップロードを進め、完了していれば次の状態へ進む。
+// エラーがあった場合は内容を表示したまま決定キーが押されるまで待つ。
+func (g *Game) updateLoading() {
+	g.loader.Update()
+	progress := g.loader.Progress()
+	if !progress.Done {
+		return
+	}
+	if len(progress.Errors) > 0 && !g.input.IsActionJustPressed("action") {
+		return
+	}
+	g.state = g.afterLoading
+	g.spawnPlayer()
+}
+
+// drawLoading - 進捗バーと処理中のファイル、エラーを描画する
+func (g *Game) drawLoading(screen *ebiten.Image) {
+	progress := g.loader.Progress()
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	x := float32(w-progressBarWidth) / 2
+	y := float32(h-progressBarHeight) / 2
+
+	vector.DrawFilledRect(screen, x, y, progressBarWidth, progressBarHeight, progressBarBackground, false)
+	vector.DrawFilledRect(screen, x, y, float32(progress.Fraction()*progressBarWidth), progressBarHeight, progressBarFill, false)
+
+	label := fmt.Sprintf("読み込み中... %d/%d (%.0f%%)", progress.ItemsDone, progress.Items, progress.Fraction()*100)
+	ebitenutil.DebugPrintAt(screen, label, int(x), int(y)-progressLineHeight*2)
+	if progress.Current != "" {
+		ebitenutil.DebugPrintAt(screen, progress.Current, int(x), int(y)+progressBarHeight+progressLineHeight/2)
+	}
+
+	if len(progress.Errors) == 0 {
+		return
+	}
+	line := int(y) + progressBarHeight + progressLineHeight*2
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("読み込みエラー (%d件) - 決定キーで続行", len(progress.Errors)), int(x), line)
+	for i, err := range progress.Errors {
+		if i == maxLoadingErrors {
+			ebitenutil.DebugPrintAt(screen, fmt.Sprintf("... 他 %d 件", len(progress.Errors)-i), int(x), line+progressLineHeight*(i+1))
+			break
+		}
+		ebitenutil.DebugPrintAt(screen, err.Error(), int(x), line+progressLineHeight*(i+1))
+	}
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"muscle-dreamer/internal/theme"
+)
+
+// メニューのレイアウト
+const (
+	menuMargin     = 32
+	menuLineHeight = 24
+	menuCursorSize = 8
+)
+
+var menuShade = color.RGBA{0, 0, 0, 160}
+
+// メニューのプレビュー画像の最大の大きさ
+const (
+	menuPreviewWidth  = 320
+	menuPreviewHeight = 180
+)
+
+// menu - テーマ選択メニュー。メニュー画面と一時停止中に表示する。
+type menu struct {
+	themes        []string
+	cursor        int
+	err           error   // 直前の切り替えの失敗
+	installErrors []error // 起動時にインストールできなかったテーマのアーカイブと、有効にできなかったMOD
+
+	// カーソル位置のテーマの情報。プレビュー画像がなければ preview は nil。
+	info    *theme.Info
+	preview *ebiten.Image
+}
+
+// updateMenu - 上下でテーマを選び、決定キーで切り替える
+func (g *Game) updateMenu() {
+	if g.menu.themes == nil {
+		g.menu.themes = g.themes.GetAvailableThemes()
+		if t := g.themes.GetCurrentTheme(); t != nil {
+			for i, name := range g.menu.themes {
+				if name == t.ID {
+					g.menu.cursor = i
+				}
+			}
+		}
+	}
+	if len(g.menu.themes) == 0 {
+		return
+	}
+
+	switch {
+	case g.input.IsActionJustPressed("move_up"):
+		g.menu.cursor = (g.menu.cursor + len(g.menu.themes) - 1) % len(g.menu.themes)
+	case g.input.IsActionJustPressed("move_down"):
+		g.menu.cursor = (g.menu.cursor + 1) % len(g.menu.themes)
+	case g.input.IsActionJustPressed("action"):
+		g.menu.err = g.SetTheme(g.menu.themes[g.menu.cursor])
+	}
+	g.selectInfo(g.menu.themes[g.menu.cursor])
+}
+
+// selectInfo - カーソル位置のテーマの作者・ライセンスとプレビュー画像を読み込む
+func (g *Game) selectInfo(name string) {
+	if g.menu.info != nil && g.menu.info.ID == name {
+		return
+	}
+	if g.menu.preview != nil {
+		g.menu.preview.Dispose()
+		g.menu.preview = nil
+	}
+	info, err := g.themes.GetThemeInfo(name)
+	if err != nil {
+		// 壊れたテーマは切り替えようとしたときにエラーを表示する
+		info = &theme.Info{ID: name}
+	}
+	g.menu.info = info
+	if info.Preview == "" {
+		return
+	}
+	if img, err := g.themes.Preview(name); err == nil {
+		g.menu.preview = ebiten.NewImageFromImage(img)
+	}
+}
+
+// drawMenu - テーマの一覧と、有効なテーマの名前・警告を描画する
+func (g *Game) drawMenu(screen *ebiten.Image) {
+	if g.state == GameStatePaused {
+		w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+		vector.DrawFilledRect(screen, 0, 0, float32(w), float32(h), menuShade, false)
+	}
+	textColor := g.uiColor(theme.ColorText, defaultText)
+	accent := g.uiColor(theme.ColorAccent, defaultAccent)
+
+	title := "マッスルドリーマー"
+	if t := g.themes.GetCurrentTheme(); t != nil {
+		title = g.uiText("theme_name", t.Spec.Metadata.Name)
+	}
+	if g.state == GameStatePaused {
+		title += " - 一時停止中"
+	}
+	y := menuMargin
+	g.drawText(screen, title, menuMargin, y, textColor)
+	y += menuLineHeight * 2
+
+	g.drawText(screen, "テーマ (上下で選択、決定キーで切り替え)", menuMargin, y, textColor)
+	y += menuLineHeight
+	current := ""
+	if t := g.themes.GetCurrentTheme(); t != nil {
+		current = t.ID
+	}
+	for i, name := range g.menu.themes {
+		label := name
+		if name == current {
+			label += " *"
+		}
+		if i == g.menu.cursor {
+			vector.DrawFilledRect(screen, menuMargin, float32(y+menuLineHeight/2-menuCursorSize/2), menuCursorSize, menuCursorSize, accent, false)
+		}
+		g.drawText(screen, label, menuMargin+menuCursorSize*2, y, textColor)
+		y += menuLineHeight
+	}
+
+	y = g.drawThemeInfo(screen, y, textColor)
+
+	var problems []error
+	if g.menu.err != nil {
+		problems = append(problems, g.menu.err)
+	}
+	problems = append(problems, g.menu.installErrors...)
+	problems = append(problems, g.themeWarnings...)
+	if len(problems) == 0 {
+		return
+	}
+	y += menuLineHeight
+	g.drawText(screen, fmt.Sprintf("テーマのエラー (%d件)", len(problems)), menuMargin, y, accent)
+	for i, err := range problems {
+		if i == maxLoadingErrors {
+			break
+		}
+		y += menuLineHeight
+		g.drawText(screen, err.Error(), menuMargin, y, textColor)
+	}
+}
+
+// drawThemeInfo - カーソル位置のテーマの作者・ライセンスを一覧の下に、プレビュー画像を右上に描画する
+func (g *Game) drawThemeInfo(screen *ebiten.Image, y int, textColor color.Color) int {
+	info := g.menu.info
+	if info == nil {
+		return y
+	}
+	if info.Author != "" || info.License != "" {
+		y += menuLineHeight
+		g.drawText(screen, fmt.Sprintf("作者: %s  ライセンス: %s", info.Author, info.License), menuMargin, y, textColor)
+	}
+	if info.Archive != nil {
+		y += menuLineHeight
+		g.drawText(screen, fmt.Sprintf("アーカイブ: %s (SHA-256 %.12s)", info.Archive.Archive, info.Archive.SHA256), menuMargin, y, textColor)
+	}
+
+	if g.menu.preview == nil {
+		return y
+	}
+	w, h := g.menu.preview.Bounds().Dx(), g.menu.preview.Bounds().Dy()
+	scale := min(float64(menuPreviewWidth)/float64(w), float64(menuPreviewHeight)/float64(h), 1)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(screen.Bounds().Dx()-menuMargin)-float64(w)*scale, menuMargin)
+	screen.DrawImage(g.menu.preview, op)
+	return y
+}
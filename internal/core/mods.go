@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/script"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+// setupMods - MODマネージャーを作り、前回有効だったMODを有効にし直す。MODのスクリプトは
+// 有効にしたときに実行し、print の出力やエラー、サンドボックスが拒否した操作は
+// 標準エラー出力に書く。
+// 有効にできなかったMODはメニューにエラーとして表示する。
+func (g *Game) setupMods() {
+	// MODディレクトリがなければ、何もマウントしていない仮想ファイルシステムから探す
+	var root fs.FS = vfs.New()
+	if g.options.ModPath != "" {
+		root = vfs.Dir(g.options.ModPath)
+	}
+	files, err := g.modFiles()
+	if err != nil {
+		g.menu.installErrors = append(g.menu.installErrors, err)
+	}
+	g.mods = mod.NewManager(root, g.files, mod.Host{
+		Entities: g.entities,
+		Systems:  g.systems,
+		Assets:   modAssets{g.assets},
+		Files:    files,
+	}, mod.Options{
+		GameVersion: g.config.Game.Version,
+		StatePath:   g.options.ModStatePath,
+		Start:       script.Start(os.Stderr),
+		Log:         os.Stderr,
+	})
+	g.menu.installErrors = append(g.menu.installErrors, g.mods.Restore()...)
+}
+
+// modFiles - MODのサンドボックスから見える仮想ファイルシステム。ゲームのファイルはそのままの
+// パスで、MODのファイルと書き込んだファイルは mods/ 以下で見える。どこまで使えるかは
+// MODごとに file_access で制限される。
+func (g *Game) modFiles() (*vfs.FS, error) {
+	files := vfs.New()
+	err := files.Mount("game", g.files, vfs.MountOptions{Priority: vfs.PriorityBase})
+	if err == nil && g.options.ModPath != "" {
+		err = files.Mount("mods", vfs.Dir(g.options.ModPath), vfs.MountOptions{Point: "mods", Priority: vfs.PriorityTheme})
+	}
+	if err == nil && g.options.ModDataPath != "" {
+		err = files.Mount("data", vfs.Dir(g.options.ModDataPath), vfs.MountOptions{Point: "mods", Priority: vfs.PriorityMod, Writable: true})
+	}
+	return files, err
+}
+
+// Mods - MODマネージャー
+func (g *Game) Mods() *mod.Manager {
+	return g.mods
+}
+
+// modAssets - MODのアセットを拡張子から種類を判定して読み込む
+type modAssets struct {
+	*asset.Manager
+}
+
+// LoadAsset - mod.AssetStore の実装
+func (a modAssets) LoadAsset(path string) error {
+	kind, ok := assetKind(theme.KindOf(path))
+	if !ok {
+		return fmt.Errorf("アセットの種類を判定できません: %s", path)
+	}
+	var err error
+	switch kind {
+	case asset.KindImage:
+		_, err = a.LoadImage(path)
+	case asset.KindAudio:
+		_, err = a.LoadAudio(path)
+	case asset.KindFont:
+		_, err = a.LoadFont(path)
+	case asset.KindAtlas:
+		_, err = a.LoadAtlas(path)
+	}
+	return err
+}
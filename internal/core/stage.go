@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/theme"
+)
+
+// CodeUnknownStage - テーマに定義されていないステージ
+const CodeUnknownStage = "THEME_UNKNOWN_STAGE"
+
+// EnterStage - ステージに必要なアセットを読み込み画面で読み込んでからプレイを開始する。
+// 前のステージのアセットは新しいステージの読み込みを始めてから手放すため、
+// 共有しているアセットは読み直されない。
+func (g *Game) EnterStage(manifest *theme.Manifest, stageID string) error {
+	if !manifest.HasStage(stageID) {
+		return gameerr.ThemeError{
+			Code:    CodeUnknownStage,
+			Message: fmt.Sprintf("ステージが定義されていません: %s", stageID),
+		}
+	}
+
+	previous := g.stageLoader
+	// HUD が使う UI 要素とスキルのアイコンも合わせて読み込む
+	g.startLoading(requests(manifest.StageAssets(stageID, theme.GroupUI, theme.GroupSkills)), GameStatePlaying)
+	g.stage, g.stageLoader = stageID, g.loader
+	if previous != nil {
+		previous.Release()
+	}
+	return nil
+}
+
+// ExitStage - ステージ開始時に読み込んだアセットの参照を手放す
+func (g *Game) ExitStage() {
+	if g.stageLoader == nil {
+		return
+	}
+	g.stageLoader.Release()
+	g.stage, g.stageLoader = "", nil
+}
+
+// CurrentStage - プレイ中のステージ。ステージ外では空文字列。
+func (g *Game) CurrentStage() string {
+	return g.stage
+}
+
+func assetKind(kind theme.AssetKind) (asset.Kind, bool) {
+	switch kind {
+	case theme.AssetImage:
+		return asset.KindImage, true
+	case theme.AssetAudio:
+		return asset.KindAudio, true
+	case theme.AssetFont:
+		return asset.KindFont, true
+	case theme.AssetAtlas:
+		return asset.KindAtlas, true
+	default:
+		return 0, false
+	}
+}
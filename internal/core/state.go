@@ -0,0 +1,13 @@
+package core
+
+// GameState - ゲーム状態列挙
+type GameState int
+
+const (
+	GameStateMenu GameState = iota
+	GameStateLoading
+	GameStatePlaying
+	GameStatePaused
+	GameStateGameOver
+	GameStateSettings
+)
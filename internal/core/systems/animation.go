@@ -0,0 +1,47 @@
+package systems
+
+import (
+	"time"
+
+	"muscle-dreamer/internal/core/ecs"
+)
+
+// AnimationSystem - AnimationComponent を進め、現在のフレームを SpriteComponent の
+// Texture と SourceRect に反映する。フレームに入ったときのイベントは
+// AnimationComponent.Events に次の更新まで残る。
+type AnimationSystem struct{}
+
+// NewAnimationSystem - AnimationSystem を作成する
+func NewAnimationSystem() *AnimationSystem {
+	return &AnimationSystem{}
+}
+
+func (s *AnimationSystem) GetType() ecs.SystemType { return ecs.AnimationSystemType }
+
+func (s *AnimationSystem) Update(entities *ecs.EntityManager, dt time.Duration) error {
+	for _, id := range entities.QueryWith(ecs.AnimationComponentType, ecs.SpriteComponentType) {
+		c, _ := entities.GetComponent(id, ecs.AnimationComponentType)
+		a := c.(*ecs.AnimationComponent)
+		a.Events = a.Events[:0]
+		clip, ok := a.Clips[a.Current]
+		if !ok {
+			continue
+		}
+
+		if !a.Finished {
+			a.Time += time.Duration(float64(dt) * a.Speed)
+		}
+		frame := clip.FrameAt(a.Time)
+		if frame != a.Frame {
+			a.Events = clip.AppendEvents(a.Events, a.Frame, frame)
+			a.Frame = frame
+		}
+		a.Finished = clip.Finished(a.Time)
+
+		c, _ = entities.GetComponent(id, ecs.SpriteComponentType)
+		sprite := c.(*ecs.SpriteComponent)
+		sprite.Texture = clip.Texture
+		sprite.SourceRect = clip.Region(frame)
+	}
+	return nil
+}
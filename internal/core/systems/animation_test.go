@@ -0,0 +1,78 @@
+package systems_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/core/systems"
+)
+
+func animated(t *testing.T) (*ecs.EntityManager, *ecs.AnimationComponent, *ecs.SpriteComponent) {
+	t.Helper()
+	idle, err := anim.NewClip("idle", "idle_sheet", "idle.png", anim.Spec{FrameCount: 4, FrameDuration: 0.25, Loop: true})
+	require.NoError(t, err)
+	attack, err := anim.NewClip("attack", "attack_sheet", "attack.png", anim.Spec{
+		FrameCount: 3, FrameDuration: 0.1, Events: map[string]int{"attack_hit": 2},
+	})
+	require.NoError(t, err)
+
+	em := ecs.NewEntityManager()
+	e := em.CreateEntity()
+	a := ecs.NewAnimationComponent(anim.Library{"idle": idle, "attack": attack}, "idle")
+	sprite := ecs.NewSpriteComponent("", "")
+	require.NoError(t, em.AddComponent(e, a))
+	require.NoError(t, em.AddComponent(e, sprite))
+	return em, a, sprite
+}
+
+func TestAnimationSystem(t *testing.T) {
+	t.Run("DrivesSourceRect", func(t *testing.T) {
+		em, _, sprite := animated(t)
+		s := systems.NewAnimationSystem()
+
+		require.NoError(t, s.Update(em, 0))
+		assert.Equal(t, "idle_sheet", sprite.Texture)
+		assert.Equal(t, "idle.png#0", sprite.SourceRect)
+
+		require.NoError(t, s.Update(em, 1100*time.Millisecond))
+		assert.Equal(t, "idle.png#0", sprite.SourceRect, "ループして先頭に戻る")
+	})
+
+	t.Run("FiresEventsAndFinishes", func(t *testing.T) {
+		em, a, sprite := animated(t)
+		s := systems.NewAnimationSystem()
+		require.True(t, a.Play("attack"))
+
+		require.NoError(t, s.Update(em, 150*time.Millisecond))
+		assert.Empty(t, a.Events)
+		assert.Equal(t, "attack.png#1", sprite.SourceRect)
+
+		require.NoError(t, s.Update(em, 100*time.Millisecond))
+		assert.Equal(t, []string{"attack_hit"}, a.Events)
+
+		require.NoError(t, s.Update(em, 100*time.Millisecond))
+		assert.Empty(t, a.Events, "イベントは1回の更新だけ残る")
+		assert.True(t, a.Finished)
+		assert.Equal(t, "attack.png#2", sprite.SourceRect)
+	})
+
+	t.Run("SpeedZeroPauses", func(t *testing.T) {
+		em, a, sprite := animated(t)
+		s := systems.NewAnimationSystem()
+		require.NoError(t, s.Update(em, 300*time.Millisecond))
+		a.Speed = 0
+		require.NoError(t, s.Update(em, time.Second))
+		assert.Equal(t, "idle.png#1", sprite.SourceRect)
+	})
+
+	t.Run("UnknownClipIsIgnored", func(t *testing.T) {
+		_, a, _ := animated(t)
+		assert.False(t, a.Play("dance"))
+		assert.Equal(t, "idle", a.Current)
+	})
+}
@@ -0,0 +1,203 @@
+package systems
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"muscle-dreamer/internal/core/ecs"
+)
+
+// HUDReference - HUD の座標の基準解像度。テーマの ui.elements はこの解像度での
+// ピクセル数で書き、描画時に画面の解像度に合わせて拡大縮小する。
+var HUDReference = image.Pt(1280, 720)
+
+// 要素の大きさを省略したときの既定値 (基準解像度でのピクセル数)
+var (
+	defaultBarSize  = image.Pt(200, 20)
+	defaultIconSize = image.Pt(32, 32)
+)
+
+// hudBorder - スプライトのない要素の枠の太さ
+const hudBorder = 1
+
+// HUDElement - HUD 要素の配置。座標と大きさは基準解像度でのピクセル数。
+type HUDElement struct {
+	Sprite   string      // 空なら色で塗る
+	Position image.Point // 左上
+	Size     image.Point // バーの大きさ
+	IconSize image.Point // アイコン1つの大きさ
+	Spacing  int         // アイコンの間隔 (左端から次の左端まで)
+}
+
+// Rect - バーを screen の解像度に合わせて配置した矩形。位置は縦横それぞれの比率で、
+// 大きさは縦横の比率の小さい方で拡大縮小し、要素の縦横比を保つ。
+func (e HUDElement) Rect(screen image.Point) image.Rectangle {
+	size := e.Size
+	if size == (image.Point{}) {
+		size = defaultBarSize
+	}
+	return e.at(e.Position, size, screen)
+}
+
+func (e HUDElement) at(pos, size, screen image.Point) image.Rectangle {
+	sx := float64(screen.X) / float64(HUDReference.X)
+	sy := float64(screen.Y) / float64(HUDReference.Y)
+	s := min(sx, sy)
+	x, y := int(float64(pos.X)*sx), int(float64(pos.Y)*sy)
+	return image.Rect(x, y, x+int(float64(size.X)*s), y+int(float64(size.Y)*s))
+}
+
+// HUDColors - スプライトのない要素を塗る色
+type HUDColors struct {
+	Health  color.Color
+	Protein color.Color
+	Frame   color.Color
+	Back    color.Color
+}
+
+// HUDLayout - テーマの ui セクションから作る HUD の構成。nil の要素は描画しない。
+type HUDLayout struct {
+	HealthBar    *HUDElement
+	ProteinMeter *HUDElement
+	SkillIcons   *HUDElement
+	Skills       []string // スキルのアイコン画像 (定義順)。空ならアイコンの枠だけを描く
+	Colors       HUDColors
+}
+
+// HUDStats - 直前の Draw の統計
+type HUDStats struct {
+	Health  float64 // 描画した体力の割合
+	Protein float64 // 描画したプロテインの割合
+	Icons   int     // 描画したスキルアイコンの数
+	Missing int     // 読み込まれていないため色で代用したスプライトの数
+}
+
+// HUDSystem - プレイヤーの体力・プロテインメーター・スキルアイコンを画面の上に描画する。
+// 値は描画のたびにプレイヤーの HealthComponent と ProteinComponent から読む。
+type HUDSystem struct {
+	sprites SpriteSource
+	layout  HUDLayout
+	stats   HUDStats
+}
+
+// NewHUDSystem - src から画像を引いて描画する HUDSystem を作成する
+func NewHUDSystem(src SpriteSource) *HUDSystem {
+	return &HUDSystem{sprites: src}
+}
+
+func (h *HUDSystem) GetType() ecs.SystemType { return ecs.HUDSystemType }
+
+// Update - 描画は Draw で行うため何もしない
+func (h *HUDSystem) Update(*ecs.EntityManager, time.Duration) error { return nil }
+
+// SetLayout - 構成を差し替える。テーマを切り替えたときに呼ぶ。
+func (h *HUDSystem) SetLayout(layout HUDLayout) {
+	h.layout = layout
+}
+
+// Stats - 直前の Draw の統計
+func (h *HUDSystem) Stats() HUDStats { return h.stats }
+
+// Draw - player の HUD を描画する。player が存在しなければ何も描かない。
+func (h *HUDSystem) Draw(entities *ecs.EntityManager, player ecs.EntityID, screen *ebiten.Image) {
+	h.stats = HUDStats{}
+	if !entities.IsEntityValid(player) {
+		return
+	}
+	size := screen.Bounds().Size()
+	if c, err := entities.GetComponent(player, ecs.HealthComponentType); err == nil && h.layout.HealthBar != nil {
+		h.stats.Health = c.(*ecs.HealthComponent).Ratio()
+		h.drawBar(screen, *h.layout.HealthBar, h.stats.Health, h.layout.Colors.Health, size)
+	}
+	if c, err := entities.GetComponent(player, ecs.ProteinComponentType); err == nil && h.layout.ProteinMeter != nil {
+		h.stats.Protein = c.(*ecs.ProteinComponent).Ratio()
+		h.drawBar(screen, *h.layout.ProteinMeter, h.stats.Protein, h.layout.Colors.Protein, size)
+	}
+	if h.layout.SkillIcons != nil {
+		h.drawIcons(screen, *h.layout.SkillIcons, size)
+	}
+}
+
+// drawBar - 背景の上に、割合の分だけバーのスプライトを左から描く
+func (h *HUDSystem) drawBar(screen *ebiten.Image, e HUDElement, ratio float64, fill color.Color, size image.Point) {
+	r := e.Rect(size)
+	fillRect(screen, r, h.layout.Colors.Back)
+
+	filled := r
+	filled.Max.X = r.Min.X + int(float64(r.Dx())*ratio)
+	if e.Sprite != "" {
+		if page, src, err := h.sprites.Sprite(e.Sprite, ""); err == nil {
+			src.Max.X = src.Min.X + int(float64(src.Dx())*ratio)
+			drawImage(screen, page.SubImage(src).(*ebiten.Image), filled)
+			return
+		}
+		h.stats.Missing++
+	}
+	fillRect(screen, filled, fill)
+	strokeRect(screen, r, h.layout.Colors.Frame)
+}
+
+// drawIcons - スキルごとに枠とアイコンを横に並べる
+func (h *HUDSystem) drawIcons(screen *ebiten.Image, e HUDElement, size image.Point) {
+	iconSize := e.IconSize
+	if iconSize == (image.Point{}) {
+		iconSize = defaultIconSize
+	}
+	spacing := e.Spacing
+	if spacing == 0 {
+		spacing = iconSize.X
+	}
+	for i, icon := range h.layout.Skills {
+		r := e.at(e.Position.Add(image.Pt(i*spacing, 0)), iconSize, size)
+		if icon != "" {
+			if !h.drawSprite(screen, icon, r) {
+				fillRect(screen, r, h.layout.Colors.Back)
+			}
+		}
+		if e.Sprite == "" || !h.drawSprite(screen, e.Sprite, r) {
+			strokeRect(screen, r, h.layout.Colors.Frame)
+		}
+		h.stats.Icons++
+	}
+}
+
+// drawSprite - 画像を r に合わせて描く。読み込まれていなければ false を返す。
+func (h *HUDSystem) drawSprite(screen *ebiten.Image, texture string, r image.Rectangle) bool {
+	page, src, err := h.sprites.Sprite(texture, "")
+	if err != nil {
+		h.stats.Missing++
+		return false
+	}
+	drawImage(screen, page.SubImage(src).(*ebiten.Image), r)
+	return true
+}
+
+func drawImage(screen, img *ebiten.Image, r image.Rectangle) {
+	b := img.Bounds()
+	if r.Empty() || b.Empty() {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(r.Dx())/float64(b.Dx()), float64(r.Dy())/float64(b.Dy()))
+	op.GeoM.Translate(float64(r.Min.X), float64(r.Min.Y))
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(img, op)
+}
+
+func fillRect(screen *ebiten.Image, r image.Rectangle, c color.Color) {
+	if c == nil || r.Empty() {
+		return
+	}
+	vector.DrawFilledRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), c, false)
+}
+
+func strokeRect(screen *ebiten.Image, r image.Rectangle, c color.Color) {
+	if c == nil || r.Empty() {
+		return
+	}
+	vector.StrokeRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), hudBorder, c, false)
+}
@@ -0,0 +1,71 @@
+package systems_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/core/systems"
+)
+
+func TestHUDElementRect(t *testing.T) {
+	e := systems.HUDElement{Position: image.Pt(20, 40), Size: image.Pt(200, 20)}
+	assert.Equal(t, image.Rect(20, 40, 220, 60), e.Rect(systems.HUDReference))
+	assert.Equal(t, image.Rect(30, 60, 330, 90), e.Rect(image.Pt(1920, 1080)), "基準解像度の1.5倍")
+	assert.Equal(t, image.Rect(40, 40, 240, 60), e.Rect(image.Pt(2560, 720)), "横長の画面でも縦横比を保つ")
+	assert.Equal(t, image.Rect(0, 0, 200, 20), systems.HUDElement{}.Rect(systems.HUDReference), "大きさの既定値")
+}
+
+func TestHUDSystem(t *testing.T) {
+	sprites := fakeSprites{
+		"assets/ui/health.png": ebiten.NewImage(64, 64),
+		"assets/ui/beam.png":   ebiten.NewImage(64, 64),
+	}
+	screen := ebiten.NewImage(640, 360)
+	layout := systems.HUDLayout{
+		HealthBar:    &systems.HUDElement{Sprite: "assets/ui/health.png", Position: image.Pt(20, 20), Size: image.Pt(200, 20)},
+		ProteinMeter: &systems.HUDElement{Position: image.Pt(20, 50), Size: image.Pt(200, 15)},
+		SkillIcons:   &systems.HUDElement{Sprite: "assets/ui/frame.png", Position: image.Pt(20, 80), Spacing: 40},
+		Skills:       []string{"assets/ui/beam.png", ""},
+		Colors:       systems.HUDColors{Health: color.White, Protein: color.White, Frame: color.White, Back: color.Black},
+	}
+
+	em := ecs.NewEntityManager()
+	player := em.CreateEntity()
+	health := ecs.NewHealthComponent(100)
+	protein := ecs.NewProteinComponent(200)
+	require.NoError(t, em.AddComponent(player, health))
+	require.NoError(t, em.AddComponent(player, protein))
+
+	t.Run("ReadsLiveValues", func(t *testing.T) {
+		hud := systems.NewHUDSystem(sprites)
+		hud.SetLayout(layout)
+		hud.Draw(em, player, screen)
+		assert.Equal(t, 1.0, hud.Stats().Health)
+		assert.Equal(t, 1.0, hud.Stats().Protein)
+
+		health.Current, protein.Current = 25, 50
+		hud.Draw(em, player, screen)
+		assert.Equal(t, systems.HUDStats{Health: 0.25, Protein: 0.25, Icons: 2, Missing: 2}, hud.Stats(),
+			"枠のスプライトが読み込まれていないアイコンは色で代用する")
+	})
+
+	t.Run("NothingWithoutPlayer", func(t *testing.T) {
+		hud := systems.NewHUDSystem(sprites)
+		hud.SetLayout(layout)
+		hud.Draw(em, ecs.NoEntity, screen)
+		assert.Equal(t, systems.HUDStats{}, hud.Stats())
+	})
+
+	t.Run("SkipsUndefinedElements", func(t *testing.T) {
+		hud := systems.NewHUDSystem(sprites)
+		hud.SetLayout(systems.HUDLayout{ProteinMeter: layout.ProteinMeter})
+		hud.Draw(em, player, screen)
+		assert.Equal(t, systems.HUDStats{Protein: 0.25}, hud.Stats())
+	})
+}
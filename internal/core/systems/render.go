@@ -0,0 +1,165 @@
+// Package systems はゲームで使う ECS システムを実装する。
+package systems
+
+import (
+	"image"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"muscle-dreamer/internal/core/ecs"
+)
+
+// SpriteSource - テクスチャ名と領域名から描画に使う画像と矩形を引く。asset.Manager が実装する。
+type SpriteSource interface {
+	Sprite(texture, region string) (*ebiten.Image, image.Rectangle, error)
+}
+
+// RenderStats - 直前の Draw の統計
+type RenderStats struct {
+	Sprites   int // 描画したスプライト数
+	DrawCalls int // 発行した描画命令数
+	Missing   int // テクスチャや領域が見つからず描画できなかったスプライト数
+}
+
+// maxBatchQuads - 1回の DrawTriangles に載せられる矩形の数
+const maxBatchQuads = min(ebiten.MaxIndicesCount/6, ebiten.MaxVerticesCount/4)
+
+// RenderSystem - スプライトを描画するシステム。レイヤー順に並べ、同じレイヤーで
+// 同じページ画像を使うスプライトを1回の DrawTriangles にまとめる。
+// 同じレイヤー内の重なり順はページごとにまとまるため、重なりを制御したい場合はレイヤーを分ける。
+type RenderSystem struct {
+	sprites SpriteSource
+
+	quads    []quad
+	vertices []ebiten.Vertex
+	indices  []uint16
+	stats    RenderStats
+	lastErr  error
+}
+
+// quad - 描画待ちのスプライト
+type quad struct {
+	layer     int
+	pageOrder int
+	page      *ebiten.Image
+	src       image.Rectangle
+	sprite    *ecs.SpriteComponent
+	transform *ecs.TransformComponent
+}
+
+// NewRenderSystem - src から画像を引いて描画する RenderSystem を作成する
+func NewRenderSystem(src SpriteSource) *RenderSystem {
+	return &RenderSystem{sprites: src}
+}
+
+func (r *RenderSystem) GetType() ecs.SystemType { return ecs.RenderSystemType }
+
+// Update - 描画は Draw で行うため何もしない
+func (r *RenderSystem) Update(*ecs.EntityManager, time.Duration) error { return nil }
+
+// Stats - 直前の Draw の統計
+func (r *RenderSystem) Stats() RenderStats { return r.stats }
+
+// LastError - 直前の Draw で最後に起きた解決エラー
+func (r *RenderSystem) LastError() error { return r.lastErr }
+
+// Draw - TransformComponent と SpriteComponent を持つエンティティを描画する
+func (r *RenderSystem) Draw(entities *ecs.EntityManager, screen *ebiten.Image) {
+	r.stats, r.lastErr = RenderStats{}, nil
+	r.quads = r.quads[:0]
+	pageOrder := make(map[*ebiten.Image]int)
+
+	for _, id := range entities.QueryWith(ecs.TransformComponentType, ecs.SpriteComponentType) {
+		s, _ := entities.GetComponent(id, ecs.SpriteComponentType)
+		sprite := s.(*ecs.SpriteComponent)
+		if !sprite.Visible || sprite.Opacity <= 0 {
+			continue
+		}
+		page, src, err := r.sprites.Sprite(sprite.Texture, sprite.SourceRect)
+		if err != nil {
+			r.stats.Missing++
+			r.lastErr = err
+			continue
+		}
+		order, ok := pageOrder[page]
+		if !ok {
+			order = len(pageOrder)
+			pageOrder[page] = order
+		}
+		t, _ := entities.GetComponent(id, ecs.TransformComponentType)
+		r.quads = append(r.quads, quad{
+			layer:     sprite.Layer,
+			pageOrder: order,
+			page:      page,
+			src:       src,
+			sprite:    sprite,
+			transform: t.(*ecs.TransformComponent),
+		})
+	}
+
+	sort.SliceStable(r.quads, func(i, j int) bool {
+		a, b := r.quads[i], r.quads[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		return a.pageOrder < b.pageOrder
+	})
+
+	for start := 0; start < len(r.quads); {
+		end := start + 1
+		for end < len(r.quads) && end-start < maxBatchQuads &&
+			r.quads[end].layer == r.quads[start].layer && r.quads[end].page == r.quads[start].page {
+			end++
+		}
+		r.flush(screen, r.quads[start:end])
+		start = end
+	}
+	r.stats.Sprites = len(r.quads)
+}
+
+// flush - 同じページのスプライトをまとめて描画する
+func (r *RenderSystem) flush(screen *ebiten.Image, batch []quad) {
+	r.vertices = r.vertices[:0]
+	r.indices = r.indices[:0]
+	for _, q := range batch {
+		base := uint16(len(r.vertices))
+		r.vertices = appendQuad(r.vertices, q)
+		r.indices = append(r.indices, base, base+1, base+2, base+1, base+3, base+2)
+	}
+	screen.DrawTriangles(r.vertices, r.indices, batch[0].page, nil)
+	r.stats.DrawCalls++
+}
+
+// appendQuad - スプライトの4頂点を追加する。中心を Position に合わせ、拡大・反転・回転を適用する。
+func appendQuad(vs []ebiten.Vertex, q quad) []ebiten.Vertex {
+	w, h := float64(q.src.Dx()), float64(q.src.Dy())
+	sx, sy := q.transform.Scale.X, q.transform.Scale.Y
+	if q.sprite.FlipX {
+		sx = -sx
+	}
+	if q.sprite.FlipY {
+		sy = -sy
+	}
+	sin, cos := math.Sincos(q.transform.Rotation)
+	alpha := float32(min(q.sprite.Opacity, 1))
+
+	corners := [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+	for _, c := range corners {
+		// 中心を原点にした座標へ拡大と回転を適用する
+		lx, ly := (c[0]-0.5)*w*sx, (c[1]-0.5)*h*sy
+		vs = append(vs, ebiten.Vertex{
+			DstX:   float32(q.transform.Position.X + lx*cos - ly*sin),
+			DstY:   float32(q.transform.Position.Y + lx*sin + ly*cos),
+			SrcX:   float32(float64(q.src.Min.X) + c[0]*w),
+			SrcY:   float32(float64(q.src.Min.Y) + c[1]*h),
+			ColorR: 1,
+			ColorG: 1,
+			ColorB: 1,
+			ColorA: alpha,
+		})
+	}
+	return vs
+}
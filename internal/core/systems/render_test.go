@@ -0,0 +1,68 @@
+package systems_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/core/systems"
+)
+
+// fakeSprites - アトラス名ごとに1枚のページを持つ SpriteSource
+type fakeSprites map[string]*ebiten.Image
+
+func (f fakeSprites) Sprite(texture, region string) (*ebiten.Image, image.Rectangle, error) {
+	page, ok := f[texture]
+	if !ok {
+		return nil, image.Rectangle{}, errors.New("not loaded")
+	}
+	return page, image.Rect(0, 0, 16, 16), nil
+}
+
+func spawn(t *testing.T, em *ecs.EntityManager, texture string, layer int) *ecs.SpriteComponent {
+	t.Helper()
+	e := em.CreateEntity()
+	sprite := ecs.NewSpriteComponent(texture, "assets/enemies/burger.png")
+	sprite.Layer = layer
+	require.NoError(t, em.AddComponent(e, ecs.NewTransformComponent(100, 100)))
+	require.NoError(t, em.AddComponent(e, sprite))
+	return sprite
+}
+
+func TestRenderSystem(t *testing.T) {
+	sprites := fakeSprites{
+		"atlas:enemies": ebiten.NewImage(64, 64),
+		"atlas:effects": ebiten.NewImage(64, 64),
+	}
+	screen := ebiten.NewImage(320, 240)
+
+	t.Run("BatchesSpritesSharingAPage", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		for i := 0; i < 50; i++ {
+			spawn(t, em, "atlas:enemies", 0)
+		}
+		spawn(t, em, "atlas:effects", 0)
+		spawn(t, em, "atlas:enemies", 1)
+
+		r := systems.NewRenderSystem(sprites)
+		r.Draw(em, screen)
+		assert.Equal(t, systems.RenderStats{Sprites: 52, DrawCalls: 3}, r.Stats())
+	})
+
+	t.Run("SkipsHiddenAndMissingSprites", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		spawn(t, em, "atlas:enemies", 0).Visible = false
+		spawn(t, em, "atlas:enemies", 0).Opacity = 0
+		spawn(t, em, "atlas:missing", 0)
+
+		r := systems.NewRenderSystem(sprites)
+		r.Draw(em, screen)
+		assert.Equal(t, systems.RenderStats{Missing: 1}, r.Stats())
+		assert.Error(t, r.LastError())
+	})
+}
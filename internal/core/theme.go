@@ -0,0 +1,266 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"muscle-dreamer/internal/asset"
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/script"
+	"muscle-dreamer/internal/theme"
+)
+
+// テーマの表示に使う既定値
+const (
+	uiFontSize = 16
+	uiFontName = "ui"
+)
+
+var (
+	defaultBackground = color.RGBA{50, 50, 100, 255}
+	defaultText       = color.RGBA{255, 255, 255, 255}
+	defaultAccent     = color.RGBA{255, 107, 53, 255}
+)
+
+// SetTheme - テーマを実行中に切り替える。スプライト・フォント・UI色・BGM・テキストを
+// 新しいテーマのものに差し替え、前のテーマのアセットの参照を手放す。
+// ステージ中 (一時停止中を含む) はエンティティを全て破棄し、同じステージを新しいテーマで
+// 読み込み直す。新しいテーマにそのステージがなければメニューに戻る。
+// 失敗した場合は前のテーマのまま変わらない。
+func (g *Game) SetTheme(name string) error {
+	sheets := g.themeSheets
+	g.themeSheets = nil
+	if err := g.themes.SetTheme(name); err != nil {
+		g.releaseAll(g.themeSheets)
+		g.themeSheets = sheets
+		// 失敗するまでの間に新しいレイヤーから読み込まれたアセットを元に戻す
+		g.revalidateAssets()
+		return err
+	}
+
+	g.themeWarnings = nil
+	stage, paused := g.stage, g.state == GameStatePaused
+	next := g.state
+	if next == GameStateLoading {
+		next = g.afterLoading
+	}
+	g.releaseAll(sheets)
+	g.releaseThemeAssets()
+	g.resetStage()
+	// 参照が残っているのは新しいテーマのプレハブのシートと、テーマと無関係なアセットだけになる
+	g.revalidateAssets()
+
+	t := g.themes.GetCurrentTheme()
+	g.applyTheme(t)
+	g.startThemeScripts(t)
+	if stage != "" && t.Manifest.HasStage(stage) {
+		if err := g.EnterStage(t.Manifest, stage); err != nil {
+			return err
+		}
+		if paused {
+			g.afterLoading = GameStatePaused
+		}
+		return nil
+	}
+	if stage != "" {
+		next = GameStateMenu
+	}
+	g.startLoading(requests(t.Manifest.PreloadAssets()), next)
+	g.themeLoader = g.loader
+	return nil
+}
+
+// applyTheme - UI色・HUD・テキスト・フォントをテーマから設定する。読み込めなかったものは
+// 既定の表示のまま警告として残す。
+func (g *Game) applyTheme(t *theme.Theme) {
+	g.palette = t.Colors
+	g.hud.SetLayout(hudLayout(t))
+
+	texts, err := t.Strings("")
+	if err != nil {
+		g.themeWarnings = append(g.themeWarnings, err)
+	}
+	g.texts = texts
+
+	fontPath := t.Spec.UI.Fonts[uiFontName]
+	if fontPath == "" {
+		fontPath = t.Spec.UI.Fonts["main"]
+	}
+	if fontPath != "" {
+		font, err := g.assets.LoadFont(fontPath)
+		if err != nil {
+			g.themeWarnings = append(g.themeWarnings, err)
+		}
+		g.font = font
+	}
+}
+
+// startThemeScripts - 前のテーマのスクリプトが加えたものを取り除き、新しいテーマのスクリプトを
+// 実行する。print の出力やエラーは標準エラー出力に書く。失敗した場合はスクリプトなしで
+// テーマを使い、警告として残す。
+func (g *Game) startThemeScripts(t *theme.Theme) {
+	if g.themeScripts != nil {
+		g.themeScripts.Close()
+		g.themeScripts = nil
+	}
+	c, err := script.StartTheme(t, mod.Host{Entities: g.entities, Systems: g.systems}, g.mods.Events(), os.Stderr)
+	if err != nil {
+		g.themeWarnings = append(g.themeWarnings, err)
+		return
+	}
+	g.themeScripts = c
+}
+
+// releaseThemeAssets - 前のテーマで読み込んだフォント・BGM・事前読み込みの参照を手放す
+func (g *Game) releaseThemeAssets() {
+	g.stopBGM()
+	if g.font != nil {
+		g.assets.UnloadAsset(g.font.GetPath())
+		g.font = nil
+	}
+	if g.themeLoader != nil {
+		g.themeLoader.Release()
+		g.themeLoader = nil
+	}
+}
+
+// resetStage - ステージのエンティティを破棄してアセットの参照を手放す
+func (g *Game) resetStage() {
+	for _, id := range g.entities.Entities() {
+		_ = g.entities.DestroyEntity(id)
+	}
+	g.player = ecs.NoEntity
+	g.ExitStage()
+}
+
+// revalidateAssets - マウント構成の変化をキャッシュに反映する。読み直せなかったアセットは
+// 元の中身のまま使われるため、警告として残す。
+func (g *Game) revalidateAssets() {
+	if _, err := g.assets.Revalidate(); err != nil {
+		g.themeWarnings = append(g.themeWarnings, err)
+	}
+}
+
+// loadSheet - テーマのスプライトシートを切り分けて読み込み、アトラスのキーを返す。
+// 参照はテーマを切り替えるときに手放す。
+func (g *Game) loadSheet(sheet string, grid atlas.Grid) (string, error) {
+	a, err := g.assets.LoadSpriteSheet(sheet, grid)
+	if err != nil {
+		return "", err
+	}
+	g.themeSheets = append(g.themeSheets, a.GetPath())
+	return a.GetPath(), nil
+}
+
+// packSprites - テーマの敵のスプライトを1つのアトラスにまとめ、アトラスのキーを返す。
+// キーは画像の組み合わせごとに変わるため、別のテーマとアトラスを取り違えない。
+// 参照はテーマを切り替えるときに手放す。
+func (g *Game) packSprites(paths []string) (string, error) {
+	h := fnv.New32a()
+	for _, p := range paths {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	a, err := g.assets.BuildAtlas(fmt.Sprintf("atlas:sprites#%08x", h.Sum32()), paths, atlas.Options{})
+	if err != nil {
+		return "", err
+	}
+	g.themeSheets = append(g.themeSheets, a.GetPath())
+	return a.GetPath(), nil
+}
+
+func (g *Game) releaseAll(keys []string) {
+	for _, key := range keys {
+		g.assets.UnloadAsset(key)
+	}
+}
+
+// updateBGM - 状態に合ったテーマのBGMを繰り返し再生する。メニューでは ui.menu_bgm、
+// ステージ中はステージの BGM を使う。読み込み中は切り替えない。
+func (g *Game) updateBGM() {
+	if g.state == GameStateLoading {
+		return
+	}
+	want := ""
+	if t := g.themes.GetCurrentTheme(); t != nil {
+		if g.stage == "" {
+			want = t.Spec.UI.MenuBGM
+		} else if def, ok := t.Stages[g.stage]; ok {
+			want = def.BGM
+		}
+	}
+	if want != g.bgmPath {
+		g.stopBGM()
+		g.bgmPath = want
+		if want != "" {
+			clip, err := g.assets.LoadAudio(want)
+			if err != nil {
+				g.themeWarnings = append(g.themeWarnings, err)
+				return
+			}
+			g.bgm = clip
+		}
+	}
+	if g.bgm != nil && !g.bgm.IsPlaying() {
+		g.bgm.SetVolume(g.audio.BGMVolume())
+		if err := g.bgm.Play(); err != nil {
+			// 同じ曲を毎フレーム再生し直さないよう bgmPath は残す
+			g.themeWarnings = append(g.themeWarnings, err)
+			g.assets.UnloadAsset(g.bgm.GetPath())
+			g.bgm = nil
+		}
+	}
+}
+
+// stopBGM - BGMを止めて参照を手放す。次の更新で状態に合った曲を読み直す。
+func (g *Game) stopBGM() {
+	if g.bgm != nil {
+		_ = g.bgm.Stop()
+		g.assets.UnloadAsset(g.bgm.GetPath())
+		g.bgm = nil
+	}
+	g.bgmPath = ""
+}
+
+// uiColor - テーマのUI色。定義されていなければ fallback。
+func (g *Game) uiColor(name string, fallback color.RGBA) color.RGBA {
+	return g.palette.Color(name, fallback)
+}
+
+// uiText - テーマのテキスト。定義されていなければ fallback。
+func (g *Game) uiText(key, fallback string) string {
+	if s := g.texts.Get(key); s != key {
+		return s
+	}
+	return fallback
+}
+
+// drawText - テーマのフォントで文字列を描画する。フォントがなければデバッグ用フォントを使う。
+// y は行の上端。
+func (g *Game) drawText(screen *ebiten.Image, s string, x, y int, clr color.Color) {
+	if g.font == nil || !g.font.IsLoaded() {
+		ebitenutil.DebugPrintAt(screen, s, x, y)
+		return
+	}
+	face := g.font.Face(uiFontSize)
+	text.Draw(screen, s, face, x, y+face.Metrics().Ascent.Ceil(), clr)
+}
+
+// requests - テーマのアセット参照を読み込み要求に変換する
+func requests(refs []theme.Ref) []asset.Request {
+	reqs := make([]asset.Request, 0, len(refs))
+	for _, ref := range refs {
+		if kind, ok := assetKind(ref.Kind); ok {
+			reqs = append(reqs, asset.Request{Path: ref.Path, Kind: kind})
+		}
+	}
+	return reqs
+}
@@ -0,0 +1,111 @@
+// Package gameerr はゲーム全体で共有するエラー型を定義する。
+package gameerr
+
+import "errors"
+
+// ErrorSeverity - エラー重要度
+type ErrorSeverity int
+
+const (
+	ErrorSeverityInfo ErrorSeverity = iota
+	ErrorSeverityWarning
+	ErrorSeverityError
+	ErrorSeverityFatal
+)
+
+// GameError - ゲームエラーインターフェース
+type GameError interface {
+	error
+	GetCode() string
+	GetSeverity() ErrorSeverity
+}
+
+// ConfigError - 設定エラー
+type ConfigError struct {
+	Code    string
+	Message string
+	Field   string
+}
+
+func (e ConfigError) Error() string {
+	return e.Message
+}
+
+func (e ConfigError) GetCode() string {
+	return e.Code
+}
+
+func (e ConfigError) GetSeverity() ErrorSeverity {
+	return ErrorSeverityError
+}
+
+// AssetError - アセットエラー
+type AssetError struct {
+	Code      string
+	Message   string
+	AssetPath string
+	Err       error // errors.Is で判定できる原因 (任意)
+}
+
+func (e AssetError) Error() string {
+	return e.Message
+}
+
+func (e AssetError) GetCode() string {
+	return e.Code
+}
+
+func (e AssetError) GetSeverity() ErrorSeverity {
+	return ErrorSeverityWarning
+}
+
+func (e AssetError) Unwrap() error {
+	return e.Err
+}
+
+// ThemeError - テーマエラー
+type ThemeError struct {
+	Code    string
+	Message string
+	ThemeID string
+}
+
+func (e ThemeError) Error() string {
+	return e.Message
+}
+
+func (e ThemeError) GetCode() string {
+	return e.Code
+}
+
+func (e ThemeError) GetSeverity() ErrorSeverity {
+	return ErrorSeverityError
+}
+
+// ModError - MODエラー
+type ModError struct {
+	Code    string
+	Message string
+	ModID   string
+}
+
+func (e ModError) Error() string {
+	return e.Message
+}
+
+func (e ModError) GetCode() string {
+	return e.Code
+}
+
+func (e ModError) GetSeverity() ErrorSeverity {
+	return ErrorSeverityError
+}
+
+// CodeOf - err が GameError を含んでいればそのエラーコード、含んでいなければ空文字列
+func CodeOf(err error) string {
+	var gameErr GameError
+	if errors.As(err, &gameErr) {
+		return gameErr.GetCode()
+	}
+	return ""
+}
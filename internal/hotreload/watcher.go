@@ -0,0 +1,174 @@
+// Package hotreload は開発ビルド向けにファイルの更新時刻をポーリングし、
+// 変更されたファイルの再読み込みハンドラを呼び出す。
+package hotreload
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInterval - 既定のポーリング間隔
+const DefaultInterval = 500 * time.Millisecond
+
+// Handler - 変更されたファイルを再読み込みする。エラーはオーバーレイ表示用に保持される。
+type Handler func(path string) error
+
+// fileState - 前回ポーリング時のファイル状態
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watch - 単一ファイル、またはディレクトリ以下の監視。ディレクトリは拡張子ごとの
+// ハンドラを持ち、1回の走査で全ての拡張子のファイルを確認する。
+type watch struct {
+	path     string
+	handler  Handler            // 単一ファイルのハンドラ
+	handlers map[string]Handler // ディレクトリの拡張子 (小文字) ごとのハンドラ
+	files    map[string]fileState
+}
+
+// Watcher - 更新時刻ポーリングによるファイル監視。
+// Poll はゲームループ (メインスレッド) から呼び出すことを想定している。
+type Watcher struct {
+	interval time.Duration
+	lastPoll time.Time
+	watches  []*watch
+	errors   map[string]error
+}
+
+// NewWatcher - 指定間隔でポーリングする監視を作成する
+func NewWatcher(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{
+		interval: interval,
+		errors:   make(map[string]error),
+	}
+}
+
+// Watch - 単一ファイルを監視する
+func (w *Watcher) Watch(path string, handler Handler) {
+	wt := &watch{path: path, handler: handler}
+	wt.files = wt.scan()
+	w.watches = append(w.watches, wt)
+}
+
+// WatchDir - ディレクトリ以下の指定拡張子のファイルを監視する。
+// 追加されたファイルも変更として扱う。同じディレクトリの監視は拡張子が違っても
+// まとめて走査する。同じ拡張子を再び指定した場合はハンドラを置き換える。
+func (w *Watcher) WatchDir(dir, ext string, handler Handler) {
+	ext = strings.ToLower(ext)
+	for _, wt := range w.watches {
+		if wt.handlers != nil && wt.path == dir {
+			wt.handlers[ext] = handler
+			// 監視中の拡張子でまだ通知していない変更を消さないよう、追加した拡張子のファイルだけを記録する
+			for path, state := range wt.scan() {
+				if strings.ToLower(filepath.Ext(path)) == ext {
+					wt.files[path] = state
+				}
+			}
+			return
+		}
+	}
+	wt := &watch{path: dir, handlers: map[string]Handler{ext: handler}}
+	wt.files = wt.scan()
+	w.watches = append(w.watches, wt)
+}
+
+// Poll - 前回から interval 経過していれば変更を確認し、変更されたファイルのハンドラを呼ぶ
+func (w *Watcher) Poll(now time.Time) {
+	if now.Sub(w.lastPoll) < w.interval {
+		return
+	}
+	w.lastPoll = now
+
+	for _, wt := range w.watches {
+		current := wt.scan()
+		var changed []string
+		for path, state := range current {
+			if prev, ok := wt.files[path]; !ok || prev != state {
+				changed = append(changed, path)
+			}
+		}
+		for path := range wt.files {
+			if _, ok := current[path]; !ok {
+				// 削除されたファイルのエラーは表示しない
+				delete(w.errors, path)
+			}
+		}
+		wt.files = current
+
+		sort.Strings(changed)
+		for _, path := range changed {
+			if err := callHandler(wt.handlerFor(path), path); err != nil {
+				w.errors[path] = err
+			} else {
+				delete(w.errors, path)
+			}
+		}
+	}
+}
+
+// Errors - 直近の再読み込みで失敗したファイルとエラー (パス順)
+func (w *Watcher) Errors() []error {
+	paths := make([]string, 0, len(w.errors))
+	for path := range w.errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := make([]error, 0, len(paths))
+	for _, path := range paths {
+		errs = append(errs, fmt.Errorf("%s: %w", path, w.errors[path]))
+	}
+	return errs
+}
+
+// callHandler - ハンドラのパニックをエラーに変換し、ゲームを落とさない
+func callHandler(handler Handler, path string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("再読み込み中にパニックしました: %v", r)
+		}
+	}()
+	return handler(path)
+}
+
+// handlerFor - 変更されたファイルのハンドラ
+func (wt *watch) handlerFor(path string) Handler {
+	if wt.handlers == nil {
+		return wt.handler
+	}
+	return wt.handlers[strings.ToLower(filepath.Ext(path))]
+}
+
+func (wt *watch) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	if wt.handlers == nil {
+		if info, err := os.Stat(wt.path); err == nil {
+			files[wt.path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return files
+	}
+
+	_ = filepath.WalkDir(wt.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if _, ok := wt.handlers[strings.ToLower(filepath.Ext(path))]; !ok {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
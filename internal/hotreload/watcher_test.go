@@ -0,0 +1,134 @@
+package hotreload_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/hotreload"
+)
+
+// touch - 内容と更新時刻を変えてファイルを書き込む
+func touch(t *testing.T, path, content string, at time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(path, at, at))
+}
+
+func TestWatcher(t *testing.T) {
+	t.Run("ReloadsChangedFile", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "game.yaml")
+		base := time.Now().Add(-time.Hour)
+		touch(t, path, "a", base)
+
+		w := hotreload.NewWatcher(time.Second)
+		var reloaded []string
+		w.Watch(path, func(p string) error {
+			reloaded = append(reloaded, p)
+			return nil
+		})
+
+		now := time.Now()
+		w.Poll(now)
+		assert.Empty(t, reloaded, "変更がなければ呼ばれない")
+
+		touch(t, path, "b", base.Add(time.Minute))
+		w.Poll(now.Add(500 * time.Millisecond))
+		assert.Empty(t, reloaded, "ポーリング間隔内は確認しない")
+
+		w.Poll(now.Add(2 * time.Second))
+		assert.Equal(t, []string{path}, reloaded)
+	})
+
+	t.Run("ErrorsAreKeptUntilFixed", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "theme.yaml")
+		base := time.Now().Add(-time.Hour)
+		touch(t, path, "ok", base)
+
+		w := hotreload.NewWatcher(time.Millisecond)
+		w.WatchDir(dir, ".yaml", func(p string) error {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			if string(data) == "broken" {
+				return errors.New("解析エラー")
+			}
+			return nil
+		})
+
+		now := time.Now()
+		touch(t, path, "broken", base.Add(time.Minute))
+		w.Poll(now)
+		errs := w.Errors()
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "theme.yaml")
+		assert.Contains(t, errs[0].Error(), "解析エラー")
+
+		touch(t, path, "fixed", base.Add(2*time.Minute))
+		w.Poll(now.Add(time.Second))
+		assert.Empty(t, w.Errors())
+	})
+
+	t.Run("NewFilesInDirectoryAreReloaded", func(t *testing.T) {
+		dir := t.TempDir()
+		w := hotreload.NewWatcher(time.Millisecond)
+		var reloaded []string
+		w.WatchDir(dir, ".yaml", func(p string) error {
+			reloaded = append(reloaded, p)
+			return nil
+		})
+
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "prefabs"), 0o755))
+		added := filepath.Join(dir, "prefabs", "burger.yaml")
+		touch(t, added, "id: burger", time.Now())
+		touch(t, filepath.Join(dir, "notes.txt"), "ignored", time.Now())
+
+		w.Poll(time.Now())
+		assert.Equal(t, []string{added}, reloaded)
+	})
+
+	t.Run("ExtensionsInSameDirectoryGoToTheirHandlers", func(t *testing.T) {
+		dir := t.TempDir()
+		w := hotreload.NewWatcher(time.Millisecond)
+		reloaded := make(map[string][]string)
+		for _, ext := range []string{".yaml", ".png", ".PNG"} {
+			ext := ext
+			w.WatchDir(dir, ext, func(p string) error {
+				reloaded[ext] = append(reloaded[ext], filepath.Base(p))
+				return nil
+			})
+		}
+
+		touch(t, filepath.Join(dir, "theme.yaml"), "a", time.Now())
+		touch(t, filepath.Join(dir, "idle.png"), "b", time.Now())
+		touch(t, filepath.Join(dir, "walk.Png"), "c", time.Now())
+		w.Poll(time.Now())
+
+		assert.Equal(t, map[string][]string{
+			".yaml": {"theme.yaml"},
+			".PNG":  {"idle.png", "walk.Png"},
+		}, reloaded, "拡張子は大文字小文字を区別せず、後から指定したハンドラを使う")
+	})
+
+	t.Run("PanicIsReportedAsError", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "game.yaml")
+		touch(t, path, "a", time.Now().Add(-time.Hour))
+
+		w := hotreload.NewWatcher(time.Millisecond)
+		w.Watch(path, func(string) error { panic("boom") })
+
+		touch(t, path, "b", time.Now())
+		assert.NotPanics(t, func() { w.Poll(time.Now()) })
+		require.Len(t, w.Errors(), 1)
+		assert.Contains(t, w.Errors()[0].Error(), "boom")
+	})
+}
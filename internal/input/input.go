@@ -0,0 +1,102 @@
+// Package input はキーボード・マウス・ゲームパッド入力を管理する。
+package input
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"muscle-dreamer/internal/config"
+)
+
+// Manager - 入力設定で無効化されたデバイスを無視する入力マネージャー
+type Manager struct {
+	settings config.InputSettings
+	bindings map[string]ebiten.Key
+}
+
+// NewManager - 設定から入力マネージャーを作成する。
+// 解釈できないキーバインドは無視される。
+func NewManager(settings config.InputSettings) *Manager {
+	m := &Manager{settings: settings, bindings: make(map[string]ebiten.Key)}
+	for action, name := range settings.KeyBindings {
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err == nil {
+			m.bindings[action] = key
+		}
+	}
+	return m
+}
+
+// Apply - 入力設定を反映する。未知のキー名が含まれる場合は何も変更しない。
+func (m *Manager) Apply(settings config.InputSettings) error {
+	bindings := make(map[string]ebiten.Key, len(settings.KeyBindings))
+	for action, name := range settings.KeyBindings {
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err != nil {
+			return fmt.Errorf("キーバインド %s のキー名が不正です: %s", action, name)
+		}
+		bindings[action] = key
+	}
+
+	m.settings = settings
+	m.bindings = bindings
+	return nil
+}
+
+// IsActionPressed - アクションに割り当てられたキーが押されているか
+func (m *Manager) IsActionPressed(action string) bool {
+	key, ok := m.bindings[action]
+	return ok && m.IsKeyPressed(key)
+}
+
+// IsActionJustPressed - このフレームでアクションのキーが押されたか
+func (m *Manager) IsActionJustPressed(action string) bool {
+	key, ok := m.bindings[action]
+	return ok && m.IsKeyJustPressed(key)
+}
+
+// IsKeyPressed - キーが押されているか
+func (m *Manager) IsKeyPressed(key ebiten.Key) bool {
+	return m.settings.KeyboardEnabled && ebiten.IsKeyPressed(key)
+}
+
+// IsKeyJustPressed - このフレームでキーが押されたか
+func (m *Manager) IsKeyJustPressed(key ebiten.Key) bool {
+	return m.settings.KeyboardEnabled && inpututil.IsKeyJustPressed(key)
+}
+
+// IsMousePressed - マウスボタンが押されているか
+func (m *Manager) IsMousePressed(button ebiten.MouseButton) bool {
+	return m.settings.MouseEnabled && ebiten.IsMouseButtonPressed(button)
+}
+
+// GetMousePosition - マウスカーソル位置
+func (m *Manager) GetMousePosition() (int, int) {
+	if !m.settings.MouseEnabled {
+		return 0, 0
+	}
+	return ebiten.CursorPosition()
+}
+
+// IsGamepadConnected - ゲームパッドが接続されているか
+func (m *Manager) IsGamepadConnected(id ebiten.GamepadID) bool {
+	if !m.settings.GamepadEnabled {
+		return false
+	}
+	for _, connected := range ebiten.AppendGamepadIDs(nil) {
+		if connected == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetGamepadAxisValue - ゲームパッドの軸の値
+func (m *Manager) GetGamepadAxisValue(id ebiten.GamepadID, axis int) float64 {
+	if !m.settings.GamepadEnabled {
+		return 0
+	}
+	return ebiten.GamepadAxisValue(id, axis)
+}
@@ -0,0 +1,133 @@
+package mod
+
+import (
+	"fmt"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// ModECSAPI - MODのスクリプトに公開する制限付きの ECS 操作 (docs/design/ecs-framework/interfaces.go)。
+// 読み取りはゲーム全体が対象だが、返すコンポーネントは複製で、書き込みは権限のある範囲に限る。
+type ModECSAPI interface {
+	IsEntityValid(id ecs.EntityID) bool
+	GetEntityCount() int
+
+	GetComponent(id ecs.EntityID, t ecs.ComponentType) (ecs.Component, error)
+	HasComponent(id ecs.EntityID, t ecs.ComponentType) bool
+
+	QueryReadOnly(mask ecs.ComponentMask) []ecs.EntityID
+	QueryWithReadOnly(types ...ecs.ComponentType) []ecs.EntityID
+
+	CreateModEntity() (ecs.EntityID, error)
+	AddModComponent(id ecs.EntityID, c ecs.Component) error
+	UpdateComponent(id ecs.EntityID, c ecs.Component) error
+
+	SubscribeToEvents(eventType string, handler EventHandler) error
+	PublishModEvent(e Event) error
+}
+
+var _ ModECSAPI = (*Context)(nil)
+
+// IsEntityValid - エンティティが存在するか
+func (c *Context) IsEntityValid(id ecs.EntityID) bool {
+	return c.host.Entities != nil && c.host.Entities.IsEntityValid(id)
+}
+
+// GetEntityCount - 存在するエンティティの数
+func (c *Context) GetEntityCount() int {
+	if c.host.Entities == nil {
+		return 0
+	}
+	return c.host.Entities.GetEntityCount()
+}
+
+// GetComponent - コンポーネントの複製。書き換えてもゲームには反映されない。
+func (c *Context) GetComponent(id ecs.EntityID, t ecs.ComponentType) (ecs.Component, error) {
+	if c.host.Entities == nil {
+		return nil, ErrUnavailable
+	}
+	comp, err := c.host.Entities.GetComponent(id, t)
+	if err != nil {
+		return nil, err
+	}
+	return comp.Clone(), nil
+}
+
+// HasComponent - コンポーネントを持っているか
+func (c *Context) HasComponent(id ecs.EntityID, t ecs.ComponentType) bool {
+	return c.host.Entities != nil && c.host.Entities.HasComponent(id, t)
+}
+
+// QueryReadOnly - mask の全てのコンポーネントを持つエンティティ
+func (c *Context) QueryReadOnly(mask ecs.ComponentMask) []ecs.EntityID {
+	if c.host.Entities == nil {
+		return nil
+	}
+	return c.host.Entities.Query(mask)
+}
+
+// QueryWithReadOnly - 指定した全ての種類のコンポーネントを持つエンティティ
+func (c *Context) QueryWithReadOnly(types ...ecs.ComponentType) []ecs.EntityID {
+	return c.QueryReadOnly(ecs.MaskOf(types...))
+}
+
+// CreateModEntity - MODが所有するエンティティを作成する。create_entities 権限が必要。
+func (c *Context) CreateModEntity() (ecs.EntityID, error) {
+	if err := c.require(PermCreateEntities); err != nil {
+		return ecs.NoEntity, err
+	}
+	return c.CreateEntity()
+}
+
+// AddModComponent - MODが所有するエンティティにコンポーネントを追加する
+func (c *Context) AddModComponent(id ecs.EntityID, comp ecs.Component) error {
+	if !c.Owns(id) {
+		return c.denied(fmt.Sprintf("エンティティ %s はMOD %s が作成したものではありません", id, c.Mod.ID))
+	}
+	return c.host.Entities.AddComponent(id, comp.Clone())
+}
+
+// UpdateComponent - エンティティの既存のコンポーネントを置き換える。MODが所有していない
+// エンティティには modify_components 権限が必要。
+func (c *Context) UpdateComponent(id ecs.EntityID, comp ecs.Component) error {
+	if !c.Owns(id) {
+		if err := c.require(PermModifyComponents); err != nil {
+			return err
+		}
+	}
+	if !c.HasComponent(id, comp.GetType()) {
+		return fmt.Errorf("%w: %s %s", ecs.ErrComponentNotFound, id, comp.GetType())
+	}
+	return c.host.Entities.AddComponent(id, comp.Clone())
+}
+
+// SubscribeToEvents - イベントを購読する。MODを無効にすると購読は外れる。
+func (c *Context) SubscribeToEvents(eventType string, handler EventHandler) error {
+	if c.events == nil {
+		return ErrUnavailable
+	}
+	c.events.Subscribe(c.Mod.ID, eventType, handler)
+	return nil
+}
+
+// PublishModEvent - イベントを発行する。Source にはMODのIDが入る。
+func (c *Context) PublishModEvent(e Event) error {
+	if c.events == nil {
+		return ErrUnavailable
+	}
+	e.Source = c.Mod.ID
+	return c.events.Publish(e)
+}
+
+// require - MODが権限を持っているか確認する
+func (c *Context) require(permission string) error {
+	if c.Mod.HasPermission(permission) {
+		return nil
+	}
+	return c.denied(fmt.Sprintf("MOD %s には %s 権限がありません", c.Mod.ID, permission))
+}
+
+func (c *Context) denied(msg string) error {
+	return gameerr.ModError{Code: CodePermission, Message: msg, ModID: c.Mod.ID}
+}
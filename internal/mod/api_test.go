@@ -0,0 +1,73 @@
+package mod_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/mod"
+)
+
+func TestModECSAPI(t *testing.T) {
+	f := newFixture(t, mod.Options{})
+	require.NoError(t, f.manager.EnableMod("zombies"))
+	zombies, core := f.manager.Context("zombies"), f.manager.Context("core_lib")
+
+	enemy := f.entities.CreateEntity()
+	require.NoError(t, f.entities.AddComponent(enemy, ecs.NewHealthComponent(10)))
+
+	t.Run("ReadsCopies", func(t *testing.T) {
+		assert.True(t, zombies.IsEntityValid(enemy))
+		assert.Equal(t, 1, zombies.GetEntityCount())
+		assert.Equal(t, []ecs.EntityID{enemy}, zombies.QueryWithReadOnly(ecs.HealthComponentType))
+
+		c, err := zombies.GetComponent(enemy, ecs.HealthComponentType)
+		require.NoError(t, err)
+		c.(*ecs.HealthComponent).Current = 0
+		live, err := f.entities.GetComponent(enemy, ecs.HealthComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, 10, live.(*ecs.HealthComponent).Current, "読み取ったコンポーネントを書き換えてもゲームには反映されない")
+	})
+
+	t.Run("WritesNeedOwnershipOrPermission", func(t *testing.T) {
+		_, err := core.CreateModEntity()
+		assert.Equal(t, mod.CodePermission, gameerr.CodeOf(err))
+
+		id, err := zombies.CreateModEntity()
+		require.NoError(t, err)
+		require.NoError(t, zombies.AddModComponent(id, ecs.NewTransformComponent(1, 2)))
+		assert.True(t, f.entities.HasComponent(id, ecs.TransformComponentType))
+
+		assert.Equal(t, mod.CodePermission, gameerr.CodeOf(zombies.AddModComponent(enemy, ecs.NewTransformComponent(0, 0))))
+		assert.Equal(t, mod.CodePermission, gameerr.CodeOf(zombies.UpdateComponent(enemy, ecs.NewHealthComponent(1))))
+		assert.ErrorIs(t, zombies.UpdateComponent(id, ecs.NewHealthComponent(1)), ecs.ErrComponentNotFound)
+		require.NoError(t, zombies.UpdateComponent(id, ecs.NewTransformComponent(5, 5)))
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		var got []mod.Event
+		require.NoError(t, core.SubscribeToEvents("spawn", func(e mod.Event) error {
+			got = append(got, e)
+			return errors.New("handler failed")
+		}))
+		require.NoError(t, zombies.SubscribeToEvents("spawn", func(e mod.Event) error {
+			got = append(got, e)
+			return nil
+		}))
+
+		err := zombies.PublishModEvent(mod.Event{Type: "spawn", Source: "spoofed", Data: map[string]interface{}{"count": 3}})
+		assert.EqualError(t, err, "handler failed")
+		require.Len(t, got, 2, "失敗したハンドラーがあっても残りに配信する")
+		assert.Equal(t, "zombies", got[0].Source)
+
+		require.NoError(t, f.manager.DisableMod("zombies"))
+		got = nil
+		assert.Error(t, f.manager.Events().Publish(mod.Event{Type: "spawn"}))
+		require.Len(t, got, 1, "無効にしたMODの購読は外れる")
+		assert.Empty(t, got[0].Source, "ゲームが発行したイベント")
+	})
+}
@@ -0,0 +1,162 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/vfs"
+)
+
+// AssetStore - MODが使うアセットの読み込みと解放。Game が asset.Manager を包んで渡す。
+type AssetStore interface {
+	// LoadAsset - アセットを読み込み、参照を1つ増やす
+	LoadAsset(path string) error
+	// UnloadAsset - アセットの参照を1つ手放す
+	UnloadAsset(path string)
+	// Revalidate - MODレイヤーのマウント構成が変わったときに、読み込み元の変わったアセットを読み直す
+	Revalidate() ([]string, error)
+}
+
+// Host - MODから使うゲームの機能。nil のものは MOD から使えない。
+type Host struct {
+	Entities *ecs.EntityManager
+	Systems  *ecs.SystemManager
+	Assets   AssetStore
+	Files    *vfs.FS // MODが file_access の範囲で読み書きする仮想ファイルシステム
+}
+
+// ErrUnavailable - ホストが提供していない機能
+var ErrUnavailable = errors.New("この機能は使用できません")
+
+// Context - 有効なMODがゲームに加えたシステム・エンティティ・アセットを記録する。
+// MODを無効にすると、記録したものは全て取り除かれる。
+type Context struct {
+	Mod *Mod
+
+	host     Host
+	events   *EventBus
+	sandbox  *Sandbox
+	systems  []ecs.SystemType
+	entities []ecs.EntityID
+	assets   []string
+	cleanups []func()
+}
+
+func newContext(m *Mod, host Host, events *EventBus, log io.Writer) *Context {
+	return &Context{Mod: m, host: host, events: events, sandbox: NewSandbox(m, host.Files, log)}
+}
+
+// NewContext - マネージャーを通さずに m の Context を作る。テーマのスクリプトのように
+// MODレイヤーをマウントしないものに使い、取り除くときは Close を呼ぶ。
+func NewContext(m *Mod, host Host, events *EventBus, log io.Writer) *Context {
+	return newContext(m, host, events, log)
+}
+
+// Close - NewContext で作った Context が加えたものを、MODを無効にしたときと同じく全て取り除く
+func (c *Context) Close() {
+	c.teardown()
+}
+
+// Sandbox - MODのファイル・ネットワーク・プロセスの操作に使うサンドボックス
+func (c *Context) Sandbox() *Sandbox {
+	return c.sandbox
+}
+
+// RegisterSystem - MODのシステムを登録する
+func (c *Context) RegisterSystem(s ecs.System) error {
+	if c.host.Systems == nil {
+		return ErrUnavailable
+	}
+	if err := c.host.Systems.RegisterSystem(s); err != nil {
+		return err
+	}
+	c.systems = append(c.systems, s.GetType())
+	return nil
+}
+
+// CreateEntity - MODが所有するエンティティを作成する。limits.max_entities を超える場合はエラーを返す。
+func (c *Context) CreateEntity() (ecs.EntityID, error) {
+	if c.host.Entities == nil {
+		return ecs.NoEntity, ErrUnavailable
+	}
+	if limit := c.Mod.Spec.Metadata.Limits.MaxEntities; limit > 0 && len(c.Entities()) >= limit {
+		return ecs.NoEntity, gameerr.ModError{
+			Code:    CodeLimitExceeded,
+			Message: fmt.Sprintf("MOD %s のエンティティ数が上限 (%d) に達しました", c.Mod.ID, limit),
+			ModID:   c.Mod.ID,
+		}
+	}
+	id := c.host.Entities.CreateEntity()
+	c.entities = append(c.entities, id)
+	return id, nil
+}
+
+// Owns - エンティティがMODの作成したもので、まだ有効か
+func (c *Context) Owns(id ecs.EntityID) bool {
+	if c.host.Entities == nil || !c.host.Entities.IsEntityValid(id) {
+		return false
+	}
+	for _, e := range c.entities {
+		if e == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Entities - MODが作成したエンティティのうち、まだ有効なもの
+func (c *Context) Entities() []ecs.EntityID {
+	if c.host.Entities == nil {
+		return nil
+	}
+	live := c.entities[:0]
+	for _, id := range c.entities {
+		if c.host.Entities.IsEntityValid(id) {
+			live = append(live, id)
+		}
+	}
+	c.entities = live
+	return append([]ecs.EntityID(nil), live...)
+}
+
+// LoadAsset - アセットを読み込み、MODを無効にするまで参照を保持する
+func (c *Context) LoadAsset(path string) error {
+	if c.host.Assets == nil {
+		return ErrUnavailable
+	}
+	if err := c.host.Assets.LoadAsset(path); err != nil {
+		return err
+	}
+	c.assets = append(c.assets, path)
+	return nil
+}
+
+// OnDisable - MODを無効にするときに呼ぶ関数を登録する。登録と逆の順に、
+// システムやエンティティを取り除く前に呼ぶ。
+func (c *Context) OnDisable(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+
+// teardown - 登録したシステムを外し、エンティティを破棄し、アセットの参照を手放す
+func (c *Context) teardown() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	if c.events != nil {
+		c.events.unsubscribe(c.Mod.ID)
+	}
+	for i := len(c.systems) - 1; i >= 0; i-- {
+		// 他から外されていても構わない
+		_ = c.host.Systems.UnregisterSystem(c.systems[i])
+	}
+	for _, id := range c.Entities() {
+		_ = c.host.Entities.DestroyEntity(id)
+	}
+	for _, path := range c.assets {
+		c.host.Assets.UnloadAsset(path)
+	}
+	c.systems, c.entities, c.assets, c.cleanups = nil, nil, nil, nil
+}
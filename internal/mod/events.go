@@ -0,0 +1,63 @@
+package mod
+
+import "errors"
+
+// Event - MODとゲームの間でやり取りするイベント
+type Event struct {
+	Type   string
+	Source string // 発行したMODのID。ゲームが発行した場合は空
+	Data   map[string]interface{}
+}
+
+// EventHandler - イベントを受け取る関数
+type EventHandler func(e Event) error
+
+// EventBus - イベントの購読と配信。ハンドラーは購読した順に同期的に呼ぶ。
+type EventBus struct {
+	subs map[string][]subscription
+}
+
+type subscription struct {
+	owner   string
+	handler EventHandler
+}
+
+// NewEventBus - 購読のない EventBus を作成する
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string][]subscription)}
+}
+
+// Subscribe - eventType のイベントを購読する。owner はMODを無効にしたときに購読を外すための名前。
+func (b *EventBus) Subscribe(owner, eventType string, handler EventHandler) {
+	b.subs[eventType] = append(b.subs[eventType], subscription{owner: owner, handler: handler})
+}
+
+// Publish - イベントを購読しているハンドラーに配信する。ハンドラーのエラーはまとめて返し、
+// 途中で失敗しても残りのハンドラーに配信する。
+func (b *EventBus) Publish(e Event) error {
+	var errs []error
+	// ハンドラーの中で購読が増減しても、配信を始めた時点の購読者に配る
+	for _, s := range append([]subscription(nil), b.subs[e.Type]...) {
+		if err := s.handler(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unsubscribe - owner の購読を全て外す
+func (b *EventBus) unsubscribe(owner string) {
+	for t, subs := range b.subs {
+		kept := subs[:0]
+		for _, s := range subs {
+			if s.owner != owner {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(b.subs, t)
+		} else {
+			b.subs[t] = kept
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/atomicfile"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/semver"
+	"muscle-dreamer/internal/vfs"
+)
+
+// StateFile - 有効なMODの一覧を保存するファイル名 (ユーザー設定ディレクトリ内)
+const StateFile = "mods.yaml"
+
+// Options - Manager の設定
+type Options struct {
+	// GameVersion - 実行中のゲームのバージョン。MODの game_version と比べる。
+	// 空の場合は確認しない。
+	GameVersion string
+
+	// StatePath - 有効なMODの一覧を保存するファイル。空の場合は保存しない。
+	StatePath string
+
+	// Start - MODを有効にしてレイヤーをマウントした後に呼ぶ。スクリプトの実行などに使う。
+	// エラーを返したMODは有効にならない。
+	Start func(c *Context) error
+
+	// Log - サンドボックスが拒否したMODの操作の記録先。nil の場合は記録しない。
+	Log io.Writer
+}
+
+// Manager - MODディレクトリ (mods/<ID>/mod.yaml) からMODを読み込み、有効なMODを
+// 読み込み順に仮想ファイルシステムのMODレイヤーとしてマウントする。
+type Manager struct {
+	root    fs.FS
+	files   *vfs.FS
+	host    Host
+	opts    Options
+	events  *EventBus
+	loaded  map[string]*Mod
+	enabled []*Context // 読み込み順
+
+	// unrestored - 保存されていたが Restore で有効にできなかったMOD。一時的な失敗で
+	// 利用者の選択が消えないよう、無効にするまで保存する一覧に残す。
+	unrestored []string
+	restoring  bool
+}
+
+// NewManager - root はMODディレクトリ、files はMODをマウントする仮想ファイルシステム
+func NewManager(root fs.FS, files *vfs.FS, host Host, opts Options) *Manager {
+	return &Manager{root: root, files: files, host: host, opts: opts, events: NewEventBus(), loaded: make(map[string]*Mod)}
+}
+
+// Events - MODとゲームが共有するイベントバス
+func (m *Manager) Events() *EventBus {
+	return m.events
+}
+
+// MountName - MODを仮想ファイルシステムにマウントするレイヤー名
+func MountName(id string) string {
+	return "mod:" + id
+}
+
+// GetAvailableMods - mod.yaml を持つMODのID (名前順)
+func (m *Manager) GetAvailableMods() []string {
+	entries, err := fs.ReadDir(m.root, ".")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(m.root, path.Join(e.Name(), FileName)); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// LoadMod - MODの mod.yaml を読み込んでスキーマと game_version を確認する。
+// 有効なMODは変えず、次に有効にするときに読み込んだ内容が使われる。
+func (m *Manager) LoadMod(modPath string) (*Mod, error) {
+	mod, err := readMod(m.root, modPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkGame(mod); err != nil {
+		return nil, err
+	}
+	m.loaded[mod.ID] = mod
+	return mod, nil
+}
+
+// ValidateMod - MODを読み込み、scripts と assets のファイルがあるか、必須の依存MODが
+// あってバージョンが合うかを確認する。見つかった問題を全てまとめて返す。
+func (m *Manager) ValidateMod(modPath string) error {
+	mod, err := readMod(m.root, modPath)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if err := m.checkGame(mod); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range append(append([]File(nil), mod.Spec.Scripts...), mod.Spec.Assets...) {
+		if _, err := fs.Stat(mod.fsys, path.Clean(f.File)); err != nil {
+			errs = append(errs, gameerr.ModError{
+				Code:    CodeFileMissing,
+				Message: fmt.Sprintf("%s: ファイルがありません: %s", mod.ID, f.File),
+				ModID:   mod.ID,
+			})
+		}
+	}
+	for _, dep := range mod.Spec.Metadata.Dependencies {
+		if dep.Optional {
+			continue
+		}
+		base, err := readMod(m.root, dep.ModID)
+		if err != nil {
+			errs = append(errs, gameerr.ModError{
+				Code:    CodeMissingDep,
+				Message: fmt.Sprintf("MOD %s には %s が必要です: %v", mod.ID, dep.ModID, err),
+				ModID:   mod.ID,
+			})
+			continue
+		}
+		if err := checkDependency(mod, dep, base); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkGame - MODの game_version が実行中のゲームのバージョンを許すか確認する
+func (m *Manager) checkGame(mod *Mod) error {
+	want := mod.Spec.Metadata.GameVersion
+	if m.opts.GameVersion == "" || want == "" {
+		return nil
+	}
+	game, err := semver.Parse(m.opts.GameVersion)
+	if err != nil {
+		return fmt.Errorf("ゲームのバージョンを解釈できません: %w", err)
+	}
+	c, err := semver.ParseConstraint(want)
+	if err != nil {
+		return gameerr.ModError{Code: CodeInvalidSpec, Message: fmt.Sprintf("%s: game_version: %v", mod.ID, err), ModID: mod.ID}
+	}
+	if !c.Check(game) {
+		return gameerr.ModError{
+			Code:    CodeIncompatible,
+			Message: fmt.Sprintf("MOD %s はゲームバージョン %s を必要としますが、実行中のバージョンは %s です", mod.ID, c, game),
+			ModID:   mod.ID,
+		}
+	}
+	return nil
+}
+
+// EnableMod - MODと、その必須の依存MODを有効にする。有効なMOD全体の読み込み順を
+// 決め直してレイヤーをマウントし、新しく有効になったMODを読み込み順に開始する。
+// 失敗した場合は有効なMODは変わらない。
+func (m *Manager) EnableMod(modName string) error {
+	if m.Context(modName) != nil {
+		return nil
+	}
+	set := make(map[string]*Mod, len(m.enabled)+1)
+	for _, c := range m.enabled {
+		set[c.Mod.ID] = c.Mod
+	}
+	if err := m.require(modName, set); err != nil {
+		return err
+	}
+	order, err := loadOrder(set)
+	if err != nil {
+		return err
+	}
+
+	prev := m.enabled
+	next := make([]*Context, 0, len(order))
+	var started []*Context
+	for _, mod := range order {
+		c := m.Context(mod.ID)
+		if c == nil {
+			c = newContext(mod, m.host, m.events, m.opts.Log)
+			started = append(started, c)
+		}
+		next = append(next, c)
+	}
+	m.enabled = next
+	if err := m.remount(); err != nil {
+		m.enabled = prev
+		_ = m.remount()
+		return err
+	}
+	for i, c := range started {
+		if m.opts.Start == nil {
+			break
+		}
+		if err := m.opts.Start(c); err != nil {
+			for _, s := range started[:i+1] {
+				s.teardown()
+			}
+			m.enabled = prev
+			_ = m.remount()
+			return gameerr.ModError{
+				Code:    CodeStartFailed,
+				Message: fmt.Sprintf("MOD %s を開始できません: %v", c.Mod.ID, err),
+				ModID:   c.Mod.ID,
+			}
+		}
+	}
+	// 有効にできたMODは有効なMODとして保存する
+	m.unrestored = slices.DeleteFunc(m.unrestored, func(id string) bool { return m.Context(id) != nil })
+	return m.save()
+}
+
+// require - MODと必須の依存MODを再帰的に読み込んで set に加える
+func (m *Manager) require(id string, set map[string]*Mod) error {
+	if _, ok := set[id]; ok {
+		return nil
+	}
+	mod, ok := m.loaded[id]
+	if !ok {
+		var err error
+		if mod, err = m.LoadMod(id); err != nil {
+			return err
+		}
+	}
+	set[id] = mod
+	for _, dep := range mod.Spec.Metadata.Dependencies {
+		if dep.Optional {
+			continue
+		}
+		if err := m.require(dep.ModID, set); err != nil {
+			var modErr gameerr.ModError
+			if errors.As(err, &modErr) && modErr.Code == CodeNotFound && modErr.ModID == dep.ModID {
+				return gameerr.ModError{
+					Code:    CodeMissingDep,
+					Message: fmt.Sprintf("MOD %s には %s が必要ですが、見つかりません", id, dep.ModID),
+					ModID:   id,
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// DisableMod - MODを無効にする。MODが登録したシステムを外し、作成したエンティティを破棄し、
+// 読み込んだアセットの参照を手放してからレイヤーを外す。有効なMODが必須の依存として
+// 使っている場合はエラーを返す。Restore で有効にできなかったMODは保存する一覧から外す。
+func (m *Manager) DisableMod(modName string) error {
+	c := m.Context(modName)
+	if c == nil {
+		if i := slices.Index(m.unrestored, modName); i >= 0 {
+			// 有効にできなかったMODは保存する一覧から外すだけ
+			m.unrestored = slices.Delete(m.unrestored, i, i+1)
+			return m.save()
+		}
+		return gameerr.ModError{Code: CodeNotEnabled, Message: fmt.Sprintf("MOD %s は有効ではありません", modName), ModID: modName}
+	}
+	if users := required(m.enabled, modName); len(users) > 0 {
+		return gameerr.ModError{
+			Code:    CodeRequired,
+			Message: fmt.Sprintf("MOD %s は %s が使っているため無効にできません", modName, strings.Join(users, ", ")),
+			ModID:   modName,
+		}
+	}
+
+	c.teardown()
+	next := make([]*Context, 0, len(m.enabled)-1)
+	for _, e := range m.enabled {
+		if e != c {
+			next = append(next, e)
+		}
+	}
+	m.enabled = next
+	err := m.remount()
+	return errors.Join(err, m.save())
+}
+
+// GetEnabledMods - 有効なMOD (読み込み順)
+func (m *Manager) GetEnabledMods() []*Mod {
+	mods := make([]*Mod, len(m.enabled))
+	for i, c := range m.enabled {
+		mods[i] = c.Mod
+	}
+	return mods
+}
+
+// Context - 有効なMODの Context。有効でなければ nil。
+func (m *Manager) Context(modName string) *Context {
+	for _, c := range m.enabled {
+		if c.Mod.ID == modName {
+			return c
+		}
+	}
+	return nil
+}
+
+// remount - MODレイヤーを全て外し、読み込み順にマウントし直す。
+// 同じ優先度では後からマウントしたレイヤーが優先されるため、後に読み込むMODが優先される。
+func (m *Manager) remount() error {
+	for _, info := range m.files.Mounts() {
+		if strings.HasPrefix(info.Name, MountName("")) {
+			m.files.Unmount(info.Name)
+		}
+	}
+	for _, c := range m.enabled {
+		if err := m.files.Mount(MountName(c.Mod.ID), c.Mod.fsys, vfs.MountOptions{Priority: vfs.PriorityMod}); err != nil {
+			return err
+		}
+	}
+	if m.host.Assets == nil {
+		return nil
+	}
+	_, err := m.host.Assets.Revalidate()
+	return err
+}
+
+// state - StatePath に保存する内容
+type state struct {
+	Enabled []string `yaml:"enabled"` // 読み込み順
+}
+
+// Restore - StatePath に保存された有効なMODを読み込み順に有効にし直す。
+// 見つからないMODや有効にできないMODは飛ばしてエラーとして返す。飛ばしたMODは
+// 保存する一覧に残るため、原因を取り除けば次の起動で有効になる。
+// 途中で保存しないため、復元に失敗しても保存した一覧は書き換わらない。
+func (m *Manager) Restore() []error {
+	if m.opts.StatePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.opts.StatePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+	var s state
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return []error{fmt.Errorf("%s: %w", m.opts.StatePath, err)}
+	}
+	m.restoring = true
+	defer func() { m.restoring = false }()
+	var errs []error
+	for _, id := range s.Enabled {
+		if err := m.EnableMod(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, id := range s.Enabled {
+		if m.Context(id) == nil && !slices.Contains(m.unrestored, id) {
+			m.unrestored = append(m.unrestored, id)
+		}
+	}
+	return errs
+}
+
+// save - 有効なMODの一覧を一時ファイルに書いて同期してからリネームする。
+// 有効にできなかったMODは有効なMODの後に残す。
+func (m *Manager) save() error {
+	if m.opts.StatePath == "" || m.restoring {
+		return nil
+	}
+	s := state{Enabled: make([]string, 0, len(m.enabled)+len(m.unrestored))}
+	for _, c := range m.enabled {
+		s.Enabled = append(s.Enabled, c.Mod.ID)
+	}
+	s.Enabled = append(s.Enabled, m.unrestored...)
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.opts.StatePath), 0o755); err != nil {
+		return fmt.Errorf("有効なMODの一覧を保存できません: %w", err)
+	}
+	if err := atomicfile.WriteFile(m.opts.StatePath, data); err != nil {
+		return fmt.Errorf("有効なMODの一覧を保存できません: %w", err)
+	}
+	return nil
+}
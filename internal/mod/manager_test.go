@@ -0,0 +1,293 @@
+package mod_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/vfs"
+)
+
+func modsFS() fstest.MapFS {
+	return fstest.MapFS{
+		"core_lib/mod.yaml":          {Data: []byte("metadata: {id: core_lib, name: Core, version: 1.2.0}\n")},
+		"core_lib/assets/shared.png": {Data: []byte("core")},
+		"zombies/mod.yaml": {Data: []byte(`
+metadata:
+  id: zombies
+  name: Zombies
+  version: 1.0.0
+  author: ModCreator
+  dependencies:
+    - {mod_id: core_lib, version: ">=1.0.0"}
+    - {mod_id: extra_ai, optional: true}
+  permissions: [create_entities]
+  limits: {max_entities: 2}
+scripts:
+  - {file: scripts/main.lua, type: main}
+assets:
+  - {file: assets/sprites/zombie.png, type: sprite}
+`)},
+		"zombies/scripts/main.lua":          {Data: []byte("-- main")},
+		"zombies/assets/sprites/zombie.png": {Data: []byte("zombie")},
+		"zombies/assets/shared.png":         {Data: []byte("zombies")},
+		"extra_ai/mod.yaml":                 {Data: []byte("metadata: {id: extra_ai, name: AI, version: 0.5.0}\n")},
+		"vampires/mod.yaml":                 {Data: []byte("metadata: {id: vampires, name: V, version: 1.0.0, conflicts: [zombies]}\n")},
+		"needs_new/mod.yaml":                {Data: []byte("metadata: {id: needs_new, name: N, version: 1.0.0, dependencies: [{mod_id: core_lib, version: '>=2.0.0'}]}\n")},
+		"orphan/mod.yaml":                   {Data: []byte("metadata: {id: orphan, name: O, version: 1.0.0, dependencies: [{mod_id: missing_lib}]}\n")},
+		"cycle_a/mod.yaml":                  {Data: []byte("metadata: {id: cycle_a, name: A, version: 1.0.0, dependencies: [{mod_id: cycle_b}]}\n")},
+		"cycle_b/mod.yaml":                  {Data: []byte("metadata: {id: cycle_b, name: B, version: 1.0.0, dependencies: [{mod_id: cycle_a}]}\n")},
+		"future/mod.yaml":                   {Data: []byte("metadata: {id: future, name: F, version: 1.0.0, game_version: '>=2.0.0'}\n")},
+		"broken/mod.yaml":                   {Data: []byte("metadata: {id: broken}\n")},
+		"renamed/mod.yaml":                  {Data: []byte("metadata: {id: other, name: O, version: 1.0.0}\n")},
+	}
+}
+
+// fakeAssets - 参照数と読み直しの回数を記録する
+type fakeAssets struct {
+	refs        map[string]int
+	revalidated int
+}
+
+func (a *fakeAssets) LoadAsset(path string) error {
+	if path == "missing.png" {
+		return errors.New("not found")
+	}
+	a.refs[path]++
+	return nil
+}
+
+func (a *fakeAssets) UnloadAsset(path string) { a.refs[path]-- }
+
+func (a *fakeAssets) Revalidate() ([]string, error) {
+	a.revalidated++
+	return nil, nil
+}
+
+type fakeSystem ecs.SystemType
+
+func (s fakeSystem) GetType() ecs.SystemType                        { return ecs.SystemType(s) }
+func (s fakeSystem) Update(*ecs.EntityManager, time.Duration) error { return nil }
+
+type fixture struct {
+	manager  *mod.Manager
+	files    *vfs.FS
+	entities *ecs.EntityManager
+	systems  *ecs.SystemManager
+	assets   *fakeAssets
+}
+
+func newFixture(t *testing.T, opts mod.Options) *fixture {
+	t.Helper()
+	f := &fixture{
+		files:    vfs.New(vfs.DefaultRoots...),
+		entities: ecs.NewEntityManager(),
+		systems:  ecs.NewSystemManager(),
+		assets:   &fakeAssets{refs: make(map[string]int)},
+	}
+	require.NoError(t, f.files.Mount("base", fstest.MapFS{"assets/shared.png": {Data: []byte("base")}}, vfs.MountOptions{}))
+	host := mod.Host{Entities: f.entities, Systems: f.systems, Assets: f.assets}
+	f.manager = mod.NewManager(modsFS(), f.files, host, opts)
+	return f
+}
+
+func ids(mods []*mod.Mod) []string {
+	out := make([]string, len(mods))
+	for i, m := range mods {
+		out[i] = m.ID
+	}
+	return out
+}
+
+func TestLoadMod(t *testing.T) {
+	f := newFixture(t, mod.Options{GameVersion: "1.0.0"})
+
+	m, err := f.manager.LoadMod("zombies")
+	require.NoError(t, err)
+	assert.Equal(t, "ModCreator", m.Spec.Metadata.Author)
+	assert.Equal(t, mod.Dependency{ModID: "core_lib", Version: ">=1.0.0"}, m.Spec.Metadata.Dependencies[0])
+	assert.Equal(t, "scripts/main.lua", m.Spec.Scripts[0].File)
+	assert.Equal(t, 2, m.Spec.Metadata.Limits.MaxEntities)
+	assert.Empty(t, f.manager.GetEnabledMods(), "読み込むだけでは有効にならない")
+
+	testCases := []struct {
+		name string
+		code string
+	}{
+		{"missing", mod.CodeNotFound},
+		{"../zombies", mod.CodeNotFound},
+		{"broken", mod.CodeInvalidSpec},
+		{"renamed", mod.CodeIDMismatch},
+		{"future", mod.CodeIncompatible},
+	}
+	for _, tc := range testCases {
+		_, err := f.manager.LoadMod(tc.name)
+		assert.Equal(t, tc.code, gameerr.CodeOf(err), tc.name)
+	}
+}
+
+func TestValidateMod(t *testing.T) {
+	f := newFixture(t, mod.Options{})
+	assert.NoError(t, f.manager.ValidateMod("zombies"))
+
+	root := modsFS()
+	delete(root, "zombies/scripts/main.lua")
+	delete(root, "core_lib/mod.yaml")
+	m := mod.NewManager(root, vfs.New(), mod.Host{}, mod.Options{})
+	err := m.ValidateMod("zombies")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "scripts/main.lua")
+	assert.Contains(t, err.Error(), "core_lib")
+
+	assert.Equal(t, mod.CodeUnsatisfied, gameerr.CodeOf(f.manager.ValidateMod("needs_new")))
+}
+
+func TestEnableMod(t *testing.T) {
+	t.Run("EnablesDependenciesFirst", func(t *testing.T) {
+		f := newFixture(t, mod.Options{})
+		require.NoError(t, f.manager.EnableMod("zombies"))
+		assert.Equal(t, []string{"core_lib", "zombies"}, ids(f.manager.GetEnabledMods()))
+
+		data, err := f.files.ReadFile("assets/shared.png")
+		require.NoError(t, err)
+		assert.Equal(t, "zombies", string(data), "後に読み込むMODが優先される")
+
+		require.NoError(t, f.manager.EnableMod("extra_ai"))
+		assert.Equal(t, []string{"core_lib", "extra_ai", "zombies"}, ids(f.manager.GetEnabledMods()),
+			"任意の依存も有効なら先に読み込む")
+		assert.Equal(t, 2, f.assets.revalidated)
+	})
+
+	t.Run("LoadOrderIsDeterministic", func(t *testing.T) {
+		for _, order := range [][]string{{"extra_ai", "zombies"}, {"zombies", "extra_ai"}} {
+			f := newFixture(t, mod.Options{})
+			for _, id := range order {
+				require.NoError(t, f.manager.EnableMod(id))
+			}
+			assert.Equal(t, []string{"core_lib", "extra_ai", "zombies"}, ids(f.manager.GetEnabledMods()))
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			enabled []string
+			code    string
+		}{
+			{"vampires", []string{"zombies"}, mod.CodeConflict},
+			{"zombies", []string{"vampires"}, mod.CodeConflict},
+			{"needs_new", nil, mod.CodeUnsatisfied},
+			{"orphan", nil, mod.CodeMissingDep},
+			{"cycle_a", nil, mod.CodeDependencyCycle},
+			{"broken", nil, mod.CodeInvalidSpec},
+		}
+		for _, tc := range testCases {
+			f := newFixture(t, mod.Options{})
+			for _, id := range tc.enabled {
+				require.NoError(t, f.manager.EnableMod(id))
+			}
+			before := ids(f.manager.GetEnabledMods())
+			assert.Equal(t, tc.code, gameerr.CodeOf(f.manager.EnableMod(tc.name)), tc.name)
+			assert.Equal(t, before, ids(f.manager.GetEnabledMods()), "失敗しても有効なMODは変わらない")
+		}
+	})
+
+	t.Run("StartFailureLeavesNothingBehind", func(t *testing.T) {
+		f := newFixture(t, mod.Options{Start: func(c *mod.Context) error {
+			if _, err := c.CreateEntity(); err != nil {
+				return err
+			}
+			if c.Mod.ID == "zombies" {
+				return errors.New("script error")
+			}
+			return nil
+		}})
+		assert.Equal(t, mod.CodeStartFailed, gameerr.CodeOf(f.manager.EnableMod("zombies")))
+		assert.Empty(t, f.manager.GetEnabledMods())
+		assert.Zero(t, f.entities.GetEntityCount())
+		_, err := f.files.Lookup("assets/sprites/zombie.png")
+		assert.Error(t, err)
+	})
+}
+
+func TestDisableMod(t *testing.T) {
+	f := newFixture(t, mod.Options{})
+	require.NoError(t, f.manager.EnableMod("zombies"))
+	c := f.manager.Context("zombies")
+	require.NotNil(t, c)
+
+	require.NoError(t, c.RegisterSystem(fakeSystem("zombie_ai")))
+	a, err := c.CreateEntity()
+	require.NoError(t, err)
+	_, err = c.CreateEntity()
+	require.NoError(t, err)
+	_, err = c.CreateEntity()
+	assert.Equal(t, mod.CodeLimitExceeded, gameerr.CodeOf(err))
+	require.NoError(t, c.LoadAsset("assets/sprites/zombie.png"))
+	assert.Error(t, c.LoadAsset("missing.png"))
+	other := f.entities.CreateEntity()
+
+	assert.True(t, c.Owns(a))
+	assert.False(t, c.Owns(other))
+
+	assert.Equal(t, mod.CodeRequired, gameerr.CodeOf(f.manager.DisableMod("core_lib")))
+	require.NoError(t, f.manager.DisableMod("zombies"))
+	assert.Equal(t, []string{"core_lib"}, ids(f.manager.GetEnabledMods()))
+	assert.Nil(t, f.manager.Context("zombies"))
+	assert.Empty(t, f.systems.GetRegisteredSystems())
+	assert.False(t, f.entities.IsEntityValid(a))
+	assert.True(t, f.entities.IsEntityValid(other), "MODのものでないエンティティは残る")
+	assert.Zero(t, f.assets.refs["assets/sprites/zombie.png"])
+
+	data, err := f.files.ReadFile("assets/shared.png")
+	require.NoError(t, err)
+	assert.Equal(t, "core", string(data))
+	assert.Equal(t, mod.CodeNotEnabled, gameerr.CodeOf(f.manager.DisableMod("zombies")))
+}
+
+func TestPersistence(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "user", mod.StateFile)
+	f := newFixture(t, mod.Options{StatePath: statePath})
+	require.NoError(t, f.manager.EnableMod("zombies"))
+	require.NoError(t, f.manager.EnableMod("extra_ai"))
+	require.NoError(t, f.manager.DisableMod("extra_ai"))
+
+	data, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled:\n    - core_lib\n    - zombies\n", string(data))
+
+	restored := newFixture(t, mod.Options{StatePath: statePath})
+	assert.Empty(t, restored.manager.Restore())
+	assert.Equal(t, []string{"core_lib", "zombies"}, ids(restored.manager.GetEnabledMods()))
+
+	require.NoError(t, os.WriteFile(statePath, []byte("enabled: [missing, zombies]\n"), 0o644))
+	restored = newFixture(t, mod.Options{StatePath: statePath})
+	errs := restored.manager.Restore()
+	require.Len(t, errs, 1)
+	assert.Equal(t, mod.CodeNotFound, gameerr.CodeOf(errs[0]))
+	assert.Equal(t, []string{"core_lib", "zombies"}, ids(restored.manager.GetEnabledMods()))
+
+	// 有効にできなかったMODは保存した一覧から消えない
+	data, err = os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled: [missing, zombies]\n", string(data), "復元中は保存しない")
+	require.NoError(t, restored.manager.EnableMod("extra_ai"))
+	data, err = os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled:\n    - core_lib\n    - extra_ai\n    - zombies\n    - missing\n", string(data))
+
+	require.NoError(t, restored.manager.DisableMod("missing"), "無効にすると一覧から外れる")
+	data, err = os.ReadFile(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled:\n    - core_lib\n    - extra_ai\n    - zombies\n", string(data))
+	assert.Equal(t, mod.CodeNotEnabled, gameerr.CodeOf(restored.manager.DisableMod("missing")))
+}
@@ -0,0 +1,184 @@
+// Package mod はMODの mod.yaml の読み込みと、MODの有効化・無効化を行う。
+//
+// 有効なMODは依存関係に従った読み込み順で仮想ファイルシステムのMODレイヤーに
+// マウントされ、後に読み込まれたMODのファイルが優先される。MODがゲームに加えた
+// システム・エンティティ・アセットは Context に記録され、無効にすると全て取り除かれる。
+// MODのファイル・ネットワーク・プロセスの操作は Sandbox を通し、mod.yaml の
+// file_access と permissions の範囲に制限する。
+package mod
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+// MODのエラーコード
+const (
+	CodeNotFound        = "MOD_NOT_FOUND"
+	CodeInvalidSpec     = "MOD_INVALID_SPEC"
+	CodeIDMismatch      = "MOD_ID_MISMATCH"
+	CodeIncompatible    = "MOD_INCOMPATIBLE_GAME"
+	CodeFileMissing     = "MOD_FILE_MISSING"
+	CodeMissingDep      = "MOD_DEPENDENCY_MISSING"
+	CodeUnsatisfied     = "MOD_DEPENDENCY_UNSATISFIED"
+	CodeDependencyCycle = "MOD_DEPENDENCY_CYCLE"
+	CodeConflict        = "MOD_CONFLICT"
+	CodeRequired        = "MOD_REQUIRED"
+	CodeNotEnabled      = "MOD_NOT_ENABLED"
+	CodeLimitExceeded   = "MOD_LIMIT_EXCEEDED"
+	CodeStartFailed     = "MOD_START_FAILED"
+	CodePermission      = "MOD_PERMISSION_DENIED"
+)
+
+// metadata.permissions の権限
+const (
+	PermCreateEntities   = "create_entities"
+	PermModifyComponents = "modify_components"
+	PermNetworkAccess    = "network_access"
+	PermSystemAccess     = "system_access"
+)
+
+// FileName - MODディレクトリ直下の定義ファイル名
+const FileName = "mod.yaml"
+
+// Spec - mod.yaml の内容 (docs/content_creation_guide.md)
+type Spec struct {
+	Metadata       Metadata          `yaml:"metadata"`
+	ThemeOverrides yaml.Node         `yaml:"theme_overrides"`
+	Scripts        []File            `yaml:"scripts"`
+	Assets         []File            `yaml:"assets"`
+	Config         map[string]Option `yaml:"config"`
+}
+
+// Metadata - MODのメタデータ
+type Metadata struct {
+	ID           string       `yaml:"id"`
+	Name         string       `yaml:"name"`
+	Version      string       `yaml:"version"`
+	Author       string       `yaml:"author"`
+	Description  string       `yaml:"description"`
+	GameVersion  string       `yaml:"game_version"`
+	APIVersion   string       `yaml:"api_version"`
+	Dependencies []Dependency `yaml:"dependencies"`
+	Conflicts    []string     `yaml:"conflicts"`
+	Category     string       `yaml:"category"`
+	Tags         []string     `yaml:"tags"`
+	License      string       `yaml:"license"`
+	Homepage     string       `yaml:"homepage"`
+	Repository   string       `yaml:"repository"`
+	Permissions  []string     `yaml:"permissions"`
+	FileAccess   []string     `yaml:"file_access"`
+	Limits       Limits       `yaml:"limits"`
+}
+
+// Dependency - 依存するMOD。Optional の依存は有効な場合だけ先に読み込む。
+type Dependency struct {
+	ModID    string `yaml:"mod_id"`
+	Version  string `yaml:"version"` // 省略した場合は任意のバージョン
+	Optional bool   `yaml:"optional"`
+}
+
+// Limits - MODの実行時の上限。MaxEntities の 0 は無制限、スクリプトの上限の 0 は
+// スクリプトの実行環境の既定値。
+type Limits struct {
+	MaxEntities     int `yaml:"max_entities"`
+	MaxMemoryMB     int `yaml:"max_memory_mb"`
+	MaxScriptTimeMS int `yaml:"max_script_time_ms"`
+}
+
+// File - スクリプト・アセットの定義
+type File struct {
+	File        string `yaml:"file"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+}
+
+// Option - MODの設定項目
+type Option struct {
+	Type        string      `yaml:"type"`
+	Default     interface{} `yaml:"default"`
+	Min         *float64    `yaml:"min"`
+	Max         *float64    `yaml:"max"`
+	Description string      `yaml:"description"`
+}
+
+// Mod - 読み込んだMOD
+type Mod struct {
+	ID       string
+	Spec     *Spec
+	Warnings []schema.Issue // スキーマの警告 (未知のキーなど)
+
+	fsys fs.FS
+}
+
+// New - mod.yaml を読まずに、定義と fsys (ルートディレクトリ) からMODを作る
+func New(spec *Spec, fsys fs.FS) *Mod {
+	return &Mod{ID: spec.Metadata.ID, Spec: spec, fsys: fsys}
+}
+
+// FS - MODのルートディレクトリ
+func (m *Mod) FS() fs.FS {
+	return m.fsys
+}
+
+// HasPermission - metadata.permissions に権限があるか
+func (m *Mod) HasPermission(name string) bool {
+	for _, p := range m.Spec.Metadata.Permissions {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readMod - root の下のMODディレクトリ name から mod.yaml を読み込んでスキーマで検証する
+func readMod(root fs.FS, name string) (*Mod, error) {
+	if !fs.ValidPath(name) || name == "." || strings.Contains(name, "/") {
+		return nil, gameerr.ModError{Code: CodeNotFound, Message: fmt.Sprintf("不正なMOD名です: %q", name), ModID: name}
+	}
+	fsys, err := fs.Sub(root, name)
+	if err != nil {
+		return nil, err
+	}
+	file := name + "/" + FileName
+	data, err := fs.ReadFile(fsys, FileName)
+	if err != nil {
+		return nil, gameerr.ModError{Code: CodeNotFound, Message: fmt.Sprintf("MODが見つかりません: %s", name), ModID: name}
+	}
+
+	issues := schema.Validate(file, data, schema.ModSchema())
+	var warnings, errs []schema.Issue
+	for _, issue := range issues {
+		if issue.Severity >= gameerr.ErrorSeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, gameerr.ModError{
+			Code:    CodeInvalidSpec,
+			Message: strings.TrimSuffix(schema.FormatAll(errs, schema.LangFromEnv()), "\n"),
+			ModID:   name,
+		}
+	}
+
+	spec := new(Spec)
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, gameerr.ModError{Code: CodeInvalidSpec, Message: fmt.Sprintf("%s: %v", file, err), ModID: name}
+	}
+	if spec.Metadata.ID != name {
+		return nil, gameerr.ModError{
+			Code:    CodeIDMismatch,
+			Message: fmt.Sprintf("%s: metadata.id %q がディレクトリ名と一致しません", file, spec.Metadata.ID),
+			ModID:   name,
+		}
+	}
+	return &Mod{ID: name, Spec: spec, Warnings: warnings, fsys: fsys}, nil
+}
@@ -0,0 +1,126 @@
+package mod
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/semver"
+)
+
+// loadOrder - 有効にするMODを依存関係に従って並べる。依存されるMODが先に来て、
+// 順序の決まらないMODはID順に並ぶため、同じ集合からは常に同じ順序になる。
+// 必須の依存が集合にない場合、バージョンが合わない場合、互いに競合するMODがある場合、
+// 依存関係が循環している場合はエラーを返す。
+func loadOrder(mods map[string]*Mod) ([]*Mod, error) {
+	ids := make([]string, 0, len(mods))
+	for id := range mods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	indegree := make(map[string]int, len(ids))
+	dependents := make(map[string][]string, len(ids))
+	for _, id := range ids {
+		m := mods[id]
+		for _, other := range m.Spec.Metadata.Conflicts {
+			if _, ok := mods[other]; ok && other != id {
+				return nil, gameerr.ModError{
+					Code:    CodeConflict,
+					Message: fmt.Sprintf("MOD %s は %s と同時に有効にできません", id, other),
+					ModID:   id,
+				}
+			}
+		}
+		for _, dep := range m.Spec.Metadata.Dependencies {
+			base, ok := mods[dep.ModID]
+			if !ok {
+				if dep.Optional {
+					continue
+				}
+				return nil, gameerr.ModError{
+					Code:    CodeMissingDep,
+					Message: fmt.Sprintf("MOD %s には %s が必要です", id, dep.ModID),
+					ModID:   id,
+				}
+			}
+			if err := checkDependency(m, dep, base); err != nil {
+				return nil, err
+			}
+			indegree[id]++
+			dependents[dep.ModID] = append(dependents[dep.ModID], id)
+		}
+	}
+
+	var ready, order []string
+	for _, id := range ids {
+		if indegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, next := range dependents[id] {
+			if indegree[next]--; indegree[next] == 0 {
+				i, _ := slices.BinarySearch(ready, next)
+				ready = slices.Insert(ready, i, next)
+			}
+		}
+	}
+	if len(order) < len(ids) {
+		var cycle []string
+		for _, id := range ids {
+			if indegree[id] > 0 {
+				cycle = append(cycle, id)
+			}
+		}
+		return nil, gameerr.ModError{
+			Code:    CodeDependencyCycle,
+			Message: fmt.Sprintf("MODの依存関係が循環しています: %s", strings.Join(cycle, ", ")),
+			ModID:   cycle[0],
+		}
+	}
+
+	out := make([]*Mod, len(order))
+	for i, id := range order {
+		out[i] = mods[id]
+	}
+	return out, nil
+}
+
+// checkDependency - 依存するMODのバージョンが制約を満たすか確認する
+func checkDependency(m *Mod, dep Dependency, base *Mod) error {
+	if dep.Version == "" {
+		return nil
+	}
+	c, err := semver.ParseConstraint(dep.Version)
+	if err != nil {
+		return gameerr.ModError{Code: CodeInvalidSpec, Message: fmt.Sprintf("%s: %s のバージョン制約: %v", m.ID, dep.ModID, err), ModID: m.ID}
+	}
+	v, err := semver.Parse(base.Spec.Metadata.Version)
+	if err != nil || !c.Check(v) {
+		return gameerr.ModError{
+			Code:    CodeUnsatisfied,
+			Message: fmt.Sprintf("MOD %s は %s %s が必要ですが、バージョン %s です", m.ID, dep.ModID, c, base.Spec.Metadata.Version),
+			ModID:   m.ID,
+		}
+	}
+	return nil
+}
+
+// required - 有効なMODのうち、id を必須の依存に持つもの
+func required(enabled []*Context, id string) []string {
+	var ids []string
+	for _, c := range enabled {
+		for _, dep := range c.Mod.Spec.Metadata.Dependencies {
+			if dep.ModID == id && !dep.Optional {
+				ids = append(ids, c.Mod.ID)
+			}
+		}
+	}
+	return ids
+}
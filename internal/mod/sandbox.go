@@ -0,0 +1,207 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"muscle-dreamer/internal/vfs"
+)
+
+// サンドボックスが拒否した操作
+var (
+	ErrAccessDenied        = errors.New("ファイルへのアクセスが許可されていません")
+	ErrNetworkAccessDenied = errors.New("ネットワークへのアクセスが許可されていません")
+	ErrSystemAccessDenied  = errors.New("コマンドの実行が許可されていません")
+)
+
+// modsDir - MODのファイルと書き込んだファイルが見える仮想ディレクトリ。MODは自分のIDの
+// ディレクトリにだけ書き込める。
+const modsDir = "mods"
+
+// MaxHTTPBodySize - HTTPGet で読み込む本文の上限 (8 MB)。これを超える応答はエラーにする。
+const MaxHTTPBodySize = 8 << 20
+
+// httpClient - HTTPGet に使うクライアント。応答が返らない接続は30秒で打ち切る。
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Sandbox - MODのファイル・ネットワーク・プロセスの操作を mod.yaml の宣言に従って制限する。
+// ファイルは file_access に書いた仮想パスの下だけ読めて、書き込めるのはそのうち mods/<ID> の下
+// だけ。ネットワークは network_access 権限、コマンドの実行は system_access 権限がある場合だけ
+// 使える。拒否した操作はMODのIDを付けて記録先に書く。
+type Sandbox struct {
+	mod      *Mod
+	files    *vfs.FS
+	log      io.Writer
+	prefixes []string
+	own      string // 書き込める mods/<ID>
+}
+
+// NewSandbox - files はMODが読み書きする仮想ファイルシステム、log は拒否した操作の記録先
+func NewSandbox(m *Mod, files *vfs.FS, log io.Writer) *Sandbox {
+	s := &Sandbox{mod: m, files: files, log: log, own: path.Join(modsDir, m.ID)}
+	for _, p := range m.Spec.Metadata.FileAccess {
+		p, ok := cleanPath(p)
+		switch {
+		case !ok || p == ".":
+			// ディレクトリの外やルート全体を指すものは無視する
+		case p == modsDir:
+			// mods/ 全体は自分のディレクトリに狭める
+			s.prefixes = append(s.prefixes, s.own)
+		case within(p, modsDir) && !within(p, s.own):
+			// 他のMODのディレクトリは無視する
+		default:
+			s.prefixes = append(s.prefixes, p)
+		}
+	}
+	return s
+}
+
+// ReadFile - file_access の範囲にあるファイルを読み込む
+func (s *Sandbox) ReadFile(name string) ([]byte, error) {
+	real, err := s.path("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return s.files.ReadFile(real)
+}
+
+// WriteFile - file_access の範囲のうち mods/<ID> の下にファイルを書き込む。書き込み可能な
+// レイヤーが必要。
+func (s *Sandbox) WriteFile(name string, data []byte) error {
+	real, err := s.path("write", name)
+	if err != nil {
+		return err
+	}
+	return s.files.WriteFile(real, data, 0o644)
+}
+
+// HTTPGet - URL の内容を取得する。network_access 権限が必要。本文は MaxHTTPBodySize まで。
+func (s *Sandbox) HTTPGet(url string) ([]byte, error) {
+	if err := s.allow(PermNetworkAccess, ErrNetworkAccessDenied, "http", url); err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxHTTPBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxHTTPBodySize {
+		return nil, fmt.Errorf("%s: 応答が上限 %d MB を超えています", url, MaxHTTPBodySize>>20)
+	}
+	return data, nil
+}
+
+// TCPConnect - TCP で接続する。network_access 権限が必要。
+func (s *Sandbox) TCPConnect(address string) (net.Conn, error) {
+	if err := s.allow(PermNetworkAccess, ErrNetworkAccessDenied, "tcp", address); err != nil {
+		return nil, err
+	}
+	return net.Dial("tcp", address)
+}
+
+// UDPConnect - UDP で接続する。network_access 権限が必要。
+func (s *Sandbox) UDPConnect(address string) (net.Conn, error) {
+	if err := s.allow(PermNetworkAccess, ErrNetworkAccessDenied, "udp", address); err != nil {
+		return nil, err
+	}
+	return net.Dial("udp", address)
+}
+
+// DNSLookup - ホスト名のアドレスを引く。network_access 権限が必要。
+func (s *Sandbox) DNSLookup(host string) ([]string, error) {
+	if err := s.allow(PermNetworkAccess, ErrNetworkAccessDenied, "dns", host); err != nil {
+		return nil, err
+	}
+	return net.LookupHost(host)
+}
+
+// ExecuteCommand - コマンドを実行して終了を待つ。system_access 権限が必要。
+func (s *Sandbox) ExecuteCommand(name string, args ...string) error {
+	if err := s.allow(PermSystemAccess, ErrSystemAccessDenied, "exec", strings.Join(append([]string{name}, args...), " ")); err != nil {
+		return err
+	}
+	return exec.Command(name, args...).Run()
+}
+
+// path - ファイルのパスを正規化し、シンボリックリンクを辿った後も file_access の範囲にあるか確認する。
+// 書き込みは mods/<ID> の下にあるかも確認する。
+func (s *Sandbox) path(op, name string) (string, error) {
+	clean, ok := cleanPath(name)
+	if !ok || !s.granted(op, clean) {
+		return "", s.deny(ErrAccessDenied, op, name)
+	}
+	if s.files == nil {
+		return "", ErrUnavailable
+	}
+	real, err := s.files.Resolve(clean)
+	if err != nil || !s.granted(op, real) {
+		return "", s.deny(ErrAccessDenied, op, name)
+	}
+	return real, nil
+}
+
+// granted - 仮想パスが file_access のいずれかの下にあるか。書き込みは mods/<ID> の下に限る。
+func (s *Sandbox) granted(op, name string) bool {
+	if op == "write" && !within(name, s.own) {
+		return false
+	}
+	for _, p := range s.prefixes {
+		if within(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// within - 仮想パス name が dir そのものかその下にあるか
+func within(name, dir string) bool {
+	return name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// allow - MODが権限を持っていなければ操作を拒否する
+func (s *Sandbox) allow(permission string, denied error, op, target string) error {
+	if s.mod.HasPermission(permission) {
+		return nil
+	}
+	return s.deny(denied, op, target)
+}
+
+// deny - 拒否した操作を記録してエラーを返す
+func (s *Sandbox) deny(reason error, op, target string) error {
+	err := fmt.Errorf("%w: %s %q", reason, op, target)
+	if s.log != nil {
+		fmt.Fprintf(s.log, "[%s] %v\n", s.mod.ID, err)
+	}
+	return fmt.Errorf("MOD %s: %w", s.mod.ID, err)
+}
+
+// cleanPath - MODが指定したパスを仮想パスに正規化する。\ は区切りとして扱う。
+// 空のパス・NUL を含むパス・絶対パス・ドライブ指定・ルートの外に出るパスは false を返す。
+func cleanPath(name string) (string, bool) {
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", false
+	}
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || strings.Contains(name, ":") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
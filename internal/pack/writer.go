@@ -0,0 +1,132 @@
+package pack
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// Writer - パックファイルを書き出す
+type Writer struct {
+	w       io.Writer
+	offset  int64
+	entries []Entry
+	names   map[string]bool
+	err     error
+}
+
+// NewWriter - w へパックを書き出す Writer を作成する
+func NewWriter(w io.Writer) *Writer {
+	pw := &Writer{w: w, names: make(map[string]bool)}
+	var header [headerSize]byte
+	copy(header[:], magic[:])
+	binary.LittleEndian.PutUint16(header[4:], Version)
+	pw.write(header[:])
+	return pw
+}
+
+// Add - エントリを追加する。圧縮して小さくならないデータ (PNG や OGG など) は無圧縮で格納する。
+func (pw *Writer) Add(name string, data []byte) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("不正なエントリ名です: %q", name)
+	}
+	if pw.names[name] {
+		return fmt.Errorf("エントリが重複しています: %s", name)
+	}
+
+	entry := Entry{Name: name, Method: MethodStore, Offset: pw.offset, Size: int64(len(data)), SHA256: sha256.Sum256(data)}
+	stored := data
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	if _, err := fw.Write(data); err == nil && fw.Close() == nil && compressed.Len() < len(data) {
+		entry.Method = MethodDeflate
+		stored = compressed.Bytes()
+	}
+	entry.Packed = int64(len(stored))
+
+	pw.write(stored)
+	if pw.err != nil {
+		return pw.err
+	}
+	pw.names[name] = true
+	pw.entries = append(pw.entries, entry)
+	return nil
+}
+
+// AddFS - fsys の root 以下の全てのファイルを prefix 以下のエントリとして追加する
+func (pw *Writer) AddFS(fsys fs.FS, root, prefix string) error {
+	var names []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			names = append(names, p)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		rel := name
+		if root != "." {
+			rel = name[len(root)+1:]
+		}
+		if err := pw.Add(path.Join(prefix, rel), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entries - 追加済みのエントリ
+func (pw *Writer) Entries() []Entry {
+	return append([]Entry(nil), pw.entries...)
+}
+
+// Close - インデックスとフッターを書き出す。下層の Writer は閉じない。
+func (pw *Writer) Close() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	entries := append([]Entry(nil), pw.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	var index bytes.Buffer
+	if err := writeIndex(&index, entries); err != nil {
+		return err
+	}
+	indexOffset := pw.offset
+	pw.write(index.Bytes())
+
+	var footer [footerSize]byte
+	binary.LittleEndian.PutUint64(footer[0:], uint64(indexOffset))
+	binary.LittleEndian.PutUint64(footer[8:], uint64(index.Len()))
+	sum := sha256.Sum256(index.Bytes())
+	copy(footer[16:], sum[:])
+	copy(footer[48:], magic[:])
+	pw.write(footer[:])
+	return pw.err
+}
+
+func (pw *Writer) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	pw.err = err
+}
@@ -0,0 +1,276 @@
+// Package scaffold は新しいテーマの雛形を生成する。コンテンツ作成ガイドのディレクトリ構造に
+// 沿って、スキーマを満たす theme.yaml・正しい大きさの仮のスプライト・言語ごとのテキストを作る。
+// 生成したテーマはそのまま検証を通り、ゲームで選べる。
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"muscle-dreamer/internal/theme"
+)
+
+// Options - 生成するテーマの設定
+type Options struct {
+	ID        string   // テーマID (ディレクトリ名にもなる)
+	Name      string   // 表示名。省略時は ID
+	Author    string   // 作者名
+	Languages []string // supported_languages。先頭が default_language になる。省略時は ja と en
+}
+
+// DefaultLanguages - Options.Languages を省略したときの対応言語
+var DefaultLanguages = []string{theme.DefaultLanguage, "en"}
+
+// Scaffold - 生成するディレクトリとファイル。パスはテーマディレクトリからの相対パス。
+type Scaffold struct {
+	Dirs  []string // 中身のないディレクトリも含めた全てのディレクトリ
+	Files map[string][]byte
+}
+
+// Theme - テーマの雛形を作る。生成した theme.yaml がスキーマを満たさない場合
+// (ID や言語の形式が不正な場合など) はエラーを返す。
+func Theme(opts Options) (*Scaffold, error) {
+	if opts.Name == "" {
+		opts.Name = opts.ID
+	}
+	if len(opts.Languages) == 0 {
+		opts.Languages = DefaultLanguages
+	}
+
+	s := &Scaffold{Files: make(map[string][]byte)}
+	var spec bytes.Buffer
+	if err := themeTemplate.Execute(&spec, templateData(opts)); err != nil {
+		return nil, err
+	}
+	if _, _, err := theme.Parse(path.Join(opts.ID, theme.FileName), spec.Bytes()); err != nil {
+		return nil, err
+	}
+	s.Files[theme.FileName] = spec.Bytes()
+	s.Files["README.md"] = []byte(readme(opts))
+
+	for _, p := range placeholders {
+		data, err := p.encode()
+		if err != nil {
+			return nil, err
+		}
+		s.Files[p.path] = data
+	}
+	s.Files[stageBGM] = silentWAV(bgmSeconds)
+
+	for _, lang := range opts.Languages {
+		s.Files[path.Join(theme.LocalizationDir, lang+".yaml")] = []byte(localization(opts, lang))
+	}
+
+	s.Dirs = append(s.Dirs, emptyDirs...)
+	for p := range s.Files {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			s.Dirs = append(s.Dirs, dir)
+		}
+	}
+	sort.Strings(s.Dirs)
+	s.Dirs = slices.Compact(s.Dirs)
+	return s, nil
+}
+
+// Write - dir にテーマディレクトリを作成して書き出す。dir が既にある場合は
+// 既存のテーマを上書きしないようエラーにする。
+func (s *Scaffold) Write(dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s は既にあります", dir)
+		}
+		return err
+	}
+	for _, d := range s.Dirs {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(d)), 0o755); err != nil {
+			return err
+		}
+	}
+	for _, p := range sortedPaths(s.Files) {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(p)), s.Files[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emptyDirs - ガイドのディレクトリ構造のうち、雛形ではファイルを置かないディレクトリ
+var emptyDirs = []string{
+	"assets/audio/sfx",
+	"assets/effects",
+	"assets/fonts",
+	"scripts",
+}
+
+type specData struct {
+	Options
+	Default string
+}
+
+func templateData(opts Options) specData {
+	return specData{Options: opts, Default: opts.Languages[0]}
+}
+
+var themeTemplate = template.Must(template.New(theme.FileName).Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+	"list": func(items []string) string {
+		quoted := make([]string, len(items))
+		for i, item := range items {
+			quoted[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	},
+}).Parse(`# new-theme で生成したテーマの雛形。docs/content_creation_guide.md の
+# 「theme.yaml 完全仕様」を参考に、仮の値と assets/ 以下の仮のスプライトを差し替えてください。
+
+metadata:
+  id: {{quote .ID}}
+  name: {{quote .Name}}
+  version: "0.1.0"
+  author: {{quote .Author}}
+  description: ""
+  tags: []
+  dependencies: []                # 継承する基底テーマ (例: ["beach >=1.0.0"])
+  game_version: ">=1.0.0"
+  license: "CC-BY-4.0"
+
+characters:
+  player:
+    name: "Hero"
+    sprite_sheets:
+      idle: "assets/characters/player_idle.png"      # 64x64 を横に4フレーム
+      walk: "assets/characters/player_walk.png"      # 64x64 を横に6フレーム
+      attack: "assets/characters/player_attack.png"  # 64x64 を横に3フレーム
+    animations:
+      idle: {frame_count: 4, frame_duration: 0.25, loop: true}
+      walk: {frame_count: 6, frame_duration: 0.15, loop: true}
+      attack:
+        frame_count: 3
+        frame_duration: 0.1
+        loop: false
+        events:
+          attack_hit: 2
+    stats:
+      base_speed: 120
+      base_health: 100
+      muscle_power: 50
+      protein_capacity: 200
+    collision: {width: 32, height: 48, offset_x: 0, offset_y: -8}
+
+enemies:
+  categories:
+    - id: "junk_food"
+      name: "Junk Food"
+      enemies:
+        - id: "enemy_1"
+          name: "Enemy"
+          sprite: "assets/enemies/enemy_1.png"           # 32x48
+          health: 30
+          speed: 80
+          damage: 15
+          temptation_power: 25
+          behavior: "chase_player"
+          spawn_weight: 1.0
+          collision: {width: 24, height: 40}
+
+stages:
+  locations:
+    - id: "stage_1"
+      name: "Stage 1"
+      background:
+        layers:
+          - {file: "assets/stages/stage_1_bg.png", scroll_speed: 0.2, z_order: -2}  # 1920x1080
+          - {file: "assets/stages/stage_1_fg.png", scroll_speed: 0.6, z_order: -1}
+      audio:
+        bgm: "assets/audio/bgm/stage_1.wav"            # 無音の仮の BGM
+      stage_config:
+        duration: 180
+        difficulty_multiplier: 1.0
+        spawn_rate_multiplier: 1.0
+
+skills:
+  muscle_skills:
+    - id: "skill_1"
+      name: "Skill"
+      icon: "assets/ui/skills/skill_1.png"             # 32x32
+      max_level: 1
+      levels:
+        1: {damage: 30, range: 150, cooldown: 3.0, protein_cost: 20}
+
+ui:
+  theme_colors:
+    primary: "#FF6B35"
+    secondary: "#F7931E"
+    background: "#2C3E50"
+    text: "#FFFFFF"
+    accent: "#E74C3C"
+  fonts: {}                       # 例: main: "assets/fonts/main.ttf"。省略時は既定のフォント
+  elements:
+    health_bar: {sprite: "assets/ui/health_bar.png", position: [20, 20], size: [200, 20]}
+    protein_meter: {sprite: "assets/ui/protein_meter.png", position: [20, 50], size: [200, 15]}
+    skill_icons: {sprite: "assets/ui/skill_frame.png", position: [20, 80], icon_size: [32, 32], spacing: 40}
+
+localization:
+  default_language: {{quote .Default}}
+  supported_languages: {{list .Languages}}
+  text:
+    theme_name: {{quote .Name}}   # 言語ごとのテキストは localization/<言語>.yaml
+
+performance:
+  max_enemies_on_screen: 50
+  particle_limit: 100
+  texture_compression: true
+  asset_preloading: ["characters", "ui"]
+`))
+
+// localization - 言語ファイルの雛形。既定の言語以外は翻訳待ちの印を付ける。
+func localization(opts Options, lang string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s のテキスト (%s)。入れ子のキーは \"menu.start\" のようにドットでつながる。\n", opts.Name, lang)
+	if lang != opts.Languages[0] {
+		fmt.Fprintf(&b, "# TODO: %s から翻訳する\n", opts.Languages[0])
+	}
+	fmt.Fprintf(&b, "theme_name: %s\n", strconv.Quote(opts.Name))
+	b.WriteString(`theme_description: ""
+player_name: "Hero"
+enemies:
+  enemy_1: "Enemy"
+stages:
+  stage_1: "Stage 1"
+skills:
+  skill_1: "Skill"
+`)
+	return b.String()
+}
+
+func readme(opts Options) string {
+	return fmt.Sprintf(`# %s
+
+テーマ %s の雛形です。
+
+- theme.yaml の仮の値と、assets/ 以下の仮のスプライトを差し替えてください。
+- 言語ごとのテキストは localization/<言語>.yaml にあります (%s)。
+- 検証: go run ./cmd/validate-theme %s
+- 起動: go run ./cmd/game -theme %s
+`, opts.Name, opts.ID, strings.Join(opts.Languages, ", "), opts.ID, opts.ID)
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,79 @@
+package scaffold_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/scaffold"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/themecheck"
+	"muscle-dreamer/internal/vfs"
+)
+
+func themesFS(s *scaffold.Scaffold, id string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for p, data := range s.Files {
+		fsys[path.Join(id, p)] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}
+
+func TestTheme(t *testing.T) {
+	t.Run("GeneratedThemeValidates", func(t *testing.T) {
+		s, err := scaffold.Theme(scaffold.Options{ID: "my_theme", Name: "My \"Theme\"", Author: "Me", Languages: []string{"en", "ja", "zh"}})
+		require.NoError(t, err)
+
+		r := themecheck.Check(themesFS(s, "my_theme"), "my_theme", themecheck.Options{GameVersion: "1.0.0"})
+		assert.Empty(t, r.Findings, "警告も出ない")
+		assert.Positive(t, r.Assets)
+
+		loaded, err := theme.NewManager(themesFS(s, "my_theme"), vfs.New(), theme.Options{}).LoadTheme("my_theme")
+		require.NoError(t, err)
+		assert.Equal(t, `My "Theme"`, loaded.Spec.Metadata.Name)
+		assert.Equal(t, "en", loaded.Spec.Localization.DefaultLanguage, "先頭の言語が既定の言語")
+		assert.True(t, loaded.Manifest.HasStage("stage_1"))
+		for _, lang := range []string{"en", "ja", "zh"} {
+			strs, err := loaded.Strings(lang)
+			require.NoError(t, err, lang)
+			assert.Equal(t, lang, strs.Language)
+			assert.Equal(t, "Stage 1", strs.Get("stages.stage_1"), lang)
+		}
+	})
+
+	t.Run("DefaultLanguages", func(t *testing.T) {
+		s, err := scaffold.Theme(scaffold.Options{ID: "plain"})
+		require.NoError(t, err)
+		assert.Contains(t, s.Files, "localization/ja.yaml")
+		assert.Contains(t, s.Files, "localization/en.yaml")
+		assert.Contains(t, s.Dirs, "scripts", "ガイドの構造のディレクトリは空でも作る")
+		assert.Contains(t, s.Dirs, "assets/audio/sfx")
+	})
+
+	t.Run("RejectsInvalidOptions", func(t *testing.T) {
+		_, err := scaffold.Theme(scaffold.Options{ID: "My Theme"})
+		assert.Error(t, err)
+		_, err = scaffold.Theme(scaffold.Options{ID: "ok", Languages: []string{"japanese"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	s, err := scaffold.Theme(scaffold.Options{ID: "written"})
+	require.NoError(t, err)
+	dir := filepath.Join(t.TempDir(), "themes", "written")
+	require.NoError(t, s.Write(dir))
+
+	r := themecheck.Check(os.DirFS(filepath.Dir(dir)), "written", themecheck.Options{})
+	assert.True(t, r.OK(), r.Findings)
+	info, err := os.Stat(filepath.Join(dir, "assets", "effects"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	assert.Error(t, s.Write(dir), "既存のテーマは上書きしない")
+}
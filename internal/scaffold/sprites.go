@@ -0,0 +1,99 @@
+package scaffold
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"muscle-dreamer/internal/theme"
+)
+
+// 仮のアセットの大きさ (docs/content_creation_guide.md のグラフィックアセット仕様)
+const (
+	playerFrame = 64 // プレイヤーの1フレーム (正方形)
+	enemyWidth  = 32
+	enemyHeight = 48
+	stageWidth  = 1920
+	stageHeight = 1080
+	iconSize    = 32
+	previewW    = 640 // テーマ一覧のプレビュー画像
+	previewH    = 360
+
+	stageBGM   = "assets/audio/bgm/stage_1.wav"
+	bgmSeconds = 1
+)
+
+// placeholder - 仮のスプライト。フレームごとに枠と位置の変わる印を描き、
+// アニメーションが切り替わっていることが分かるようにする。
+type placeholder struct {
+	path          string
+	width, height int // 1フレームの大きさ
+	frames        int // 横に並べるフレーム数
+	fill          color.NRGBA
+}
+
+var placeholders = []placeholder{
+	{"assets/characters/player_idle.png", playerFrame, playerFrame, 4, color.NRGBA{0xFF, 0x6B, 0x35, 0xFF}},
+	{"assets/characters/player_walk.png", playerFrame, playerFrame, 6, color.NRGBA{0xFF, 0x6B, 0x35, 0xFF}},
+	{"assets/characters/player_attack.png", playerFrame, playerFrame, 3, color.NRGBA{0xC0, 0x39, 0x2B, 0xFF}},
+	{"assets/enemies/enemy_1.png", enemyWidth, enemyHeight, 1, color.NRGBA{0x8E, 0x44, 0xAD, 0xFF}},
+	{"assets/stages/stage_1_bg.png", stageWidth, stageHeight, 1, color.NRGBA{0x34, 0x49, 0x5E, 0xFF}},
+	{"assets/stages/stage_1_fg.png", stageWidth, stageHeight, 1, color.NRGBA{0x2C, 0x3E, 0x50, 0x60}},
+	{"assets/ui/health_bar.png", 200, 20, 1, color.NRGBA{0xE7, 0x4C, 0x3C, 0xFF}},
+	{"assets/ui/protein_meter.png", 200, 15, 1, color.NRGBA{0xF3, 0x9C, 0x12, 0xFF}},
+	{"assets/ui/skill_frame.png", iconSize, iconSize, 1, color.NRGBA{0xEC, 0xF0, 0xF1, 0xFF}},
+	{"assets/ui/skills/skill_1.png", iconSize, iconSize, 1, color.NRGBA{0x34, 0x98, 0xDB, 0xFF}},
+	{theme.PreviewFile, previewW, previewH, 1, color.NRGBA{0xFF, 0x6B, 0x35, 0xFF}},
+}
+
+var (
+	outline = color.NRGBA{0x00, 0x00, 0x00, 0xFF}
+	marker  = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+func (p placeholder) encode() ([]byte, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, p.width*p.frames, p.height))
+	for i := 0; i < p.frames; i++ {
+		frame := image.Rect(i*p.width, 0, (i+1)*p.width, p.height)
+		draw.Draw(img, frame, image.NewUniform(outline), image.Point{}, draw.Src)
+		draw.Draw(img, frame.Inset(1), image.NewUniform(p.fill), image.Point{}, draw.Src)
+		if p.frames > 1 {
+			// 印はフレームごとに右へずれる
+			size := max(p.height/8, 2)
+			x := frame.Min.X + 2 + (p.width-size-4)*i/(p.frames-1)
+			draw.Draw(img, image.Rect(x, p.height-size-2, x+size, p.height-2), image.NewUniform(marker), image.Point{}, draw.Src)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// silentWAV - 44.1kHz 16bit モノラルの無音の WAV
+func silentWAV(seconds int) []byte {
+	const sampleRate, bytesPerSample = 44100, 2
+	size := seconds * sampleRate * bytesPerSample
+
+	var b bytes.Buffer
+	write := func(v interface{}) { _ = binary.Write(&b, binary.LittleEndian, v) }
+	b.WriteString("RIFF")
+	write(uint32(36 + size))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	write(uint32(16))                          // fmt チャンクの大きさ
+	write(uint16(1))                           // PCM
+	write(uint16(1))                           // チャンネル数
+	write(uint32(sampleRate))                  // サンプルレート
+	write(uint32(sampleRate * bytesPerSample)) // 1秒あたりのバイト数
+	write(uint16(bytesPerSample))              // ブロックサイズ
+	write(uint16(bytesPerSample * 8))          // 量子化ビット数
+	b.WriteString("data")
+	write(uint32(size))
+	b.Write(make([]byte, size))
+	return b.Bytes()
+}
@@ -0,0 +1,223 @@
+package schema
+
+// permissionNames - mod.yaml の metadata.permissions に指定できる権限
+var permissionNames = []string{
+	"create_entities", "modify_ai", "load_assets", "play_sounds", "modify_ui",
+	"modify_components", "access_save_data", "network_access", "system_access",
+}
+
+// GameSchema - config/game.yaml のスキーマ。全ての項目は既定値で補完されるため省略可能。
+func GameSchema() *Node {
+	return Object(
+		Optional("game", Object(
+			Optional("title", String().AtLeast(1)),
+			Optional("version", String().WithFormat(FormatSemver)),
+		)),
+		Optional("graphics", Object(
+			Optional("width", Int().Positive()),
+			Optional("height", Int().Positive()),
+			Optional("fullscreen", Bool()),
+			Optional("vsync", Bool()),
+		)),
+		Optional("audio", Object(
+			Optional("master_volume", Number().Range(0, 1)),
+			Optional("bgm_volume", Number().Range(0, 1)),
+			Optional("sfx_volume", Number().Range(0, 1)),
+		)),
+		Optional("input", Object(
+			Optional("keyboard_enabled", Bool()),
+			Optional("mouse_enabled", Bool()),
+			Optional("gamepad_enabled", Bool()),
+			Optional("key_bindings", MapOf(String().AtLeast(1))),
+		)),
+	)
+}
+
+// ThemeSchema - テーマの theme.yaml のスキーマ (docs/content_creation_guide.md)
+func ThemeSchema() *Node {
+	collision := func() *Node {
+		return Object(
+			Optional("width", Number().Positive()),
+			Optional("height", Number().Positive()),
+			Optional("offset_x", Number()),
+			Optional("offset_y", Number()),
+		)
+	}
+	pair := func() *Node { return List(Int().AtLeast(0)).Length(2) }
+
+	return Object(
+		Required("metadata", Object(
+			Required("id", String().WithFormat(FormatID)),
+			Required("name", String().AtLeast(1)),
+			Required("version", String().WithFormat(FormatSemver)),
+			Optional("author", String()),
+			Optional("description", String()),
+			Optional("tags", List(String())),
+			Optional("dependencies", List(String().WithFormat(FormatDependency))),
+			Optional("game_version", String().WithFormat(FormatVersionConstraint)),
+			Optional("license", String()),
+			Optional("homepage", String().WithFormat(FormatURL)),
+		)),
+		Optional("characters", Object(
+			Optional("player", Object(
+				Optional("name", String()),
+				Optional("description", String()),
+				Optional("sprite_sheets", MapOf(String().WithFormat(FormatPath))),
+				Optional("animations", MapOf(Object(
+					Required("frame_count", Int().Positive()),
+					Required("frame_duration", Number().Positive()),
+					Optional("loop", Bool()),
+					Optional("frame_width", Int().Positive()),
+					Optional("frame_height", Int().Positive()),
+					Optional("events", MapOf(Int().AtLeast(0))),
+				))),
+				Optional("stats", MapOf(Number())),
+				Optional("collision", collision()),
+			)),
+		)),
+		Optional("enemies", Object(
+			Optional("categories", List(Object(
+				Required("id", String().WithFormat(FormatID)),
+				Required("name", String()),
+				Optional("description", String()),
+				Optional("enemies", List(Object(
+					Required("id", String().WithFormat(FormatID)),
+					Required("name", String()),
+					Required("sprite", String().WithFormat(FormatPath)),
+					Optional("health", Number().Positive()),
+					Optional("speed", Number().AtLeast(0)),
+					Optional("damage", Number().AtLeast(0)),
+					Optional("temptation_power", Number().AtLeast(0)),
+					Optional("behavior", String()),
+					Optional("spawn_weight", Number().Positive()),
+					Optional("collision", collision()),
+					Optional("special_effects", List(String())),
+				))),
+			))),
+		)),
+		Optional("stages", Object(
+			Optional("locations", List(Object(
+				Required("id", String().WithFormat(FormatID)),
+				Required("name", String()),
+				Optional("description", String()),
+				Optional("background", Object(
+					Optional("layers", List(Object(
+						Required("file", String().WithFormat(FormatPath)),
+						Optional("scroll_speed", Number().AtLeast(0)),
+						Optional("z_order", Int()),
+					))),
+				)),
+				Optional("audio", Object(
+					Optional("bgm", String().WithFormat(FormatPath)),
+					Optional("ambient_sounds", List(Object(
+						Required("file", String().WithFormat(FormatPath)),
+						Optional("volume", Number().Range(0, 1)),
+						Optional("loop", Bool()),
+					))),
+				)),
+				Optional("stage_config", Object(
+					Optional("duration", Number().Positive()),
+					Optional("difficulty_multiplier", Number().Positive()),
+					Optional("spawn_rate_multiplier", Number().Positive()),
+					Optional("special_events", List(Object(
+						Required("time", Number().AtLeast(0)),
+						Required("type", String()),
+						Optional("duration", Number().Positive()),
+						Optional("enemy", String()),
+						Optional("description", String()),
+					))),
+				)),
+				Optional("special_enemies", List(String())),
+				Optional("special_items", List(String())),
+			))),
+		)),
+		Optional("skills", Object(
+			Optional("muscle_skills", List(Object(
+				Required("id", String().WithFormat(FormatID)),
+				Required("name", String()),
+				Optional("description", String()),
+				Optional("icon", String().WithFormat(FormatPath)),
+				Optional("max_level", Int().Positive()),
+				Optional("type", String()),
+				Optional("levels", MapOf(MapOf(Number()))),
+				Optional("effects", MapOf(String().WithFormat(FormatPath))),
+			))),
+		)),
+		Optional("ui", Object(
+			Optional("theme_colors", MapOf(String().WithFormat(FormatColor))),
+			Optional("fonts", MapOf(String().WithFormat(FormatPath))),
+			Optional("menu_bgm", String().WithFormat(FormatPath)),
+			Optional("elements", MapOf(Object(
+				Optional("sprite", String().WithFormat(FormatPath)),
+				Optional("position", pair()),
+				Optional("size", pair()),
+				Optional("icon_size", pair()),
+				Optional("spacing", Int().AtLeast(0)),
+			))),
+		)),
+		Optional("localization", Object(
+			Optional("default_language", String().WithFormat(FormatLanguage)),
+			Optional("supported_languages", List(String().WithFormat(FormatLanguage)).AtLeast(1)),
+			Optional("text", MapOf(String())),
+		)),
+		Optional("performance", Object(
+			Optional("max_enemies_on_screen", Int().Positive()),
+			Optional("particle_limit", Int().AtLeast(0)),
+			Optional("texture_compression", Bool()),
+			Optional("asset_preloading", List(String())),
+		)),
+		Optional("scripts", List(fileEntry())),
+	)
+}
+
+// fileEntry - スクリプト・アセットの定義 ({file, type, description})
+func fileEntry() *Node {
+	return Object(
+		Required("file", String().WithFormat(FormatPath)),
+		Optional("type", String()),
+		Optional("description", String()),
+	)
+}
+
+// ModSchema - MODの mod.yaml のスキーマ (docs/content_creation_guide.md)
+func ModSchema() *Node {
+	return Object(
+		Required("metadata", Object(
+			Required("id", String().WithFormat(FormatID)),
+			Required("name", String().AtLeast(1)),
+			Required("version", String().WithFormat(FormatSemver)),
+			Optional("author", String()),
+			Optional("description", String()),
+			Optional("game_version", String().WithFormat(FormatVersionConstraint)),
+			Optional("api_version", String()),
+			Optional("dependencies", List(Object(
+				Required("mod_id", String().WithFormat(FormatID)),
+				Optional("version", String().WithFormat(FormatVersionConstraint)),
+				Optional("optional", Bool()),
+			))),
+			Optional("conflicts", List(String().WithFormat(FormatID))),
+			Optional("category", String()),
+			Optional("tags", List(String())),
+			Optional("license", String()),
+			Optional("homepage", String().WithFormat(FormatURL)),
+			Optional("repository", String().WithFormat(FormatURL)),
+			Optional("permissions", List(String().OneOf(permissionNames...))),
+			Optional("file_access", List(String().WithFormat(FormatPath))),
+			Optional("limits", Object(
+				Optional("max_entities", Int().Positive()),
+				Optional("max_memory_mb", Int().Positive()),
+				Optional("max_script_time_ms", Int().Positive()),
+			)),
+		)),
+		Optional("theme_overrides", Any()),
+		Optional("scripts", List(fileEntry())),
+		Optional("assets", List(fileEntry())),
+		Optional("config", MapOf(Object(
+			Required("type", String().OneOf("boolean", "integer", "float", "string")),
+			Optional("default", Any()),
+			Optional("min", Number()),
+			Optional("max", Number()),
+			Optional("description", String()),
+		))),
+	)
+}
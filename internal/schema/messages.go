@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"muscle-dreamer/internal/gameerr"
+)
+
+// 検証エラーコード。値はツールやCIから参照されるため変更しないこと。
+const (
+	CodeSyntax        = "SCHEMA_SYNTAX"
+	CodeRequired      = "SCHEMA_REQUIRED"
+	CodeUnknownField  = "SCHEMA_UNKNOWN_FIELD"
+	CodeTypeMismatch  = "SCHEMA_TYPE_MISMATCH"
+	CodeOutOfRange    = "SCHEMA_OUT_OF_RANGE"
+	CodeInvalidFormat = "SCHEMA_INVALID_FORMAT"
+	CodeInvalidEnum   = "SCHEMA_INVALID_ENUM"
+	CodeLength        = "SCHEMA_LENGTH"
+)
+
+// 表示言語
+const (
+	LangJa = "ja"
+	LangEn = "en"
+)
+
+// Issue - 検証で見つかった問題1件
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Path     string // "graphics.width" のようなキーパス
+	Code     string
+	Severity gameerr.ErrorSeverity
+	Args     []string // メッセージと修正案に埋め込む値
+}
+
+// localized - 言語別の文言
+type localized map[string]string
+
+type catalogEntry struct {
+	message    localized
+	suggestion localized
+}
+
+// catalog - コード別のメッセージと修正案。%[n]s は Issue.Args を参照する。
+var catalog = map[string]catalogEntry{
+	CodeSyntax: {
+		message:    localized{LangJa: "YAMLの構文エラー: %[1]s", LangEn: "YAML syntax error: %[1]s"},
+		suggestion: localized{LangJa: "インデントとコロンの後の空白を確認してください", LangEn: "check indentation and the space after each colon"},
+	},
+	CodeRequired: {
+		message:    localized{LangJa: "必須項目 %[1]s がありません", LangEn: "required field %[1]s is missing"},
+		suggestion: localized{LangJa: "%[1]s: を追加してください", LangEn: "add %[1]s:"},
+	},
+	CodeUnknownField: {
+		message:    localized{LangJa: "未知の項目 %[1]s です", LangEn: "unknown field %[1]s"},
+		suggestion: localized{LangJa: "%[2]s の誤りではありませんか", LangEn: "did you mean %[2]s?"},
+	},
+	CodeTypeMismatch: {
+		message:    localized{LangJa: "%[1]s 型が必要ですが %[2]s が指定されています", LangEn: "expected %[1]s but got %[2]s"},
+		suggestion: localized{LangJa: "%[3]s", LangEn: "%[4]s"},
+	},
+	CodeOutOfRange: {
+		message:    localized{LangJa: "値 %[1]s は範囲 %[2]s の外です", LangEn: "value %[1]s is outside the range %[2]s"},
+		suggestion: localized{LangJa: "%[2]s の範囲の値を指定してください", LangEn: "use a value in %[2]s"},
+	},
+	CodeInvalidFormat: {
+		message:    localized{LangJa: "%[1]q は %[2]s 形式ではありません", LangEn: "%[1]q is not a valid %[2]s"},
+		suggestion: localized{LangJa: "例: %[3]s", LangEn: "for example: %[3]s"},
+	},
+	CodeInvalidEnum: {
+		message:    localized{LangJa: "%[1]q は使用できない値です", LangEn: "%[1]q is not an allowed value"},
+		suggestion: localized{LangJa: "次のいずれかを指定してください: %[2]s", LangEn: "use one of: %[2]s"},
+	},
+	CodeLength: {
+		message:    localized{LangJa: "要素数 %[1]s は %[2]s である必要があります", LangEn: "length %[1]s must be %[2]s"},
+		suggestion: localized{LangJa: "要素数を %[2]s にしてください", LangEn: "provide %[2]s elements"},
+	},
+}
+
+// Message - 指定言語のメッセージ
+func (i Issue) Message(lang string) string {
+	entry, ok := catalog[i.Code]
+	if !ok {
+		return i.Code
+	}
+	return i.render(entry.message, lang)
+}
+
+// Suggestion - 指定言語の修正案。修正案がない場合は空文字。
+func (i Issue) Suggestion(lang string) string {
+	entry, ok := catalog[i.Code]
+	if !ok {
+		return ""
+	}
+	if i.Code == CodeUnknownField && len(i.Args) < 2 {
+		return ""
+	}
+	return i.render(entry.suggestion, lang)
+}
+
+func (i Issue) render(text localized, lang string) string {
+	template, ok := text[lang]
+	if !ok {
+		template = text[LangJa]
+	}
+	args := make([]interface{}, len(i.Args))
+	for n, arg := range i.Args {
+		args[n] = arg
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// Location - "file:line:col" 形式の位置。ファイル名がなければ "line:col"。
+func (i Issue) Location() string {
+	if i.File == "" {
+		return fmt.Sprintf("%d:%d", i.Line, i.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Column)
+}
+
+// Format - 1件の問題を表示用に整形する
+func (i Issue) Format(lang string) string {
+	level := "error"
+	if i.Severity < gameerr.ErrorSeverityError {
+		level = "warning"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s [%s] ", i.Location(), level, i.Code)
+	if i.Path != "" {
+		fmt.Fprintf(&b, "%s: ", i.Path)
+	}
+	b.WriteString(i.Message(lang))
+	if s := i.Suggestion(lang); s != "" {
+		fmt.Fprintf(&b, "\n    → %s", s)
+	}
+	return b.String()
+}
+
+// FormatAll - 問題一覧を表示用に整形する
+func FormatAll(issues []Issue, lang string) string {
+	var b strings.Builder
+	for _, issue := range issues {
+		b.WriteString(issue.Format(lang))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// HasErrors - エラー (警告以外) が含まれるか
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity >= gameerr.ErrorSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LangFromEnv - 環境変数 LANG から表示言語を決める。日本語以外は英語。
+func LangFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if strings.HasPrefix(value, "ja") {
+				return LangJa
+			}
+			return LangEn
+		}
+	}
+	return LangJa
+}
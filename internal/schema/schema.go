@@ -0,0 +1,232 @@
+// Package schema はゲーム設定・テーマ・MODのYAMLをスキーマに基づいて検証し、
+// ファイル上の位置・エラーコード・修正案を含む問題一覧を日本語と英語で報告する。
+package schema
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Type - YAMLノードの期待型
+type Type int
+
+const (
+	TypeAny Type = iota
+	TypeMap
+	TypeList
+	TypeString
+	TypeInt
+	TypeNumber
+	TypeBool
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeMap:
+		return "map"
+	case TypeList:
+		return "list"
+	case TypeString:
+		return "string"
+	case TypeInt:
+		return "integer"
+	case TypeNumber:
+		return "number"
+	case TypeBool:
+		return "boolean"
+	default:
+		return "any"
+	}
+}
+
+// Format - 文字列の書式
+type Format int
+
+const (
+	FormatNone              Format = iota
+	FormatID                       // 英数字とアンダースコアのみ
+	FormatSemver                   // 1.2.3
+	FormatVersionConstraint        // >=1.0.0, <2.0.0
+	FormatColor                    // #FF6B35
+	FormatPath                     // スラッシュ区切りの相対パス
+	FormatLanguage                 // ja, en, zh-TW
+	FormatURL                      // http(s)://...
+	FormatDependency               // base_theme または base_theme >=1.0.0
+)
+
+// versionConstraint - FormatVersionConstraint と FormatDependency の制約部分
+const versionConstraint = `(\*|(>=|<=|>|<|=|\^|~)?\s*\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?)(\s*,?\s+(>=|<=|>|<|=|\^|~)?\s*\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?)*`
+
+var formatPatterns = map[Format]*regexp.Regexp{
+	FormatID:                regexp.MustCompile(`^[A-Za-z0-9_]+$`),
+	FormatSemver:            regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`),
+	FormatVersionConstraint: regexp.MustCompile(`^\s*` + versionConstraint + `\s*$`),
+	FormatDependency:        regexp.MustCompile(`^[A-Za-z0-9_]+(\s+` + versionConstraint + `)?\s*$`),
+	FormatColor:             regexp.MustCompile(`^#([0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$`),
+	FormatLanguage:          regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`),
+	FormatURL:               regexp.MustCompile(`^https?://\S+$`),
+}
+
+// formatExamples - 修正案に表示する書式の例
+var formatExamples = map[Format]string{
+	FormatID:                "my_theme_01",
+	FormatSemver:            "1.0.0",
+	FormatVersionConstraint: ">=1.0.0",
+	FormatColor:             "#FF6B35",
+	FormatPath:              "assets/characters/player_idle.png",
+	FormatLanguage:          "ja",
+	FormatURL:               "https://example.com",
+	FormatDependency:        "base_theme >=1.0.0",
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatID:
+		return "id"
+	case FormatSemver:
+		return "semver"
+	case FormatVersionConstraint:
+		return "version-constraint"
+	case FormatColor:
+		return "color"
+	case FormatPath:
+		return "path"
+	case FormatLanguage:
+		return "language"
+	case FormatURL:
+		return "url"
+	case FormatDependency:
+		return "dependency"
+	default:
+		return "none"
+	}
+}
+
+// Node - スキーマの1ノード
+type Node struct {
+	Type      Type
+	Fields    []*Field // TypeMap: 既知のキー (定義順)
+	Values    *Node    // TypeMap: 任意キーの値 (Fields と併用しない)
+	Items     *Node    // TypeList: 要素
+	Min       *float64 // 数値の下限 / リスト・文字列の最小長
+	Max       *float64 // 数値の上限 / リスト・文字列の最大長
+	Format    Format
+	Enum      []string
+	Exclusive bool // Min を含まない (0 < x)
+}
+
+// Field - マップの既知のキー
+type Field struct {
+	Name     string
+	Required bool
+	Node     *Node
+}
+
+// Object - 既知のキーを持つマップ
+func Object(fields ...*Field) *Node {
+	return &Node{Type: TypeMap, Fields: fields}
+}
+
+// MapOf - 任意のキーと同じ型の値を持つマップ
+func MapOf(values *Node) *Node {
+	return &Node{Type: TypeMap, Values: values}
+}
+
+// List - 要素の型が同じリスト
+func List(items *Node) *Node {
+	return &Node{Type: TypeList, Items: items}
+}
+
+// String - 文字列
+func String() *Node { return &Node{Type: TypeString} }
+
+// Int - 整数
+func Int() *Node { return &Node{Type: TypeInt} }
+
+// Number - 数値 (整数も可)
+func Number() *Node { return &Node{Type: TypeNumber} }
+
+// Bool - 真偽値
+func Bool() *Node { return &Node{Type: TypeBool} }
+
+// Any - 検証しない値
+func Any() *Node { return &Node{Type: TypeAny} }
+
+// Required - 必須キー
+func Required(name string, node *Node) *Field {
+	return &Field{Name: name, Required: true, Node: node}
+}
+
+// Optional - 省略可能なキー
+func Optional(name string, node *Node) *Field {
+	return &Field{Name: name, Node: node}
+}
+
+// Range - 数値の範囲 (両端を含む)
+func (n *Node) Range(min, max float64) *Node {
+	n.Min, n.Max = &min, &max
+	return n
+}
+
+// AtLeast - 数値の下限 (含む) / リスト・文字列の最小長
+func (n *Node) AtLeast(min float64) *Node {
+	n.Min = &min
+	return n
+}
+
+// Positive - 0より大きい数値
+func (n *Node) Positive() *Node {
+	zero := 0.0
+	n.Min = &zero
+	n.Exclusive = true
+	return n
+}
+
+// Length - リストの要素数
+func (n *Node) Length(count int) *Node {
+	c := float64(count)
+	n.Min, n.Max = &c, &c
+	return n
+}
+
+// WithFormat - 文字列の書式
+func (n *Node) WithFormat(format Format) *Node {
+	n.Format = format
+	return n
+}
+
+// OneOf - 許可される値の列挙
+func (n *Node) OneOf(values ...string) *Node {
+	n.Enum = values
+	return n
+}
+
+// field - 名前でキーを探す
+func (n *Node) field(name string) *Field {
+	for _, f := range n.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Lookup - "graphics.width" のようなキーパスで子ノードを探す。見つからなければ nil。
+func (n *Node) Lookup(path string) *Node {
+	node := n
+	for _, name := range strings.Split(path, ".") {
+		if node == nil || node.Type != TypeMap {
+			return nil
+		}
+		if node.Values != nil {
+			node = node.Values
+			continue
+		}
+		f := node.field(name)
+		if f == nil {
+			return nil
+		}
+		node = f.Node
+	}
+	return node
+}
@@ -0,0 +1,161 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+const validTheme = `
+metadata:
+  id: "beach_theme"
+  name: "Beach"
+  version: "1.0.0"
+  game_version: ">=1.0.0"
+characters:
+  player:
+    sprite_sheets:
+      idle: "assets/characters/player_idle.png"
+    animations:
+      idle:
+        frame_count: 4
+        frame_duration: 0.25
+        loop: true
+ui:
+  theme_colors:
+    primary: "#FF6B35"
+  elements:
+    health_bar:
+      position: [20, 20]
+      size: [200, 20]
+localization:
+  default_language: "ja"
+  supported_languages: ["ja", "en", "zh-TW"]
+`
+
+// codes - 問題のコード一覧
+func codes(issues []schema.Issue) []string {
+	out := make([]string, len(issues))
+	for i, issue := range issues {
+		out[i] = issue.Code
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("ValidThemeHasNoIssues", func(t *testing.T) {
+		issues := schema.Validate("theme.yaml", []byte(validTheme), schema.ThemeSchema())
+		assert.Empty(t, issues)
+	})
+
+	t.Run("ReportsEveryProblemWithPosition", func(t *testing.T) {
+		data := "metadata:\n" +
+			"  id: \"bad id\"\n" +
+			"  version: 1.0\n" +
+			"ui:\n" +
+			"  theme_colors:\n" +
+			"    primary: \"FF6B35\"\n"
+		issues := schema.Validate("theme.yaml", []byte(data), schema.ThemeSchema())
+
+		assert.Equal(t, []string{
+			schema.CodeRequired,
+			schema.CodeInvalidFormat,
+			schema.CodeTypeMismatch,
+			schema.CodeInvalidFormat,
+		}, codes(issues))
+
+		idIssue := issues[1]
+		assert.Equal(t, "theme.yaml", idIssue.File)
+		assert.Equal(t, 2, idIssue.Line)
+		assert.Equal(t, 7, idIssue.Column)
+		assert.Equal(t, "metadata.id", idIssue.Path)
+		assert.Equal(t, "metadata.name", issues[0].Path)
+		assert.Equal(t, "ui.theme_colors.primary", issues[3].Path)
+	})
+
+	t.Run("UnknownFieldIsWarningWithSuggestion", func(t *testing.T) {
+		issues := schema.Validate("game.yaml", []byte("grahpics:\n  width: 640\n"), schema.GameSchema())
+
+		require.Len(t, issues, 1)
+		assert.Equal(t, schema.CodeUnknownField, issues[0].Code)
+		assert.Equal(t, gameerr.ErrorSeverityWarning, issues[0].Severity)
+		assert.False(t, schema.HasErrors(issues))
+		assert.Equal(t, "did you mean graphics?", issues[0].Suggestion(schema.LangEn))
+	})
+
+	t.Run("SyntaxErrorHasLine", func(t *testing.T) {
+		issues := schema.Validate("game.yaml", []byte("graphics:\n  width: 640\n   height: 480\n"), schema.GameSchema())
+
+		require.Len(t, issues, 1)
+		assert.Equal(t, schema.CodeSyntax, issues[0].Code)
+		assert.Equal(t, 3, issues[0].Line)
+	})
+
+	testCases := []struct {
+		name string
+		yaml string
+		path string
+		code string
+	}{
+		{"VolumeOutOfRange", "audio:\n  bgm_volume: 1.5\n", "audio.bgm_volume", schema.CodeOutOfRange},
+		{"ZeroWidth", "graphics:\n  width: 0\n", "graphics.width", schema.CodeOutOfRange},
+		{"FloatWidth", "graphics:\n  width: 12.5\n", "graphics.width", schema.CodeTypeMismatch},
+		{"QuotedBool", "graphics:\n  vsync: \"true\"\n", "graphics.vsync", schema.CodeTypeMismatch},
+		{"EmptyTitle", "game:\n  title: \"\"\n", "game.title", schema.CodeRequired},
+		{"BadVersion", "game:\n  version: \"v1\"\n", "game.version", schema.CodeInvalidFormat},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues := schema.Validate("game.yaml", []byte(tc.yaml), schema.GameSchema())
+
+			require.Len(t, issues, 1)
+			assert.Equal(t, tc.path, issues[0].Path)
+			assert.Equal(t, tc.code, issues[0].Code)
+		})
+	}
+
+	t.Run("ModPermissionsAndPaths", func(t *testing.T) {
+		data := "metadata:\n" +
+			"  id: zombie\n" +
+			"  name: Zombie\n" +
+			"  version: \"1.2.0\"\n" +
+			"  permissions: [load_assets, root_access]\n" +
+			"  file_access: [\"../saves\"]\n"
+		issues := schema.Validate("mod.yaml", []byte(data), schema.ModSchema())
+
+		assert.Equal(t, []string{schema.CodeInvalidEnum, schema.CodeInvalidFormat}, codes(issues))
+		assert.Equal(t, "metadata.permissions[1]", issues[0].Path)
+		assert.Equal(t, "metadata.file_access[0]", issues[1].Path)
+	})
+
+	t.Run("ThemeDependencies", func(t *testing.T) {
+		data := "metadata:\n" +
+			"  id: night\n" +
+			"  name: Night\n" +
+			"  version: \"1.0.0\"\n" +
+			"  dependencies: [beach, \"gym >=1.0.0, <2.0.0\", \"bad id\"]\n"
+		issues := schema.Validate("theme.yaml", []byte(data), schema.ThemeSchema())
+
+		assert.Equal(t, []string{schema.CodeInvalidFormat}, codes(issues))
+		assert.Equal(t, "metadata.dependencies[2]", issues[0].Path)
+	})
+}
+
+func TestIssueFormat(t *testing.T) {
+	issue := schema.Issue{
+		File: "game.yaml", Line: 3, Column: 16,
+		Path: "audio.bgm_volume", Code: schema.CodeOutOfRange,
+		Severity: gameerr.ErrorSeverityError, Args: []string{"1.5", "0〜1"},
+	}
+
+	assert.Equal(t,
+		"game.yaml:3:16: error [SCHEMA_OUT_OF_RANGE] audio.bgm_volume: 値 1.5 は範囲 0〜1 の外です\n    → 0〜1 の範囲の値を指定してください",
+		issue.Format(schema.LangJa))
+	assert.Equal(t,
+		"game.yaml:3:16: error [SCHEMA_OUT_OF_RANGE] audio.bgm_volume: value 1.5 is outside the range 0〜1\n    → use a value in 0〜1",
+		issue.Format(schema.LangEn))
+}
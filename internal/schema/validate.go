@@ -0,0 +1,318 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+)
+
+var syntaxLine = regexp.MustCompile(`line (\d+)`)
+
+// Validate - YAMLデータをスキーマで検証し、見つかった全ての問題を返す
+func Validate(file string, data []byte, root *Node) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line := 0
+		if m := syntaxLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		return []Issue{{
+			File: file, Line: line, Column: 1,
+			Code: CodeSyntax, Severity: gameerr.ErrorSeverityError,
+			Args: []string{msg},
+		}}
+	}
+
+	return ValidateNode(file, &doc, root)
+}
+
+// ValidateNode - 解析済みのYAMLドキュメントをスキーマで検証する。
+// 複数のファイルを合成したドキュメントでは、各ノードの行番号は元のファイルのものになる。
+func ValidateNode(file string, doc *yaml.Node, root *Node) []Issue {
+	v := &validator{file: file}
+	if len(doc.Content) == 0 {
+		// 空ファイルはルートの必須項目がすべて欠けているものとして扱う
+		v.checkMap(&yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}, root, "")
+		return v.issues
+	}
+	v.check(doc.Content[0], root, "")
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		a, b := v.issues[i], v.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.issues
+}
+
+type validator struct {
+	file   string
+	issues []Issue
+}
+
+func (v *validator) report(node *yaml.Node, path, code string, severity gameerr.ErrorSeverity, args ...string) {
+	v.issues = append(v.issues, Issue{
+		File: v.file, Line: node.Line, Column: node.Column,
+		Path: path, Code: code, Severity: severity, Args: args,
+	})
+}
+
+func (v *validator) errorf(node *yaml.Node, path, code string, args ...string) {
+	v.report(node, path, code, gameerr.ErrorSeverityError, args...)
+}
+
+func (v *validator) check(node *yaml.Node, s *Node, path string) {
+	if s == nil || s.Type == TypeAny {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch s.Type {
+	case TypeMap:
+		if node.Kind != yaml.MappingNode {
+			v.typeMismatch(node, s, path)
+			return
+		}
+		v.checkMap(node, s, path)
+	case TypeList:
+		if node.Kind != yaml.SequenceNode {
+			v.typeMismatch(node, s, path)
+			return
+		}
+		v.checkList(node, s, path)
+	default:
+		if node.Kind != yaml.ScalarNode || !scalarMatches(node, s.Type) {
+			v.typeMismatch(node, s, path)
+			return
+		}
+		v.checkScalar(node, s, path)
+	}
+}
+
+func (v *validator) checkMap(node *yaml.Node, s *Node, path string) {
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		child := join(path, key.Value)
+		seen[key.Value] = true
+
+		if s.Values != nil {
+			v.check(value, s.Values, child)
+			continue
+		}
+		f := s.field(key.Value)
+		if f == nil {
+			args := []string{key.Value}
+			if closest := v.closestField(s, key.Value); closest != "" {
+				args = append(args, closest)
+			}
+			v.report(key, child, CodeUnknownField, gameerr.ErrorSeverityWarning, args...)
+			continue
+		}
+		v.check(value, f.Node, child)
+	}
+
+	for _, f := range s.Fields {
+		if f.Required && !seen[f.Name] {
+			v.errorf(node, join(path, f.Name), CodeRequired, f.Name)
+		}
+	}
+}
+
+func (v *validator) checkList(node *yaml.Node, s *Node, path string) {
+	count := float64(len(node.Content))
+	if (s.Min != nil && count < *s.Min) || (s.Max != nil && count > *s.Max) {
+		v.errorf(node, path, CodeLength, strconv.Itoa(len(node.Content)), bounds(s))
+	}
+	for i, item := range node.Content {
+		v.check(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+func (v *validator) checkScalar(node *yaml.Node, s *Node, path string) {
+	switch s.Type {
+	case TypeInt, TypeNumber:
+		value, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			// 0x10 のような整数表記
+			n, _ := strconv.ParseInt(node.Value, 0, 64)
+			value = float64(n)
+		}
+		tooSmall := s.Min != nil && (value < *s.Min || (s.Exclusive && value == *s.Min))
+		tooLarge := s.Max != nil && value > *s.Max
+		if tooSmall || tooLarge {
+			v.errorf(node, path, CodeOutOfRange, node.Value, bounds(s))
+		}
+	case TypeString:
+		if len(s.Enum) > 0 && !contains(s.Enum, node.Value) {
+			v.errorf(node, path, CodeInvalidEnum, node.Value, strings.Join(s.Enum, ", "))
+			return
+		}
+		if s.Min != nil && float64(len(node.Value)) < *s.Min {
+			v.errorf(node, path, CodeRequired, lastSegment(path))
+			return
+		}
+		if s.Format != FormatNone && !matchesFormat(s.Format, node.Value) {
+			v.errorf(node, path, CodeInvalidFormat, node.Value, s.Format.String(), formatExamples[s.Format])
+		}
+	}
+}
+
+func (v *validator) typeMismatch(node *yaml.Node, s *Node, path string) {
+	got := describe(node)
+	ja, en := "値の型を確認してください", "check the value type"
+	switch {
+	case s.Type == TypeString && node.Kind == yaml.ScalarNode:
+		ja, en = fmt.Sprintf("\"%s\" のように引用符で囲んでください", node.Value), fmt.Sprintf("quote the value: \"%s\"", node.Value)
+	case s.Type == TypeBool:
+		ja, en = "true または false を指定してください", "use true or false"
+	case s.Type == TypeList:
+		ja, en = "[a, b] または - で始まる行のリストにしてください", "use a list: [a, b] or lines starting with -"
+	case s.Type == TypeMap:
+		ja, en = "key: value 形式の項目を記述してください", "use key: value entries"
+	}
+	v.errorf(node, path, CodeTypeMismatch, s.Type.String(), got, ja, en)
+}
+
+// closestField - 綴り間違いと思われる既知のキーを探す
+func (v *validator) closestField(s *Node, name string) string {
+	best, bestDist := "", 3
+	names := make([]string, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	for _, candidate := range names {
+		if d := levenshtein(strings.ToLower(name), candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+func scalarMatches(node *yaml.Node, t Type) bool {
+	switch t {
+	case TypeString:
+		return node.Tag == "!!str"
+	case TypeInt:
+		return node.Tag == "!!int"
+	case TypeNumber:
+		return node.Tag == "!!int" || node.Tag == "!!float"
+	case TypeBool:
+		return node.Tag == "!!bool"
+	default:
+		return true
+	}
+}
+
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return TypeMap.String()
+	case yaml.SequenceNode:
+		return TypeList.String()
+	}
+	switch node.Tag {
+	case "!!int":
+		return TypeInt.String()
+	case "!!float":
+		return TypeNumber.String()
+	case "!!bool":
+		return TypeBool.String()
+	case "!!null":
+		return "null"
+	}
+	return TypeString.String()
+}
+
+func matchesFormat(format Format, value string) bool {
+	if format == FormatPath {
+		return ValidPath(value)
+	}
+	pattern, ok := formatPatterns[format]
+	return !ok || pattern.MatchString(value)
+}
+
+// ValidPath - パックやテーマ内を指すスラッシュ区切りの相対パスか
+func ValidPath(value string) bool {
+	if value == "" || strings.HasPrefix(value, "/") || strings.ContainsAny(value, "\\:\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(value, "/") {
+		if segment == ".." || segment == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func bounds(s *Node) string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	switch {
+	case s.Min != nil && s.Max != nil && *s.Min == *s.Max:
+		return format(*s.Min)
+	case s.Min != nil && s.Max != nil:
+		return format(*s.Min) + "〜" + format(*s.Max)
+	case s.Min != nil && s.Exclusive:
+		return "> " + format(*s.Min)
+	case s.Min != nil:
+		return ">= " + format(*s.Min)
+	case s.Max != nil:
+		return "<= " + format(*s.Max)
+	}
+	return ""
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func lastSegment(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
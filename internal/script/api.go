@@ -0,0 +1,206 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/mod"
+)
+
+// newAPI - スクリプトに公開する ModAPI テーブル。エンティティIDは "3#1" のような文字列で表し、
+// コンポーネントはフィールド名を小文字のスネークケースにしたテーブルで表す。
+// 失敗した操作は Lua のエラーになるため、スクリプトは pcall で受け止められる。
+func (r *Runtime) newAPI() *lua.LTable {
+	return r.L.SetFuncs(r.L.NewTable(), map[string]lua.LGFunction{
+		"IsValid":        r.isValid,
+		"GetEntityCount": r.getEntityCount,
+		"GetComponent":   r.getComponent,
+		"HasComponent":   r.hasComponent,
+		"Query":          r.query,
+		"CreateEntity":   r.createEntity,
+		"AddComponent":   r.addComponent,
+		"SetComponent":   r.setComponent,
+		"RegisterSystem": r.registerSystem,
+		"Subscribe":      r.subscribe,
+		"Publish":        r.publish,
+		"ReadFile":       r.readFile,
+		"WriteFile":      r.writeFile,
+	})
+}
+
+// ModAPI.IsValid(id) - エンティティが存在するか
+func (r *Runtime) isValid(L *lua.LState) int {
+	id, ok := entityID(L.CheckString(1))
+	L.Push(lua.LBool(ok && r.api.IsEntityValid(id)))
+	return 1
+}
+
+// ModAPI.GetEntityCount() - 存在するエンティティの数
+func (r *Runtime) getEntityCount(L *lua.LState) int {
+	L.Push(lua.LNumber(r.api.GetEntityCount()))
+	return 1
+}
+
+// ModAPI.GetComponent(id, type) - コンポーネントの複製。持っていなければ nil。
+func (r *Runtime) getComponent(L *lua.LState) int {
+	id, t := r.checkEntity(L, 1), r.checkType(L, 2)
+	c, err := r.api.GetComponent(id, t)
+	if errors.Is(err, ecs.ErrComponentNotFound) || errors.Is(err, ecs.ErrEntityNotFound) {
+		L.Push(lua.LNil)
+		return 1
+	}
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	L.Push(toLua(L, reflect.ValueOf(c)))
+	return 1
+}
+
+// ModAPI.HasComponent(id, type) - コンポーネントを持っているか
+func (r *Runtime) hasComponent(L *lua.LState) int {
+	id, t := r.checkEntity(L, 1), r.checkType(L, 2)
+	L.Push(lua.LBool(r.api.HasComponent(id, t)))
+	return 1
+}
+
+// ModAPI.Query(type, ...) - 指定した全ての種類のコンポーネントを持つエンティティIDの配列
+func (r *Runtime) query(L *lua.LState) int {
+	types := make([]ecs.ComponentType, L.GetTop())
+	for i := range types {
+		types[i] = r.checkType(L, i+1)
+	}
+	ids := r.api.QueryWithReadOnly(types...)
+	t := L.CreateTable(len(ids), 0)
+	for _, id := range ids {
+		t.Append(lua.LString(id.String()))
+	}
+	L.Push(t)
+	return 1
+}
+
+// ModAPI.CreateEntity() - MODが所有するエンティティを作成してIDを返す
+func (r *Runtime) createEntity(L *lua.LState) int {
+	id, err := r.api.CreateModEntity()
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	L.Push(lua.LString(id.String()))
+	return 1
+}
+
+// ModAPI.AddComponent(id, type, fields) - MODが所有するエンティティにコンポーネントを追加する
+func (r *Runtime) addComponent(L *lua.LState) int {
+	id, t := r.checkEntity(L, 1), r.checkType(L, 2)
+	fields := L.OptTable(3, L.NewTable())
+	create, ok := newComponents[t]
+	if !ok {
+		L.ArgError(2, fmt.Sprintf("スクリプトから追加できないコンポーネントです: %s", t))
+	}
+	c := create()
+	if err := fromLua(fields, reflect.ValueOf(c)); err != nil {
+		L.ArgError(3, err.Error())
+	}
+	if err := r.api.AddModComponent(id, c); err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+// ModAPI.SetComponent(id, type, fields) - 既存のコンポーネントの fields にあるフィールドだけを書き換える
+func (r *Runtime) setComponent(L *lua.LState) int {
+	id, t := r.checkEntity(L, 1), r.checkType(L, 2)
+	fields := L.CheckTable(3)
+	c, err := r.api.GetComponent(id, t)
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	if err := fromLua(fields, reflect.ValueOf(c)); err != nil {
+		L.ArgError(3, err.Error())
+	}
+	if err := r.api.UpdateComponent(id, c); err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+// ModAPI.RegisterSystem(name, fn) - 毎フレーム fn(dt) を呼ぶ。dt は経過時間 (秒)。
+func (r *Runtime) registerSystem(L *lua.LState) int {
+	name, fn := L.CheckString(1), L.CheckFunction(2)
+	s := &system{r: r, kind: ecs.SystemType(fmt.Sprintf("mod:%s:%s", r.ctx.Mod.ID, name)), fn: fn}
+	if err := r.ctx.RegisterSystem(s); err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+// ModAPI.Subscribe(type, fn) - イベントを購読する。fn には {type=, source=, data=} を渡す。
+func (r *Runtime) subscribe(L *lua.LState) int {
+	eventType, fn := L.CheckString(1), L.CheckFunction(2)
+	err := r.api.SubscribeToEvents(eventType, func(e mod.Event) error {
+		event := r.L.CreateTable(0, 3)
+		event.RawSetString("type", lua.LString(e.Type))
+		event.RawSetString("source", lua.LString(e.Source))
+		event.RawSetString("data", toLua(r.L, reflect.ValueOf(e.Data)))
+		if err := r.call(fn, event); err != nil {
+			r.logf("イベント %s の処理に失敗しました: %v", e.Type, err)
+			return r.error(CodeFailed, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+// ModAPI.Publish(type, data) - イベントを発行する。購読側のエラーは購読したMODがログに出し、
+// 発行したスクリプトには返さない。
+func (r *Runtime) publish(L *lua.LState) int {
+	e := mod.Event{Type: L.CheckString(1)}
+	if data, ok := eventData(L.OptTable(2, L.NewTable())).(map[string]interface{}); ok {
+		e.Data = data
+	}
+	if err := r.api.PublishModEvent(e); errors.Is(err, mod.ErrUnavailable) {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+// ModAPI.ReadFile(path) - file_access の範囲にあるファイルの内容
+func (r *Runtime) readFile(L *lua.LState) int {
+	data, err := r.ctx.Sandbox().ReadFile(L.CheckString(1))
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	L.Push(lua.LString(data))
+	return 1
+}
+
+// ModAPI.WriteFile(path, data) - file_access の範囲にファイルを書き込む
+func (r *Runtime) writeFile(L *lua.LState) int {
+	if err := r.ctx.Sandbox().WriteFile(L.CheckString(1), []byte(L.CheckString(2))); err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+func (r *Runtime) checkEntity(L *lua.LState, n int) ecs.EntityID {
+	id, ok := entityID(L.CheckString(n))
+	if !ok {
+		L.ArgError(n, "エンティティIDではありません")
+	}
+	return id
+}
+
+func (r *Runtime) checkType(L *lua.LState, n int) ecs.ComponentType {
+	name := L.CheckString(n)
+	t, ok := componentType(name)
+	if !ok {
+		L.ArgError(n, fmt.Sprintf("不明なコンポーネントです: %s", name))
+	}
+	return t
+}
@@ -0,0 +1,201 @@
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"muscle-dreamer/internal/core/ecs"
+)
+
+// newComponents - スクリプトから追加できるコンポーネント。指定しなかったフィールドはここでの値になる。
+var newComponents = map[ecs.ComponentType]func() ecs.Component{
+	ecs.TransformComponentType: func() ecs.Component { return ecs.NewTransformComponent(0, 0) },
+	ecs.SpriteComponentType:    func() ecs.Component { return ecs.NewSpriteComponent("", "") },
+	ecs.VelocityComponentType:  func() ecs.Component { return &ecs.VelocityComponent{} },
+	ecs.HealthComponentType:    func() ecs.Component { return ecs.NewHealthComponent(0) },
+	ecs.CollisionComponentType: func() ecs.Component { return &ecs.CollisionComponent{} },
+	ecs.ProteinComponentType:   func() ecs.Component { return ecs.NewProteinComponent(0) },
+}
+
+// componentType - スクリプトでのコンポーネント名 ("Transform" や "transform") から種別を求める
+func componentType(name string) (ecs.ComponentType, bool) {
+	return ecs.ParseComponentType(strings.ToLower(name))
+}
+
+// entityID - ID の文字列 (EntityID.String の結果) をエンティティIDに戻す
+func entityID(s string) (ecs.EntityID, bool) {
+	var id ecs.EntityID
+	if _, err := fmt.Sscanf(s, "%d#%d", &id.Index, &id.Generation); err != nil || id.String() != s {
+		return ecs.NoEntity, false
+	}
+	return id, true
+}
+
+// fieldName - Go のフィールド名をスクリプトでのキーにする (SourceRect → source_rect)
+func fieldName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// toLua - コンポーネントなどの Go の値を Lua の値にする。時間は秒にする。
+// 表せない値 (アニメーションのクリップ一覧など) は nil を返し、テーブルには入れない。
+func toLua(L *lua.LState, v reflect.Value) lua.LValue {
+	if v.Type() == durationType {
+		return lua.LNumber(time.Duration(v.Int()).Seconds())
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return lua.LNil
+		}
+		return toLua(L, v.Elem())
+	case reflect.Bool:
+		return lua.LBool(v.Bool())
+	case reflect.String:
+		return lua.LString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return lua.LNumber(v.Float())
+	case reflect.Slice:
+		t := L.CreateTable(v.Len(), 0)
+		for i := 0; i < v.Len(); i++ {
+			t.Append(toLua(L, v.Index(i)))
+		}
+		return t
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return lua.LNil
+		}
+		t := L.CreateTable(0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			t.RawSetString(iter.Key().String(), toLua(L, iter.Value()))
+		}
+		return t
+	case reflect.Struct:
+		t := L.CreateTable(0, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Type.Kind() == reflect.Map {
+				continue
+			}
+			t.RawSetString(fieldName(f.Name), toLua(L, v.Field(i)))
+		}
+		return t
+	}
+	return lua.LNil
+}
+
+// fromLua - Lua のテーブルの内容を構造体のフィールドに書き込む。テーブルにないフィールドは変えない。
+func fromLua(t *lua.LTable, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	fields := make(map[string]int, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.IsExported() {
+			fields[fieldName(f.Name)] = i
+		}
+	}
+	var err error
+	t.ForEach(func(key, value lua.LValue) {
+		if err != nil {
+			return
+		}
+		i, ok := fields[key.String()]
+		if !ok {
+			err = fmt.Errorf("不明なフィールドです: %s", key)
+			return
+		}
+		if e := setField(v.Field(i), value); e != nil {
+			err = fmt.Errorf("%s: %w", key, e)
+		}
+	})
+	return err
+}
+
+func setField(f reflect.Value, value lua.LValue) error {
+	if f.Type() == durationType {
+		n, ok := value.(lua.LNumber)
+		if !ok {
+			return fmt.Errorf("数値が必要です")
+		}
+		f.SetInt(int64(float64(n) * float64(time.Second)))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.Bool:
+		f.SetBool(lua.LVAsBool(value))
+		return nil
+	case reflect.String:
+		s, ok := value.(lua.LString)
+		if !ok {
+			return fmt.Errorf("文字列が必要です")
+		}
+		f.SetString(string(s))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(lua.LNumber)
+		if !ok {
+			return fmt.Errorf("数値が必要です")
+		}
+		f.SetInt(int64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(lua.LNumber)
+		if !ok {
+			return fmt.Errorf("数値が必要です")
+		}
+		f.SetFloat(float64(n))
+		return nil
+	case reflect.Struct:
+		t, ok := value.(*lua.LTable)
+		if !ok {
+			return fmt.Errorf("テーブルが必要です")
+		}
+		return fromLua(t, f)
+	}
+	return fmt.Errorf("スクリプトから設定できません")
+}
+
+// eventData - イベントの Lua のテーブルを Go の値にする。配列は []interface{}、
+// それ以外のテーブルは map[string]interface{} になる。
+func eventData(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		if n := v.Len(); n > 0 {
+			list := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				list = append(list, eventData(v.RawGetInt(i)))
+			}
+			return list
+		}
+		m := make(map[string]interface{})
+		v.ForEach(func(key, value lua.LValue) {
+			m[key.String()] = eventData(value)
+		})
+		return m
+	}
+	return nil
+}
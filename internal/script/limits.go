@@ -0,0 +1,99 @@
+package script
+
+import (
+	"math"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// formatArgSize - string.format の文字列以外の引数1つが占める大きさの見積もり。
+// 数値は %f で 300 桁を超えることがある。
+const formatArgSize = 512
+
+// limit - 標準ライブラリの関数を、作る文字列の大きさを size で見積もって上限と比べてから
+// 元の関数を呼ぶものに置き換える。1度の割り当ては実行時間の上限では中断できないため、
+// 割り当てる前に止める。
+func (r *Runtime) limit(lib, name string, size func(L *lua.LState) int64) {
+	t := r.L.GetGlobal(lib).(*lua.LTable)
+	orig := t.RawGetString(name).(*lua.LFunction)
+	t.RawSetString(name, r.L.NewFunction(func(L *lua.LState) int {
+		if n := size(L); n > r.memory {
+			L.RaiseError("%s.%s: 作成する文字列 (%d バイト) が上限 %d MB を超えています", lib, name, n, r.memory>>20)
+		}
+		return orig.GFunction(L)
+	}))
+}
+
+// repSize - string.rep(s, n) の結果の大きさ
+func (r *Runtime) repSize(L *lua.LState) int64 {
+	s, n := int64(len(L.CheckString(1))), int64(L.CheckInt(2))
+	if s == 0 || n <= 0 {
+		return 0
+	}
+	if n > math.MaxInt64/s {
+		return math.MaxInt64
+	}
+	return s * n
+}
+
+// formatSize - string.format の結果の大きさの上限。書式・引数の長さに、幅と精度で
+// 埋められる分を足す。
+func (r *Runtime) formatSize(L *lua.LState) int64 {
+	format := L.CheckString(1)
+	size := int64(len(format))
+	for i := 2; i <= L.GetTop(); i++ {
+		if s, ok := L.Get(i).(lua.LString); ok {
+			size += int64(len(s))
+		} else {
+			size += formatArgSize
+		}
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && (format[i] == '-' || format[i] == '+' || format[i] == ' ' || format[i] == '#' || format[i] == '0') {
+			i++
+		}
+		var width, precision int64
+		width, i = digits(format, i)
+		if i < len(format) && format[i] == '.' {
+			precision, i = digits(format, i+1)
+		}
+		size += max(width, precision)
+	}
+	return size
+}
+
+// digits - format[i:] の先頭の数字を読む。大きすぎる値は上限で止める。
+func digits(format string, i int) (int64, int) {
+	var n int64
+	for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+		n = min(n*10+int64(format[i]-'0'), math.MaxInt32)
+	}
+	return n, i
+}
+
+// concatSize - table.concat(t, sep, i, j) の結果の大きさ。上限を超えた時点で数えるのをやめる。
+func (r *Runtime) concatSize(L *lua.LState) int64 {
+	t := L.CheckTable(1)
+	sep := int64(len(L.OptString(2, "")))
+	first, last := L.OptInt(3, 1), L.OptInt(4, t.Len())
+	var size int64
+	for i := first; i <= last && size <= r.memory; i++ {
+		switch v := t.RawGetInt(i).(type) {
+		case lua.LString:
+			size += int64(len(v))
+		case lua.LNumber:
+			size += int64(len(v.String()))
+		default:
+			// 文字列でも数値でもない値は元の関数がエラーにする
+			return size
+		}
+		if i < last {
+			size += sep
+		}
+	}
+	return size
+}
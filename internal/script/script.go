@@ -0,0 +1,226 @@
+// Package script はMODとテーマの Lua スクリプトを実行する。
+//
+// スクリプトは純粋な Go で書かれた Lua 5.1 の処理系 (gopher-lua) で動くため、
+// WebAssembly を含む全てのプラットフォームで同じように動作する。ファイルやOSに触れる
+// 標準ライブラリは開かず、スクリプトからは ModAPI テーブルを通して mod.ModECSAPI だけを使わせる。
+package script
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/mod"
+)
+
+// スクリプトのエラーコード
+const (
+	CodeUnsupported = "SCRIPT_UNSUPPORTED"
+	CodeFailed      = "SCRIPT_FAILED"
+)
+
+// Ext - 実行できるスクリプトの拡張子
+const Ext = ".lua"
+
+// DefaultTimeout - limits.max_script_time_ms を指定しないMODの1回の呼び出しの上限。
+// スクリプトはメインスレッドで動くため、無限ループでゲームが止まらないよう常に上限を設ける。
+const DefaultTimeout = 100 * time.Millisecond
+
+// DefaultMaxMemoryMB - limits.max_memory_mb を指定しないMODが1度に作れる文字列の上限 (MB)
+const DefaultMaxMemoryMB = 64
+
+// Lua の状態の大きさ。値スタックと呼び出しの深さを固定し、深い再帰や大量の値の展開は
+// Lua のエラーにする。値スタックを伸ばせるようにすると、伸ばすたびの複製が
+// 実行時間の上限で中断できない長い処理になる。
+const (
+	callStackSize = 200
+	registrySize  = 64 * 1024
+)
+
+// Runtime - 1つのMODのスクリプトを実行する Lua の状態。MODのスクリプトは全て同じ
+// グローバル環境で、mod.yaml の scripts の順に実行する。
+type Runtime struct {
+	ctx     *mod.Context
+	api     mod.ModECSAPI
+	L       *lua.LState
+	out     io.Writer
+	timeout time.Duration // 1回の呼び出しの上限
+	memory  int64         // 1度に作れる文字列の上限 (バイト)
+}
+
+// Start - mod.Options.Start に渡す関数を返す。MODのスクリプトを実行し、MODを無効にすると
+// Lua の状態を閉じる。print の出力とスクリプトのエラーはMODのIDを付けて out に書く。
+func Start(out io.Writer) func(c *mod.Context) error {
+	return func(c *mod.Context) error {
+		if len(c.Mod.Spec.Scripts) == 0 {
+			return nil
+		}
+		r := New(c, out)
+		c.OnDisable(r.Close)
+		return r.Run()
+	}
+}
+
+// New - MODの Lua の状態を作る。スクリプトはまだ実行しない。
+func New(c *mod.Context, out io.Writer) *Runtime {
+	r := &Runtime{
+		ctx: c,
+		api: c,
+		L: lua.NewState(lua.Options{
+			SkipOpenLibs:  true,
+			CallStackSize: callStackSize,
+			RegistrySize:  registrySize,
+		}),
+		out:     out,
+		timeout: DefaultTimeout,
+		memory:  DefaultMaxMemoryMB << 20,
+	}
+	limits := c.Mod.Spec.Metadata.Limits
+	if limits.MaxScriptTimeMS > 0 {
+		r.timeout = time.Duration(limits.MaxScriptTimeMS) * time.Millisecond
+	}
+	if limits.MaxMemoryMB > 0 {
+		r.memory = int64(limits.MaxMemoryMB) << 20
+	}
+	r.openLibs()
+	r.L.SetGlobal("ModAPI", r.newAPI())
+	return r
+}
+
+// openLibs - ファイルやOSに触れない標準ライブラリだけを開く。文字列からコードを読み込む
+// load・loadstring は使わせず、長い文字列を1度に作る関数は作る前に大きさを上限と比べる。
+func (r *Runtime) openLibs() {
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		r.L.Push(r.L.NewFunction(lib.open))
+		r.L.Push(lua.LString(lib.name))
+		r.L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "load", "loadfile", "loadstring", "module", "require"} {
+		r.L.SetGlobal(name, lua.LNil)
+	}
+	r.limit(lua.StringLibName, "rep", r.repSize)
+	r.limit(lua.StringLibName, "format", r.formatSize)
+	r.limit(lua.TabLibName, "concat", r.concatSize)
+	r.L.SetGlobal("print", r.L.NewFunction(r.print))
+}
+
+// Run - mod.yaml の scripts を順に実行する
+func (r *Runtime) Run() error {
+	for _, s := range r.ctx.Mod.Spec.Scripts {
+		if err := r.runFile(s.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runtime) runFile(name string) error {
+	name = path.Clean(name)
+	if !strings.EqualFold(path.Ext(name), Ext) {
+		return r.error(CodeUnsupported, fmt.Sprintf("%s: 対応していないスクリプトです", name))
+	}
+	src, err := fs.ReadFile(r.ctx.Mod.FS(), name)
+	if err != nil {
+		return r.error(CodeFailed, fmt.Sprintf("%s: スクリプトを読み込めません: %v", name, err))
+	}
+	fn, err := r.L.Load(strings.NewReader(string(src)), name)
+	if err != nil {
+		return r.error(CodeFailed, err.Error())
+	}
+	if err := r.call(fn); err != nil {
+		return r.error(CodeFailed, err.Error())
+	}
+	return nil
+}
+
+// Check - スクリプトが実行できる拡張子で、Lua として読み込めるか調べる。実行はしない。
+// ファイルがない場合は fs.ErrNotExist を含むエラーを返す。
+func Check(fsys fs.FS, name string) error {
+	if !strings.EqualFold(path.Ext(name), Ext) {
+		return fmt.Errorf("%s: 対応していないスクリプトです", name)
+	}
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	_, err = parse.Parse(bytes.NewReader(src), name)
+	return err
+}
+
+// call - Lua の関数を呼ぶ。limits.max_script_time_ms (指定しなければ DefaultTimeout) を超えると中断する。
+// スクリプトから呼ばれた Go の関数の中で呼び直した場合は、外側の呼び出しの上限に従う。
+func (r *Runtime) call(fn *lua.LFunction, args ...lua.LValue) error {
+	if r.L.Context() == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		defer cancel()
+		r.L.SetContext(ctx)
+		defer r.L.RemoveContext()
+	}
+	return r.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+}
+
+// Close - Lua の状態を閉じる
+func (r *Runtime) Close() {
+	r.L.Close()
+}
+
+// print - MODのIDを付けて出力する
+func (r *Runtime) print(L *lua.LState) int {
+	parts := make([]string, L.GetTop())
+	for i := range parts {
+		parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+	}
+	r.logf("%s", strings.Join(parts, "\t"))
+	return 0
+}
+
+func (r *Runtime) logf(format string, args ...interface{}) {
+	if r.out != nil {
+		fmt.Fprintf(r.out, "[%s] %s\n", r.ctx.Mod.ID, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *Runtime) error(code, msg string) error {
+	return gameerr.ModError{Code: code, Message: msg, ModID: r.ctx.Mod.ID}
+}
+
+// system - スクリプトが ModAPI.RegisterSystem で登録した、毎フレーム呼ばれる関数。
+// エラーが起きた場合はゲームを止めずにログに出し、以降は呼ばない。
+type system struct {
+	r      *Runtime
+	kind   ecs.SystemType
+	fn     *lua.LFunction
+	failed bool
+}
+
+func (s *system) GetType() ecs.SystemType { return s.kind }
+
+// Update - 経過時間 (秒) を渡して関数を呼ぶ
+func (s *system) Update(_ *ecs.EntityManager, dt time.Duration) error {
+	if s.failed {
+		return nil
+	}
+	if err := s.r.call(s.fn, lua.LNumber(dt.Seconds())); err != nil {
+		s.failed = true
+		s.r.logf("システム %s を停止しました: %v", s.kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package script_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/script"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+const bruteYAML = `
+metadata:
+  id: brute
+  name: Brute
+  version: 1.0.0
+  permissions: [create_entities, modify_components]
+  limits: {max_script_time_ms: 200}
+scripts:
+  - {file: scripts/main.lua, type: main}
+  - {file: scripts/ai.lua, type: ai_behavior}
+`
+
+const mainLua = `
+print("loaded", ModAPI.GetEntityCount())
+Brute = {speed = 10}
+local id = ModAPI.CreateEntity()
+ModAPI.AddComponent(id, "Transform", {position = {x = 1, y = 2}})
+ModAPI.AddComponent(id, "health", {maximum = 50, current = 50})
+ModAPI.Subscribe("hit", function(e)
+  ModAPI.SetComponent(id, "health", {current = e.data.damage})
+end)
+`
+
+const aiLua = `
+-- プレイヤー以外の速度を持つエンティティを右へ動かす
+ModAPI.RegisterSystem("brute_ai", function(dt)
+  for _, id in ipairs(ModAPI.Query("velocity")) do
+    ModAPI.SetComponent(id, "velocity", {velocity = {x = Brute.speed, y = 0}})
+  end
+end)
+`
+
+const saverLua = `
+ModAPI.WriteFile("mods/saver/state.txt", "wave=3")
+print(ModAPI.ReadFile("mods/saver/state.txt"))
+local ok = pcall(ModAPI.WriteFile, "mods/saver/../other/state.txt", "x")
+print(ok)
+`
+
+func modsFS() fstest.MapFS {
+	return fstest.MapFS{
+		"brute/mod.yaml":         {Data: []byte(bruteYAML)},
+		"brute/scripts/main.lua": {Data: []byte(mainLua)},
+		"brute/scripts/ai.lua":   {Data: []byte(aiLua)},
+		"sneaky/mod.yaml":        {Data: []byte("metadata: {id: sneaky, name: S, version: 1.0.0}\nscripts: [{file: main.lua}]\n")},
+		"sneaky/main.lua":        {Data: []byte(`os.execute("rm -rf /")`)},
+		"spin/mod.yaml":          {Data: []byte("metadata: {id: spin, name: S, version: 1.0.0, limits: {max_script_time_ms: 20}}\nscripts: [{file: main.lua}]\n")},
+		"spin/main.lua":          {Data: []byte(`while true do end`)},
+		"unbound/mod.yaml":       {Data: []byte("metadata: {id: unbound, name: U, version: 1.0.0}\nscripts: [{file: main.lua}]\n")},
+		"unbound/main.lua":       {Data: []byte(`while true do end`)},
+		"crash/mod.yaml":         {Data: []byte("metadata: {id: crash, name: C, version: 1.0.0}\nscripts: [{file: main.lua}]\n")},
+		"crash/main.lua":         {Data: []byte(`ModAPI.RegisterSystem("boom", function(dt) error("boom") end)`)},
+		"python/mod.yaml":        {Data: []byte("metadata: {id: python, name: P, version: 1.0.0}\nscripts: [{file: main.py}]\n")},
+		"python/main.py":         {Data: []byte(`print("hi")`)},
+		"saver/mod.yaml":         {Data: []byte("metadata: {id: saver, name: S, version: 1.0.0, file_access: [mods/saver]}\nscripts: [{file: main.lua}]\n")},
+		"saver/main.lua":         {Data: []byte(saverLua)},
+	}
+}
+
+// newHost - スクリプトから使うゲームの機能。ファイルは書き込める一時ディレクトリに置く。
+func newHost(t *testing.T) mod.Host {
+	t.Helper()
+	files := vfs.New()
+	require.NoError(t, files.Mount("data", vfs.Dir(t.TempDir()), vfs.MountOptions{Writable: true}))
+	return mod.Host{Entities: ecs.NewEntityManager(), Systems: ecs.NewSystemManager(), Files: files}
+}
+
+// start - fsys のMODを有効にするとスクリプトを実行するマネージャー。print の出力と
+// サンドボックスが拒否した操作は out に書く。
+func start(fsys fs.FS, host mod.Host, out io.Writer) *mod.Manager {
+	return mod.NewManager(fsys, vfs.New(), host, mod.Options{Start: script.Start(out), Log: out})
+}
+
+func TestRuntime(t *testing.T) {
+	t.Run("RunsScriptsWithModAPI", func(t *testing.T) {
+		host, out := newHost(t), new(bytes.Buffer)
+		m := start(modsFS(), host, out)
+		enemy := host.Entities.CreateEntity()
+		require.NoError(t, host.Entities.AddComponent(enemy, &ecs.VelocityComponent{}))
+
+		require.NoError(t, m.EnableMod("brute"))
+		assert.Equal(t, "[brute] loaded\t1\n", out.String())
+
+		owned := m.Context("brute").Entities()
+		require.Len(t, owned, 1)
+		c, err := host.Entities.GetComponent(owned[0], ecs.TransformComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, ecs.Vector2{X: 1, Y: 2}, c.(*ecs.TransformComponent).Position)
+		assert.Equal(t, ecs.Vector2{X: 1, Y: 1}, c.(*ecs.TransformComponent).Scale, "指定しないフィールドは既定値")
+
+		require.NoError(t, host.Systems.UpdateSystems(host.Entities, time.Second/60))
+		c, err = host.Entities.GetComponent(enemy, ecs.VelocityComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, ecs.Vector2{X: 10}, c.(*ecs.VelocityComponent).Velocity)
+
+		require.NoError(t, m.Events().Publish(mod.Event{Type: "hit", Data: map[string]interface{}{"damage": 7}}))
+		c, err = host.Entities.GetComponent(owned[0], ecs.HealthComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, 7, c.(*ecs.HealthComponent).Current)
+
+		require.NoError(t, m.DisableMod("brute"))
+		assert.Empty(t, host.Systems.GetRegisteredSystems())
+		assert.False(t, host.Entities.IsEntityValid(owned[0]))
+	})
+
+	t.Run("StartFailures", func(t *testing.T) {
+		m := start(modsFS(), mod.Host{}, io.Discard)
+		for _, name := range []string{"sneaky", "spin", "python"} {
+			err := m.EnableMod(name)
+			assert.Equal(t, mod.CodeStartFailed, gameerr.CodeOf(err), name)
+			assert.Nil(t, m.Context(name), name)
+		}
+	})
+
+	t.Run("DefaultTimeLimit", func(t *testing.T) {
+		err := start(modsFS(), mod.Host{}, io.Discard).EnableMod("unbound")
+		assert.Equal(t, mod.CodeStartFailed, gameerr.CodeOf(err), "上限を指定しないMODも無限ループで止まらない")
+	})
+
+	t.Run("ResourceLimits", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			lua  string
+			err  string
+		}{
+			{"Rep", `string.rep("x", 2 * 1024 * 1024)`, "string.rep"},
+			{"RepMethod", `local s = ("x"):rep(2^40)`, "string.rep"},
+			{"Concat", `local t = {} for i = 1, 1024 do t[i] = ("x"):rep(1024) end table.concat(t, ",")`, "table.concat"},
+			{"FormatArgs", `string.format("%s%s", ("x"):rep(2^19), ("x"):rep(2^19))`, "string.format"},
+			{"FormatWidth", `string.format("%099999d%099999d%099999d%099999d%099999d%099999d%099999d%099999d%099999d%099999d%099999d", 1)`, "string.format"},
+			{"Recursion", `local function f() return f() + 1 end f()`, "stack overflow"},
+			{"Registry", `string.byte(("x"):rep(100000), 1, -1)`, "registry overflow"},
+			{"Load", `load(function() return nil end)`, "non-function"},
+			{"Loadstring", `loadstring("x = 1")`, "non-function"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				fsys := fstest.MapFS{
+					"greedy/mod.yaml": {Data: []byte("metadata: {id: greedy, name: G, version: 1.0.0, limits: {max_memory_mb: 1}}\nscripts: [{file: main.lua}]\n")},
+					"greedy/main.lua": {Data: []byte(tc.lua)},
+				}
+				err := start(fsys, newHost(t), io.Discard).EnableMod("greedy")
+				assert.Equal(t, mod.CodeStartFailed, gameerr.CodeOf(err))
+				assert.ErrorContains(t, err, tc.err)
+			})
+		}
+
+		fsys := fstest.MapFS{
+			"modest/mod.yaml": {Data: []byte("metadata: {id: modest, name: M, version: 1.0.0, limits: {max_memory_mb: 1}}\nscripts: [{file: main.lua}]\n")},
+			"modest/main.lua": {Data: []byte(`print(#("ab"):rep(1000), #table.concat({"a", 1, "b"}, "-"), string.format("%5.1f|%%|%s", 1.25, "x"))`)},
+		}
+		out := new(bytes.Buffer)
+		require.NoError(t, start(fsys, newHost(t), out).EnableMod("modest"))
+		assert.Equal(t, "[modest] 2000\t5\t  1.2|%|x\n", out.String(), "上限より小さいものは作れる")
+	})
+
+	t.Run("RunsThemeScriptsWithoutFiles", func(t *testing.T) {
+		themes := fstest.MapFS{
+			"forest/theme.yaml":         {Data: []byte("metadata: {id: forest, name: Forest, version: 1.0.0}\nscripts: [{file: ./scripts/ai.lua}]\n")},
+			"forest/scripts/ai.lua":     {Data: []byte(aiLua + "Brute = {speed = 3}\nprint(pcall(ModAPI.ReadFile, \"mods/saver/state.txt\"))\n")},
+			"forest/scripts/unused.lua": {Data: []byte(`error("unused")`)},
+		}
+		th, err := theme.NewManager(themes, vfs.New(), theme.Options{}).LoadTheme("forest")
+		require.NoError(t, err)
+		host, out := newHost(t), new(bytes.Buffer)
+		enemy := host.Entities.CreateEntity()
+		require.NoError(t, host.Entities.AddComponent(enemy, &ecs.VelocityComponent{}))
+
+		c, err := script.StartTheme(th, host, mod.NewEventBus(), out)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "[theme:forest] false", "テーマのスクリプトはファイルを読めない")
+		require.NoError(t, host.Systems.UpdateSystems(host.Entities, time.Second/60))
+		v, err := host.Entities.GetComponent(enemy, ecs.VelocityComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, ecs.Vector2{X: 3}, v.(*ecs.VelocityComponent).Velocity)
+
+		c.Close()
+		assert.Empty(t, host.Systems.GetRegisteredSystems(), "テーマを切り替えるとシステムは外れる")
+
+		c, err = script.StartTheme(&theme.Theme{ID: "plain", Spec: &theme.Spec{}}, host, mod.NewEventBus(), out)
+		assert.NoError(t, err)
+		assert.Nil(t, c, "スクリプトのないテーマは何もしない")
+	})
+
+	t.Run("PermissionErrorsReachScript", func(t *testing.T) {
+		fsys := modsFS()
+		fsys["brute/mod.yaml"] = &fstest.MapFile{Data: bytes.Replace([]byte(bruteYAML), []byte("create_entities, "), nil, 1)}
+		err := start(fsys, newHost(t), io.Discard).EnableMod("brute")
+		assert.Equal(t, mod.CodeStartFailed, gameerr.CodeOf(err))
+		assert.Contains(t, err.Error(), "create_entities")
+	})
+
+	t.Run("FilesGoThroughSandbox", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		require.NoError(t, start(modsFS(), newHost(t), out).EnableMod("saver"))
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "[saver] wave=3", lines[0])
+		assert.Contains(t, lines[1], "[saver] ファイルへのアクセスが許可されていません")
+		assert.Equal(t, "[saver] false", lines[2])
+	})
+
+	t.Run("FailingSystemStopsWithoutStoppingGame", func(t *testing.T) {
+		host, out := newHost(t), new(bytes.Buffer)
+		require.NoError(t, start(modsFS(), host, out).EnableMod("crash"))
+		require.NoError(t, host.Systems.UpdateSystems(host.Entities, time.Second/60))
+		require.NoError(t, host.Systems.UpdateSystems(host.Entities, time.Second/60))
+		assert.Equal(t, 1, bytes.Count(out.Bytes(), []byte("[crash] システム mod:crash:boom を停止しました")))
+	})
+}
@@ -0,0 +1,38 @@
+package script
+
+import (
+	"io"
+
+	"muscle-dreamer/internal/mod"
+	"muscle-dreamer/internal/theme"
+)
+
+// themePermissions - テーマのスクリプトに与える権限。敵の行動などエンティティの操作だけを許し、
+// file_access を持たないためファイルにも触れない。
+var themePermissions = []string{mod.PermCreateEntities, mod.PermModifyComponents}
+
+// StartTheme - テーマの scripts をMODのスクリプトと同じ実行環境で順に実行する。
+// ファイル・ネットワーク・コマンドは使えず、上限は limits を指定しないMODと同じ既定値になる。
+// テーマを切り替えるときは返した Context の Close を呼ぶ。スクリプトがない場合は nil を返す。
+func StartTheme(t *theme.Theme, host mod.Host, events *mod.EventBus, out io.Writer) (*mod.Context, error) {
+	if len(t.Spec.Scripts) == 0 {
+		return nil, nil
+	}
+	spec := &mod.Spec{Metadata: mod.Metadata{
+		ID:          "theme:" + t.ID, // MODのIDと重ならない
+		Name:        t.Spec.Metadata.Name,
+		Version:     t.Spec.Metadata.Version,
+		Permissions: themePermissions,
+	}}
+	for _, s := range t.Spec.Scripts {
+		spec.Scripts = append(spec.Scripts, mod.File{File: s.File, Type: s.Type, Description: s.Description})
+	}
+	// テーマのスクリプトはゲームのファイルを使わない
+	host.Files = nil
+	c := mod.NewContext(mod.New(spec, t.FS()), host, events, out)
+	if err := Start(out)(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
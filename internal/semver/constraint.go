@@ -0,0 +1,153 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint - バージョン制約。全ての比較を満たすバージョンを許す。
+//
+//	"*"               任意
+//	">=1.0.0, <2.0.0" 範囲 (カンマまたは空白区切り)
+//	"^1.2.3"          >=1.2.3 <2.0.0 (0.x は minor、0.0.x は patch まで固定)
+//	"~1.2.3"          >=1.2.3 <1.3.0
+//	"1.2" / "=1.2"    >=1.2.0 <1.3.0 (省略した要素は任意)
+type Constraint struct {
+	text  string
+	terms []term
+}
+
+type term struct {
+	op string // ">=", ">", "<", "<=", "="
+	v  Version
+}
+
+// ParseConstraint - バージョン制約を解析する
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	if c.text == "" {
+		return c, fmt.Errorf("バージョン制約が空です")
+	}
+	tokens := strings.Fields(strings.ReplaceAll(c.text, ",", " "))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok == "*" {
+			continue
+		}
+		op := operator(tok)
+		// ">= 1.0.0" のように演算子とバージョンの間に空白がある場合
+		if op == tok && i+1 < len(tokens) {
+			i++
+			tok += tokens[i]
+		}
+		terms, err := expand(op, strings.TrimPrefix(tok, op))
+		if err != nil {
+			return Constraint{}, fmt.Errorf("不正なバージョン制約です: %q: %w", s, err)
+		}
+		c.terms = append(c.terms, terms...)
+	}
+	return c, nil
+}
+
+// MustParseConstraint - 定数の制約を解析する。不正な場合は panic する。
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func operator(tok string) string {
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(tok, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// expand - 1つの比較を下限・上限の組に展開する
+func expand(op, s string) ([]term, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return nil, err
+	}
+	// next - 指定された最後の要素を1つ上げたバージョン (1.2 → 1.3.0)
+	next := func(parts int) Version {
+		switch parts {
+		case 1:
+			return Version{Major: v.Major + 1}
+		case 2:
+			return Version{Major: v.Major, Minor: v.Minor + 1}
+		default:
+			return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+		}
+	}
+
+	switch op {
+	case "", "=":
+		if parts == 3 {
+			return []term{{"=", v}}, nil
+		}
+		return []term{{">=", v}, {"<", next(parts)}}, nil
+	case ">=":
+		return []term{{">=", v}}, nil
+	case "<":
+		return []term{{"<", v}}, nil
+	case ">":
+		if parts == 3 {
+			return []term{{">", v}}, nil
+		}
+		return []term{{">=", next(parts)}}, nil
+	case "<=":
+		if parts == 3 {
+			return []term{{"<=", v}}, nil
+		}
+		return []term{{"<", next(parts)}}, nil
+	case "~":
+		return []term{{">=", v}, {"<", next(min(parts, 2))}}, nil
+	case "^":
+		// 0 でない最初の要素までを固定する
+		fixed := 1
+		switch {
+		case v.Major == 0 && parts >= 2 && v.Minor == 0 && parts == 3:
+			fixed = 3
+		case v.Major == 0 && parts >= 2:
+			fixed = 2
+		}
+		return []term{{">=", v}, {"<", next(fixed)}}, nil
+	}
+	return nil, fmt.Errorf("未知の演算子です: %q", op)
+}
+
+// Check - v が制約を満たすか
+func (c Constraint) Check(v Version) bool {
+	for _, t := range c.terms {
+		d := v.Compare(t.v)
+		ok := false
+		switch t.op {
+		case "=":
+			ok = d == 0
+		case ">":
+			ok = d > 0
+		case ">=":
+			ok = d >= 0
+		case "<":
+			ok = d < 0
+		case "<=":
+			ok = d <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	if c.text == "" {
+		return "*"
+	}
+	return c.text
+}
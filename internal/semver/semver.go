@@ -0,0 +1,124 @@
+// Package semver はセマンティックバージョンとバージョン制約 (">=1.0.0, <2.0.0" など) を扱う。
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version - セマンティックバージョン。ビルドメタデータは比較に使わないため保持しない。
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // プレリリース ("beta.1" など)
+}
+
+// Parse - "1.2.3" / "1.2.3-beta.1+build" 形式のバージョンを解析する
+func Parse(s string) (Version, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if parts != 3 {
+		return Version{}, fmt.Errorf("バージョンは major.minor.patch の形式で指定してください: %q", s)
+	}
+	return v, nil
+}
+
+// MustParse - 定数のバージョンを解析する。不正な場合は panic する。
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parsePartial - "1" / "1.2" のように省略されたバージョンも受け付け、指定された要素数を返す
+func parsePartial(s string) (Version, int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, hasPre := strings.Cut(s, "-")
+	if hasPre && pre == "" {
+		return Version{}, 0, fmt.Errorf("プレリリースが空です: %q", s)
+	}
+
+	fields := strings.Split(core, ".")
+	if len(fields) > 3 {
+		return Version{}, 0, fmt.Errorf("不正なバージョンです: %q", s)
+	}
+	var nums [3]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || (len(f) > 1 && f[0] == '0') {
+			return Version{}, 0, fmt.Errorf("不正なバージョンです: %q", s)
+		}
+		nums[i] = n
+	}
+	if hasPre && len(fields) != 3 {
+		return Version{}, 0, fmt.Errorf("プレリリースは完全なバージョンにのみ付けられます: %q", s)
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2], Pre: pre}, len(fields), nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare - v < w なら負、等しければ 0、v > w なら正を返す。
+// プレリリースは同じ番号のリリースより小さい。
+func (v Version) Compare(w Version) int {
+	for _, d := range [...]int{v.Major - w.Major, v.Minor - w.Minor, v.Patch - w.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	return comparePre(v.Pre, w.Pre)
+}
+
+// comparePre - ドット区切りの識別子を順に比べる。数値は数値として、数値は文字列より小さい。
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,76 @@
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/semver"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		v, err := semver.Parse("1.2.3-beta.1+build.5")
+		require.NoError(t, err)
+		assert.Equal(t, semver.Version{Major: 1, Minor: 2, Patch: 3, Pre: "beta.1"}, v)
+		assert.Equal(t, "1.2.3-beta.1", v.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, s := range []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.2.x", "1.2.3-", "-1.2.3"} {
+			_, err := semver.Parse(s)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, b := semver.MustParse(ordered[i-1]), semver.MustParse(ordered[i])
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", a, b)
+		assert.Equal(t, 1, b.Compare(a), "%s > %s", b, a)
+	}
+	assert.Equal(t, 0, semver.MustParse("1.0.0+a").Compare(semver.MustParse("1.0.0+b")), "ビルドメタデータは無視する")
+}
+
+func TestConstraint(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		match      []string
+		reject     []string
+	}{
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+		{">=1.0.0", []string{"1.0.0", "3.2.1"}, []string{"0.9.9", "1.0.0-rc.1"}},
+		{">=1.0.0, <2.0.0", []string{"1.0.0", "1.9.9"}, []string{"2.0.0", "0.1.0"}},
+		{">= 1.2 < 1.4", []string{"1.2.0", "1.3.9"}, []string{"1.4.0", "1.1.9"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.2", []string{"1.2.0", "1.2.7"}, []string{"1.3.0"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+	}
+	for _, tc := range testCases {
+		c, err := semver.ParseConstraint(tc.constraint)
+		require.NoError(t, err, tc.constraint)
+		for _, v := range tc.match {
+			assert.True(t, c.Check(semver.MustParse(v)), "%s は %s を満たす", v, tc.constraint)
+		}
+		for _, v := range tc.reject {
+			assert.False(t, c.Check(semver.MustParse(v)), "%s は %s を満たさない", v, tc.constraint)
+		}
+	}
+
+	for _, s := range []string{"", ">=", "1.x", "=>1.0.0", "1.0.0 - 2.0.0"} {
+		_, err := semver.ParseConstraint(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,238 @@
+// Package settings は実行中の設定変更の反映・通知・永続化を行う。
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/atomicfile"
+	"muscle-dreamer/internal/config"
+)
+
+// 設定セクション
+const (
+	SectionAll      = "" // ファイル再読み込みなどによる全体の変更
+	SectionGraphics = "graphics"
+	SectionAudio    = "audio"
+	SectionInput    = "input"
+)
+
+// Applier - 設定変更を各サブシステムへ反映する
+type Applier interface {
+	ApplyGraphics(settings config.GraphicsSettings) error
+	ApplyAudio(settings config.AudioSettings) error
+	ApplyInput(settings config.InputSettings) error
+}
+
+// Change - 設定変更イベント
+type Change struct {
+	Section  string
+	Previous *config.GameConfig
+	Current  *config.GameConfig
+}
+
+// Service - 設定画面からの変更を検証・反映・保存するサービス
+type Service struct {
+	mu        sync.Mutex
+	current   *config.GameConfig
+	user      *config.GameConfig
+	base      *config.GameConfig
+	sources   []string
+	userDir   string
+	applier   Applier
+	listeners []func(Change)
+}
+
+// NewService - レイヤー合成した設定からサービスを作成する。
+// userDir が空の場合は変更を保存しない。
+func NewService(layers *config.Layered, userDir string) *Service {
+	s := &Service{userDir: userDir}
+	s.setLayers(layers)
+	return s
+}
+
+// setLayers - 実効設定・ユーザーレイヤー・比較元を置き換える。
+// 比較元がなければ既定値を、ユーザーレイヤーがなければ比較元を使う。
+func (s *Service) setLayers(layers *config.Layered) {
+	base := layers.Base
+	if base == nil {
+		base = config.Default()
+	}
+	user := layers.User
+	if user == nil {
+		user = base
+	}
+	s.current = layers.Config.Clone()
+	s.user = user.Clone()
+	s.base = base.Clone()
+	s.sources = slices.Clone(layers.Sources)
+}
+
+// SetApplier - 変更を反映するサブシステムを設定する
+func (s *Service) SetApplier(applier Applier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applier = applier
+}
+
+// Subscribe - 設定変更イベントを購読する
+func (s *Service) Subscribe(listener func(Change)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Current - 現在の実効設定のコピー
+func (s *Service) Current() *config.GameConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Clone()
+}
+
+// Sources - 設定を読み込んだファイル。変更されたら Reload で読み直す。
+func (s *Service) Sources() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sources)
+}
+
+// SetGraphics - 描画設定を変更する
+func (s *Service) SetGraphics(graphics config.GraphicsSettings) error {
+	return s.update(SectionGraphics, func(cfg *config.GameConfig) {
+		cfg.Graphics = graphics
+	}, func(a Applier, cfg *config.GameConfig) error {
+		return a.ApplyGraphics(cfg.Graphics)
+	})
+}
+
+// SetAudio - オーディオ設定を変更する
+func (s *Service) SetAudio(audio config.AudioSettings) error {
+	return s.update(SectionAudio, func(cfg *config.GameConfig) {
+		cfg.Audio = audio
+	}, func(a Applier, cfg *config.GameConfig) error {
+		return a.ApplyAudio(cfg.Audio)
+	})
+}
+
+// SetInput - 入力設定 (キーバインドを含む) を変更する
+func (s *Service) SetInput(input config.InputSettings) error {
+	return s.update(SectionInput, func(cfg *config.GameConfig) {
+		cfg.Input = input
+	}, func(a Applier, cfg *config.GameConfig) error {
+		return a.ApplyInput(cfg.Input)
+	})
+}
+
+// Reload - 再読み込みした設定全体を反映する。ユーザー設定ファイルには保存しない。
+func (s *Service) Reload(layers *config.Layered) error {
+	current := layers.Config
+	if err := current.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.applier != nil {
+		if err := applyAll(s.applier, current); err != nil {
+			_ = applyAll(s.applier, s.current)
+			s.mu.Unlock()
+			return err
+		}
+	}
+	previous := s.current
+	s.setLayers(layers)
+	listeners := append([]func(Change){}, s.listeners...)
+	s.mu.Unlock()
+
+	change := Change{Section: SectionAll, Previous: previous.Clone(), Current: current.Clone()}
+	for _, listener := range listeners {
+		listener(change)
+	}
+	return nil
+}
+
+func applyAll(a Applier, cfg *config.GameConfig) error {
+	if err := a.ApplyGraphics(cfg.Graphics); err != nil {
+		return err
+	}
+	if err := a.ApplyAudio(cfg.Audio); err != nil {
+		return err
+	}
+	return a.ApplyInput(cfg.Input)
+}
+
+// update - 検証 → 反映 → 保存 → 通知 の順に変更を処理する。
+// 検証・反映・保存のいずれかに失敗した場合、現在の設定は変更されない。
+func (s *Service) update(section string, mutate func(*config.GameConfig), apply func(Applier, *config.GameConfig) error) error {
+	s.mu.Lock()
+
+	next := s.current.Clone()
+	mutate(next)
+	if err := next.Validate(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	if s.applier != nil {
+		if err := apply(s.applier, next); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+
+	user := s.user.Clone()
+	user.CopyChanged(s.current, next, section)
+	if err := s.persist(user, section); err != nil {
+		if s.applier != nil {
+			// 反映済みの変更を元に戻す
+			_ = apply(s.applier, s.current)
+		}
+		s.mu.Unlock()
+		return err
+	}
+
+	previous := s.current
+	s.current = next
+	s.user = user
+	listeners := append([]func(Change){}, s.listeners...)
+	s.mu.Unlock()
+
+	change := Change{Section: section, Previous: previous.Clone(), Current: next.Clone()}
+	for _, listener := range listeners {
+		listener(change)
+	}
+	return nil
+}
+
+// persist - ユーザーレイヤーのうち比較元と異なる値だけをユーザー設定ファイルへ書き出す。
+// 環境変数やフラグで一時的に指定した値はユーザーレイヤーに含まれないため保存されない。
+func (s *Service) persist(user *config.GameConfig, section string) error {
+	if s.userDir == "" {
+		return nil
+	}
+	path := filepath.Join(s.userDir, config.UserFile(section))
+
+	overrides := user.Overrides(s.base, section)
+	if len(overrides) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("ユーザー設定を削除できません: %w", err)
+		}
+		return nil
+	}
+
+	data, err := yaml.Marshal(overrides)
+	if err != nil {
+		return fmt.Errorf("ユーザー設定を書き出せません: %w", err)
+	}
+	if err := os.MkdirAll(s.userDir, 0o755); err != nil {
+		return fmt.Errorf("ユーザー設定ディレクトリを作成できません: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, data); err != nil {
+		return fmt.Errorf("ユーザー設定を保存できません: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package settings_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/config"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/settings"
+)
+
+// recordingApplier - 反映された設定を記録するテスト用Applier
+type recordingApplier struct {
+	graphics []config.GraphicsSettings
+	audio    []config.AudioSettings
+	input    []config.InputSettings
+	inputErr error
+}
+
+func (r *recordingApplier) ApplyGraphics(s config.GraphicsSettings) error {
+	r.graphics = append(r.graphics, s)
+	return nil
+}
+
+func (r *recordingApplier) ApplyAudio(s config.AudioSettings) error {
+	r.audio = append(r.audio, s)
+	return nil
+}
+
+func (r *recordingApplier) ApplyInput(s config.InputSettings) error {
+	r.input = append(r.input, s)
+	return r.inputErr
+}
+
+// defaults - 既定値だけを合成したレイヤー
+func defaults() *config.Layered {
+	return &config.Layered{Config: config.Default(), Base: config.Default()}
+}
+
+func TestService(t *testing.T) {
+	t.Run("ApplyPublishAndPersist", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := settings.NewService(defaults(), dir)
+		applier := &recordingApplier{}
+		svc.SetApplier(applier)
+
+		var changes []settings.Change
+		svc.Subscribe(func(c settings.Change) { changes = append(changes, c) })
+
+		audio := svc.Current().Audio
+		audio.BGMVolume = 0.25
+		require.NoError(t, svc.SetAudio(audio))
+
+		require.Len(t, applier.audio, 1)
+		assert.Equal(t, 0.25, applier.audio[0].BGMVolume)
+		assert.Equal(t, 0.25, svc.Current().Audio.BGMVolume)
+
+		require.Len(t, changes, 1)
+		assert.Equal(t, settings.SectionAudio, changes[0].Section)
+		assert.Equal(t, 0.8, changes[0].Previous.Audio.BGMVolume)
+		assert.Equal(t, 0.25, changes[0].Current.Audio.BGMVolume)
+
+		data, err := os.ReadFile(filepath.Join(dir, "audio.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "bgm_volume: 0.25\n", string(data))
+
+		// 保存した上書きは次回起動時にユーザーレイヤーとして読み込まれる
+		l, err := config.LoadLayered(config.Options{UserDir: dir})
+		require.NoError(t, err)
+		assert.Equal(t, 0.25, l.Config.Audio.BGMVolume)
+		assert.Equal(t, config.SourceUser, l.Origin("audio.bgm_volume").Source)
+	})
+
+	t.Run("KeyBindingsPersistToKeybindsFile", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := settings.NewService(defaults(), dir)
+
+		input := svc.Current().Input
+		input.KeyBindings["action"] = "Enter"
+		require.NoError(t, svc.SetInput(input))
+
+		l, err := config.LoadLayered(config.Options{UserDir: dir})
+		require.NoError(t, err)
+		assert.Equal(t, "Enter", l.Config.Input.KeyBindings["action"])
+		assert.Equal(t, "W", l.Config.Input.KeyBindings["move_up"])
+	})
+
+	t.Run("RevertingToBaseRemovesOverrideFile", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := settings.NewService(defaults(), dir)
+
+		graphics := svc.Current().Graphics
+		graphics.Fullscreen = true
+		require.NoError(t, svc.SetGraphics(graphics))
+		assert.FileExists(t, filepath.Join(dir, "graphics.yaml"))
+
+		graphics.Fullscreen = false
+		require.NoError(t, svc.SetGraphics(graphics))
+		assert.NoFileExists(t, filepath.Join(dir, "graphics.yaml"))
+	})
+
+	t.Run("EnvironmentOverridesAreNotPersisted", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "graphics.yaml"), []byte("vsync: false\n"), 0o644))
+		l, err := config.LoadLayered(config.Options{
+			UserDir: dir,
+			Environ: []string{config.EnvName("graphics.width") + "=1920"},
+		})
+		require.NoError(t, err)
+		svc := settings.NewService(l, dir)
+
+		graphics := svc.Current().Graphics
+		require.Equal(t, 1920, graphics.Width)
+		graphics.Fullscreen = true
+		require.NoError(t, svc.SetGraphics(graphics))
+		assert.Equal(t, 1920, svc.Current().Graphics.Width, "実行中は環境変数の値を使い続ける")
+
+		data, err := os.ReadFile(filepath.Join(dir, "graphics.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "fullscreen: true\nvsync: false\n", string(data), "ユーザーファイルの値と変更した値だけを保存する")
+
+		// 環境変数の値のまま変えていないキーは、他のキーを変更しても保存しない
+		graphics.Height = 1080
+		require.NoError(t, svc.SetGraphics(graphics))
+		data, err = os.ReadFile(filepath.Join(dir, "graphics.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "fullscreen: true\nheight: 1080\nvsync: false\n", string(data))
+	})
+
+	t.Run("InvalidChangeIsRejected", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := settings.NewService(defaults(), dir)
+		applier := &recordingApplier{}
+		svc.SetApplier(applier)
+
+		graphics := svc.Current().Graphics
+		graphics.Width = 0
+		err := svc.SetGraphics(graphics)
+
+		var cfgErr gameerr.ConfigError
+		require.True(t, errors.As(err, &cfgErr))
+		assert.Equal(t, "graphics.width", cfgErr.Field)
+		assert.Empty(t, applier.graphics)
+		assert.Equal(t, 1280, svc.Current().Graphics.Width)
+		assert.NoFileExists(t, filepath.Join(dir, "graphics.yaml"))
+	})
+
+	t.Run("ApplierFailureKeepsCurrentSettings", func(t *testing.T) {
+		svc := settings.NewService(defaults(), "")
+		svc.SetApplier(&recordingApplier{inputErr: errors.New("unknown key")})
+
+		input := svc.Current().Input
+		input.KeyBindings["action"] = "NoSuchKey"
+		assert.Error(t, svc.SetInput(input))
+		assert.Equal(t, "Space", svc.Current().Input.KeyBindings["action"])
+	})
+
+	t.Run("ReloadAppliesAllSectionsWithoutPersisting", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := settings.NewService(defaults(), dir)
+		applier := &recordingApplier{}
+		svc.SetApplier(applier)
+
+		var changes []settings.Change
+		svc.Subscribe(func(c settings.Change) { changes = append(changes, c) })
+
+		reloaded := defaults()
+		reloaded.Config.Graphics.Width = 1920
+		reloaded.Sources = []string{"game.yaml", filepath.Join(dir, "config.yaml")}
+		require.NoError(t, svc.Reload(reloaded))
+		assert.Equal(t, reloaded.Sources, svc.Sources(), "監視するファイルも置き換える")
+
+		assert.Len(t, applier.graphics, 1)
+		assert.Len(t, applier.audio, 1)
+		assert.Len(t, applier.input, 1)
+		assert.Equal(t, 1920, svc.Current().Graphics.Width)
+		require.Len(t, changes, 1)
+		assert.Equal(t, settings.SectionAll, changes[0].Section)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
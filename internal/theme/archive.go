@@ -0,0 +1,381 @@
+package theme
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // プレビュー画像のデコーダー
+	_ "image/png"  // プレビュー画像のデコーダー
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/pack"
+)
+
+// アーカイブのエラーコード。壊れたパックは pack のエラーコードのまま返す。
+const (
+	CodeArchiveInvalid  = "THEME_ARCHIVE_INVALID"
+	CodeArchiveChecksum = "THEME_ARCHIVE_CHECKSUM_MISMATCH"
+	CodeArchiveTooLarge = "THEME_ARCHIVE_TOO_LARGE"
+)
+
+// DefaultMaxArchiveSize - インストールするアーカイブのサイズの既定の上限
+const DefaultMaxArchiveSize = 512 << 20
+
+// ChecksumExt - アーカイブの SHA-256 を書いたファイルの拡張子 (<ID>.mdpk.sha256)。
+// sha256sum と同じ形式で、置かれていればアーカイブ全体のハッシュと照合する。
+const ChecksumExt = ".sha256"
+
+// PreviewFile - テーマ一覧に表示するプレビュー画像 (テーマのルート直下)
+const PreviewFile = "preview.png"
+
+// Installation - アーカイブからインストールしたテーマの記録
+type Installation struct {
+	ID      string
+	Archive string // テーマディレクトリ内のアーカイブのファイル名
+	SHA256  string // アーカイブ全体の SHA-256 (16進数)
+	Size    int64
+	Files   int
+	Author  string
+	License string
+}
+
+// installed - インストール済みのアーカイブ。内容はテーマを読み込むときに初めて開く。
+type installed struct {
+	Installation
+	modTime time.Time
+	fsys    *pack.Reader // 開いたアーカイブ。まだ開いていなければ nil
+	closer  io.Closer
+}
+
+// Info - テーマ一覧に表示する情報。metadata は基底テーマから継承しない。
+type Info struct {
+	ID          string
+	Name        string
+	Version     string
+	Author      string
+	License     string
+	Description string
+	Preview     string        // プレビュー画像のパス (テーマのルートから)。ない場合は空
+	Archive     *Installation // アーカイブからインストールしたテーマ。ディレクトリのテーマは nil
+}
+
+// Install - テーマディレクトリ直下のアーカイブ (<ID>.mdpk) を全て検証してインストールする。
+// 全エントリのハッシュを照合し、チェックサムファイルがあればアーカイブ全体とも照合する。
+// 壊れた・改ざんされたアーカイブは gameerr.AssetError を返してインストールしない。
+// インストールしたテーマは GetAvailableThemes に並び、有効にするとアーカイブの内容が
+// 仮想ファイルシステムのテーマレイヤーとしてマウントされる。
+// 呼び直すとアーカイブを読み直し、なくなったアーカイブのテーマは一覧から外れる。
+func (m *Manager) Install() []error {
+	entries, err := fs.ReadDir(m.root, ".")
+	if err != nil {
+		return nil
+	}
+	archives := make(map[string]*installed)
+	var errs []error
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), pack.Ext)
+		if !ok || e.IsDir() {
+			continue
+		}
+		a, err := m.installArchive(id, e.Name())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// 変わっていないアーカイブは開いたものを使い続ける
+		if prev, ok := m.archives[id]; ok && prev.Installation == a.Installation && prev.modTime.Equal(a.modTime) {
+			a = prev
+		}
+		archives[id] = a
+	}
+	for id, prev := range m.archives {
+		if archives[id] != prev && !m.inUse(id) {
+			prev.close()
+		}
+	}
+	m.archives = archives
+	return errs
+}
+
+// GetInstalledArchives - インストール済みのアーカイブの記録 (ID順)
+func (m *Manager) GetInstalledArchives() []Installation {
+	list := make([]Installation, 0, len(m.archives))
+	for _, a := range m.archives {
+		list = append(list, a.Installation)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// installArchive - アーカイブを検証して記録を作る。アーカイブ全体はメモリに読み込まず、
+// ハッシュは読みながら計算し、エントリは1つずつ展開して照合する。
+func (m *Manager) installArchive(id, file string) (*installed, error) {
+	src, err := m.openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(src, 0, src.size)); err != nil {
+		return nil, archiveError(CodeArchiveInvalid, file, fmt.Sprintf("アーカイブを読み込めません: %s: %v", file, err))
+	}
+	sum := [32]byte(h.Sum(nil))
+	if err := m.checkSum(file, sum); err != nil {
+		return nil, err
+	}
+
+	r, err := pack.NewReader(file, src, src.size)
+	if err != nil {
+		return nil, err
+	}
+	// インデックスに書かれた展開後のサイズは改ざんされていなくても安全とは限らないため、
+	// 展開する前にテーマの上限と比べる
+	limits := m.limits()
+	entries := r.Entries()
+	for _, e := range entries {
+		if err := limits.CheckFileSize(path.Join(id, e.Name), e.Size); err != nil {
+			return nil, err
+		}
+	}
+	if errs := r.Verify(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if _, err := fs.Stat(m.root, id); err == nil {
+		return nil, archiveError(CodeArchiveInvalid, file, fmt.Sprintf("同じIDのテーマディレクトリがあります: %s", id))
+	}
+
+	var spec struct {
+		Metadata Metadata `yaml:"metadata"`
+	}
+	doc, err := r.ReadFile(FileName)
+	if err != nil {
+		return nil, archiveError(CodeArchiveInvalid, file, fmt.Sprintf("アーカイブの直下に %s がありません: %s", FileName, file))
+	}
+	// 内容の誤りはテーマを読み込むときにスキーマで検証する
+	_ = yaml.Unmarshal(doc, &spec)
+	if spec.Metadata.ID != id {
+		return nil, archiveError(CodeArchiveInvalid, file,
+			fmt.Sprintf("%s: metadata.id %q がアーカイブ名と一致しません", file, spec.Metadata.ID))
+	}
+
+	return &installed{
+		Installation: Installation{
+			ID:      id,
+			Archive: file,
+			SHA256:  hex.EncodeToString(sum[:]),
+			Size:    src.size,
+			Files:   len(entries),
+			Author:  spec.Metadata.Author,
+			License: spec.Metadata.License,
+		},
+		modTime: src.modTime,
+	}, nil
+}
+
+// archiveSource - 開いたアーカイブ
+type archiveSource struct {
+	io.ReaderAt
+	io.Closer
+	size    int64
+	modTime time.Time
+}
+
+// openArchive - サイズを上限と比べてからアーカイブを開く。ファイルが io.ReaderAt を
+// 実装していればそのまま読み、そうでなければ上限まで読み込む。
+func (m *Manager) openArchive(file string) (*archiveSource, error) {
+	limit := m.opts.MaxArchiveSize
+	if limit <= 0 {
+		limit = DefaultMaxArchiveSize
+	}
+	tooLarge := func(size int64) error {
+		return gameerr.AssetError{
+			Code:      CodeArchiveTooLarge,
+			Message:   fmt.Sprintf("アーカイブのサイズ %d バイトが上限 %d バイトを超えています: %s", size, limit, file),
+			AssetPath: file,
+			Err:       inspect.ErrTooLarge,
+		}
+	}
+	unreadable := func(err error) error {
+		return archiveError(CodeArchiveInvalid, file, fmt.Sprintf("アーカイブを読み込めません: %s: %v", file, err))
+	}
+
+	f, err := m.root.Open(file)
+	if err != nil {
+		return nil, unreadable(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, unreadable(err)
+	}
+	if info.Size() > limit {
+		f.Close()
+		return nil, tooLarge(info.Size())
+	}
+	if ra, ok := f.(io.ReaderAt); ok {
+		return &archiveSource{ReaderAt: ra, Closer: f, size: info.Size(), modTime: info.ModTime()}, nil
+	}
+
+	defer f.Close()
+	// 読み込み中に置き換えられても上限を1バイト超えたところで止める
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, unreadable(err)
+	}
+	if int64(len(data)) > limit {
+		return nil, tooLarge(int64(len(data)))
+	}
+	return &archiveSource{ReaderAt: bytes.NewReader(data), Closer: io.NopCloser(nil), size: int64(len(data)), modTime: info.ModTime()}, nil
+}
+
+// open - テーマを読み込むときにアーカイブを開く。インストールしたときからサイズや
+// 更新時刻が変わっていれば、インストールし直すまで使わない。エントリは展開するたびに
+// インデックスのハッシュと照合される。
+func (m *Manager) open(a *installed) (*pack.Reader, error) {
+	if a.fsys != nil {
+		return a.fsys, nil
+	}
+	src, err := m.openArchive(a.Archive)
+	if err != nil {
+		return nil, err
+	}
+	if src.size != a.Size || !src.modTime.Equal(a.modTime) {
+		src.Close()
+		return nil, archiveError(CodeArchiveChecksum, a.Archive, fmt.Sprintf("インストール後にアーカイブが変更されました: %s", a.Archive))
+	}
+	r, err := pack.NewReader(a.Archive, src, src.size)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	a.fsys, a.closer = r, src
+	return r, nil
+}
+
+func (a *installed) close() {
+	if a.closer != nil {
+		a.closer.Close()
+	}
+	a.fsys, a.closer = nil, nil
+}
+
+// inUse - 有効なテーマがそのテーマのレイヤーを使っているか
+func (m *Manager) inUse(id string) bool {
+	return m.current != nil && slices.Contains(m.current.Chain, id)
+}
+
+// limits - テーマから直接読み込むファイルに適用する上限
+func (m *Manager) limits() inspect.Limits {
+	if m.opts.Limits != nil {
+		return *m.opts.Limits
+	}
+	return inspect.DefaultLimits().Theme
+}
+
+// checkSum - チェックサムファイルがあれば、アーカイブ全体のハッシュと照合する
+func (m *Manager) checkSum(file string, sum [32]byte) error {
+	data, err := fs.ReadFile(m.root, file+ChecksumExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return archiveError(CodeArchiveInvalid, file, fmt.Sprintf("チェックサムを読み込めません: %s%s: %v", file, ChecksumExt, err))
+	}
+	// sha256sum の出力 ("<16進数>  <ファイル名>") の先頭のハッシュだけを使う
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+		return archiveError(CodeArchiveChecksum, file, fmt.Sprintf("アーカイブのチェックサムが一致しません: %s", file))
+	}
+	return nil
+}
+
+func archiveError(code, file, msg string) error {
+	return gameerr.AssetError{Code: code, Message: msg, AssetPath: file}
+}
+
+// themeFS - テーマのルート。インストールしたアーカイブはアーカイブの内容になる。
+func (m *Manager) themeFS(name string) (fs.FS, error) {
+	if a, ok := m.archives[name]; ok {
+		r, err := m.open(a)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	return fs.Sub(m.root, name)
+}
+
+// GetThemeInfo - テーマ一覧に表示する作者・ライセンス・プレビュー画像などの情報
+func (m *Manager) GetThemeInfo(name string) (*Info, error) {
+	l, err := m.readLayer(name)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{
+		ID:          name,
+		Name:        l.meta.Name,
+		Version:     l.meta.Version,
+		Author:      l.meta.Author,
+		License:     l.meta.License,
+		Description: l.meta.Description,
+	}
+	if _, err := fs.Stat(l.fsys, PreviewFile); err == nil {
+		info.Preview = PreviewFile
+	}
+	if a, ok := m.archives[name]; ok {
+		record := a.Installation
+		info.Archive = &record
+	}
+	return info, nil
+}
+
+// Preview - テーマのプレビュー画像を読み込む。ない場合は fs.ErrNotExist を返す。
+// アセットと同じく、ファイルサイズとヘッダーの寸法を上限と比べてからデコードする。
+func (m *Manager) Preview(name string) (image.Image, error) {
+	fsys, err := m.themeFS(name)
+	if err != nil {
+		return nil, err
+	}
+	limits := m.limits()
+	p := path.Join(name, PreviewFile)
+	if info, err := fs.Stat(fsys, PreviewFile); err == nil {
+		if err := limits.CheckFileSize(p, info.Size()); err != nil {
+			return nil, err
+		}
+	}
+	f, err := fsys.Open(PreviewFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := limits.ReadLimited(p, f)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("プレビュー画像を読み込めません: %s: %w", p, err)
+	}
+	if err := limits.CheckImage(p, cfg); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("プレビュー画像を読み込めません: %s: %w", p, err)
+	}
+	return img, nil
+}
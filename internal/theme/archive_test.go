@@ -0,0 +1,217 @@
+package theme_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+const islandTheme = `
+metadata: {id: island, name: Island, version: 1.2.0, author: Castaway, license: CC-BY-4.0}
+stages:
+  locations:
+    - id: shore
+      name: Shore
+`
+
+// buildArchive - files をまとめたアーカイブを作る
+func buildArchive(t *testing.T, files fstest.MapFS) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := pack.NewWriter(&buf)
+	require.NoError(t, w.AddFS(files, ".", ""))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func islandArchive(t *testing.T) []byte {
+	t.Helper()
+	var preview bytes.Buffer
+	require.NoError(t, png.Encode(&preview, image.NewNRGBA(image.Rect(0, 0, 64, 36))))
+	return buildArchive(t, fstest.MapFS{
+		"theme.yaml":                 {Data: []byte(islandTheme)},
+		"preview.png":                {Data: preview.Bytes()},
+		"assets/characters/idle.png": {Data: []byte("island idle")},
+	})
+}
+
+// setEntrySize - アーカイブのインデックスに書かれたエントリの展開後のサイズを書き換え、
+// インデックスのハッシュを合わせる
+func setEntrySize(t *testing.T, archive []byte, name string, size uint64) []byte {
+	t.Helper()
+	archive = bytes.Clone(archive)
+	footer := archive[len(archive)-52:]
+	index := archive[binary.LittleEndian.Uint64(footer[0:]) : len(archive)-52]
+	i := bytes.Index(index, []byte(name))
+	require.GreaterOrEqual(t, i, 0)
+	// 名前の後に格納方式 (1バイト)・位置・格納サイズ・展開後のサイズが続く
+	binary.LittleEndian.PutUint64(index[i+len(name)+1+16:], size)
+	sum := sha256.Sum256(index)
+	copy(footer[16:48], sum[:])
+	return archive
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallArchive(t *testing.T) {
+	t.Run("InstallsVerifiedArchive", func(t *testing.T) {
+		archive := islandArchive(t)
+		root := themesFS()
+		root["island.mdpk"] = &fstest.MapFile{Data: archive}
+		root["island.mdpk.sha256"] = &fstest.MapFile{Data: []byte(sha256Hex(archive) + "  island.mdpk\n")}
+		files := vfs.New(vfs.DefaultRoots...)
+		m := theme.NewManager(root, files, theme.Options{})
+
+		assert.Empty(t, m.Install())
+		assert.Equal(t, []string{"beach", "broken", "gym", "island", "renamed"}, m.GetAvailableThemes())
+		assert.Equal(t, []theme.Installation{{
+			ID:      "island",
+			Archive: "island.mdpk",
+			SHA256:  sha256Hex(archive),
+			Size:    int64(len(archive)),
+			Files:   3,
+			Author:  "Castaway",
+			License: "CC-BY-4.0",
+		}}, m.GetInstalledArchives())
+
+		info, err := m.GetThemeInfo("island")
+		require.NoError(t, err)
+		assert.Equal(t, "Island", info.Name)
+		assert.Equal(t, theme.PreviewFile, info.Preview)
+		require.NotNil(t, info.Archive)
+		assert.Equal(t, "CC-BY-4.0", info.Archive.License)
+		preview, err := m.Preview("island")
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 64, 36), preview.Bounds())
+
+		require.NoError(t, m.SetTheme("island"))
+		data, err := files.ReadFile("assets/characters/idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, "island idle", string(data), "アーカイブの内容がテーマレイヤーになる")
+	})
+
+	t.Run("DirectoryThemeInfo", func(t *testing.T) {
+		m := theme.NewManager(themesFS(), vfs.New(), theme.Options{})
+		info, err := m.GetThemeInfo("gym")
+		require.NoError(t, err)
+		assert.Equal(t, &theme.Info{ID: "gym", Name: "Gym", Version: "1.0.0"}, info)
+		_, err = m.Preview("gym")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("RejectsBrokenArchives", func(t *testing.T) {
+		archive := islandArchive(t)
+		tampered := bytes.Clone(archive)
+		i := bytes.Index(tampered, []byte("island idle"))
+		require.GreaterOrEqual(t, i, 0)
+		tampered[i] = 'I'
+
+		testCases := []struct {
+			name  string
+			files fstest.MapFS
+			code  string
+		}{
+			{"Tampered", fstest.MapFS{"island.mdpk": {Data: tampered}}, pack.CodeCorrupted},
+			{"Truncated", fstest.MapFS{"island.mdpk": {Data: archive[:len(archive)-10]}}, pack.CodeInvalidPack},
+			{"ChecksumMismatch", fstest.MapFS{
+				"island.mdpk":        {Data: archive},
+				"island.mdpk.sha256": {Data: []byte(sha256Hex(tampered))},
+			}, theme.CodeArchiveChecksum},
+			{"Renamed", fstest.MapFS{"atoll.mdpk": {Data: archive}}, theme.CodeArchiveInvalid},
+			{"NoThemeFile", fstest.MapFS{"island.mdpk": {Data: buildArchive(t, fstest.MapFS{"readme.txt": {}})}}, theme.CodeArchiveInvalid},
+			{"ShadowsDirectory", fstest.MapFS{
+				"island.mdpk":       {Data: archive},
+				"island/theme.yaml": {Data: []byte(islandTheme)},
+			}, theme.CodeArchiveInvalid},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := theme.NewManager(tc.files, vfs.New(), theme.Options{})
+				errs := m.Install()
+				require.Len(t, errs, 1)
+				assert.Equal(t, tc.code, gameerr.CodeOf(errs[0]))
+				assert.Empty(t, m.GetInstalledArchives())
+			})
+		}
+	})
+
+	t.Run("RejectsOversizedArchive", func(t *testing.T) {
+		archive := islandArchive(t)
+		m := theme.NewManager(fstest.MapFS{"island.mdpk": {Data: archive}}, vfs.New(), theme.Options{
+			MaxArchiveSize: int64(len(archive) - 1),
+		})
+		errs := m.Install()
+		require.Len(t, errs, 1)
+		assert.Equal(t, theme.CodeArchiveTooLarge, gameerr.CodeOf(errs[0]))
+		assert.ErrorIs(t, errs[0], inspect.ErrTooLarge)
+		assert.Empty(t, m.GetAvailableThemes())
+	})
+
+	t.Run("RejectsEntriesOverLimitsBeforeInflating", func(t *testing.T) {
+		archive := setEntrySize(t, islandArchive(t), "assets/characters/idle.png", 1<<30)
+		m := theme.NewManager(fstest.MapFS{"island.mdpk": {Data: archive}}, vfs.New(), theme.Options{})
+		errs := m.Install()
+		require.Len(t, errs, 1)
+		assert.Equal(t, inspect.CodeTooLarge, gameerr.CodeOf(errs[0]), "インデックスが 1GiB と書いたエントリは展開しない")
+		assert.Empty(t, m.GetInstalledArchives())
+	})
+
+	t.Run("OpensArchiveWhenThemeIsLoaded", func(t *testing.T) {
+		root := fstest.MapFS{"island.mdpk": {Data: islandArchive(t)}}
+		m := theme.NewManager(root, vfs.New(), theme.Options{})
+		require.Empty(t, m.Install())
+
+		root["island.mdpk"] = &fstest.MapFile{Data: append(islandArchive(t), 0)}
+		_, err := m.LoadTheme("island")
+		assert.Equal(t, theme.CodeArchiveChecksum, gameerr.CodeOf(err), "インストール後に変わったアーカイブは使わない")
+
+		root["island.mdpk"] = &fstest.MapFile{Data: islandArchive(t)}
+		require.Empty(t, m.Install())
+		_, err = m.LoadTheme("island")
+		assert.NoError(t, err, "インストールし直せば使える")
+	})
+
+	t.Run("PreviewIsCheckedAgainstLimits", func(t *testing.T) {
+		limits := inspect.DefaultLimits().Theme
+		limits.MaxImageSide = 32
+		m := theme.NewManager(fstest.MapFS{"island.mdpk": {Data: islandArchive(t)}}, vfs.New(), theme.Options{Limits: &limits})
+		require.Empty(t, m.Install())
+
+		_, err := m.Preview("island")
+		assert.ErrorIs(t, err, inspect.ErrTooLarge, "64x36 のプレビューは一辺32の上限を超える")
+
+		limits.MaxImageSide = 64
+		img, err := m.Preview("island")
+		require.NoError(t, err)
+		assert.Equal(t, 64, img.Bounds().Dx())
+	})
+
+	t.Run("ReinstallDropsRemovedArchives", func(t *testing.T) {
+		root := fstest.MapFS{"island.mdpk": {Data: islandArchive(t)}}
+		m := theme.NewManager(root, vfs.New(), theme.Options{})
+		require.Empty(t, m.Install())
+		assert.Equal(t, []string{"island"}, m.GetAvailableThemes())
+
+		delete(root, "island.mdpk")
+		assert.Empty(t, m.Install())
+		assert.Empty(t, m.GetAvailableThemes())
+	})
+}
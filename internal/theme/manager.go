@@ -0,0 +1,234 @@
+package theme
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/vfs"
+)
+
+// テーマ管理のエラーコード
+const (
+	CodeNotFound   = "THEME_NOT_FOUND"
+	CodeIDMismatch = "THEME_ID_MISMATCH"
+	CodeMountFail  = "THEME_MOUNT_FAILED"
+)
+
+// Theme - 読み込んだテーマ。基底テーマ (metadata.dependencies) を重ねた結果で、
+// アセットパスはテーマのルートからの相対パスに正規化済み。
+// Prefabs は SetTheme で有効にしたときに作られる。
+type Theme struct {
+	ID       string
+	Chain    []string // 重ねたテーマのID。基底テーマが先で、最後がこのテーマ。
+	Spec     *Spec
+	Warnings []schema.Issue // スキーマの警告 (未知のキーなど)
+	Manifest *Manifest
+	Stages   map[string]*StageDef
+	Skills   map[string]*SkillTable
+	Colors   Palette
+	Prefabs  map[string]*Prefab
+
+	layers []*layer
+	fsys   fs.FS
+}
+
+// FS - テーマのルートディレクトリ。基底テーマのファイルも見え、同じパスは継承先が優先される。
+func (t *Theme) FS() fs.FS {
+	return t.fsys
+}
+
+// MountName - テーマを仮想ファイルシステムにマウントするレイヤー名
+func MountName(id string) string {
+	return "theme:" + id
+}
+
+// Options - Manager の設定
+type Options struct {
+	// LoadSheet - プレイヤーのスプライトシートを切り分けて読み込む。
+	// nil の場合、プレハブはアニメーションを持たない。
+	LoadSheet anim.SheetLoader
+
+	// PackSprites - 敵のスプライトを1つのアトラスにまとめて読み込む。
+	// nil の場合、敵のプレハブは画像をそのままテクスチャに使う。
+	PackSprites SpritePacker
+
+	// Limits - アーカイブのエントリやプレビュー画像など、テーマから直接読み込むファイルに適用する上限。
+	// nil の場合は inspect.DefaultLimits のテーマの上限。
+	Limits *inspect.Limits
+
+	// MaxArchiveSize - インストールするアーカイブのサイズの上限 (バイト)。
+	// 0 の場合は DefaultMaxArchiveSize。
+	MaxArchiveSize int64
+
+	// GameVersion - 実行中のゲームのバージョン。テーマの game_version と比べる。
+	// 空の場合は確認しない。
+	GameVersion string
+}
+
+// Manager - テーマディレクトリ (themes/<ID>/theme.yaml) からテーマを読み込み、
+// 有効なテーマを仮想ファイルシステムのテーマレイヤーとしてマウントする。
+type Manager struct {
+	root     fs.FS
+	files    *vfs.FS
+	opts     Options
+	current  *Theme
+	archives map[string]*installed // Install でインストールしたアーカイブ (ID別)
+}
+
+// NewManager - root はテーマディレクトリ、files はテーマをマウントする仮想ファイルシステム
+func NewManager(root fs.FS, files *vfs.FS, opts Options) *Manager {
+	return &Manager{root: root, files: files, opts: opts}
+}
+
+// GetAvailableThemes - theme.yaml を持つテーマとインストールしたアーカイブのID (名前順)
+func (m *Manager) GetAvailableThemes() []string {
+	var names []string
+	for id := range m.archives {
+		names = append(names, id)
+	}
+	entries, _ := fs.ReadDir(m.root, ".")
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(m.root, path.Join(e.Name(), FileName)); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme - テーマと依存する基底テーマを読み込み、基底テーマから順に重ねて
+// ステージ定義・スキル表・アセット一覧を作る。有効なテーマは変えない。
+// マップはキーごと、id を持つ要素のリストは id ごとに重なるため、継承先には
+// 変更したい値だけを書けばよい。
+func (m *Manager) LoadTheme(name string) (*Theme, error) {
+	t, issues, err := m.Inspect(name)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		_, err := splitIssues(issues)
+		return nil, withThemeID(err, name)
+	}
+	return t, nil
+}
+
+// Inspect - LoadTheme と同じ手順でテーマを読み込み、重ねた theme.yaml のスキーマの問題を
+// 全て返す。スキーマのエラーがある場合、テーマは nil になる。依存関係の解決やパスの
+// 正規化に失敗した場合はエラーを返す。
+func (m *Manager) Inspect(name string) (*Theme, []schema.Issue, error) {
+	layers, err := m.resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	top := layers[len(layers)-1]
+	doc := mergeLayers(layers)
+	issues := schema.ValidateNode(top.file, doc, schema.ThemeSchema())
+	if schema.HasErrors(issues) {
+		return nil, issues, nil
+	}
+	spec, err := decodeSpec(doc)
+	if err != nil {
+		return nil, issues, withThemeID(err, name)
+	}
+	if err := spec.resolvePaths(); err != nil {
+		return nil, issues, err
+	}
+
+	fsys := vfs.New()
+	for _, l := range layers {
+		// 新しい仮想ファイルシステムに重複しない名前でマウントするので失敗しない
+		_ = fsys.Mount(MountName(l.id), l.fsys, vfs.MountOptions{Priority: vfs.PriorityTheme})
+	}
+	t := newTheme(spec, issues, fsys)
+	t.layers = layers
+	for _, l := range layers {
+		t.Chain = append(t.Chain, l.id)
+	}
+	return t, issues, nil
+}
+
+func newTheme(spec *Spec, warnings []schema.Issue, fsys fs.FS) *Theme {
+	t := &Theme{
+		ID:       spec.Metadata.ID,
+		Spec:     spec,
+		Warnings: warnings,
+		Manifest: BuildManifest(spec),
+		Stages:   make(map[string]*StageDef, len(spec.Stages.Locations)),
+		Skills:   make(map[string]*SkillTable, len(spec.Skills.MuscleSkills)),
+		Colors:   newPalette(spec.UI.ThemeColors),
+		fsys:     fsys,
+	}
+	for _, stage := range spec.Stages.Locations {
+		t.Stages[stage.ID] = newStageDef(spec, stage)
+	}
+	for _, skill := range spec.Skills.MuscleSkills {
+		t.Skills[skill.ID] = newSkillTable(skill)
+	}
+	return t
+}
+
+// SetTheme - テーマを読み込んで有効にする。前のテーマのレイヤーを外し、新しいテーマを
+// 基底テーマから順にマウントしてプレハブを作る。失敗した場合は前のテーマのまま変わらない。
+func (m *Manager) SetTheme(name string) error {
+	t, err := m.LoadTheme(name)
+	if err != nil {
+		return err
+	}
+
+	prev := m.current
+	m.unmount(prev)
+	restore := func() {
+		m.unmount(t)
+		_ = m.mount(prev)
+	}
+	if err := m.mount(t); err != nil {
+		restore()
+		return gameerr.ThemeError{Code: CodeMountFail, Message: fmt.Sprintf("テーマをマウントできません: %s: %v", name, err), ThemeID: name}
+	}
+
+	prefabs, err := buildPrefabs(t.Spec, m.opts.LoadSheet, m.opts.PackSprites)
+	if err != nil {
+		restore()
+		return err
+	}
+	t.Prefabs = prefabs
+	m.current = t
+	return nil
+}
+
+// mount - テーマのレイヤーを基底テーマから順にマウントする。同じ優先度では後のレイヤーが優先される。
+func (m *Manager) mount(t *Theme) error {
+	if t == nil {
+		return nil
+	}
+	for _, l := range t.layers {
+		if err := m.files.Mount(MountName(l.id), l.fsys, vfs.MountOptions{Priority: vfs.PriorityTheme}); err != nil {
+			m.unmount(t)
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) unmount(t *Theme) {
+	if t == nil {
+		return
+	}
+	for _, l := range t.layers {
+		m.files.Unmount(MountName(l.id))
+	}
+}
+
+// GetCurrentTheme - 有効なテーマ。まだ設定されていなければ nil。
+func (m *Manager) GetCurrentTheme() *Theme {
+	return m.current
+}
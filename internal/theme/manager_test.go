@@ -0,0 +1,292 @@
+package theme_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+const gymTheme = `
+metadata: {id: gym, name: Gym, version: 1.0.0}
+characters:
+  player:
+    stats: {base_speed: 150, base_health: 120}
+skills:
+  muscle_skills:
+    - id: curl
+      name: Curl
+      max_level: 3
+      levels:
+        1: {damage: 30, range: 150}
+        3: {damage: 50}
+stages:
+  locations:
+    - id: gym
+      name: Gym
+      background:
+        layers:
+          - {file: ./assets/stages/front.png, z_order: 2}
+          - {file: assets/stages/back.png, z_order: -1}
+`
+
+func themesFS() fstest.MapFS {
+	return fstest.MapFS{
+		"beach/theme.yaml":                 {Data: []byte(beachTheme)},
+		"beach/assets/characters/idle.png": {Data: []byte("beach idle")},
+		"gym/theme.yaml":                   {Data: []byte(gymTheme)},
+		"gym/assets/characters/idle.png":   {Data: []byte("gym idle")},
+		"broken/theme.yaml":                {Data: []byte("metadata: {id: broken}")},
+		"renamed/theme.yaml":               {Data: []byte("metadata: {id: other, name: Other, version: 1.0.0}")},
+		"notes/readme.txt":                 {Data: []byte("not a theme")},
+	}
+}
+
+// sheetLoader - 読み込んだシートを記録し、シートのパスをテクスチャ名として返す
+type sheetLoader struct {
+	loaded []string
+	fail   bool
+}
+
+func (l *sheetLoader) load(sheet string, _ atlas.Grid) (string, error) {
+	if l.fail {
+		return "", errors.New("読み込み失敗")
+	}
+	l.loaded = append(l.loaded, sheet)
+	return sheet, nil
+}
+
+func newManager(t *testing.T, sheets *sheetLoader) (*theme.Manager, *vfs.FS) {
+	t.Helper()
+	files := vfs.New(vfs.DefaultRoots...)
+	require.NoError(t, files.Mount("base", fstest.MapFS{
+		"assets/characters/idle.png": {Data: []byte("base idle")},
+	}, vfs.MountOptions{Priority: vfs.PriorityBase}))
+	return theme.NewManager(themesFS(), files, theme.Options{LoadSheet: sheets.load}), files
+}
+
+func TestThemeManager(t *testing.T) {
+	t.Run("ListsThemeDirectories", func(t *testing.T) {
+		m, _ := newManager(t, &sheetLoader{})
+		assert.Equal(t, []string{"beach", "broken", "gym", "renamed"}, m.GetAvailableThemes())
+		assert.Nil(t, m.GetCurrentTheme())
+	})
+
+	t.Run("LoadErrors", func(t *testing.T) {
+		m, _ := newManager(t, &sheetLoader{})
+		testCases := []struct {
+			name string
+			code string
+		}{
+			{"missing", theme.CodeNotFound},
+			{"../beach", theme.CodeNotFound},
+			{"broken", theme.CodeInvalidSpec},
+			{"renamed", theme.CodeIDMismatch},
+		}
+		for _, tc := range testCases {
+			_, err := m.LoadTheme(tc.name)
+			assert.Equal(t, tc.code, gameerr.CodeOf(err), tc.name)
+		}
+	})
+
+	t.Run("ResolvesAssetPaths", func(t *testing.T) {
+		m, _ := newManager(t, &sheetLoader{})
+		gym, err := m.LoadTheme("gym")
+		require.NoError(t, err)
+
+		stage := gym.Stages["gym"]
+		require.Len(t, stage.Background, 2)
+		assert.Equal(t, "assets/stages/back.png", stage.Background[0].File, "奥のレイヤーから並ぶ")
+		assert.Equal(t, "assets/stages/front.png", stage.Background[1].File)
+		assert.Equal(t, theme.DefaultStageDuration, stage.Duration)
+		assert.Equal(t, 1.0, stage.Difficulty)
+	})
+
+	t.Run("ResolvesScriptPaths", func(t *testing.T) {
+		const spec = "metadata: {id: forest, name: Forest, version: 1.0.0}\nscripts: [{file: %q}]\n"
+		m := theme.NewManager(fstest.MapFS{"forest/theme.yaml": {Data: []byte(fmt.Sprintf(spec, "./scripts/ai.lua"))}}, vfs.New(), theme.Options{})
+		forest, err := m.LoadTheme("forest")
+		require.NoError(t, err)
+		assert.Equal(t, []theme.Script{{File: "scripts/ai.lua"}}, forest.Spec.Scripts)
+
+		m = theme.NewManager(fstest.MapFS{"forest/theme.yaml": {Data: []byte(fmt.Sprintf(spec, "../gym/ai.lua"))}}, vfs.New(), theme.Options{})
+		_, err = m.LoadTheme("forest")
+		assert.ErrorContains(t, err, "scripts[0].file", "テーマの外のスクリプトは読み込まない")
+	})
+
+	t.Run("SetThemeMountsLayerAndBuildsPrefabs", func(t *testing.T) {
+		sheets := &sheetLoader{}
+		m, files := newManager(t, sheets)
+
+		require.NoError(t, m.SetTheme("beach"))
+		data, err := files.ReadFile("assets/characters/idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, "beach idle", string(data))
+		assert.Equal(t, []string{"assets/characters/idle.png"}, sheets.loaded)
+
+		current := m.GetCurrentTheme()
+		require.NotNil(t, current)
+		assert.Equal(t, "beach", current.ID)
+		require.Contains(t, current.Prefabs, theme.PrefabPlayer)
+		require.Contains(t, current.Prefabs, "burger")
+
+		require.NoError(t, m.SetTheme("gym"))
+		data, err = files.ReadFile("assets/characters/idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, "gym idle", string(data), "前のテーマのレイヤーは外れる")
+		assert.Equal(t, "gym", m.GetCurrentTheme().ID)
+	})
+
+	t.Run("FailedSetThemeKeepsPrevious", func(t *testing.T) {
+		sheets := &sheetLoader{}
+		m, files := newManager(t, sheets)
+		require.NoError(t, m.SetTheme("gym"))
+
+		sheets.fail = true
+		err := m.SetTheme("beach")
+		assert.Equal(t, theme.CodePrefabFailed, gameerr.CodeOf(err))
+		assert.Equal(t, "gym", m.GetCurrentTheme().ID)
+		data, err := files.ReadFile("assets/characters/idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, "gym idle", string(data))
+
+		assert.Error(t, m.SetTheme("broken"))
+		assert.Equal(t, "gym", m.GetCurrentTheme().ID)
+	})
+}
+
+func TestResolvePath(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"assets/ui/bar.png", "assets/ui/bar.png", true},
+		{"./assets//ui/../ui/bar.png", "assets/ui/bar.png", true},
+		{"assets\\ui\\bar.png", "assets/ui/bar.png", true},
+		{"../other/assets/bar.png", "", false},
+		{"assets/../../bar.png", "", false},
+		{"/etc/passwd", "", false},
+		{".", "", false},
+	}
+	for _, tc := range testCases {
+		got, ok := theme.ResolvePath(tc.in)
+		assert.Equal(t, tc.ok, ok, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+}
+
+func TestPrefab(t *testing.T) {
+	m, _ := newManager(t, &sheetLoader{})
+	require.NoError(t, m.SetTheme("beach"))
+	prefabs := m.GetCurrentTheme().Prefabs
+
+	t.Run("PlayerHasAnimationAndStats", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		id, err := prefabs[theme.PrefabPlayer].Spawn(em, 10, 20)
+		require.NoError(t, err)
+
+		transform, err := em.GetComponent(id, ecs.TransformComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, ecs.Vector2{X: 10, Y: 20}, transform.(*ecs.TransformComponent).Position)
+
+		animation, err := em.GetComponent(id, ecs.AnimationComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, "idle", animation.(*ecs.AnimationComponent).Current)
+
+		sprite, err := em.GetComponent(id, ecs.SpriteComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, "assets/characters/idle.png#0", sprite.(*ecs.SpriteComponent).SourceRect)
+
+		protein, err := em.GetComponent(id, ecs.ProteinComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, 80.0, protein.(*ecs.ProteinComponent).Capacity)
+	})
+
+	t.Run("SpawnedEntitiesDoNotShareComponents", func(t *testing.T) {
+		em := ecs.NewEntityManager()
+		first, err := prefabs["burger"].Spawn(em, 0, 0)
+		require.NoError(t, err)
+		second, err := prefabs["burger"].Spawn(em, 5, 5)
+		require.NoError(t, err)
+
+		health, err := em.GetComponent(first, ecs.HealthComponentType)
+		require.NoError(t, err)
+		health.(*ecs.HealthComponent).Current = 0
+
+		other, err := em.GetComponent(second, ecs.HealthComponentType)
+		require.NoError(t, err)
+		assert.NotSame(t, health, other)
+		assert.Equal(t, 1.0, other.(*ecs.HealthComponent).Ratio())
+	})
+
+	t.Run("EnemySpritesArePackedIntoOneAtlas", func(t *testing.T) {
+		var packed [][]string
+		m := theme.NewManager(themesFS(), vfs.New(), theme.Options{
+			PackSprites: func(paths []string) (string, error) {
+				packed = append(packed, paths)
+				return "atlas:enemies", nil
+			},
+		})
+		require.NoError(t, m.SetTheme("beach"))
+		assert.Equal(t, [][]string{{"assets/enemies/boss.png", "assets/enemies/burger.png"}}, packed)
+
+		em := ecs.NewEntityManager()
+		id, err := m.GetCurrentTheme().Prefabs["burger"].Spawn(em, 0, 0)
+		require.NoError(t, err)
+		sprite, err := em.GetComponent(id, ecs.SpriteComponentType)
+		require.NoError(t, err)
+		assert.Equal(t, "atlas:enemies", sprite.(*ecs.SpriteComponent).Texture)
+		assert.Equal(t, "assets/enemies/burger.png", sprite.(*ecs.SpriteComponent).SourceRect)
+	})
+
+	t.Run("PackFailureKeepsPreviousTheme", func(t *testing.T) {
+		m := theme.NewManager(themesFS(), vfs.New(), theme.Options{
+			PackSprites: func([]string) (string, error) { return "", errors.New("大きすぎます") },
+		})
+		require.NoError(t, m.SetTheme("gym"), "敵がいなければ詰め込まない")
+		assert.Equal(t, theme.CodePrefabFailed, gameerr.CodeOf(m.SetTheme("beach")))
+		assert.Equal(t, "gym", m.GetCurrentTheme().ID)
+	})
+}
+
+func TestTables(t *testing.T) {
+	m, _ := newManager(t, &sheetLoader{})
+	beach, err := m.LoadTheme("beach")
+	require.NoError(t, err)
+
+	t.Run("SkillLevelsCarryForward", func(t *testing.T) {
+		gym, err := m.LoadTheme("gym")
+		require.NoError(t, err)
+		curl := gym.Skills["curl"]
+		require.NotNil(t, curl)
+		assert.Equal(t, 3, curl.MaxLevel)
+		assert.Equal(t, map[string]float64{"damage": 30, "range": 150}, curl.Level(2))
+		assert.Equal(t, map[string]float64{"damage": 50, "range": 150}, curl.Level(3))
+		assert.Equal(t, curl.Level(3), curl.Level(9), "範囲外のレベルは丸める")
+		_, ok := curl.Value(1, "cooldown")
+		assert.False(t, ok)
+
+		assert.Equal(t, 1, beach.Skills["protein_beam"].MaxLevel, "max_level 省略時は定義済みの最大レベル")
+	})
+
+	t.Run("StageSpawnsAndEvents", func(t *testing.T) {
+		stage := beach.Stages["muscle_beach"]
+		require.NotNil(t, stage)
+		assert.Equal(t, []theme.Spawn{{Enemy: "burger", Weight: 1}, {Enemy: "boss", Weight: 1}}, stage.Spawns)
+		events := stage.EventsBetween(100*time.Second, 130*time.Second)
+		require.Len(t, events, 1)
+		assert.Equal(t, "boss", events[0].Enemy)
+		assert.Empty(t, stage.EventsBetween(0, 60*time.Second))
+	})
+}
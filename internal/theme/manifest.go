@@ -0,0 +1,272 @@
+package theme
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/atlas"
+)
+
+// アセットグループ名。ステージのグループは StageGroup で作る。
+const (
+	GroupCharacters = "characters"
+	GroupEnemies    = "enemies"
+	GroupSkills     = "skills"
+	GroupUI         = "ui"
+)
+
+// AssetDir - テーマ内でアセットを置くディレクトリ
+const AssetDir = "assets"
+
+// StageGroup - ステージ専用アセットのグループ名
+func StageGroup(stageID string) string {
+	return "stage:" + stageID
+}
+
+// AssetKind - 参照されているアセットの種類 (拡張子で決まる)
+type AssetKind int
+
+const (
+	AssetImage AssetKind = iota
+	AssetAudio
+	AssetFont
+	AssetAtlas
+	AssetUnknown
+)
+
+// KindOf - パスの拡張子からアセットの種類を判定する
+func KindOf(p string) AssetKind {
+	if strings.HasSuffix(p, atlas.Ext) {
+		return AssetAtlas
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".png", ".jpg", ".jpeg":
+		return AssetImage
+	case ".ogg", ".wav", ".mp3":
+		return AssetAudio
+	case ".ttf", ".otf":
+		return AssetFont
+	default:
+		return AssetUnknown
+	}
+}
+
+// Ref - 定義から参照されているアセット
+type Ref struct {
+	Path   string
+	Kind   AssetKind
+	Group  string
+	Source string // 参照している定義の位置 (例: "stages.locations[0].audio.bgm")
+}
+
+// Manifest - テーマ定義から集めたアセットの一覧。グループ単位で読み込み・解放する。
+type Manifest struct {
+	groups     map[string][]Ref
+	preload    []string
+	unresolved []string
+}
+
+// BuildManifest - キャラクター・敵・ステージ・スキル・UI の定義をたどって参照されている
+// アセットを集める。同じグループ内の重複は最初の参照だけを残す。
+func BuildManifest(spec *Spec) *Manifest {
+	b := &manifestBuilder{m: &Manifest{groups: make(map[string][]Ref)}, seen: make(map[string]bool)}
+
+	spec.walkAssets(func(group, source string, p *string) {
+		b.add(group, *p, source)
+	})
+	for i, stage := range spec.Stages.Locations {
+		group, prefix := StageGroup(stage.ID), indexed("stages.locations", i)
+		for j, id := range stage.SpecialEnemies {
+			b.addEnemy(spec, group, id, indexed(prefix+".special_enemies", j))
+		}
+		for j, event := range stage.Config.SpecialEvents {
+			if event.Enemy != "" {
+				b.addEnemy(spec, group, event.Enemy, indexed(prefix+".stage_config.special_events", j)+".enemy")
+			}
+		}
+		// グループが空でもステージとして存在することを記録する
+		if _, ok := b.m.groups[group]; !ok {
+			b.m.groups[group] = nil
+		}
+	}
+
+	b.m.preload = append([]string(nil), spec.Performance.AssetPreloading...)
+	return b.m
+}
+
+type manifestBuilder struct {
+	m    *Manifest
+	seen map[string]bool // グループ名 + "\x00" + パス
+}
+
+func (b *manifestBuilder) add(group, p, source string) {
+	if p == "" {
+		return
+	}
+	key := group + "\x00" + p
+	if b.seen[key] {
+		return
+	}
+	b.seen[key] = true
+	b.m.groups[group] = append(b.m.groups[group], Ref{Path: p, Kind: KindOf(p), Group: group, Source: source})
+}
+
+// addEnemy - ステージ専用の敵のスプライトをステージのグループに加える。
+// 定義されていない敵は未解決として記録する。
+func (b *manifestBuilder) addEnemy(spec *Spec, group, id, source string) {
+	enemy, ok := spec.Enemy(id)
+	if !ok {
+		b.m.unresolved = append(b.m.unresolved, source+": "+id)
+		return
+	}
+	b.add(group, enemy.Sprite, source)
+}
+
+// Groups - グループ名の一覧 (名前順)
+func (m *Manifest) Groups() []string {
+	return sortedKeys(m.groups)
+}
+
+// Group - グループに属するアセット
+func (m *Manifest) Group(name string) []Ref {
+	return m.groups[name]
+}
+
+// HasStage - ステージが定義されているか
+func (m *Manifest) HasStage(stageID string) bool {
+	_, ok := m.groups[StageGroup(stageID)]
+	return ok
+}
+
+// Preload - performance.asset_preloading に指定されたグループ
+func (m *Manifest) Preload() []string {
+	return m.preload
+}
+
+// StageAssets - ステージ開始時に読み込むアセット。asset_preloading のグループと
+// ステージ専用のグループ、extra のグループを合わせ、パスの重複を取り除いたもの。
+// それ以外のグループのアセットは初めて使われたときに読み込まれる。
+func (m *Manifest) StageAssets(stageID string, extra ...string) []Ref {
+	groups := append(append([]string(nil), m.preload...), StageGroup(stageID))
+	return m.collect(append(groups, extra...))
+}
+
+// PreloadAssets - テーマを有効にしたときに読み込むアセット (asset_preloading のグループ)
+func (m *Manifest) PreloadAssets() []Ref {
+	return m.collect(m.preload)
+}
+
+// collect - グループのアセットをパスの重複を取り除いて並べる
+func (m *Manifest) collect(groups []string) []Ref {
+	seen := make(map[string]bool)
+	var refs []Ref
+	for _, group := range groups {
+		for _, ref := range m.groups[group] {
+			if !seen[ref.Path] {
+				seen[ref.Path] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// Report - マニフェストとテーマのファイルを突き合わせた結果
+type Report struct {
+	Missing      []Ref    // 参照されているが存在しないファイル
+	Unreferenced []string // assets/ 以下にあるがどこからも参照されていないファイル
+	Unresolved   []string // 定義されていない敵ID ("参照元: ID")
+	UnknownGroup []string // asset_preloading に指定された存在しないグループ
+}
+
+// OK - 問題がないか
+func (r Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.Unreferenced) == 0 && len(r.Unresolved) == 0 && len(r.UnknownGroup) == 0
+}
+
+// Check - テーマのルートを fsys として、参照されているファイルの有無と
+// 参照されていないファイルを調べる。アトラス定義が参照するページ画像は参照済みとみなす。
+// 読み込むのはアトラス定義だけで、limits のファイルサイズの上限まで読む。
+func (m *Manifest) Check(fsys fs.FS, limits inspect.Limits) (Report, error) {
+	report := Report{Unresolved: m.unresolved}
+	for _, group := range m.preload {
+		if _, ok := m.groups[group]; !ok {
+			report.UnknownGroup = append(report.UnknownGroup, group)
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, group := range m.Groups() {
+		for _, ref := range m.groups[group] {
+			if referenced[ref.Path] {
+				continue
+			}
+			referenced[ref.Path] = true
+			info, err := fs.Stat(fsys, ref.Path)
+			if err != nil || info.IsDir() {
+				report.Missing = append(report.Missing, ref)
+				continue
+			}
+			if ref.Kind == AssetAtlas {
+				for _, page := range atlasPages(fsys, ref.Path, info.Size(), limits) {
+					referenced[page] = true
+				}
+			}
+		}
+	}
+	// アトラスのページ自体が存在しない場合は読み込み時にエラーになるので、ここでは扱わない
+
+	err := fs.WalkDir(fsys, AssetDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !referenced[p] && KindOf(p) != AssetUnknown {
+			report.Unreferenced = append(report.Unreferenced, p)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return report, err
+	}
+	return report, nil
+}
+
+// atlasPages - アトラス定義が参照するページ画像。読めない定義や上限を超える定義は
+// アセットの検証で報告されるので、ここでは空を返す。
+func atlasPages(fsys fs.FS, p string, size int64, limits inspect.Limits) []string {
+	if limits.CheckFileSize(p, size) != nil {
+		return nil
+	}
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	data, err := limits.ReadLimited(p, f)
+	if err != nil {
+		return nil
+	}
+	a, err := atlas.Parse(data)
+	if err != nil {
+		return nil
+	}
+	return a.PagePaths(p)
+}
+
+func indexed(prefix string, i int) string {
+	return prefix + "[" + strconv.Itoa(i) + "]"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package theme
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"muscle-dreamer/internal/gameerr"
+)
+
+// CodeInvalidPath - テーマの外を指すアセットパス
+const CodeInvalidPath = "THEME_INVALID_PATH"
+
+// walkAssets - 定義中のアセットパスを全て訪れる。visit には書き換えられるよう
+// フィールドへのポインタを渡す。ステージ専用の敵のスプライトは敵の定義側で訪れる。
+func (s *Spec) walkAssets(visit func(group, source string, p *string)) {
+	player := &s.Characters.Player
+	for _, name := range sortedKeys(player.SpriteSheets) {
+		p := player.SpriteSheets[name]
+		visit(GroupCharacters, "characters.player.sprite_sheets."+name, &p)
+		player.SpriteSheets[name] = p
+	}
+
+	for i := range s.Enemies.Categories {
+		category := &s.Enemies.Categories[i]
+		for j := range category.Enemies {
+			visit(GroupEnemies, indexed("enemies.categories", i)+indexed(".enemies", j)+".sprite", &category.Enemies[j].Sprite)
+		}
+	}
+
+	for i := range s.Skills.MuscleSkills {
+		skill, prefix := &s.Skills.MuscleSkills[i], indexed("skills.muscle_skills", i)
+		visit(GroupSkills, prefix+".icon", &skill.Icon)
+		for _, name := range sortedKeys(skill.Effects) {
+			// エフェクトにはアセット以外の値も書けるため、拡張子で判別できるものだけを対象にする
+			p := skill.Effects[name]
+			if KindOf(p) == AssetUnknown {
+				continue
+			}
+			visit(GroupSkills, prefix+".effects."+name, &p)
+			skill.Effects[name] = p
+		}
+	}
+
+	for _, name := range sortedKeys(s.UI.Fonts) {
+		p := s.UI.Fonts[name]
+		visit(GroupUI, "ui.fonts."+name, &p)
+		s.UI.Fonts[name] = p
+	}
+	visit(GroupUI, "ui.menu_bgm", &s.UI.MenuBGM)
+	for _, name := range sortedKeys(s.UI.Elements) {
+		element := s.UI.Elements[name]
+		visit(GroupUI, "ui.elements."+name+".sprite", &element.Sprite)
+		s.UI.Elements[name] = element
+	}
+
+	for i := range s.Stages.Locations {
+		stage := &s.Stages.Locations[i]
+		group, prefix := StageGroup(stage.ID), indexed("stages.locations", i)
+		for j := range stage.Background.Layers {
+			visit(group, indexed(prefix+".background.layers", j)+".file", &stage.Background.Layers[j].File)
+		}
+		visit(group, prefix+".audio.bgm", &stage.Audio.BGM)
+		for j := range stage.Audio.AmbientSounds {
+			visit(group, indexed(prefix+".audio.ambient_sounds", j)+".file", &stage.Audio.AmbientSounds[j].File)
+		}
+	}
+}
+
+// resolvePaths - アセットとスクリプトのパスをテーマのルートからのスラッシュ区切りの相対パスに揃える。
+// "./" や "\\" を含む書き方は正規化し、テーマの外を指すパスはエラーにする。
+func (s *Spec) resolvePaths() error {
+	var err error
+	s.walkAssets(func(_, source string, p *string) {
+		if *p == "" || err != nil {
+			return
+		}
+		resolved, ok := ResolvePath(*p)
+		if !ok {
+			err = gameerr.ThemeError{
+				Code:    CodeInvalidPath,
+				Message: fmt.Sprintf("%s: テーマの外を指すパスです: %q", source, *p),
+				ThemeID: s.Metadata.ID,
+			}
+			return
+		}
+		*p = resolved
+	})
+	if err != nil {
+		return err
+	}
+	for i := range s.Scripts {
+		resolved, ok := ResolvePath(s.Scripts[i].File)
+		if !ok {
+			return gameerr.ThemeError{
+				Code:    CodeInvalidPath,
+				Message: fmt.Sprintf("%s.file: テーマの外を指すパスです: %q", indexed("scripts", i), s.Scripts[i].File),
+				ThemeID: s.Metadata.ID,
+			}
+		}
+		s.Scripts[i].File = resolved
+	}
+	return nil
+}
+
+// ResolvePath - テーマ内のアセットパスを正規化する。テーマの外を指す場合は false。
+func ResolvePath(p string) (string, bool) {
+	p = strings.ReplaceAll(p, "\\", "/")
+	if strings.HasPrefix(p, "/") {
+		return "", false
+	}
+	p = path.Clean(p)
+	if !fs.ValidPath(p) || p == "." {
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,151 @@
+package theme
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/core/ecs"
+	"muscle-dreamer/internal/gameerr"
+)
+
+// CodePrefabFailed - プレハブを作成できない (スプライトシートの読み込み失敗など)
+const CodePrefabFailed = "THEME_PREFAB_FAILED"
+
+// PrefabPlayer - プレイヤーのプレハブID。敵のプレハブは敵IDを使う。
+const PrefabPlayer = "player"
+
+// 描画レイヤー
+const (
+	LayerEnemy  = 10
+	LayerPlayer = 20
+)
+
+// SpritePacker - 画像を1つのアトラスにまとめて読み込み、テクスチャ名を返す。
+// アトラスの領域名は各画像のパスになる。
+type SpritePacker func(paths []string) (texture string, err error)
+
+// Prefab - エンティティの雛形。Spawn するたびにコンポーネントを複製して使う。
+type Prefab struct {
+	ID         string
+	Components []ecs.Component
+}
+
+// Spawn - (x, y) にエンティティを作成する
+func (p *Prefab) Spawn(em *ecs.EntityManager, x, y float64) (ecs.EntityID, error) {
+	id := em.CreateEntity()
+	for _, c := range p.Components {
+		clone := c.Clone()
+		if t, ok := clone.(*ecs.TransformComponent); ok {
+			t.Position = ecs.Vector2{X: x, Y: y}
+		}
+		if err := em.AddComponent(id, clone); err != nil {
+			_ = em.DestroyEntity(id)
+			return ecs.EntityID{}, err
+		}
+	}
+	return id, nil
+}
+
+// buildPrefabs - プレイヤーと全ての敵のプレハブを作る。プレイヤーのアニメーションは
+// load でスプライトシートを切り分けて作る。load が nil ならアニメーションを持たない。
+// 敵のスプライトは pack で1つのアトラスにまとめ、まとめて描画できるようにする。
+// pack が nil なら画像をそのままテクスチャに使う。
+func buildPrefabs(spec *Spec, load anim.SheetLoader, pack SpritePacker) (map[string]*Prefab, error) {
+	prefabs := make(map[string]*Prefab)
+
+	player := spec.Characters.Player
+	var clips anim.Library
+	if load != nil {
+		var err error
+		clips, err = anim.Load(player.SpriteSheets, player.Animations, load)
+		if err != nil {
+			return nil, gameerr.ThemeError{
+				Code:    CodePrefabFailed,
+				Message: fmt.Sprintf("プレイヤーのアニメーションを作成できません: %v", err),
+				ThemeID: spec.Metadata.ID,
+			}
+		}
+	}
+	components := []ecs.Component{
+		ecs.NewTransformComponent(0, 0),
+		&ecs.VelocityComponent{MaxSpeed: player.Stats["base_speed"]},
+		ecs.NewHealthComponent(int(math.Round(player.Stats["base_health"]))),
+		ecs.NewProteinComponent(player.Stats["protein_capacity"]),
+		newCollision(&player.Collision),
+	}
+	if len(clips) > 0 {
+		// 最初の更新より前に描画されても表示できるよう、先頭のフレームを設定しておく
+		initial := clips[initialClip(clips)]
+		sprite := ecs.NewSpriteComponent(initial.Texture, initial.Region(0))
+		sprite.Layer = LayerPlayer
+		components = append(components, sprite, ecs.NewAnimationComponent(clips, initial.Name))
+	}
+	prefabs[PrefabPlayer] = &Prefab{ID: PrefabPlayer, Components: components}
+
+	enemyTexture := ""
+	if paths := enemySprites(spec); pack != nil && len(paths) > 0 {
+		var err error
+		enemyTexture, err = pack(paths)
+		if err != nil {
+			return nil, gameerr.ThemeError{
+				Code:    CodePrefabFailed,
+				Message: fmt.Sprintf("敵のスプライトをアトラスにまとめられません: %v", err),
+				ThemeID: spec.Metadata.ID,
+			}
+		}
+	}
+	for _, category := range spec.Enemies.Categories {
+		for _, enemy := range category.Enemies {
+			sprite := ecs.NewSpriteComponent(enemy.Sprite, "")
+			if enemyTexture != "" && enemy.Sprite != "" {
+				sprite = ecs.NewSpriteComponent(enemyTexture, enemy.Sprite)
+			}
+			sprite.Layer = LayerEnemy
+			prefabs[enemy.ID] = &Prefab{ID: enemy.ID, Components: []ecs.Component{
+				ecs.NewTransformComponent(0, 0),
+				sprite,
+				&ecs.VelocityComponent{MaxSpeed: enemy.Speed},
+				ecs.NewHealthComponent(int(math.Round(enemy.Health))),
+				newCollision(enemy.Collision),
+			}}
+		}
+	}
+	return prefabs, nil
+}
+
+// enemySprites - 敵のスプライトのパス (重複なし、名前順)
+func enemySprites(spec *Spec) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, category := range spec.Enemies.Categories {
+		for _, enemy := range category.Enemies {
+			if enemy.Sprite != "" && !seen[enemy.Sprite] {
+				seen[enemy.Sprite] = true
+				paths = append(paths, enemy.Sprite)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// initialClip - 最初に再生するクリップ。idle がなければ名前順で最初のもの。
+func initialClip(clips anim.Library) string {
+	if _, ok := clips["idle"]; ok {
+		return "idle"
+	}
+	return sortedKeys(clips)[0]
+}
+
+// newCollision - 当たり判定の指定がない場合は大きさ0 (判定なし) にする
+func newCollision(c *Collision) *ecs.CollisionComponent {
+	if c == nil {
+		return &ecs.CollisionComponent{}
+	}
+	return &ecs.CollisionComponent{
+		Size:   ecs.Vector2{X: c.Width, Y: c.Height},
+		Offset: ecs.Vector2{X: c.OffsetX, Y: c.OffsetY},
+	}
+}
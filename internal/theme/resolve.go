@@ -0,0 +1,350 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/semver"
+)
+
+// 依存関係のエラーコード
+const (
+	CodeDependencyCycle = "THEME_DEPENDENCY_CYCLE"
+	CodeUnsatisfied     = "THEME_DEPENDENCY_UNSATISFIED"
+	CodeIncompatible    = "THEME_INCOMPATIBLE_GAME"
+)
+
+// Dependency - metadata.dependencies の1項目 ("base_theme" または "base_theme >=1.0.0")
+type Dependency struct {
+	ID         string
+	Constraint semver.Constraint // 省略した場合は任意のバージョン
+}
+
+// ParseDependency - 依存テーマの指定を解析する
+func ParseDependency(s string) (Dependency, error) {
+	id, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	if id == "" {
+		return Dependency{}, fmt.Errorf("依存テーマのIDが空です")
+	}
+	dep := Dependency{ID: id}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		c, err := semver.ParseConstraint(rest)
+		if err != nil {
+			return Dependency{}, err
+		}
+		dep.Constraint = c
+	}
+	return dep, nil
+}
+
+// layer - 継承チェーン上の1テーマ分の theme.yaml
+type layer struct {
+	id   string
+	file string
+	doc  *yaml.Node
+	meta Metadata
+	fsys fs.FS
+}
+
+// resolver - 依存テーマを深さ優先でたどり、基底テーマが先に来る順に並べる。
+// 複数のテーマから依存されるテーマは1度だけ並ぶ。
+type resolver struct {
+	m      *Manager
+	game   *semver.Version
+	layers []*layer
+	done   map[string]*layer
+	stack  []string
+}
+
+func (m *Manager) resolve(name string) ([]*layer, error) {
+	r := &resolver{m: m, done: make(map[string]*layer)}
+	if m.opts.GameVersion != "" {
+		v, err := semver.Parse(m.opts.GameVersion)
+		if err != nil {
+			return nil, fmt.Errorf("ゲームのバージョンを解釈できません: %w", err)
+		}
+		r.game = &v
+	}
+	if _, err := r.visit(name); err != nil {
+		return nil, err
+	}
+	return r.layers, nil
+}
+
+func (r *resolver) visit(name string) (*layer, error) {
+	if l, ok := r.done[name]; ok {
+		return l, nil
+	}
+	if i := slices.Index(r.stack, name); i >= 0 {
+		cycle := append(slices.Clone(r.stack[i:]), name)
+		return nil, gameerr.ThemeError{
+			Code:    CodeDependencyCycle,
+			Message: fmt.Sprintf("テーマの依存関係が循環しています: %s", strings.Join(cycle, " → ")),
+			ThemeID: r.stack[0],
+		}
+	}
+	r.stack = append(r.stack, name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	l, err := r.m.readLayer(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkGame(l); err != nil {
+		return nil, err
+	}
+	for _, s := range l.meta.Dependencies {
+		dep, err := ParseDependency(s)
+		if err != nil {
+			return nil, gameerr.ThemeError{
+				Code:    CodeInvalidSpec,
+				Message: fmt.Sprintf("%s: 依存テーマの指定 %q を解釈できません: %v", l.file, s, err),
+				ThemeID: name,
+			}
+		}
+		base, err := r.visit(dep.ID)
+		if err != nil {
+			var themeErr gameerr.ThemeError
+			if errors.As(err, &themeErr) && themeErr.Code == CodeNotFound && themeErr.ThemeID == dep.ID {
+				return nil, gameerr.ThemeError{
+					Code:    CodeUnsatisfied,
+					Message: fmt.Sprintf("%s: 依存テーマ %s が見つかりません", l.file, dep.ID),
+					ThemeID: name,
+				}
+			}
+			return nil, err
+		}
+		if err := checkDependency(l, dep, base); err != nil {
+			return nil, err
+		}
+	}
+
+	r.done[name] = l
+	r.layers = append(r.layers, l)
+	return l, nil
+}
+
+// checkGame - テーマの game_version が実行中のゲームのバージョンを許すか確認する
+func (r *resolver) checkGame(l *layer) error {
+	if r.game == nil || l.meta.GameVersion == "" {
+		return nil
+	}
+	c, err := semver.ParseConstraint(l.meta.GameVersion)
+	if err != nil {
+		return gameerr.ThemeError{Code: CodeInvalidSpec, Message: fmt.Sprintf("%s: game_version: %v", l.file, err), ThemeID: l.id}
+	}
+	if !c.Check(*r.game) {
+		return gameerr.ThemeError{
+			Code:    CodeIncompatible,
+			Message: fmt.Sprintf("テーマ %s はゲームバージョン %s を必要としますが、実行中のバージョンは %s です", l.id, c, r.game),
+			ThemeID: l.id,
+		}
+	}
+	return nil
+}
+
+// checkDependency - 依存テーマのバージョンが制約を満たすか確認する
+func checkDependency(l *layer, dep Dependency, base *layer) error {
+	v, err := semver.Parse(base.meta.Version)
+	if err != nil {
+		return gameerr.ThemeError{
+			Code:    CodeUnsatisfied,
+			Message: fmt.Sprintf("%s: 依存テーマ %s のバージョン %q を解釈できません", l.file, dep.ID, base.meta.Version),
+			ThemeID: l.id,
+		}
+	}
+	if !dep.Constraint.Check(v) {
+		return gameerr.ThemeError{
+			Code:    CodeUnsatisfied,
+			Message: fmt.Sprintf("%s: 依存テーマ %s は %s が必要ですが、バージョン %s です", l.file, dep.ID, dep.Constraint, v),
+			ThemeID: l.id,
+		}
+	}
+	return nil
+}
+
+// readLayer - テーマの theme.yaml を合成前の状態で読み込む。スキーマの検証は合成後に行う。
+func (m *Manager) readLayer(name string) (*layer, error) {
+	if !fs.ValidPath(name) || name == "." || strings.Contains(name, "/") {
+		return nil, gameerr.ThemeError{Code: CodeNotFound, Message: fmt.Sprintf("不正なテーマ名です: %q", name), ThemeID: name}
+	}
+	file := path.Join(name, FileName)
+	fsys, err := m.themeFS(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(fsys, FileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, gameerr.ThemeError{Code: CodeNotFound, Message: fmt.Sprintf("テーマが見つかりません: %s", name), ThemeID: name}
+		}
+		return nil, gameerr.ThemeError{Code: CodeNotFound, Message: fmt.Sprintf("テーマを読み込めません: %s: %v", name, err), ThemeID: name}
+	}
+
+	doc := new(yaml.Node)
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		_, err := splitIssues(schema.Validate(file, data, schema.Any()))
+		return nil, withThemeID(err, name)
+	}
+	var meta struct {
+		Metadata Metadata `yaml:"metadata"`
+	}
+	if decodeErr := doc.Decode(&meta); decodeErr != nil {
+		// 型の誤りはスキーマの検証で場所とともに報告する
+		_, err := splitIssues(schema.Validate(file, data, schema.ThemeSchema()))
+		if err == nil {
+			err = gameerr.ThemeError{Code: CodeInvalidSpec, Message: fmt.Sprintf("%s: %v", file, decodeErr)}
+		}
+		return nil, withThemeID(err, name)
+	}
+	if id := meta.Metadata.ID; id != "" && id != name {
+		return nil, gameerr.ThemeError{
+			Code:    CodeIDMismatch,
+			Message: fmt.Sprintf("%s: metadata.id %q がディレクトリ名と一致しません", file, id),
+			ThemeID: name,
+		}
+	}
+
+	return &layer{id: name, file: file, doc: doc, meta: meta.Metadata, fsys: fsys}, nil
+}
+
+func withThemeID(err error, id string) error {
+	var themeErr gameerr.ThemeError
+	if errors.As(err, &themeErr) {
+		themeErr.ThemeID = id
+		return themeErr
+	}
+	return err
+}
+
+// mergeLayers - 基底テーマから順に theme.yaml を重ねた1つのドキュメントを作る。
+// metadata は継承せず、最後のテーマのものを使う。
+func mergeLayers(layers []*layer) *yaml.Node {
+	var root *yaml.Node
+	for _, l := range layers {
+		body := documentBody(l.doc)
+		if root == nil {
+			root = body
+			continue
+		}
+		root = merge(root, body)
+	}
+
+	top := documentBody(layers[len(layers)-1].doc)
+	root = withoutKey(root, "metadata")
+	if i := mapIndex(top, "metadata"); i >= 0 {
+		root.Content = append([]*yaml.Node{top.Content[i], top.Content[i+1]}, root.Content...)
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{root}}
+}
+
+// documentBody - ドキュメントのルート。空のファイルは空のマップとして扱う。
+func documentBody(doc *yaml.Node) *yaml.Node {
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}
+	}
+	return deref(doc.Content[0])
+}
+
+// merge - base に over を重ねる。マップはキーごとに、全要素が id を持つマップのリストは
+// id ごとに再帰的に重ね、新しい要素は末尾に追加する。それ以外の値は over で置き換える。
+// base は変更せずに新しいノードを返す。
+func merge(base, over *yaml.Node) *yaml.Node {
+	base, over = deref(base), deref(over)
+	switch {
+	case base.Kind == yaml.MappingNode && over.Kind == yaml.MappingNode:
+		out := *base
+		out.Content = slices.Clone(base.Content)
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			key, value := over.Content[i], over.Content[i+1]
+			if j := mapIndex(&out, key.Value); j >= 0 {
+				out.Content[j+1] = merge(out.Content[j+1], value)
+			} else {
+				out.Content = append(out.Content, key, value)
+			}
+		}
+		return &out
+
+	case base.Kind == yaml.SequenceNode && over.Kind == yaml.SequenceNode && keyed(base) && keyed(over):
+		out := *base
+		out.Content = slices.Clone(base.Content)
+		for _, item := range over.Content {
+			if j := itemIndex(&out, itemID(item)); j >= 0 {
+				out.Content[j] = merge(out.Content[j], item)
+			} else {
+				out.Content = append(out.Content, item)
+			}
+		}
+		return &out
+	}
+	return over
+}
+
+func deref(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// mapIndex - マップで key の位置 (キーノードの添字)。ない場合は -1。
+func mapIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func withoutKey(node *yaml.Node, key string) *yaml.Node {
+	i := mapIndex(node, key)
+	if i < 0 {
+		return node
+	}
+	out := *node
+	out.Content = slices.Delete(slices.Clone(node.Content), i, i+2)
+	return &out
+}
+
+// keyed - 空でなく、全要素が id を持つマップのリストか
+func keyed(seq *yaml.Node) bool {
+	if len(seq.Content) == 0 {
+		return false
+	}
+	for _, item := range seq.Content {
+		if itemID(item) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func itemID(item *yaml.Node) string {
+	item = deref(item)
+	i := mapIndex(item, "id")
+	if i < 0 || deref(item.Content[i+1]).Kind != yaml.ScalarNode {
+		return ""
+	}
+	return deref(item.Content[i+1]).Value
+}
+
+func itemIndex(seq *yaml.Node, id string) int {
+	for i, item := range seq.Content {
+		if itemID(item) == id {
+			return i
+		}
+	}
+	return -1
+}
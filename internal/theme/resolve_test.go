@@ -0,0 +1,239 @@
+package theme_test
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+const nightBeachTheme = `
+metadata:
+  id: night_beach
+  name: Night Beach
+  version: 0.2.0
+  dependencies: ["beach ^1.0"]
+  game_version: ">=1.0.0"
+enemies:
+  categories:
+    - id: junk_food
+      enemies:
+        - {id: burger, health: 50}
+        - {id: pizza, name: Pizza, sprite: assets/enemies/pizza.png}
+stages:
+  locations:
+    - id: gym
+      audio:
+        bgm: assets/audio/night.ogg
+ui:
+  fonts:
+    main: assets/fonts/night.ttf
+`
+
+func dependencyFS() fstest.MapFS {
+	fsys := themesFS()
+	add := func(name, yaml string) {
+		fsys[name+"/theme.yaml"] = &fstest.MapFile{Data: []byte(yaml)}
+	}
+	add("night_beach", nightBeachTheme)
+	fsys["night_beach/assets/characters/idle.png"] = &fstest.MapFile{Data: []byte("night idle")}
+	fsys["beach/assets/characters/walk.png"] = &fstest.MapFile{Data: []byte("beach walk")}
+
+	add("left", "metadata: {id: left, name: Left, version: 1.0.0, dependencies: [beach]}")
+	add("right", "metadata: {id: right, name: Right, version: 1.0.0, dependencies: [beach]}")
+	add("both", "metadata: {id: both, name: Both, version: 1.0.0, dependencies: [left, right]}")
+
+	add("loop_a", "metadata: {id: loop_a, name: A, version: 1.0.0, dependencies: [loop_b]}")
+	add("loop_b", "metadata: {id: loop_b, name: B, version: 1.0.0, dependencies: [loop_a]}")
+	add("orphan", "metadata: {id: orphan, name: Orphan, version: 1.0.0, dependencies: [missing]}")
+	add("too_new", "metadata: {id: too_new, name: New, version: 1.0.0, dependencies: [beach >=2.0]}")
+	add("future", "metadata: {id: future, name: Future, version: 1.0.0, game_version: \">=2.0.0\"}")
+	add("future_base", "metadata: {id: future_base, name: FB, version: 1.0.0, dependencies: [future]}")
+	return fsys
+}
+
+func newDependencyManager(t *testing.T) (*theme.Manager, *vfs.FS) {
+	t.Helper()
+	files := vfs.New(vfs.DefaultRoots...)
+	m := theme.NewManager(dependencyFS(), files, theme.Options{
+		LoadSheet:   (&sheetLoader{}).load,
+		GameVersion: "1.2.0",
+	})
+	return m, files
+}
+
+func TestDependencies(t *testing.T) {
+	t.Run("MergesChildOverBase", func(t *testing.T) {
+		m, _ := newDependencyManager(t)
+		night, err := m.LoadTheme("night_beach")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"beach", "night_beach"}, night.Chain)
+		assert.Equal(t, "Night Beach", night.Spec.Metadata.Name, "metadata は継承しない")
+
+		burger, ok := night.Spec.Enemy("burger")
+		require.True(t, ok)
+		assert.Equal(t, 50.0, burger.Health)
+		assert.Equal(t, "Burger", burger.Name, "書かなかった値は基底テーマのまま")
+		assert.Equal(t, "assets/enemies/burger.png", burger.Sprite)
+		_, ok = night.Spec.Enemy("pizza")
+		assert.True(t, ok, "新しい要素は追加される")
+		_, ok = night.Spec.Enemy("boss")
+		assert.True(t, ok)
+
+		require.Contains(t, night.Stages, "muscle_beach")
+		assert.Equal(t, "assets/audio/night.ogg", night.Stages["gym"].BGM)
+		assert.Equal(t, "Gym", night.Stages["gym"].Name)
+		assert.Equal(t, "assets/fonts/night.ttf", night.Spec.UI.Fonts["main"])
+		assert.Len(t, night.Spec.Characters.Player.SpriteSheets, 2)
+	})
+
+	t.Run("FSOverlaysBase", func(t *testing.T) {
+		m, files := newDependencyManager(t)
+		require.NoError(t, m.SetTheme("night_beach"))
+		for _, fsys := range []interface {
+			ReadFile(string) ([]byte, error)
+		}{files, m.GetCurrentTheme().FS().(*vfs.FS)} {
+			data, err := fsys.ReadFile("assets/characters/idle.png")
+			require.NoError(t, err)
+			assert.Equal(t, "night idle", string(data))
+			data, err = fsys.ReadFile("assets/characters/walk.png")
+			require.NoError(t, err)
+			assert.Equal(t, "beach walk", string(data), "基底テーマのファイルも見える")
+		}
+
+		require.NoError(t, m.SetTheme("gym"))
+		_, err := files.ReadFile("assets/characters/walk.png")
+		assert.Error(t, err, "基底テーマのレイヤーも外れる")
+	})
+
+	t.Run("SharedBaseLoadsOnce", func(t *testing.T) {
+		m, _ := newDependencyManager(t)
+		both, err := m.LoadTheme("both")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"beach", "left", "right", "both"}, both.Chain)
+		_, ok := both.Spec.Enemy("burger")
+		assert.True(t, ok)
+	})
+
+	t.Run("ReportsUnresolvableDependencies", func(t *testing.T) {
+		m, _ := newDependencyManager(t)
+		testCases := []struct {
+			name    string
+			code    string
+			message string
+		}{
+			{"loop_a", theme.CodeDependencyCycle, "loop_a → loop_b → loop_a"},
+			{"orphan", theme.CodeUnsatisfied, "依存テーマ missing が見つかりません"},
+			{"too_new", theme.CodeUnsatisfied, "beach は >=2.0 が必要ですが、バージョン 1.0.0 です"},
+			{"future", theme.CodeIncompatible, "実行中のバージョンは 1.2.0 です"},
+			{"future_base", theme.CodeIncompatible, "テーマ future は"},
+		}
+		for _, tc := range testCases {
+			_, err := m.LoadTheme(tc.name)
+			require.Error(t, err, tc.name)
+			assert.Equal(t, tc.code, gameerr.CodeOf(err), tc.name)
+			assert.Contains(t, err.Error(), tc.message, tc.name)
+		}
+	})
+
+	t.Run("WithoutGameVersionSkipsCheck", func(t *testing.T) {
+		m := theme.NewManager(dependencyFS(), vfs.New(), theme.Options{})
+		_, err := m.LoadTheme("future")
+		assert.NoError(t, err)
+	})
+}
+
+func TestParseDependency(t *testing.T) {
+	dep, err := theme.ParseDependency("beach")
+	require.NoError(t, err)
+	assert.Equal(t, "beach", dep.ID)
+	assert.Equal(t, "*", dep.Constraint.String())
+
+	dep, err = theme.ParseDependency("beach >=1.0.0, <2.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "beach", dep.ID)
+	assert.Equal(t, ">=1.0.0, <2.0.0", dep.Constraint.String())
+
+	_, err = theme.ParseDependency("beach >=one")
+	assert.Error(t, err)
+}
+
+func TestUIResources(t *testing.T) {
+	t.Run("ParsesColors", func(t *testing.T) {
+		c, err := theme.ParseColor("#FF6B35")
+		require.NoError(t, err)
+		assert.Equal(t, color.RGBA{R: 0xff, G: 0x6b, B: 0x35, A: 0xff}, c)
+		c, err = theme.ParseColor("#2C3E5080")
+		require.NoError(t, err)
+		assert.Equal(t, color.RGBA{R: 0x2c, G: 0x3e, B: 0x50, A: 0x80}, c)
+		for _, bad := range []string{"FF6B35", "#FF6B3", "#GGGGGG"} {
+			_, err := theme.ParseColor(bad)
+			assert.Error(t, err, bad)
+		}
+
+		palette := theme.Palette{theme.ColorText: {R: 1, A: 255}}
+		fallback := color.RGBA{A: 255}
+		assert.Equal(t, color.RGBA{R: 1, A: 255}, palette.Color(theme.ColorText, fallback))
+		assert.Equal(t, fallback, palette.Color(theme.ColorAccent, fallback))
+	})
+
+	t.Run("StringsLayerBaseAndChild", func(t *testing.T) {
+		fsys := dependencyFS()
+		beach := strings.Replace(beachTheme, "ui:\n", "ui:\n  theme_colors: {background: \"#000000\"}\n", 1)
+		fsys["beach/theme.yaml"].Data = []byte(beach + `
+localization:
+  supported_languages: [ja, en]
+  text: {theme_name: Beach, player_name: Hero}
+`)
+		fsys["beach/localization/en.yaml"] = &fstest.MapFile{Data: []byte("menu:\n  start: Start\n  quit: Quit\n")}
+		fsys["night_beach/localization/en.yaml"] = &fstest.MapFile{Data: []byte("menu: {start: Begin}\nlives: 3\n")}
+		m := theme.NewManager(fsys, vfs.New(), theme.Options{})
+
+		night, err := m.LoadTheme("night_beach")
+		require.NoError(t, err)
+		assert.Equal(t, color.RGBA{A: 255}, night.Colors[theme.ColorBackground])
+
+		en, err := night.Strings("en")
+		require.NoError(t, err)
+		assert.Equal(t, "en", en.Language)
+		assert.Equal(t, "Begin", en.Get("menu.start"), "継承先の言語ファイルが優先される")
+		assert.Equal(t, "Quit", en.Get("menu.quit"))
+		assert.Equal(t, "3", en.Get("lives"))
+		assert.Equal(t, "Hero", en.Get("player_name"), "theme.yaml の text が基になる")
+		assert.Equal(t, "missing.key", en.Get("missing.key"))
+
+		ja, err := night.Strings("fr")
+		require.NoError(t, err)
+		assert.Equal(t, theme.DefaultLanguage, ja.Language, "未対応の言語は既定の言語になる")
+		assert.Equal(t, "menu.start", ja.Get("menu.start"))
+	})
+
+	t.Run("BrokenStringsFile", func(t *testing.T) {
+		fsys := dependencyFS()
+		fsys["gym/localization/ja.yaml"] = &fstest.MapFile{Data: []byte("menu: [a, b]\n")}
+		gym, err := theme.NewManager(fsys, vfs.New(), theme.Options{}).LoadTheme("gym")
+		require.NoError(t, err)
+		_, err = gym.Strings("")
+		assert.Equal(t, theme.CodeInvalidSpec, gameerr.CodeOf(err))
+	})
+
+	t.Run("PreloadAssets", func(t *testing.T) {
+		m, _ := newDependencyManager(t)
+		beach, err := m.LoadTheme("beach")
+		require.NoError(t, err)
+		var groups []string
+		for _, ref := range beach.Manifest.PreloadAssets() {
+			groups = append(groups, ref.Group)
+		}
+		assert.Contains(t, groups, theme.GroupCharacters)
+		assert.Contains(t, groups, theme.GroupUI)
+		assert.NotContains(t, groups, theme.StageGroup("gym"))
+	})
+}
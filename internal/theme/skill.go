@@ -0,0 +1,70 @@
+package theme
+
+import "sort"
+
+// SkillTable - レベルごとの数値を引けるスキル定義。
+// theme.yaml で省略したレベルは直前のレベルの値を引き継ぐ。
+type SkillTable struct {
+	ID          string
+	Name        string
+	Description string
+	Type        string
+	Icon        string
+	MaxLevel    int
+	Effects     map[string]string
+	levels      []map[string]float64 // levels[0] がレベル1
+}
+
+// newSkillTable - 最大レベルは max_level、省略時は定義されている最大のレベル
+func newSkillTable(skill Skill) *SkillTable {
+	defined := make([]int, 0, len(skill.Levels))
+	for level := range skill.Levels {
+		if level >= 1 {
+			defined = append(defined, level)
+		}
+	}
+	sort.Ints(defined)
+
+	maxLevel := skill.MaxLevel
+	if maxLevel <= 0 && len(defined) > 0 {
+		maxLevel = defined[len(defined)-1]
+	}
+	maxLevel = max(maxLevel, 1)
+
+	t := &SkillTable{
+		ID:          skill.ID,
+		Name:        skill.Name,
+		Description: skill.Description,
+		Type:        skill.Type,
+		Icon:        skill.Icon,
+		MaxLevel:    maxLevel,
+		Effects:     skill.Effects,
+		levels:      make([]map[string]float64, maxLevel),
+	}
+	values := map[string]float64{}
+	for level := 1; level <= maxLevel; level++ {
+		if defined, ok := skill.Levels[level]; ok {
+			merged := make(map[string]float64, len(values)+len(defined))
+			for k, v := range values {
+				merged[k] = v
+			}
+			for k, v := range defined {
+				merged[k] = v
+			}
+			values = merged
+		}
+		t.levels[level-1] = values
+	}
+	return t
+}
+
+// Level - レベルの数値。範囲外のレベルは 1〜MaxLevel に丸める。返した map は変更しない。
+func (t *SkillTable) Level(level int) map[string]float64 {
+	return t.levels[min(max(level, 1), t.MaxLevel)-1]
+}
+
+// Value - レベルの数値を1つ引く
+func (t *SkillTable) Value(level int, key string) (float64, bool) {
+	v, ok := t.Level(level)[key]
+	return v, ok
+}
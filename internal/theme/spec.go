@@ -0,0 +1,284 @@
+// Package theme はテーマの theme.yaml の読み込みと、テーマが参照するアセットの管理を行う。
+package theme
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/schema"
+)
+
+// テーマのエラーコード
+const (
+	CodeInvalidSpec = "THEME_INVALID_SPEC"
+)
+
+// FileName - テーマディレクトリ直下の定義ファイル名
+const FileName = "theme.yaml"
+
+// Spec - theme.yaml の内容 (docs/content_creation_guide.md)
+type Spec struct {
+	Metadata     Metadata     `yaml:"metadata"`
+	Characters   Characters   `yaml:"characters"`
+	Enemies      Enemies      `yaml:"enemies"`
+	Stages       Stages       `yaml:"stages"`
+	Skills       Skills       `yaml:"skills"`
+	UI           UI           `yaml:"ui"`
+	Localization Localization `yaml:"localization"`
+	Performance  Performance  `yaml:"performance"`
+	Scripts      []Script     `yaml:"scripts"`
+}
+
+// Metadata - テーマメタデータ
+type Metadata struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name"`
+	Version      string   `yaml:"version"`
+	Author       string   `yaml:"author"`
+	Description  string   `yaml:"description"`
+	Tags         []string `yaml:"tags"`
+	Dependencies []string `yaml:"dependencies"`
+	GameVersion  string   `yaml:"game_version"`
+	License      string   `yaml:"license"`
+	Homepage     string   `yaml:"homepage"`
+}
+
+// Characters - キャラクター定義
+type Characters struct {
+	Player Player `yaml:"player"`
+}
+
+// Player - プレイヤーキャラクター
+type Player struct {
+	Name         string               `yaml:"name"`
+	Description  string               `yaml:"description"`
+	SpriteSheets map[string]string    `yaml:"sprite_sheets"`
+	Animations   map[string]anim.Spec `yaml:"animations"`
+	Stats        map[string]float64   `yaml:"stats"`
+	Collision    Collision            `yaml:"collision"`
+}
+
+// Collision - 当たり判定
+type Collision struct {
+	Width   float64 `yaml:"width"`
+	Height  float64 `yaml:"height"`
+	OffsetX float64 `yaml:"offset_x"`
+	OffsetY float64 `yaml:"offset_y"`
+}
+
+// Enemies - 敵キャラクター定義
+type Enemies struct {
+	Categories []EnemyCategory `yaml:"categories"`
+}
+
+// EnemyCategory - 敵の分類
+type EnemyCategory struct {
+	ID          string  `yaml:"id"`
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Enemies     []Enemy `yaml:"enemies"`
+}
+
+// Enemy - 敵キャラクター
+type Enemy struct {
+	ID              string     `yaml:"id"`
+	Name            string     `yaml:"name"`
+	Sprite          string     `yaml:"sprite"`
+	Health          float64    `yaml:"health"`
+	Speed           float64    `yaml:"speed"`
+	Damage          float64    `yaml:"damage"`
+	TemptationPower float64    `yaml:"temptation_power"`
+	Behavior        string     `yaml:"behavior"`
+	SpawnWeight     float64    `yaml:"spawn_weight"`
+	Collision       *Collision `yaml:"collision"`
+	SpecialEffects  []string   `yaml:"special_effects"`
+}
+
+// Stages - ステージ定義
+type Stages struct {
+	Locations []Stage `yaml:"locations"`
+}
+
+// Stage - ステージ
+type Stage struct {
+	ID             string      `yaml:"id"`
+	Name           string      `yaml:"name"`
+	Description    string      `yaml:"description"`
+	Background     Background  `yaml:"background"`
+	Audio          StageAudio  `yaml:"audio"`
+	Config         StageConfig `yaml:"stage_config"`
+	SpecialEnemies []string    `yaml:"special_enemies"`
+	SpecialItems   []string    `yaml:"special_items"`
+}
+
+// Background - ステージ背景
+type Background struct {
+	Layers []BackgroundLayer `yaml:"layers"`
+}
+
+// BackgroundLayer - パララックス背景の1層
+type BackgroundLayer struct {
+	File        string  `yaml:"file"`
+	ScrollSpeed float64 `yaml:"scroll_speed"`
+	ZOrder      int     `yaml:"z_order"`
+}
+
+// StageAudio - ステージの音響設定
+type StageAudio struct {
+	BGM           string         `yaml:"bgm"`
+	AmbientSounds []AmbientSound `yaml:"ambient_sounds"`
+}
+
+// AmbientSound - 環境音
+type AmbientSound struct {
+	File   string  `yaml:"file"`
+	Volume float64 `yaml:"volume"`
+	Loop   bool    `yaml:"loop"`
+}
+
+// StageConfig - ステージ固有設定
+type StageConfig struct {
+	Duration             float64      `yaml:"duration"`
+	DifficultyMultiplier float64      `yaml:"difficulty_multiplier"`
+	SpawnRateMultiplier  float64      `yaml:"spawn_rate_multiplier"`
+	SpecialEvents        []StageEvent `yaml:"special_events"`
+}
+
+// StageEvent - ステージ中の特殊イベント
+type StageEvent struct {
+	Time        float64 `yaml:"time"`
+	Type        string  `yaml:"type"`
+	Duration    float64 `yaml:"duration"`
+	Enemy       string  `yaml:"enemy"`
+	Description string  `yaml:"description"`
+}
+
+// Skills - スキル定義
+type Skills struct {
+	MuscleSkills []Skill `yaml:"muscle_skills"`
+}
+
+// Skill - 筋肉スキル
+type Skill struct {
+	ID          string                     `yaml:"id"`
+	Name        string                     `yaml:"name"`
+	Description string                     `yaml:"description"`
+	Icon        string                     `yaml:"icon"`
+	MaxLevel    int                        `yaml:"max_level"`
+	Type        string                     `yaml:"type"`
+	Levels      map[int]map[string]float64 `yaml:"levels"`
+	Effects     map[string]string          `yaml:"effects"`
+}
+
+// UI - UI設定
+type UI struct {
+	ThemeColors map[string]string    `yaml:"theme_colors"`
+	Fonts       map[string]string    `yaml:"fonts"`
+	MenuBGM     string               `yaml:"menu_bgm"`
+	Elements    map[string]UIElement `yaml:"elements"`
+}
+
+// UIElement - HUD 要素
+type UIElement struct {
+	Sprite   string `yaml:"sprite"`
+	Position []int  `yaml:"position"`
+	Size     []int  `yaml:"size"`
+	IconSize []int  `yaml:"icon_size"`
+	Spacing  int    `yaml:"spacing"`
+}
+
+// Localization - ローカライゼーション設定
+type Localization struct {
+	DefaultLanguage    string            `yaml:"default_language"`
+	SupportedLanguages []string          `yaml:"supported_languages"`
+	Text               map[string]string `yaml:"text"`
+}
+
+// Performance - パフォーマンス設定
+type Performance struct {
+	MaxEnemiesOnScreen int      `yaml:"max_enemies_on_screen"`
+	ParticleLimit      int      `yaml:"particle_limit"`
+	TextureCompression bool     `yaml:"texture_compression"`
+	AssetPreloading    []string `yaml:"asset_preloading"`
+}
+
+// Script - テーマを有効にしたときに実行するスクリプト。MODのスクリプトと同じ実行環境で
+// 動くが、ファイル・ネットワーク・コマンドは使えない。
+type Script struct {
+	File        string `yaml:"file"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+}
+
+// Parse - theme.yaml をスキーマで検証して読み込む。警告 (未知のキーなど) は返すが読み込みは続ける。
+func Parse(file string, data []byte) (*Spec, []schema.Issue, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		warnings, err := splitIssues(schema.Validate(file, data, schema.ThemeSchema()))
+		return nil, warnings, err
+	}
+	return parseNode(file, &doc)
+}
+
+// parseNode - 解析済みのドキュメントをスキーマで検証して読み込む
+func parseNode(file string, doc *yaml.Node) (*Spec, []schema.Issue, error) {
+	warnings, err := splitIssues(schema.ValidateNode(file, doc, schema.ThemeSchema()))
+	if err != nil {
+		return nil, warnings, err
+	}
+	spec, err := decodeSpec(doc)
+	return spec, warnings, err
+}
+
+// decodeSpec - スキーマで検証済みのドキュメントを Spec に変換する
+func decodeSpec(doc *yaml.Node) (*Spec, error) {
+	var spec Spec
+	if err := doc.Decode(&spec); err != nil {
+		return nil, gameerr.ThemeError{Code: CodeInvalidSpec, Message: err.Error()}
+	}
+	return &spec, nil
+}
+
+// splitIssues - 問題を警告とエラーに分け、エラーがあれば1つの ThemeError にまとめる
+func splitIssues(issues []schema.Issue) ([]schema.Issue, error) {
+	var warnings, errs []schema.Issue
+	for _, issue := range issues {
+		if issue.Severity >= gameerr.ErrorSeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, gameerr.ThemeError{
+			Code:    CodeInvalidSpec,
+			Message: strings.TrimSuffix(schema.FormatAll(errs, schema.LangFromEnv()), "\n"),
+		}
+	}
+	return warnings, nil
+}
+
+// Enemy - ID で敵を探す
+func (s *Spec) Enemy(id string) (*Enemy, bool) {
+	for i := range s.Enemies.Categories {
+		for j := range s.Enemies.Categories[i].Enemies {
+			if e := &s.Enemies.Categories[i].Enemies[j]; e.ID == id {
+				return e, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Stage - ID でステージを探す
+func (s *Spec) Stage(id string) (*Stage, bool) {
+	for i := range s.Stages.Locations {
+		if st := &s.Stages.Locations[i]; st.ID == id {
+			return st, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package theme
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultStageDuration - stage_config.duration を省略したステージの長さ
+const DefaultStageDuration = 180 * time.Second
+
+// StageDef - ゲームが実行するステージの定義。省略された倍率などは既定値で埋める。
+type StageDef struct {
+	ID          string
+	Name        string
+	Description string
+	Duration    time.Duration
+	Difficulty  float64
+	SpawnRate   float64
+	Background  []BackgroundLayer // z_order の小さい (奥の) 順
+	BGM         string
+	Ambient     []AmbientSound
+	Events      []StageEvent // 発生時刻順
+	Spawns      []Spawn      // 通常の出現で選ばれる敵
+	Items       []string
+}
+
+// Spawn - 出現する敵と出現確率の重み
+type Spawn struct {
+	Enemy  string
+	Weight float64
+}
+
+// newStageDef - 全カテゴリの敵とステージ専用の敵を出現候補にする。
+// ボスなどイベントで出現する敵は候補に含めない。
+func newStageDef(spec *Spec, stage Stage) *StageDef {
+	def := &StageDef{
+		ID:          stage.ID,
+		Name:        stage.Name,
+		Description: stage.Description,
+		Duration:    DefaultStageDuration,
+		Difficulty:  orOne(stage.Config.DifficultyMultiplier),
+		SpawnRate:   orOne(stage.Config.SpawnRateMultiplier),
+		Background:  append([]BackgroundLayer(nil), stage.Background.Layers...),
+		BGM:         stage.Audio.BGM,
+		Ambient:     stage.Audio.AmbientSounds,
+		Events:      append([]StageEvent(nil), stage.Config.SpecialEvents...),
+		Items:       stage.SpecialItems,
+	}
+	if stage.Config.Duration > 0 {
+		def.Duration = time.Duration(stage.Config.Duration * float64(time.Second))
+	}
+	sort.SliceStable(def.Background, func(i, j int) bool { return def.Background[i].ZOrder < def.Background[j].ZOrder })
+	sort.SliceStable(def.Events, func(i, j int) bool { return def.Events[i].Time < def.Events[j].Time })
+
+	for _, category := range spec.Enemies.Categories {
+		for _, enemy := range category.Enemies {
+			def.Spawns = append(def.Spawns, Spawn{Enemy: enemy.ID, Weight: orOne(enemy.SpawnWeight)})
+		}
+	}
+	for _, id := range stage.SpecialEnemies {
+		if enemy, ok := spec.Enemy(id); ok {
+			def.Spawns = append(def.Spawns, Spawn{Enemy: enemy.ID, Weight: orOne(enemy.SpawnWeight)})
+		}
+	}
+	return def
+}
+
+// EventsBetween - (from, to] の間に発生するイベント
+func (d *StageDef) EventsBetween(from, to time.Duration) []StageEvent {
+	var events []StageEvent
+	for _, e := range d.Events {
+		at := time.Duration(e.Time * float64(time.Second))
+		if at > from && at <= to {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func orOne(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
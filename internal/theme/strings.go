@@ -0,0 +1,111 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"muscle-dreamer/internal/gameerr"
+)
+
+// LocalizationDir - 言語別のテキストを置くディレクトリ (localization/<言語>.yaml)
+const LocalizationDir = "localization"
+
+// DefaultLanguage - localization.default_language を省略したときの言語
+const DefaultLanguage = "ja"
+
+// Strings - 1言語分のテキスト
+type Strings struct {
+	Language string
+	text     map[string]string
+}
+
+// Get - キーのテキスト。定義されていなければキーをそのまま返す。
+func (s *Strings) Get(key string) string {
+	if s != nil {
+		if v, ok := s.text[key]; ok {
+			return v
+		}
+	}
+	return key
+}
+
+// Keys - 定義されているキー (名前順)
+func (s *Strings) Keys() []string {
+	keys := make([]string, 0, len(s.text))
+	for k := range s.text {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Language - lang が supported_languages にあればそれを、なければ default_language を返す
+func (t *Theme) Language(lang string) string {
+	l := t.Spec.Localization
+	if lang != "" && slices.Contains(l.SupportedLanguages, lang) {
+		return lang
+	}
+	if l.DefaultLanguage != "" {
+		return l.DefaultLanguage
+	}
+	return DefaultLanguage
+}
+
+// Strings - 言語のテキストを読み込む。theme.yaml の localization.text を基に、
+// 基底テーマから順に localization/<言語>.yaml の値で上書きする。
+// 言語ファイルの入れ子のマップは "menu.start" のようにドットでつないだキーになる。
+func (t *Theme) Strings(lang string) (*Strings, error) {
+	s := &Strings{Language: t.Language(lang), text: make(map[string]string, len(t.Spec.Localization.Text))}
+	for k, v := range t.Spec.Localization.Text {
+		s.text[k] = v
+	}
+
+	file := path.Join(LocalizationDir, s.Language+".yaml")
+	for _, l := range t.layers {
+		data, err := fs.ReadFile(l.fsys, file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var doc map[string]interface{}
+			if err = yaml.Unmarshal(data, &doc); err == nil {
+				err = flatten("", doc, s.text)
+			}
+		}
+		if err != nil {
+			return nil, gameerr.ThemeError{
+				Code:    CodeInvalidSpec,
+				Message: fmt.Sprintf("%s: テキストを読み込めません: %v", path.Join(l.id, file), err),
+				ThemeID: t.ID,
+			}
+		}
+	}
+	return s, nil
+}
+
+func flatten(prefix string, doc map[string]interface{}, out map[string]string) error {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, v, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: テキストにリストは書けません", key)
+		case nil:
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
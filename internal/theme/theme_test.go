@@ -0,0 +1,238 @@
+package theme_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/theme"
+)
+
+const beachTheme = `
+metadata:
+  id: beach
+  name: Beach
+  version: 1.0.0
+characters:
+  player:
+    stats: {protein_capacity: 80}
+    sprite_sheets:
+      idle: assets/characters/idle.png
+      walk: assets/characters/walk.png
+    animations:
+      idle: {frame_count: 4, frame_duration: 0.25, loop: true}
+enemies:
+  categories:
+    - id: junk_food
+      name: Junk
+      enemies:
+        - {id: burger, name: Burger, sprite: assets/enemies/burger.png, health: 30}
+        - {id: boss, name: Boss, sprite: assets/enemies/boss.png}
+stages:
+  locations:
+    - id: muscle_beach
+      name: Muscle Beach
+      background:
+        layers:
+          - {file: assets/stages/sky.png, scroll_speed: 0.1, z_order: -2}
+          - {file: assets/stages/sand.png, scroll_speed: 0.5, z_order: -1}
+      audio:
+        bgm: assets/audio/beach.ogg
+        ambient_sounds:
+          - {file: assets/audio/waves.ogg, volume: 0.3, loop: true}
+      stage_config:
+        special_events:
+          - {time: 120, type: boss_spawn, enemy: boss}
+      special_enemies: [ghost]
+    - id: gym
+      name: Gym
+      audio:
+        bgm: assets/audio/gym.ogg
+skills:
+  muscle_skills:
+    - id: protein_beam
+      name: Beam
+      icon: assets/ui/skills/beam.png
+      levels:
+        1: {damage: 30}
+      effects:
+        sound_cast: assets/audio/cast.ogg
+ui:
+  fonts:
+    main: assets/fonts/main.ttf
+  elements:
+    health_bar: {sprite: assets/ui/health.png, position: [20, 20], size: [200, 20]}
+performance:
+  asset_preloading: [characters, ui]
+`
+
+func parse(t *testing.T) *theme.Spec {
+	t.Helper()
+	spec, warnings, err := theme.Parse("theme.yaml", []byte(beachTheme))
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	return spec
+}
+
+func paths(refs []theme.Ref) []string {
+	out := make([]string, len(refs))
+	for i, ref := range refs {
+		out[i] = ref.Path
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	t.Run("DecodesTypedSpec", func(t *testing.T) {
+		spec := parse(t)
+		assert.Equal(t, "beach", spec.Metadata.ID)
+		assert.Equal(t, 4, spec.Characters.Player.Animations["idle"].FrameCount)
+		assert.Equal(t, 30.0, spec.Skills.MuscleSkills[0].Levels[1]["damage"])
+		assert.Equal(t, []int{20, 20}, spec.UI.Elements["health_bar"].Position)
+
+		stage, ok := spec.Stage("muscle_beach")
+		require.True(t, ok)
+		assert.Equal(t, "assets/audio/beach.ogg", stage.Audio.BGM)
+		enemy, ok := spec.Enemy("boss")
+		require.True(t, ok)
+		assert.Equal(t, "assets/enemies/boss.png", enemy.Sprite)
+	})
+
+	t.Run("SchemaErrorsAreThemeErrors", func(t *testing.T) {
+		_, _, err := theme.Parse("theme.yaml", []byte("metadata:\n  id: beach\n"))
+		var themeErr gameerr.ThemeError
+		require.ErrorAs(t, err, &themeErr)
+		assert.Equal(t, theme.CodeInvalidSpec, themeErr.Code)
+		assert.Contains(t, themeErr.Message, "name")
+	})
+}
+
+func TestManifest(t *testing.T) {
+	t.Run("GroupsAssetsByDefinition", func(t *testing.T) {
+		m := theme.BuildManifest(parse(t))
+		assert.Equal(t, []string{"characters", "enemies", "skills", "stage:gym", "stage:muscle_beach", "ui"}, m.Groups())
+		assert.Equal(t, []string{"assets/characters/idle.png", "assets/characters/walk.png"}, paths(m.Group(theme.GroupCharacters)))
+		assert.Equal(t, []string{"assets/ui/skills/beam.png", "assets/audio/cast.ogg"}, paths(m.Group(theme.GroupSkills)))
+		assert.Equal(t, []string{"assets/fonts/main.ttf", "assets/ui/health.png"}, paths(m.Group(theme.GroupUI)))
+
+		stage := m.Group(theme.StageGroup("muscle_beach"))
+		assert.Equal(t, []string{
+			"assets/stages/sky.png",
+			"assets/stages/sand.png",
+			"assets/audio/beach.ogg",
+			"assets/audio/waves.ogg",
+			"assets/enemies/boss.png",
+		}, paths(stage))
+		assert.Equal(t, theme.AssetAudio, stage[2].Kind)
+		assert.Equal(t, "stages.locations[0].audio.bgm", stage[2].Source)
+	})
+
+	t.Run("StageAssetsIncludePreloadGroups", func(t *testing.T) {
+		m := theme.BuildManifest(parse(t))
+		assert.True(t, m.HasStage("gym"))
+		assert.False(t, m.HasStage("moon"))
+		assert.Equal(t, []string{
+			"assets/characters/idle.png",
+			"assets/characters/walk.png",
+			"assets/fonts/main.ttf",
+			"assets/ui/health.png",
+			"assets/audio/gym.ogg",
+		}, paths(m.StageAssets("gym")))
+
+		with := paths(m.StageAssets("gym", theme.GroupUI, theme.GroupSkills))
+		assert.Contains(t, with, "assets/ui/skills/beam.png")
+		assert.Equal(t, len(m.StageAssets("gym"))+2, len(with), "既に含まれる UI のアセットは重複しない")
+	})
+
+	t.Run("ReportsMissingAndUnreferencedFiles", func(t *testing.T) {
+		spec := parse(t)
+		spec.Performance.AssetPreloading = append(spec.Performance.AssetPreloading, "effects")
+		m := theme.BuildManifest(spec)
+
+		fsys := fstest.MapFS{"theme.yaml": {Data: []byte(beachTheme)}}
+		for _, group := range m.Groups() {
+			for _, ref := range m.Group(group) {
+				fsys[ref.Path] = &fstest.MapFile{}
+			}
+		}
+		delete(fsys, "assets/audio/waves.ogg")
+		fsys["assets/stages/old.png"] = &fstest.MapFile{}
+		fsys["assets/stages/notes.txt"] = &fstest.MapFile{}
+		fsys["assets/ui/hud.atlas.yaml"] = &fstest.MapFile{}
+
+		report, err := m.Check(fsys, inspect.DefaultLimits().Theme)
+		require.NoError(t, err)
+		require.Len(t, report.Missing, 1)
+		assert.Equal(t, "assets/audio/waves.ogg", report.Missing[0].Path)
+		assert.Equal(t, []string{"assets/stages/old.png", "assets/ui/hud.atlas.yaml"}, report.Unreferenced)
+		assert.Equal(t, []string{"stages.locations[0].special_enemies[0]: ghost"}, report.Unresolved)
+		assert.Equal(t, []string{"effects"}, report.UnknownGroup)
+		assert.False(t, report.OK())
+	})
+
+	t.Run("AtlasPagesCountAsReferenced", func(t *testing.T) {
+		spec := parse(t)
+		spec.UI.Elements["skill_icons"] = theme.UIElement{Sprite: "assets/ui/hud.atlas.yaml"}
+		spec.Stages.Locations[0].SpecialEnemies = nil
+		m := theme.BuildManifest(spec)
+
+		fsys := fstest.MapFS{
+			"assets/ui/hud.atlas.yaml": {Data: []byte("pages: [hud_0.png]\nregions:\n  frame: {page: 0, x: 0, y: 0, w: 8, h: 8}\n")},
+			"assets/ui/hud_0.png":      {},
+		}
+		report, err := m.Check(fsys, inspect.DefaultLimits().Theme)
+		require.NoError(t, err)
+		assert.Empty(t, report.Unreferenced)
+		assert.NotEmpty(t, report.Missing)
+	})
+
+	t.Run("ReadsOnlyAtlasDefinitionsWithinLimits", func(t *testing.T) {
+		spec := parse(t)
+		spec.UI.Elements["skill_icons"] = theme.UIElement{Sprite: "assets/ui/hud.atlas.yaml"}
+		m := theme.BuildManifest(spec)
+
+		atlasData := "pages: [hud_0.png]\nregions:\n  frame: {page: 0, x: 0, y: 0, w: 8, h: 8}\n"
+		fsys := openLog{MapFS: fstest.MapFS{
+			"assets/ui/hud.atlas.yaml": {Data: []byte(atlasData)},
+			"assets/ui/hud_0.png":      {},
+		}, opened: new([]string)}
+		for _, group := range m.Groups() {
+			for _, ref := range m.Group(group) {
+				if _, ok := fsys.MapFS[ref.Path]; !ok {
+					fsys.MapFS[ref.Path] = &fstest.MapFile{Data: []byte("data")}
+				}
+			}
+		}
+
+		report, err := m.Check(fsys, inspect.DefaultLimits().Theme)
+		require.NoError(t, err)
+		assert.Empty(t, report.Missing)
+		assert.Empty(t, report.Unreferenced)
+		assert.Equal(t, []string{"assets/ui/hud.atlas.yaml"}, *fsys.opened, "存在の確認には読み込まない")
+
+		report, err = m.Check(fsys, inspect.Limits{MaxFileSize: int64(len(atlasData)) - 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"assets/ui/hud_0.png"}, report.Unreferenced, "上限を超えるアトラス定義は読まない")
+	})
+}
+
+// openLog - 開いたファイルを記録する。ディレクトリの列挙と Stat は記録しない。
+type openLog struct {
+	fstest.MapFS
+	opened *[]string
+}
+
+func (f openLog) Open(name string) (fs.File, error) {
+	file, err := f.MapFS.Open(name)
+	if err == nil {
+		if info, _ := file.Stat(); info != nil && !info.IsDir() {
+			*f.opened = append(*f.opened, name)
+		}
+	}
+	return file, err
+}
@@ -0,0 +1,62 @@
+package theme
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// UI色の名前 (ui.theme_colors のキー)
+const (
+	ColorPrimary    = "primary"
+	ColorSecondary  = "secondary"
+	ColorBackground = "background"
+	ColorText       = "text"
+	ColorAccent     = "accent"
+)
+
+// HUD 要素の名前 (ui.elements のキー)
+const (
+	ElementHealthBar    = "health_bar"
+	ElementProteinMeter = "protein_meter"
+	ElementSkillIcons   = "skill_icons"
+)
+
+// Palette - ui.theme_colors を解析した色
+type Palette map[string]color.RGBA
+
+// Color - 名前の色。定義されていなければ fallback。
+func (p Palette) Color(name string, fallback color.RGBA) color.RGBA {
+	if c, ok := p[name]; ok {
+		return c
+	}
+	return fallback
+}
+
+// ParseColor - "#RRGGBB" または "#RRGGBBAA" を色に変換する
+func ParseColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 6 && len(hex) != 8) {
+		return color.RGBA{}, fmt.Errorf("不正な色です: %q", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("不正な色です: %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// newPalette - 解析できない色は無視する (スキーマで検証済み)
+func newPalette(colors map[string]string) Palette {
+	p := make(Palette, len(colors))
+	for name, s := range colors {
+		if c, err := ParseColor(s); err == nil {
+			p[name] = c
+		}
+	}
+	return p
+}
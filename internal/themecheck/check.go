@@ -0,0 +1,269 @@
+package themecheck
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"sort"
+
+	"muscle-dreamer/internal/anim"
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/atlas"
+	"muscle-dreamer/internal/gameerr"
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/script"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/vfs"
+)
+
+// 検証のエラーコード。スキーマの問題は schema のコード、依存関係の問題は theme のコードを使う。
+const (
+	CodeDuplicateID     = "THEME_DUPLICATE_ID"
+	CodeBadSpriteSheet  = "THEME_BAD_SPRITE_SHEET"
+	CodeAssetMissing    = "THEME_ASSET_MISSING"
+	CodeAssetUnused     = "THEME_ASSET_UNREFERENCED"
+	CodeAssetUndecoded  = "THEME_ASSET_DECODE_FAILED"
+	CodeAssetTooLarge   = "THEME_ASSET_TOO_LARGE"
+	CodeUnresolvedRef   = "THEME_UNRESOLVED_REFERENCE"
+	CodeUnknownGroup    = "THEME_UNKNOWN_PRELOAD_GROUP"
+	CodePerformance     = "THEME_PERFORMANCE"
+	CodeCheckFailed     = "THEME_CHECK_FAILED"
+	CodeScriptInvalid   = "THEME_SCRIPT_INVALID"
+	codeUnknownProblems = "THEME_ERROR"
+)
+
+// パフォーマンス設定の推奨上限。超えても動くが、低スペックの環境でフレームレートが落ちる。
+const (
+	RecommendedMaxEnemies   = 200
+	RecommendedParticles    = 1000
+	RecommendedPreloadBytes = 64 << 20
+)
+
+// Options - 検証の設定
+type Options struct {
+	GameVersion string // 空ならテーマの game_version を調べない
+	Lang        string // スキーマの問題のメッセージの言語 (schema.LangJa / LangEn)
+}
+
+// Check - themes の下のテーマ name を検証する。テーマのアーカイブ (<name>.mdpk) は
+// ゲームと同じ手順で整合性を確かめてから検証する。テーマを読み込めない場合も、
+// 見つかった問題を Report に入れて返す。
+func Check(themes fs.FS, name string, opts Options) *Report {
+	r := &Report{Theme: name}
+	m := theme.NewManager(themes, vfs.New(), theme.Options{GameVersion: opts.GameVersion})
+	for _, err := range m.Install() {
+		var assetErr gameerr.AssetError
+		if errors.As(err, &assetErr) && assetErr.AssetPath == name+pack.Ext {
+			r.addError(err)
+			return r
+		}
+	}
+	t, issues, err := m.Inspect(name)
+	for _, issue := range issues {
+		r.addIssue(issue, opts.Lang)
+	}
+	if err != nil {
+		r.addError(err)
+		return r
+	}
+	if t == nil {
+		return r
+	}
+	r.Chain = t.Chain
+
+	checkIDs(r, t.Spec)
+	checkSpriteSheets(r, t)
+	checkAssets(r, t)
+	checkScripts(r, t)
+	checkPerformance(r, t)
+	return r
+}
+
+func (r *Report) addIssue(issue schema.Issue, lang string) {
+	severity := SeverityWarning
+	if issue.Severity >= gameerr.ErrorSeverityError {
+		severity = SeverityError
+	}
+	r.Findings = append(r.Findings, Finding{
+		Severity:   severity,
+		Code:       issue.Code,
+		File:       issue.File,
+		Line:       issue.Line,
+		Column:     issue.Column,
+		Path:       issue.Path,
+		Message:    issue.Message(lang),
+		Suggestion: issue.Suggestion(lang),
+	})
+}
+
+// addError - 読み込みを止めたエラー。テーマとアセットのエラーはそのコードで記録する。
+func (r *Report) addError(err error) {
+	code := codeUnknownProblems
+	msg := err.Error()
+	var themeErr gameerr.ThemeError
+	var assetErr gameerr.AssetError
+	switch {
+	case errors.As(err, &themeErr):
+		code, msg = themeErr.Code, themeErr.Message
+	case errors.As(err, &assetErr):
+		code, msg = assetErr.Code, assetErr.Message
+	}
+	r.add(SeverityError, code, "", "%s", msg)
+}
+
+// checkIDs - 同じ種類の定義で ID が重複していないか調べる。継承で重なった要素は
+// 1つにまとまるので、重複するのは同じ theme.yaml 内に2回書いた場合だけ。
+func checkIDs(r *Report, spec *theme.Spec) {
+	seen := make(map[string]map[string]string)
+	check := func(kind, id, where string) {
+		if seen[kind] == nil {
+			seen[kind] = make(map[string]string)
+		}
+		if first, ok := seen[kind][id]; ok {
+			r.add(SeverityError, CodeDuplicateID, where, "ID %s は %s でも使われています", id, first)
+			return
+		}
+		seen[kind][id] = where
+	}
+	for i, c := range spec.Enemies.Categories {
+		check("category", c.ID, fmt.Sprintf("enemies.categories[%d].id", i))
+		for j, e := range c.Enemies {
+			check("enemy", e.ID, fmt.Sprintf("enemies.categories[%d].enemies[%d].id", i, j))
+		}
+	}
+	for i, s := range spec.Stages.Locations {
+		check("stage", s.ID, fmt.Sprintf("stages.locations[%d].id", i))
+	}
+	for i, s := range spec.Skills.MuscleSkills {
+		check("skill", s.ID, fmt.Sprintf("skills.muscle_skills[%d].id", i))
+	}
+}
+
+// checkSpriteSheets - アニメーションの指定でスプライトシートを切り分けられるか調べる。
+// 画像を開けない場合はアセットの検証で報告するので、ここでは扱わない。
+func checkSpriteSheets(r *Report, t *theme.Theme) {
+	player := t.Spec.Characters.Player
+	for _, name := range sortedKeys(player.Animations) {
+		spec := player.Animations[name]
+		sheet, ok := player.SpriteSheets[name]
+		if !ok {
+			r.add(SeverityError, CodeBadSpriteSheet, "characters.player.animations."+name,
+				"アニメーション %s のスプライトシートが sprite_sheets にありません", name)
+			continue
+		}
+		cfg, err := imageConfig(t.FS(), sheet)
+		if err != nil {
+			continue
+		}
+		if _, err := spec.Grid().Slice(image.Pt(cfg.Width, cfg.Height)); err != nil {
+			r.add(SeverityError, CodeBadSpriteSheet, "characters.player.animations."+name, "%s: %v", sheet, err)
+			continue
+		}
+		if _, err := anim.NewClip(name, sheet, sheet, spec); err != nil {
+			r.add(SeverityError, CodeBadSpriteSheet, "characters.player.animations."+name, "%v", err)
+		}
+	}
+}
+
+// checkAssets - 参照されているアセットの有無とデコードできるかを調べる
+func checkAssets(r *Report, t *theme.Theme) {
+	fsys := t.FS()
+	report, err := t.Manifest.Check(fsys, limits)
+	if err != nil {
+		r.add(SeverityError, CodeCheckFailed, theme.AssetDir, "アセットを列挙できません: %v", err)
+	}
+	missing := make(map[string]bool, len(report.Missing))
+	for _, ref := range report.Missing {
+		missing[ref.Path] = true
+		r.add(SeverityError, CodeAssetMissing, ref.Source, "%s が見つかりません", ref.Path)
+	}
+	for _, p := range report.Unreferenced {
+		r.add(SeverityWarning, CodeAssetUnused, p, "どこからも参照されていません")
+	}
+	for _, ref := range report.Unresolved {
+		r.add(SeverityError, CodeUnresolvedRef, "", "定義されていない敵を参照しています: %s", ref)
+	}
+	for _, group := range report.UnknownGroup {
+		r.add(SeverityWarning, CodeUnknownGroup, "performance.asset_preloading", "グループ %s はありません", group)
+	}
+
+	checked := make(map[string]bool)
+	for _, group := range t.Manifest.Groups() {
+		for _, ref := range t.Manifest.Group(group) {
+			if checked[ref.Path] || missing[ref.Path] {
+				continue
+			}
+			checked[ref.Path] = true
+			r.Assets++
+			if err := decode(fsys, ref.Path, ref.Kind); errors.Is(err, inspect.ErrTooLarge) {
+				// ゲームは読み込みを拒否する。メッセージはパスを含む
+				r.add(SeverityError, CodeAssetTooLarge, ref.Source, "%v", err)
+			} else if err != nil {
+				r.add(SeverityError, CodeAssetUndecoded, ref.Source, "%s: %v", ref.Path, err)
+			}
+		}
+	}
+}
+
+// checkScripts - スクリプトがあり、ゲームの実行環境で読み込める Lua か調べる
+func checkScripts(r *Report, t *theme.Theme) {
+	for i, s := range t.Spec.Scripts {
+		where := fmt.Sprintf("scripts[%d].file", i)
+		err := script.Check(t.FS(), s.File)
+		if errors.Is(err, fs.ErrNotExist) {
+			r.add(SeverityError, CodeAssetMissing, where, "%s が見つかりません", s.File)
+		} else if err != nil {
+			r.add(SeverityError, CodeScriptInvalid, where, "%v", err)
+		}
+	}
+}
+
+// checkPerformance - パフォーマンス設定と事前読み込みの量が推奨の範囲か調べる
+func checkPerformance(r *Report, t *theme.Theme) {
+	perf := t.Spec.Performance
+	if perf.MaxEnemiesOnScreen > RecommendedMaxEnemies {
+		r.add(SeverityWarning, CodePerformance, "performance.max_enemies_on_screen",
+			"%d は推奨の上限 %d を超えています", perf.MaxEnemiesOnScreen, RecommendedMaxEnemies)
+	}
+	if perf.ParticleLimit > RecommendedParticles {
+		r.add(SeverityWarning, CodePerformance, "performance.particle_limit",
+			"%d は推奨の上限 %d を超えています", perf.ParticleLimit, RecommendedParticles)
+	}
+
+	var total int64
+	seen := make(map[string]bool)
+	for _, ref := range t.Manifest.PreloadAssets() {
+		if seen[ref.Path] {
+			continue
+		}
+		seen[ref.Path] = true
+		if info, err := fs.Stat(t.FS(), ref.Path); err == nil {
+			total += info.Size()
+		}
+	}
+	if total > RecommendedPreloadBytes {
+		r.add(SeverityWarning, CodePerformance, "performance.asset_preloading",
+			"事前読み込みするアセットが %d MB あり、推奨の上限 %d MB を超えています。起動が遅くなります",
+			total>>20, RecommendedPreloadBytes>>20)
+	}
+}
+
+// atlasPages - アトラス定義が参照するページ画像のパス
+func atlasPages(data []byte, manifest string) ([]string, error) {
+	a, err := atlas.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return a.PagePaths(manifest), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
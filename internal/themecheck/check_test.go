@@ -0,0 +1,267 @@
+package themecheck_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"muscle-dreamer/internal/pack"
+	"muscle-dreamer/internal/schema"
+	"muscle-dreamer/internal/theme"
+	"muscle-dreamer/internal/themecheck"
+)
+
+const gymTheme = `
+metadata:
+  id: gym
+  name: Gym
+  version: 1.0.0
+characters:
+  player:
+    sprite_sheets:
+      idle: assets/characters/idle.png
+    animations:
+      idle: {frame_count: 4, frame_duration: 0.25, loop: true}
+enemies:
+  categories:
+    - id: junk_food
+      name: Junk
+      enemies:
+        - {id: burger, name: Burger, sprite: assets/enemies/burger.png, spawn_weight: 2}
+stages:
+  locations:
+    - id: gym
+      name: Gym
+      audio:
+        bgm: assets/audio/gym.wav
+ui:
+  theme_colors: {primary: "#FF6B35"}
+  fonts:
+    main: assets/fonts/main.ttf
+performance:
+  max_enemies_on_screen: 50
+  asset_preloading: [characters, ui]
+`
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+// wavOf - 16bit モノラルの無音の WAV
+func wavOf(samples int) []byte {
+	var b bytes.Buffer
+	le := func(v interface{}) { _ = binary.Write(&b, binary.LittleEndian, v) }
+	b.WriteString("RIFF")
+	le(uint32(36 + samples*2))
+	b.WriteString("WAVEfmt ")
+	le(uint32(16))
+	le(uint16(1))
+	le(uint16(1))
+	le(uint32(44100))
+	le(uint32(44100 * 2))
+	le(uint16(2))
+	le(uint16(16))
+	b.WriteString("data")
+	le(uint32(samples * 2))
+	b.Write(make([]byte, samples*2))
+	return b.Bytes()
+}
+
+func gymFS(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"gym/theme.yaml":                    {Data: []byte(gymTheme)},
+		"gym/assets/characters/idle.png":    {Data: pngOf(t, 128, 32)},
+		"gym/assets/enemies/burger.png":     {Data: pngOf(t, 32, 32)},
+		"gym/assets/audio/gym.wav":          {Data: wavOf(100)},
+		"gym/assets/fonts/main.ttf":         {Data: goregular.TTF},
+		"gym/localization/ja.yaml":          {Data: []byte("menu: {start: スタート}\n")},
+		"gym/assets/characters/unused.png":  {Data: pngOf(t, 8, 8)},
+		"gym/assets/characters/readme.txt":  {Data: []byte("not an asset")},
+		"derived/theme.yaml":                {Data: []byte("metadata: {id: derived, name: D, version: 1.0.0, dependencies: [gym]}\n")},
+		"derived/assets/enemies/burger.png": {Data: []byte("not a png")},
+	}
+}
+
+func codes(r *themecheck.Report) []string {
+	var out []string
+	for _, f := range r.Findings {
+		out = append(out, f.Code)
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("ValidTheme", func(t *testing.T) {
+		r := themecheck.Check(gymFS(t), "gym", themecheck.Options{})
+		assert.True(t, r.OK(), codes(r))
+		assert.Equal(t, []string{"gym"}, r.Chain)
+		assert.Equal(t, 4, r.Assets)
+		require.Len(t, r.Findings, 1)
+		assert.Equal(t, themecheck.CodeAssetUnused, r.Findings[0].Code)
+		assert.Equal(t, "assets/characters/unused.png", r.Findings[0].Path)
+	})
+
+	t.Run("ReportsSchemaIssuesWithLocation", func(t *testing.T) {
+		fsys := gymFS(t)
+		bad := strings.NewReplacer("version: 1.0.0", "version: one", "id: burger", "id: Burger!",
+			"frame_count: 4", "frame_count: 0", "spawn_weight: 2", "spawn_weight: -1", "#FF6B35", "orange").Replace(gymTheme)
+		fsys["gym/theme.yaml"].Data = []byte(bad)
+		r := themecheck.Check(fsys, "gym", themecheck.Options{Lang: schema.LangEn})
+		assert.False(t, r.OK())
+		paths := make(map[string]themecheck.Finding)
+		for _, f := range r.Findings {
+			paths[f.Path] = f
+		}
+		for _, p := range []string{
+			"metadata.version", "enemies.categories[0].enemies[0].id", "characters.player.animations.idle.frame_count",
+			"enemies.categories[0].enemies[0].spawn_weight", "ui.theme_colors.primary",
+		} {
+			require.Contains(t, paths, p)
+			assert.Equal(t, themecheck.SeverityError, paths[p].Severity, p)
+			assert.Equal(t, "gym/theme.yaml", paths[p].File, p)
+			assert.Positive(t, paths[p].Line, p)
+		}
+		assert.Zero(t, r.Assets, "スキーマのエラーがあればアセットは調べない")
+	})
+
+	t.Run("ReportsAssetProblems", func(t *testing.T) {
+		fsys := gymFS(t)
+		delete(fsys, "gym/assets/fonts/main.ttf")
+		fsys["gym/assets/audio/gym.wav"].Data = []byte("RIFF\x04\x00\x00\x00WAVE")
+		fsys["gym/assets/characters/idle.png"].Data = pngOf(t, 90, 32)
+		r := themecheck.Check(fsys, "gym", themecheck.Options{})
+		assert.False(t, r.OK())
+		assert.Contains(t, codes(r), themecheck.CodeAssetMissing)
+		assert.Contains(t, codes(r), themecheck.CodeAssetUndecoded)
+		assert.Contains(t, codes(r), themecheck.CodeBadSpriteSheet, "幅90は4フレームで割り切れない")
+	})
+
+	t.Run("ChecksScripts", func(t *testing.T) {
+		fsys := gymFS(t)
+		fsys["gym/theme.yaml"].Data = []byte(gymTheme + "scripts:\n  - {file: scripts/ai.lua}\n  - {file: scripts/broken.lua}\n  - {file: scripts/missing.lua}\n")
+		fsys["gym/scripts/ai.lua"] = &fstest.MapFile{Data: []byte(`ModAPI.RegisterSystem("ai", function(dt) end)`)}
+		fsys["gym/scripts/broken.lua"] = &fstest.MapFile{Data: []byte(`function (`)}
+		r := themecheck.Check(fsys, "gym", themecheck.Options{})
+		found := make(map[string]string)
+		for _, f := range r.Findings {
+			found[f.Path] = f.Code
+		}
+		assert.NotContains(t, found, "scripts[0].file")
+		assert.Equal(t, themecheck.CodeScriptInvalid, found["scripts[1].file"])
+		assert.Equal(t, themecheck.CodeAssetMissing, found["scripts[2].file"])
+	})
+
+	t.Run("ReportsAssetsOverGameLimits", func(t *testing.T) {
+		fsys := gymFS(t)
+		fsys["gym/assets/enemies/burger.png"].Data = pngOf(t, 9000, 1)
+		long := wavOf(1000)
+		binary.LittleEndian.PutUint32(long[24:], 1) // 1Hz の1000サンプルは約17分
+		binary.LittleEndian.PutUint32(long[28:], 2)
+		fsys["gym/assets/audio/gym.wav"].Data = long
+
+		r := themecheck.Check(fsys, "gym", themecheck.Options{})
+		assert.False(t, r.OK())
+		var tooLarge []string
+		for _, f := range r.Findings {
+			if f.Code == themecheck.CodeAssetTooLarge {
+				assert.Equal(t, themecheck.SeverityError, f.Severity)
+				tooLarge = append(tooLarge, f.Message)
+			}
+		}
+		require.Len(t, tooLarge, 2, codes(r))
+		assert.Contains(t, strings.Join(tooLarge, "\n"), "assets/enemies/burger.png")
+		assert.Contains(t, strings.Join(tooLarge, "\n"), "assets/audio/gym.wav")
+	})
+
+	t.Run("ChecksInheritedAssets", func(t *testing.T) {
+		r := themecheck.Check(gymFS(t), "derived", themecheck.Options{})
+		assert.Equal(t, []string{"gym", "derived"}, r.Chain)
+		require.False(t, r.OK())
+		var found bool
+		for _, f := range r.Findings {
+			if f.Code == themecheck.CodeAssetUndecoded {
+				found = true
+				assert.Contains(t, f.Message, "assets/enemies/burger.png", "継承先で置き換えたファイルを調べる")
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("ChecksArchives", func(t *testing.T) {
+		var archive bytes.Buffer
+		w := pack.NewWriter(&archive)
+		require.NoError(t, w.AddFS(gymFS(t), "gym", ""))
+		require.NoError(t, w.Close())
+
+		r := themecheck.Check(fstest.MapFS{"gym.mdpk": {Data: archive.Bytes()}}, "gym", themecheck.Options{})
+		assert.True(t, r.OK(), codes(r))
+		assert.Equal(t, 4, r.Assets)
+
+		broken := archive.Bytes()
+		broken[len(broken)-1] = 0
+		r = themecheck.Check(fstest.MapFS{"gym.mdpk": {Data: broken}}, "gym", themecheck.Options{})
+		assert.Equal(t, []string{pack.CodeInvalidPack}, codes(r))
+	})
+
+	t.Run("ReportsDependencyErrors", func(t *testing.T) {
+		fsys := gymFS(t)
+		fsys["derived/theme.yaml"].Data = []byte("metadata: {id: derived, name: D, version: 1.0.0, dependencies: [gym >=2.0]}\n")
+		r := themecheck.Check(fsys, "derived", themecheck.Options{})
+		require.Len(t, r.Findings, 1)
+		assert.Equal(t, theme.CodeUnsatisfied, r.Findings[0].Code)
+	})
+
+	t.Run("ReportsDuplicateIDs", func(t *testing.T) {
+		fsys := gymFS(t)
+		dup := strings.Replace(gymTheme, "    - id: gym\n      name: Gym\n", "    - id: gym\n      name: Gym\n    - id: gym\n      name: Gym2\n", 1)
+		fsys["gym/theme.yaml"].Data = []byte(dup)
+		r := themecheck.Check(fsys, "gym", themecheck.Options{})
+		assert.Contains(t, codes(r), themecheck.CodeDuplicateID)
+	})
+
+	t.Run("LintsPerformance", func(t *testing.T) {
+		fsys := gymFS(t)
+		fsys["gym/theme.yaml"].Data = []byte(strings.Replace(gymTheme, "max_enemies_on_screen: 50", "max_enemies_on_screen: 5000", 1))
+		r := themecheck.Check(fsys, "gym", themecheck.Options{})
+		assert.True(t, r.OK(), "パフォーマンスの問題は警告")
+		assert.Contains(t, codes(r), themecheck.CodePerformance)
+	})
+}
+
+func TestReportOutput(t *testing.T) {
+	fsys := gymFS(t)
+	delete(fsys, "gym/assets/fonts/main.ttf")
+	r := themecheck.Check(fsys, "gym", themecheck.Options{})
+
+	var text bytes.Buffer
+	require.NoError(t, r.WriteText(&text))
+	assert.Contains(t, text.String(), "error [THEME_ASSET_MISSING] ui.fonts.main: assets/fonts/main.ttf が見つかりません")
+	assert.Contains(t, text.String(), "gym: NG (エラー 1 件, 警告 1 件")
+
+	var out bytes.Buffer
+	require.NoError(t, themecheck.WriteJSON(&out, []*themecheck.Report{r}))
+	var decoded struct {
+		OK     bool
+		Themes []struct {
+			Theme    string
+			Findings []themecheck.Finding
+		}
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.False(t, decoded.OK)
+	require.Len(t, decoded.Themes, 1)
+	assert.Equal(t, "gym", decoded.Themes[0].Theme)
+	assert.Equal(t, r.Findings, decoded.Themes[0].Findings)
+}
@@ -0,0 +1,159 @@
+package themecheck
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 画像のデコーダを登録する
+	_ "image/png"
+	"io"
+	"io/fs"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+	"golang.org/x/image/font/opentype"
+
+	"muscle-dreamer/internal/asset/inspect"
+	"muscle-dreamer/internal/theme"
+)
+
+// limits - ゲームがテーマのアセットに適用する上限
+var limits = inspect.DefaultLimits().Theme
+
+// decode - アセットをゲームと同じ形式として最後までデコードできるか調べる。
+// ゲームの読み込みは描画・再生のデバイスを必要とするため、ここでは同じ形式の
+// デコーダで中身だけを確認する。ゲームと同じく、デコードする前にヘッダーから
+// 分かる大きさをテーマの上限と比べる。
+func decode(fsys fs.FS, p string, kind theme.AssetKind) error {
+	data, err := readFile(fsys, p)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case theme.AssetImage:
+		err = decodeImage(p, data)
+	case theme.AssetAudio:
+		err = decodeAudio(p, data)
+	case theme.AssetFont:
+		err = decodeFont(data)
+	case theme.AssetAtlas:
+		err = decodeAtlas(fsys, p, data)
+	default:
+		err = fmt.Errorf("対応していない種類のファイルです")
+	}
+	return err
+}
+
+// readFile - ファイルサイズの上限まで読み込む
+func readFile(fsys fs.FS, p string) ([]byte, error) {
+	if info, err := fs.Stat(fsys, p); err == nil {
+		if err := limits.CheckFileSize(p, info.Size()); err != nil {
+			return nil, err
+		}
+	}
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return limits.ReadLimited(p, f)
+}
+
+func imageConfig(fsys fs.FS, p string) (image.Config, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	return cfg, err
+}
+
+// decodeImage - ヘッダーの寸法を上限と比べてからデコードする
+func decodeImage(p string, data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := limits.CheckImage(p, cfg); err != nil {
+		return err
+	}
+	_, _, err = image.Decode(bytes.NewReader(data))
+	return err
+}
+
+// decodeAudio - ゲームと同じく内容から形式を判定し、長さを上限と比べてから
+// 全てのサンプルをデコードする
+func decodeAudio(p string, data []byte) error {
+	switch f := inspect.Sniff(data); f {
+	case inspect.FormatOGG:
+		r, err := oggvorbis.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if err := limits.CheckAudio(p, r.Length()*inspect.BytesPerFrame, r.SampleRate()); err != nil {
+			return err
+		}
+		// 長さを記録していないストリームもあるため、デコードした分も上限と比べる
+		buf := make([]float32, 4096)
+		var frames int64
+		for {
+			n, err := r.Read(buf)
+			frames += int64(n / r.Channels())
+			if err := limits.CheckAudio(p, frames*inspect.BytesPerFrame, r.SampleRate()); err != nil {
+				return err
+			}
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	case inspect.FormatMP3:
+		d, err := mp3.NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if err := limits.CheckAudio(p, d.Length(), d.SampleRate()); err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, d)
+		return err
+	case inspect.FormatWAV:
+		w, err := inspect.ParseWAV(data)
+		if err != nil {
+			return err
+		}
+		return limits.CheckAudio(p, w.PCMSize(), w.SampleRate)
+	case inspect.FormatUnknown:
+		return fmt.Errorf("対応していない音声形式です")
+	default:
+		return fmt.Errorf("対応していない音声形式です (%s)", f)
+	}
+}
+
+func decodeFont(data []byte) error {
+	if _, err := opentype.Parse(data); err == nil {
+		return nil
+	}
+	_, err := opentype.ParseCollection(data)
+	return err
+}
+
+// decodeAtlas - アトラス定義と、定義が参照するページ画像をデコードする
+func decodeAtlas(fsys fs.FS, p string, data []byte) error {
+	pages, err := atlasPages(data, p)
+	if err != nil {
+		return err
+	}
+	for _, page := range pages {
+		f, err := readFile(fsys, page)
+		if err != nil {
+			return fmt.Errorf("ページ %s を読み込めません: %w", page, err)
+		}
+		if err := decodeImage(page, f); err != nil {
+			return fmt.Errorf("ページ %s: %w", page, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package themecheck はテーマディレクトリを検証する。スキーマ・依存関係・ID の重複・
+// スプライトシートの切り分け・アセットの有無とデコード・パフォーマンス設定を調べ、
+// 結果を人が読める形式と JSON で出力する。
+package themecheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity - 問題の重要度
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding - 検証で見つかった1件の問題
+type Finding struct {
+	Severity   Severity `json:"severity"`
+	Code       string   `json:"code"`
+	File       string   `json:"file,omitempty"`
+	Line       int      `json:"line,omitempty"`
+	Column     int      `json:"column,omitempty"`
+	Path       string   `json:"path,omitempty"` // YAML 上の位置 (metadata.id など) またはアセットパス
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// location - "file:line:col" 形式の位置。分からない部分は省く。
+func (f Finding) location() string {
+	switch {
+	case f.File != "" && f.Line > 0:
+		return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	case f.File != "":
+		return f.File
+	default:
+		return ""
+	}
+}
+
+// Report - 1テーマの検証結果
+type Report struct {
+	Theme    string    `json:"theme"`
+	Chain    []string  `json:"chain,omitempty"` // 重ねたテーマのID (基底テーマが先)
+	Assets   int       `json:"assets_checked"`  // 存在とデコードを確認したアセット数
+	Findings []Finding `json:"findings"`
+}
+
+// Errors - エラーの件数
+func (r *Report) Errors() int {
+	return r.count(SeverityError)
+}
+
+// Warnings - 警告の件数
+func (r *Report) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+// OK - エラーがないか (警告は含めない)
+func (r *Report) OK() bool {
+	return r.Errors() == 0
+}
+
+func (r *Report) count(s Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(severity Severity, code, path, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{
+		Severity: severity,
+		Code:     code,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// WriteText - 1件1行 (修正案は次の行) の人が読める形式で書き出し、最後に件数をまとめる
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, f := range r.Findings {
+		if loc := f.location(); loc != "" {
+			fmt.Fprintf(&b, "%s: ", loc)
+		}
+		fmt.Fprintf(&b, "%s [%s] ", f.Severity, f.Code)
+		if f.Path != "" {
+			fmt.Fprintf(&b, "%s: ", f.Path)
+		}
+		b.WriteString(f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(&b, "\n    → %s", f.Suggestion)
+		}
+		b.WriteByte('\n')
+	}
+	status := "OK"
+	if !r.OK() {
+		status = "NG"
+	}
+	fmt.Fprintf(&b, "%s: %s (エラー %d 件, 警告 %d 件, アセット %d 件を確認)\n", r.Theme, status, r.Errors(), r.Warnings(), r.Assets)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON - CI 向けに JSON で書き出す
+func WriteJSON(w io.Writer, reports []*Report) error {
+	for _, r := range reports {
+		if r.Findings == nil {
+			r.Findings = []Finding{}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		OK     bool      `json:"ok"`
+		Themes []*Report `json:"themes"`
+	}{OK: allOK(reports), Themes: reports})
+}
+
+func allOK(reports []*Report) bool {
+	for _, r := range reports {
+		if !r.OK() {
+			return false
+		}
+	}
+	return true
+}
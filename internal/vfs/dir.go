@@ -0,0 +1,102 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside - ディレクトリの外を指すシンボリックリンク
+var ErrOutside = errors.New("ディレクトリの外を指すリンクです")
+
+// Dir - OSのディレクトリを書き込み可能なレイヤーとして扱う。
+// シンボリックリンクはディレクトリの中を指すものだけを辿る。
+type Dir string
+
+// Open - fs.FS の実装
+func (d Dir) Open(name string) (fs.File, error) {
+	real, err := d.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.DirFS(string(d)).Open(real)
+}
+
+// WriteFile - 親ディレクトリを作成してファイルを書き込む
+func (d Dir) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path, err := d.join("write", name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// Remove - ファイルを削除する
+func (d Dir) Remove(name string) error {
+	path, err := d.join("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Resolve - ResolveFS の実装
+func (d Dir) Resolve(name string) (string, error) {
+	return d.resolve("resolve", name)
+}
+
+// resolve - 存在する最も深い親までシンボリックリンクを辿り、ディレクトリ内のパスを返す。
+// まだ存在しない残りの要素はそのまま付け足す。リンクがディレクトリの外を指す場合や、
+// リンク先が存在しない場合は ErrOutside を返す。
+func (d Dir) resolve(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	root, err := filepath.EvalSymlinks(string(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		// まだ作られていないディレクトリにはリンクもない
+		return name, nil
+	}
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	existing, rest := filepath.Join(root, filepath.FromSlash(name)), "."
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = real
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		// リンク先のないリンクに書き込むと、リンクの指す外の場所にファイルができる
+		if info, err := os.Lstat(existing); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", &fs.PathError{Op: op, Path: name, Err: ErrOutside}
+		}
+		rest = path.Join(filepath.Base(existing), rest)
+		existing = filepath.Dir(existing)
+	}
+	rel, err := filepath.Rel(root, existing)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: name, Err: ErrOutside}
+	}
+	return path.Join(filepath.ToSlash(rel), rest), nil
+}
+
+func (d Dir) join(op, name string) (string, error) {
+	if name == "." {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	real, err := d.resolve(op, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(string(d), filepath.FromSlash(real)), nil
+}
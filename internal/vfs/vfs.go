@@ -0,0 +1,473 @@
+// Package vfs はゲーム本体・テーマ・MODのファイルを優先度付きで重ね合わせる
+// 仮想ファイルシステムを提供する。
+//
+// 各レイヤーはマウントポイントに io/fs.FS としてマウントされ、同じパスのファイルは
+// 優先度の高いレイヤーのものが使われる。アクセスできるパスはルール (許可された
+// トップレベルディレクトリ) で制限され、書き込みは書き込み可能としてマウントされた
+// レイヤーにだけ許される。
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRoots - アセットとして読み込みを許可するトップレベルディレクトリ
+var DefaultRoots = []string{"assets", "themes"}
+
+// ErrNotAllowed - マウントルールで許可されていないパス
+var ErrNotAllowed = errors.New("マウントルールで許可されていないパスです")
+
+// 標準レイヤーの優先度。同じ優先度ではあとからマウントしたものが優先される。
+const (
+	PriorityBase  = 0
+	PriorityTheme = 100
+	PriorityMod   = 200
+)
+
+// WriteFS - 書き込み可能なファイルシステム
+type WriteFS interface {
+	fs.FS
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Remove(name string) error
+}
+
+// ResolveFS - シンボリックリンクを辿れるファイルシステム
+type ResolveFS interface {
+	fs.FS
+	// Resolve - リンクを辿った後のパス。ファイルシステムの外を指す場合はエラーを返す。
+	Resolve(name string) (string, error)
+}
+
+// MountOptions - マウント設定
+type MountOptions struct {
+	Point    string // マウントポイント ("" はルート)
+	Priority int
+	Writable bool // false の場合は読み取り専用
+}
+
+// MountInfo - マウント済みレイヤーの情報
+type MountInfo struct {
+	Name string
+	MountOptions
+}
+
+type mount struct {
+	MountInfo
+	fsys fs.FS
+	seq  int
+}
+
+// covers - name がこのマウントポイント以下であれば、レイヤー内の相対パスを返す
+func (m *mount) covers(name string) (string, bool) {
+	switch {
+	case m.Point == "":
+		return name, true
+	case name == m.Point:
+		return ".", true
+	case strings.HasPrefix(name, m.Point+"/"):
+		return name[len(m.Point)+1:], true
+	}
+	return "", false
+}
+
+// FS - レイヤーを重ね合わせた仮想ファイルシステム。複数のゴルーチンから使用できる。
+type FS struct {
+	mu     sync.RWMutex
+	roots  []string
+	mounts []*mount // 優先度の高い順
+	seq    int
+}
+
+// New - roots 以下のパスだけを公開する仮想ファイルシステムを作成する。
+// roots が空の場合は全てのパスを公開する。
+func New(roots ...string) *FS {
+	return &FS{roots: roots}
+}
+
+// Mount - レイヤーをマウントする
+func (v *FS) Mount(name string, fsys fs.FS, opts MountOptions) error {
+	if opts.Point != "" && !fs.ValidPath(opts.Point) {
+		return fmt.Errorf("不正なマウントポイントです: %q", opts.Point)
+	}
+	if opts.Writable {
+		if _, ok := fsys.(WriteFS); !ok {
+			return fmt.Errorf("%s は書き込みに対応していません", name)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, m := range v.mounts {
+		if m.Name == name {
+			return fmt.Errorf("%s は既にマウントされています", name)
+		}
+	}
+	v.seq++
+	v.mounts = append(v.mounts, &mount{MountInfo: MountInfo{Name: name, MountOptions: opts}, fsys: fsys, seq: v.seq})
+	sort.SliceStable(v.mounts, func(i, j int) bool {
+		a, b := v.mounts[i], v.mounts[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.seq > b.seq
+	})
+	return nil
+}
+
+// Unmount - レイヤーを取り外す。存在しなければ false を返す。
+func (v *FS) Unmount(name string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, m := range v.mounts {
+		if m.Name == name {
+			v.mounts = append(v.mounts[:i], v.mounts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Mounts - マウント済みのレイヤー (優先度の高い順)
+func (v *FS) Mounts() []MountInfo {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	infos := make([]MountInfo, len(v.mounts))
+	for i, m := range v.mounts {
+		infos[i] = m.MountInfo
+	}
+	return infos
+}
+
+// Which - name を提供しているレイヤーの名前
+func (v *FS) Which(name string) (string, error) {
+	info, err := v.Lookup(name)
+	return info.Name, err
+}
+
+// Lookup - name を提供しているレイヤーの情報。優先度から読み込み元の種類が分かる。
+func (v *FS) Lookup(name string) (MountInfo, error) {
+	var info MountInfo
+	err := v.resolve("lookup", name, func(m *mount, rel string) error {
+		if _, err := fs.Stat(m.fsys, rel); err != nil {
+			return err
+		}
+		info = m.MountInfo
+		return nil
+	})
+	return info, err
+}
+
+// Resolve - ResolveFS の実装。name を提供するレイヤー、なければ書き込み先のレイヤーで
+// シンボリックリンクを辿った仮想パスを返す。どのレイヤーにもなければ name をそのまま返す。
+func (v *FS) Resolve(name string) (string, error) {
+	var real string
+	err := v.resolve("resolve", name, func(m *mount, rel string) error {
+		if _, err := fs.Stat(m.fsys, rel); err != nil {
+			return err
+		}
+		r, err := m.resolveLink(rel)
+		real = r
+		return err
+	})
+	if !errors.Is(err, fs.ErrNotExist) {
+		return real, err
+	}
+	m, rel, err := v.writable("resolve", name)
+	if err != nil {
+		return name, nil
+	}
+	return m.resolveLink(rel)
+}
+
+// resolveLink - レイヤー内のパスのリンクを辿り、マウントポイントを付けた仮想パスにする
+func (m *mount) resolveLink(rel string) (string, error) {
+	if r, ok := m.fsys.(ResolveFS); ok {
+		var err error
+		if rel, err = r.Resolve(rel); err != nil {
+			return "", err
+		}
+	}
+	return path.Join(m.Point, rel), nil
+}
+
+// Open - fs.FS の実装。ディレクトリは全レイヤーの内容を合成して返す。
+func (v *FS) Open(name string) (fs.File, error) {
+	info, err := v.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: unwrap(err)}
+	}
+	if info.IsDir() {
+		entries, err := v.ReadDir(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: unwrap(err)}
+		}
+		return &dir{info: info, entries: entries}, nil
+	}
+
+	var file fs.File
+	err = v.resolve("open", name, func(m *mount, rel string) error {
+		f, err := m.fsys.Open(rel)
+		file = f
+		return err
+	})
+	return file, err
+}
+
+// ReadFile - fs.ReadFileFS の実装
+func (v *FS) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := v.resolve("read", name, func(m *mount, rel string) error {
+		d, err := fs.ReadFile(m.fsys, rel)
+		data = d
+		return err
+	})
+	return data, err
+}
+
+// Stat - fs.StatFS の実装
+func (v *FS) Stat(name string) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := v.resolve("stat", name, func(m *mount, rel string) error {
+		i, err := fs.Stat(m.fsys, rel)
+		info = i
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) && v.isMountParent(name) {
+		return syntheticDir(name), nil
+	}
+	return info, err
+}
+
+// ReadDir - fs.ReadDirFS の実装。同名のエントリは優先度の高いレイヤーのものを使う。
+func (v *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := v.check("readdir", name); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	seen := make(map[string]fs.DirEntry)
+	found := false
+	for _, m := range v.mounts {
+		if rel, ok := m.covers(name); ok {
+			entries, err := fs.ReadDir(m.fsys, rel)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, &fs.PathError{Op: "readdir", Path: name, Err: unwrap(err)}
+			}
+			found = true
+			for _, e := range entries {
+				if _, dup := seen[e.Name()]; !dup {
+					seen[e.Name()] = e
+				}
+			}
+		}
+		// name より深いマウントポイントは途中のディレクトリとして見せる
+		if child, ok := childOf(name, m.Point); ok {
+			found = true
+			if _, dup := seen[child]; !dup {
+				seen[child] = fs.FileInfoToDirEntry(syntheticDir(child))
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(seen))
+	for entryName, e := range seen {
+		if v.allowed(path.Join(name, entryName)) || v.isRootParent(path.Join(name, entryName)) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// WriteFile - name を提供する最優先のレイヤーへ書き込む。そのレイヤーが
+// 読み取り専用であれば fs.ErrPermission を返す。
+func (v *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m, rel, err := v.writable("write", name)
+	if err != nil {
+		return err
+	}
+	return m.fsys.(WriteFS).WriteFile(rel, data, perm)
+}
+
+// Remove - name を最優先のレイヤーから削除する
+func (v *FS) Remove(name string) error {
+	m, rel, err := v.writable("remove", name)
+	if err != nil {
+		return err
+	}
+	return m.fsys.(WriteFS).Remove(rel)
+}
+
+// writable - 書き込み先となる最優先のレイヤーを探す
+func (v *FS) writable(op, name string) (*mount, string, error) {
+	if err := v.check(op, name); err != nil {
+		return nil, "", err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, m := range v.mounts {
+		rel, ok := m.covers(name)
+		if !ok {
+			continue
+		}
+		if !m.Writable {
+			return nil, "", &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("%w: %s は読み取り専用です", fs.ErrPermission, m.Name)}
+		}
+		return m, rel, nil
+	}
+	return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// resolve - 優先度の高いレイヤーから順に fn を呼び、最初に成功したところで止める。
+// ファイルが存在しない以外のエラーはその場で返す。
+func (v *FS) resolve(op, name string, fn func(m *mount, rel string) error) error {
+	if err := v.check(op, name); err != nil {
+		return err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, m := range v.mounts {
+		rel, ok := m.covers(name)
+		if !ok {
+			continue
+		}
+		err := fn(m, rel)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return &fs.PathError{Op: op, Path: name, Err: unwrap(err)}
+		}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// check - パスの形式とマウントルールを確認する
+func (v *FS) check(op, name string) error {
+	if !fs.ValidPath(name) || strings.ContainsAny(name, "\\:\x00") {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if !v.allowed(name) && !v.isRootParent(name) {
+		return &fs.PathError{Op: op, Path: name, Err: ErrNotAllowed}
+	}
+	return nil
+}
+
+// allowed - name が許可されたディレクトリ以下にあるか
+func (v *FS) allowed(name string) bool {
+	if len(v.roots) == 0 {
+		return true
+	}
+	for _, root := range v.roots {
+		if name == root || strings.HasPrefix(name, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isRootParent - name が許可されたディレクトリの親 (ルートなど) か
+func (v *FS) isRootParent(name string) bool {
+	if name == "." {
+		return true
+	}
+	for _, root := range v.roots {
+		if strings.HasPrefix(root, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isMountParent - name がいずれかのマウントポイントの途中のディレクトリか
+func (v *FS) isMountParent(name string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, m := range v.mounts {
+		if m.Point == name && m.Point != "" {
+			return true
+		}
+		if _, ok := childOf(name, m.Point); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// childOf - point が dir より深い場合、dir 直下の要素名を返す
+func childOf(dir, point string) (string, bool) {
+	if point == "" {
+		return "", false
+	}
+	rest := point
+	if dir != "." {
+		if !strings.HasPrefix(point, dir+"/") {
+			return "", false
+		}
+		rest = point[len(dir)+1:]
+	}
+	child, _, _ := strings.Cut(rest, "/")
+	return child, true
+}
+
+// unwrap - レイヤー内のパスを含む PathError を外し、仮想パスで包み直せるようにする
+func unwrap(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// syntheticDir - マウントポイントの途中にある実体のないディレクトリ
+type syntheticDir string
+
+func (d syntheticDir) Name() string       { return path.Base(string(d)) }
+func (d syntheticDir) Size() int64        { return 0 }
+func (d syntheticDir) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (d syntheticDir) ModTime() time.Time { return time.Time{} }
+func (d syntheticDir) IsDir() bool        { return true }
+func (d syntheticDir) Sys() interface{}   { return nil }
+
+// dir - 合成したディレクトリを fs.ReadDirFile として開いたもの
+type dir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("ディレクトリです")}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,214 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"muscle-dreamer/internal/vfs"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+// layered - ゲーム本体・テーマ・MODを重ねた仮想ファイルシステム
+func layered(t *testing.T) *vfs.FS {
+	t.Helper()
+	v := vfs.New(vfs.DefaultRoots...)
+	require.NoError(t, v.Mount("base", fstest.MapFS{
+		"assets/characters/player_idle.png": file("base idle"),
+		"assets/characters/player_walk.png": file("base walk"),
+		"assets/ui/health_bar.png":          file("base bar"),
+		"config/secrets.yaml":               file("secret"),
+	}, vfs.MountOptions{Priority: vfs.PriorityBase}))
+	require.NoError(t, v.Mount("theme:beach", fstest.MapFS{
+		"assets/characters/player_idle.png": file("beach idle"),
+		"assets/ui/health_bar.png":          file("beach bar"),
+		"theme.yaml":                        file("metadata: {}"),
+	}, vfs.MountOptions{Priority: vfs.PriorityTheme}))
+	require.NoError(t, v.Mount("mod:zombie", fstest.MapFS{
+		"assets/ui/health_bar.png": file("zombie bar"),
+	}, vfs.MountOptions{Priority: vfs.PriorityMod}))
+	return v
+}
+
+func TestFS(t *testing.T) {
+	t.Run("HigherPriorityLayerWins", func(t *testing.T) {
+		v := layered(t)
+
+		testCases := []struct {
+			path    string
+			content string
+			layer   string
+		}{
+			{"assets/characters/player_idle.png", "beach idle", "theme:beach"},
+			{"assets/characters/player_walk.png", "base walk", "base"},
+			{"assets/ui/health_bar.png", "zombie bar", "mod:zombie"},
+		}
+		for _, tc := range testCases {
+			data, err := v.ReadFile(tc.path)
+			require.NoError(t, err, tc.path)
+			assert.Equal(t, tc.content, string(data))
+
+			layer, err := v.Which(tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.layer, layer)
+		}
+
+		info, err := v.Lookup("assets/ui/health_bar.png")
+		require.NoError(t, err)
+		assert.Equal(t, vfs.PriorityMod, info.Priority)
+	})
+
+	t.Run("SamePriorityLaterMountWins", func(t *testing.T) {
+		v := vfs.New()
+		require.NoError(t, v.Mount("mod:a", fstest.MapFS{"assets/x.png": file("a")}, vfs.MountOptions{Priority: vfs.PriorityMod}))
+		require.NoError(t, v.Mount("mod:b", fstest.MapFS{"assets/x.png": file("b")}, vfs.MountOptions{Priority: vfs.PriorityMod}))
+
+		layer, err := v.Which("assets/x.png")
+		require.NoError(t, err)
+		assert.Equal(t, "mod:b", layer)
+	})
+
+	t.Run("UnmountRevealsLowerLayer", func(t *testing.T) {
+		v := layered(t)
+		require.True(t, v.Unmount("theme:beach"))
+
+		data, err := v.ReadFile("assets/characters/player_idle.png")
+		require.NoError(t, err)
+		assert.Equal(t, "base idle", string(data))
+		assert.False(t, v.Unmount("theme:beach"))
+	})
+
+	t.Run("MountRulesRejectPaths", func(t *testing.T) {
+		v := layered(t)
+
+		testCases := []struct {
+			path string
+			err  error
+		}{
+			{"config/secrets.yaml", vfs.ErrNotAllowed},
+			{"theme.yaml", vfs.ErrNotAllowed},
+			{"/etc/passwd", fs.ErrInvalid},
+			{"../sensitive/data.txt", fs.ErrInvalid},
+			{"assets/../../etc/shadow", fs.ErrInvalid},
+			{"C:\\Windows\\System32\\config\\sam", fs.ErrInvalid},
+			{"assets/\x00malicious.png", fs.ErrInvalid},
+		}
+		for _, tc := range testCases {
+			_, err := v.ReadFile(tc.path)
+			assert.ErrorIs(t, err, tc.err, tc.path)
+		}
+	})
+
+	t.Run("MountPointPrefixesLayer", func(t *testing.T) {
+		v := vfs.New(vfs.DefaultRoots...)
+		require.NoError(t, v.Mount("theme:beach", fstest.MapFS{"theme.yaml": file("beach")}, vfs.MountOptions{Point: "themes/beach"}))
+
+		data, err := v.ReadFile("themes/beach/theme.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "beach", string(data))
+
+		entries, err := v.ReadDir("themes")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "beach", entries[0].Name())
+		assert.True(t, entries[0].IsDir())
+	})
+
+	t.Run("DirectoriesAreMerged", func(t *testing.T) {
+		v := layered(t)
+
+		entries, err := v.ReadDir(".")
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal(t, []string{"assets"}, names, "許可されていないディレクトリは見せない")
+
+		var files []string
+		require.NoError(t, fs.WalkDir(v, "assets", func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		}))
+		assert.Equal(t, []string{
+			"assets/characters/player_idle.png",
+			"assets/characters/player_walk.png",
+			"assets/ui/health_bar.png",
+		}, files)
+	})
+
+	t.Run("ReadOnlyLayersRejectWrites", func(t *testing.T) {
+		v := layered(t)
+		err := v.WriteFile("assets/ui/health_bar.png", []byte("x"), 0o644)
+		assert.ErrorIs(t, err, fs.ErrPermission)
+		assert.Error(t, v.Mount("rw", fstest.MapFS{}, vfs.MountOptions{Writable: true}), "書き込みに対応しないFSは書き込み可能にできない")
+	})
+
+	t.Run("WritableDirLayer", func(t *testing.T) {
+		dir := t.TempDir()
+		v := layered(t)
+		require.NoError(t, v.Mount("user", vfs.Dir(dir), vfs.MountOptions{Point: "assets/user", Priority: vfs.PriorityMod + 1, Writable: true}))
+
+		require.NoError(t, v.WriteFile("assets/user/screenshots/1.png", []byte("png"), 0o644))
+		data, err := os.ReadFile(filepath.Join(dir, "screenshots", "1.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "png", string(data))
+
+		layer, err := v.Which("assets/user/screenshots/1.png")
+		require.NoError(t, err)
+		assert.Equal(t, "user", layer)
+
+		require.NoError(t, v.Remove("assets/user/screenshots/1.png"))
+		_, err = v.Stat("assets/user/screenshots/1.png")
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("DirResolvesLinksInside", func(t *testing.T) {
+		dir, outside := t.TempDir(), t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "saves"), 0o755))
+		if err := os.Symlink("saves", filepath.Join(dir, "latest")); err != nil {
+			t.Skipf("シンボリックリンクを作れません: %v", err)
+		}
+		require.NoError(t, os.Symlink(outside, filepath.Join(dir, "escape")))
+		require.NoError(t, os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(dir, "dangling")))
+
+		v := vfs.New()
+		require.NoError(t, v.Mount("user", vfs.Dir(dir), vfs.MountOptions{Point: "user", Writable: true}))
+
+		real, err := v.Resolve("user/latest/1.sav")
+		require.NoError(t, err)
+		assert.Equal(t, "user/saves/1.sav", real, "まだないファイルは書き込み先のレイヤーで辿る")
+		require.NoError(t, v.WriteFile("user/latest/1.sav", []byte("save"), 0o644))
+		real, err = v.Resolve("user/latest/1.sav")
+		require.NoError(t, err)
+		assert.Equal(t, "user/saves/1.sav", real)
+
+		_, err = v.Resolve("user/escape/secret.txt")
+		assert.ErrorIs(t, err, vfs.ErrOutside)
+		_, err = v.ReadFile("user/escape/secret.txt")
+		assert.ErrorIs(t, err, vfs.ErrOutside)
+		assert.ErrorIs(t, v.WriteFile("user/dangling", []byte("x"), 0o644), vfs.ErrOutside)
+		_, err = os.Stat(filepath.Join(outside, "new.txt"))
+		assert.True(t, errors.Is(err, fs.ErrNotExist), "リンク先に書き込まない")
+	})
+
+	t.Run("ImplementsFSContract", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(layered(t),
+			"assets/characters/player_idle.png",
+			"assets/characters/player_walk.png",
+			"assets/ui/health_bar.png",
+		))
+	})
+}